@@ -0,0 +1,407 @@
+// Package autorule implements the rule-based auto tagging / categorization engine used by "ptool autorule".
+// Rules are defined in "autorules" of ptool.toml, see config.AutoruleConfigStruct.
+package autorule
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/site/public"
+	"github.com/sagan/ptool/site/tpl"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/expr"
+)
+
+// A compiled rule.
+type Rule struct {
+	*config.AutoruleConfigStruct
+	Name               string // rule name. Rules without name in config are named by position, e.g. "#1"
+	nameRegex          *regexp.Regexp
+	where              *expr.Program
+	minSize            int64 // -1 means not set
+	maxSize            int64 // -1 means not set
+	extensions         []string
+	seedingTimeLimit   int64
+	uploadSpeedLimit   int64
+	downloadSpeedLimit int64
+}
+
+// The changes to be made to a torrent by matched rules. Only fields that differ from current state are set.
+type Change struct {
+	Torrent            *client.Torrent
+	Rules              []string // names of matched rules
+	Category           string   // "none" means removing category
+	AddTags            []string
+	RemoveTags         []string
+	SavePath           string
+	RatioLimit         float64
+	SeedingTimeLimit   int64
+	UploadSpeedLimit   int64
+	DownloadSpeedLimit int64
+}
+
+type Engine struct {
+	rules []*Rule
+	// domain => sitename cache
+	domainSites map[string]string
+	// Return site name of torrent. Can be overridden in tests.
+	siteOf func(torrent *client.Torrent) string
+}
+
+func Compile(rule *config.AutoruleConfigStruct) (*Rule, error) {
+	r := &Rule{AutoruleConfigStruct: rule, Name: rule.Name, minSize: -1, maxSize: -1}
+	var err error
+	if rule.NameRegex != "" {
+		if r.nameRegex, err = regexp.Compile(rule.NameRegex); err != nil {
+			return nil, fmt.Errorf("invalid nameRegex: %w", err)
+		}
+	}
+	if r.where, err = client.CompileWhere(rule.Where); err != nil {
+		return nil, err
+	}
+	if rule.MinSize != "" {
+		if r.minSize, err = util.RAMInBytes(rule.MinSize); err != nil {
+			return nil, fmt.Errorf("invalid minSize: %w", err)
+		}
+	}
+	if rule.MaxSize != "" {
+		if r.maxSize, err = util.RAMInBytes(rule.MaxSize); err != nil {
+			return nil, fmt.Errorf("invalid maxSize: %w", err)
+		}
+	}
+	r.extensions = util.Map(rule.Extensions, func(ext string) string {
+		return "." + strings.ToLower(strings.TrimPrefix(ext, "."))
+	})
+	if rule.SeedingTimeLimit == "-1" {
+		r.seedingTimeLimit = -1
+	} else if rule.SeedingTimeLimit != "" {
+		if r.seedingTimeLimit, err = util.ParseTimeDuration(rule.SeedingTimeLimit); err != nil {
+			return nil, fmt.Errorf("invalid seedingTimeLimit: %w", err)
+		}
+	}
+	if rule.UploadSpeedLimit != "" {
+		if r.uploadSpeedLimit, err = util.RAMInBytes(rule.UploadSpeedLimit); err != nil {
+			return nil, fmt.Errorf("invalid uploadSpeedLimit: %w", err)
+		}
+	}
+	if rule.DownloadSpeedLimit != "" {
+		if r.downloadSpeedLimit, err = util.RAMInBytes(rule.DownloadSpeedLimit); err != nil {
+			return nil, fmt.Errorf("invalid downloadSpeedLimit: %w", err)
+		}
+	}
+	return r, nil
+}
+
+// Create an engine with the enabled rules that apply to client. If names is not empty, only use these rules.
+func New(clientName string, rules []*config.AutoruleConfigStruct, names []string) (*Engine, error) {
+	engine := &Engine{domainSites: map[string]string{}}
+	engine.siteOf = engine.guessSite
+	for i, rule := range rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		if len(names) > 0 {
+			if !slices.Contains(names, name) {
+				continue
+			}
+		} else if rule.Disabled {
+			continue
+		}
+		if len(rule.Clients) > 0 &&
+			!slices.Contains(config.ParseClientGroupAndOtherNames(rule.Clients...), clientName) {
+			continue
+		}
+		r, err := Compile(rule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid autorule %s: %w", name, err)
+		}
+		r.Name = name
+		engine.rules = append(engine.rules, r)
+	}
+	return engine, nil
+}
+
+func (engine *Engine) Rules() []*Rule {
+	return engine.rules
+}
+
+// Match torrents against rules and return the changes needed. Torrents that need no change are not returned.
+func (engine *Engine) Plan(clientInstance client.Client, torrents []*client.Torrent) ([]*Change, error) {
+	var changes []*Change
+	now := util.Now()
+	for _, torrent := range torrents {
+		var matched []*Rule
+		var contentFiles []*client.TorrentContentFile
+		for _, rule := range engine.rules {
+			if len(rule.extensions) > 0 && contentFiles == nil {
+				files, err := clientInstance.GetTorrentContents(torrent.InfoHash)
+				if err != nil {
+					return nil, fmt.Errorf("failed to get torrent %s contents: %w", torrent.InfoHash, err)
+				}
+				contentFiles = files
+			}
+			ok, err := engine.match(rule, torrent, contentFiles, now)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: %w", rule.Name, err)
+			}
+			if !ok {
+				continue
+			}
+			matched = append(matched, rule)
+			if rule.Final {
+				break
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		if change := buildChange(torrent, matched); change != nil {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func (engine *Engine) match(rule *Rule, torrent *client.Torrent, contentFiles []*client.TorrentContentFile,
+	now int64) (bool, error) {
+	if len(rule.Sites) > 0 && !slices.Contains(rule.Sites, engine.siteOf(torrent)) {
+		return false, nil
+	}
+	if len(rule.Trackers) > 0 && !slices.ContainsFunc(rule.Trackers, func(domain string) bool {
+		return torrent.TrackerDomain == domain || strings.HasSuffix(torrent.TrackerDomain, "."+domain)
+	}) {
+		return false, nil
+	}
+	if rule.nameRegex != nil && !rule.nameRegex.MatchString(torrent.Name) {
+		return false, nil
+	}
+	if rule.minSize >= 0 && torrent.Size < rule.minSize {
+		return false, nil
+	}
+	if rule.maxSize >= 0 && torrent.Size > rule.maxSize {
+		return false, nil
+	}
+	if len(rule.extensions) > 0 && !slices.ContainsFunc(contentFiles, func(file *client.TorrentContentFile) bool {
+		return slices.Contains(rule.extensions, strings.ToLower(path.Ext(file.Path)))
+	}) {
+		return false, nil
+	}
+	if len(rule.SavePaths) > 0 && !slices.ContainsFunc(rule.SavePaths, func(savePath string) bool {
+		return isSubPath(savePath, torrent.SavePath)
+	}) {
+		return false, nil
+	}
+	if rule.where != nil {
+		return rule.where.EvalBool(torrent.WhereEnv(now))
+	}
+	return true, nil
+}
+
+// Merge actions of matched rules and compare them with current state of torrent. Return nil if nothing changes.
+func buildChange(torrent *client.Torrent, rules []*Rule) *Change {
+	change := &Change{Torrent: torrent}
+	category := ""
+	savePath := ""
+	ratioLimit := float64(0)
+	seedingTimeLimit := int64(0)
+	uploadSpeedLimit := int64(0)
+	downloadSpeedLimit := int64(0)
+	var addTags, removeTags []string
+	for _, rule := range rules {
+		change.Rules = append(change.Rules, rule.Name)
+		if rule.SetCategory != "" {
+			category = rule.SetCategory
+		}
+		if rule.SetSavePath != "" {
+			savePath = rule.SetSavePath
+		}
+		if rule.RatioLimit != 0 {
+			ratioLimit = rule.RatioLimit
+		}
+		if rule.seedingTimeLimit != 0 {
+			seedingTimeLimit = rule.seedingTimeLimit
+		}
+		if rule.uploadSpeedLimit != 0 {
+			uploadSpeedLimit = rule.uploadSpeedLimit
+		}
+		if rule.downloadSpeedLimit != 0 {
+			downloadSpeedLimit = rule.downloadSpeedLimit
+		}
+		// a later rule may re-add a tag removed by an earlier rule, and vice versa
+		for _, tag := range rule.AddTags {
+			removeTags = slices.DeleteFunc(removeTags, func(t string) bool { return t == tag })
+			addTags = append(addTags, tag)
+		}
+		for _, tag := range rule.RemoveTags {
+			addTags = slices.DeleteFunc(addTags, func(t string) bool { return t == tag })
+			removeTags = append(removeTags, tag)
+		}
+	}
+	changed := false
+	if category != "" && category != torrent.Category && !(category == constants.NONE && torrent.Category == "") {
+		change.Category = category
+		changed = true
+	}
+	change.AddTags = util.UniqueSlice(util.Filter(addTags, func(tag string) bool { return !torrent.HasTag(tag) }))
+	change.RemoveTags = util.UniqueSlice(util.Filter(removeTags, torrent.HasTag))
+	if len(change.AddTags) > 0 || len(change.RemoveTags) > 0 {
+		changed = true
+	}
+	if savePath != "" && path.Clean(util.ToSlash(savePath)) != path.Clean(util.ToSlash(torrent.SavePath)) {
+		change.SavePath = savePath
+		changed = true
+	}
+	if (ratioLimit != 0 && ratioLimit != torrent.RatioLimit) ||
+		(seedingTimeLimit != 0 && !seedingTimeLimitEqual(seedingTimeLimit, torrent.SeedingTimeLimit)) {
+		// share limits are set together, keep the current value of the one not set by rules
+		change.RatioLimit = ratioLimit
+		if ratioLimit == 0 {
+			change.RatioLimit = torrent.RatioLimit
+		}
+		change.SeedingTimeLimit = seedingTimeLimit
+		if seedingTimeLimit == 0 {
+			change.SeedingTimeLimit = torrent.SeedingTimeLimit
+		}
+		changed = true
+	}
+	if uploadSpeedLimit != 0 && !speedLimitEqual(uploadSpeedLimit, torrent.UploadedSpeedLimit) {
+		change.UploadSpeedLimit = uploadSpeedLimit
+		changed = true
+	}
+	if downloadSpeedLimit != 0 && !speedLimitEqual(downloadSpeedLimit, torrent.DownloadSpeedLimit) {
+		change.DownloadSpeedLimit = downloadSpeedLimit
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return change
+}
+
+// Clients store seeding time limit in minutes, so compare it at minute granularity.
+func seedingTimeLimitEqual(limit int64, current int64) bool {
+	if limit < 0 || current < 0 {
+		return limit == current
+	}
+	return (limit+59)/60 == (current+59)/60
+}
+
+// Different clients use 0 or -1 as "no limit" of torrent speed limit.
+func speedLimitEqual(limit int64, current int64) bool {
+	if limit < 0 {
+		return current <= 0
+	}
+	return limit == current
+}
+
+// Apply change to torrent in client.
+func Apply(clientInstance client.Client, change *Change) error {
+	infoHashes := []string{change.Torrent.InfoHash}
+	if change.Category != "" {
+		if err := clientInstance.SetTorrentsCatetory(infoHashes, change.Category); err != nil {
+			return fmt.Errorf("failed to set category: %w", err)
+		}
+	}
+	if len(change.AddTags) > 0 {
+		if err := clientInstance.AddTagsToTorrents(infoHashes, change.AddTags); err != nil {
+			return fmt.Errorf("failed to add tags: %w", err)
+		}
+	}
+	if len(change.RemoveTags) > 0 {
+		if err := clientInstance.RemoveTagsFromTorrents(infoHashes, change.RemoveTags); err != nil {
+			return fmt.Errorf("failed to remove tags: %w", err)
+		}
+	}
+	if change.SavePath != "" {
+		if err := clientInstance.SetTorrentsSavePath(infoHashes, change.SavePath); err != nil {
+			return fmt.Errorf("failed to set save path: %w", err)
+		}
+	}
+	if change.RatioLimit != 0 || change.SeedingTimeLimit != 0 {
+		err := clientInstance.SetTorrentsShareLimits(infoHashes, change.RatioLimit, change.SeedingTimeLimit)
+		if err != nil {
+			return fmt.Errorf("failed to set share limits: %w", err)
+		}
+	}
+	if change.UploadSpeedLimit != 0 || change.DownloadSpeedLimit != 0 {
+		err := clientInstance.ModifyTorrent(change.Torrent.InfoHash, &client.TorrentOption{
+			UploadSpeedLimit:   change.UploadSpeedLimit,
+			DownloadSpeedLimit: change.DownloadSpeedLimit,
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to set speed limits: %w", err)
+		}
+	}
+	return nil
+}
+
+// Return a human-readable summary of change. E.g. `category="movies" addTags=[foo]`.
+func (change *Change) String() string {
+	var parts []string
+	if change.Category != "" {
+		parts = append(parts, fmt.Sprintf("category=%q", change.Category))
+	}
+	if len(change.AddTags) > 0 {
+		parts = append(parts, fmt.Sprintf("addTags=%v", change.AddTags))
+	}
+	if len(change.RemoveTags) > 0 {
+		parts = append(parts, fmt.Sprintf("removeTags=%v", change.RemoveTags))
+	}
+	if change.SavePath != "" {
+		parts = append(parts, fmt.Sprintf("savePath=%q", change.SavePath))
+	}
+	if change.RatioLimit != 0 {
+		parts = append(parts, fmt.Sprintf("ratioLimit=%g", change.RatioLimit))
+	}
+	if change.SeedingTimeLimit != 0 {
+		parts = append(parts, fmt.Sprintf("seedingTimeLimit=%d", change.SeedingTimeLimit))
+	}
+	if change.UploadSpeedLimit != 0 {
+		parts = append(parts, fmt.Sprintf("uploadSpeedLimit=%d", change.UploadSpeedLimit))
+	}
+	if change.DownloadSpeedLimit != 0 {
+		parts = append(parts, fmt.Sprintf("downloadSpeedLimit=%d", change.DownloadSpeedLimit))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Return true if p is parent or a sub path of parent.
+func isSubPath(parent string, p string) bool {
+	parent = path.Clean(util.ToSlash(parent))
+	p = path.Clean(util.ToSlash(p))
+	return p == parent || strings.HasPrefix(p, strings.TrimSuffix(parent, "/")+"/")
+}
+
+// Return the site of torrent, using it's "site:xxx" tag, or guessing by it's tracker domain.
+func (engine *Engine) guessSite(torrent *client.Torrent) string {
+	if sitename := torrent.GetSiteFromTag(); sitename != "" {
+		return sitename
+	}
+	domain := torrent.TrackerBaseDomain
+	if domain == "" {
+		return ""
+	}
+	if sitename, ok := engine.domainSites[domain]; ok {
+		return sitename
+	}
+	sitename, err := tpl.GuessSiteByDomain(domain, "")
+	if err != nil {
+		log.Debugf("Failed to guess site of %s: %v", domain, err)
+	}
+	if sitename == "" {
+		if site := public.GetSiteByDomain(domain); site != nil {
+			sitename = site.Name
+		}
+	}
+	engine.domainSites[domain] = sitename
+	return sitename
+}
@@ -0,0 +1,120 @@
+package autorule
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/client/clienttest"
+	"github.com/sagan/ptool/config"
+)
+
+func TestPlan(t *testing.T) {
+	rules := []*config.AutoruleConfigStruct{
+		{Name: "mteam", Sites: []string{"mteam"}, AddTags: []string{"pt"}, RatioLimit: 2},
+		{Name: "video", Extensions: []string{"MKV"}, MinSize: "1GiB", SetCategory: "movies",
+			SetSavePath: "/downloads/movies", Final: true},
+		{Name: "small", Where: `size < 1GiB`, SetCategory: "small", RemoveTags: []string{"pt"}},
+		{Name: "disabled", Disabled: true, SetCategory: "disabled"},
+		{Name: "other-client", Clients: []string{"remote"}, SetCategory: "remote"},
+	}
+	engine, err := New("local", rules, nil)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	if len(engine.Rules()) != 3 {
+		t.Fatalf("engine has %d rules, want 3", len(engine.Rules()))
+	}
+	engine.siteOf = func(torrent *client.Torrent) string { return torrent.GetSiteFromTag() }
+	torrents := []*client.Torrent{
+		// matches "mteam" & "video" (final)
+		{InfoHash: "a", Name: "Movie", Size: 10 << 30, Tags: []string{"site:mteam"}, SavePath: "/downloads"},
+		// matches "mteam" & "small", which removes the "pt" tag added by "mteam"
+		{InfoHash: "b", Name: "Small", Size: 1 << 20, Tags: []string{"site:mteam", "pt"}, RatioLimit: 2},
+		// matches "small", but already in that state
+		{InfoHash: "c", Name: "Done", Size: 1 << 20, Category: "small"},
+	}
+	clientInstance := clienttest.New(torrents...)
+	clientInstance.Files["a"] = []*client.TorrentContentFile{{Path: "Movie/movie.mkv"}, {Index: 1, Path: "Movie/movie.nfo"}}
+	clientInstance.Files["b"] = []*client.TorrentContentFile{{Path: "small.mkv"}}
+	clientInstance.Files["c"] = []*client.TorrentContentFile{{Path: "done.txt"}}
+	changes, err := engine.Plan(clientInstance, torrents)
+	if err != nil {
+		t.Fatalf("Plan error: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("Plan() = %d changes, want 2", len(changes))
+	}
+	a := changes[0]
+	if a.Torrent.InfoHash != "a" || !slices.Equal(a.Rules, []string{"mteam", "video"}) ||
+		a.Category != "movies" || a.SavePath != "/downloads/movies" || !slices.Equal(a.AddTags, []string{"pt"}) ||
+		a.RatioLimit != 2 {
+		t.Errorf("change of a = %+v, want rules [mteam video] with their merged actions", a)
+	}
+	b := changes[1]
+	if b.Torrent.InfoHash != "b" || b.Category != "small" || len(b.AddTags) != 0 ||
+		!slices.Equal(b.RemoveTags, []string{"pt"}) || b.RatioLimit != 0 {
+		t.Errorf("change of b = %+v, want category=small removeTags=[pt]", b)
+	}
+}
+
+func TestRuleName(t *testing.T) {
+	rules := []*config.AutoruleConfigStruct{{SetCategory: "a"}, {Name: "b", SetCategory: "b"}}
+	engine, err := New("local", rules, []string{"#1"})
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	if len(engine.Rules()) != 1 || engine.Rules()[0].Name != "#1" {
+		t.Errorf("engine rules = %v, want the unnamed rule #1", engine.Rules())
+	}
+	if rules[0].Name != "" {
+		t.Errorf("rule config name is changed to %q", rules[0].Name)
+	}
+}
+
+// Clients store seeding time limit in minutes, which should not cause a change every time.
+func TestSeedingTimeLimitChange(t *testing.T) {
+	rule, err := Compile(&config.AutoruleConfigStruct{Name: "seed", SeedingTimeLimit: "1h30m10s"})
+	if err != nil {
+		t.Fatalf("Compile error: %v", err)
+	}
+	if change := buildChange(&client.Torrent{SeedingTimeLimit: 91 * 60}, []*Rule{rule}); change != nil {
+		t.Errorf("buildChange() = %v, want no change", change)
+	}
+	change := buildChange(&client.Torrent{SeedingTimeLimit: 90 * 60}, []*Rule{rule})
+	if change == nil || change.SeedingTimeLimit != 5410 {
+		t.Errorf("buildChange() = %v, want seedingTimeLimit=5410", change)
+	}
+	if change := buildChange(&client.Torrent{SeedingTimeLimit: -1}, []*Rule{rule}); change == nil {
+		t.Errorf("buildChange() = nil, want a change of unlimited torrent")
+	}
+}
+
+// A rule which sets only one of the share limits should keep the other one of torrent.
+func TestShareLimitsChange(t *testing.T) {
+	ratioRule, err := Compile(&config.AutoruleConfigStruct{Name: "ratio", RatioLimit: 2})
+	if err != nil {
+		t.Fatalf("Compile error: %v", err)
+	}
+	seedRule, err := Compile(&config.AutoruleConfigStruct{Name: "seed", SeedingTimeLimit: "2h"})
+	if err != nil {
+		t.Fatalf("Compile error: %v", err)
+	}
+	change := buildChange(&client.Torrent{RatioLimit: 1, SeedingTimeLimit: 3600}, []*Rule{ratioRule})
+	if change == nil || change.RatioLimit != 2 || change.SeedingTimeLimit != 3600 {
+		t.Errorf("buildChange() = %v, want ratioLimit=2 seedingTimeLimit=3600", change)
+	}
+	change = buildChange(&client.Torrent{RatioLimit: -1, SeedingTimeLimit: 3600}, []*Rule{seedRule})
+	if change == nil || change.RatioLimit != -1 || change.SeedingTimeLimit != 7200 {
+		t.Errorf("buildChange() = %v, want ratioLimit=-1 seedingTimeLimit=7200", change)
+	}
+	clientInstance := clienttest.New(&client.Torrent{InfoHash: "a", RatioLimit: 1, SeedingTimeLimit: 3600})
+	change = buildChange(clientInstance.Torrents[0], []*Rule{ratioRule})
+	if err = Apply(clientInstance, change); err != nil {
+		t.Fatalf("Apply error: %v", err)
+	}
+	if torrent := clientInstance.Torrents[0]; torrent.RatioLimit != 2 || torrent.SeedingTimeLimit != 3600 {
+		t.Errorf("torrent share limits after Apply = %v / %d, want 2 / 3600", torrent.RatioLimit,
+			torrent.SeedingTimeLimit)
+	}
+}
@@ -0,0 +1,291 @@
+// Package backup backs up the state of a BitTorrent client (all torrents with their .torrent files and
+// metadata) to a portable zip archive, and restores it to a client of any supported type.
+//
+// The archive contains a "manifest.json" file and a "torrents/<infoHash>.torrent" file for each torrent.
+package backup
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/util"
+)
+
+const (
+	MANIFEST_FILENAME = "manifest.json"
+	TORRENTS_DIR      = "torrents"
+	MANIFEST_VERSION  = 1
+)
+
+type Manifest struct {
+	Version    int64                     `json:"version"`
+	Client     string                    `json:"client"`
+	ClientType string                    `json:"clientType"`
+	Time       int64                     `json:"time"`
+	Categories []*client.TorrentCategory `json:"categories"`
+	Torrents   []*TorrentRecord          `json:"torrents"`
+}
+
+type TorrentRecord struct {
+	InfoHash           string           `json:"infoHash"`
+	Name               string           `json:"name"`
+	SavePath           string           `json:"savePath"`
+	Category           string           `json:"category"`
+	Tags               []string         `json:"tags"`
+	Meta               map[string]int64 `json:"meta,omitempty"`
+	Trackers           []string         `json:"trackers"`
+	Paused             bool             `json:"paused"`
+	RatioLimit         float64          `json:"ratioLimit"`       // 0: use global; -1: no limit
+	SeedingTimeLimit   int64            `json:"seedingTimeLimit"` // seconds. 0: use global; -1: no limit
+	DownloadSpeedLimit int64            `json:"downloadSpeedLimit"`
+	UploadSpeedLimit   int64            `json:"uploadSpeedLimit"`
+	// file index => qBittorrent style priority. Only files with non-normal (!= 1) priority are recorded
+	FilePriorities map[int64]int64 `json:"filePriorities,omitempty"`
+	TorrentFile    string          `json:"torrentFile"` // .torrent file path in archive
+}
+
+// Backup all torrents of client and write the zip archive to writer.
+// Torrents that fail to be backed up are skipped, and the count of them is returned as error.
+func Backup(clientInstance client.Client, writer io.Writer) (manifest *Manifest, err error) {
+	torrents, err := clientInstance.GetTorrents("", "", true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client torrents: %w", err)
+	}
+	sort.Slice(torrents, func(i, j int) bool { return torrents[i].Atime < torrents[j].Atime })
+	manifest = &Manifest{
+		Version:    MANIFEST_VERSION,
+		Client:     clientInstance.GetName(),
+		ClientType: clientInstance.GetClientConfig().Type,
+		Time:       util.Now(),
+	}
+	if manifest.Categories, err = clientInstance.GetCategories(); err != nil {
+		log.Warnf("Failed to get client categories: %v", err)
+	}
+	zipWriter := zip.NewWriter(writer)
+	errorCnt := int64(0)
+	for _, torrent := range torrents {
+		record, contents, err := backupTorrent(clientInstance, torrent)
+		if err != nil {
+			log.Errorf("Failed to backup torrent %s (%s): %v", torrent.InfoHash, torrent.Name, err)
+			errorCnt++
+			continue
+		}
+		fileWriter, err := zipWriter.Create(record.TorrentFile)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = fileWriter.Write(contents); err != nil {
+			return nil, err
+		}
+		manifest.Torrents = append(manifest.Torrents, record)
+	}
+	fileWriter, err := zipWriter.Create(MANIFEST_FILENAME)
+	if err != nil {
+		return nil, err
+	}
+	encoder := json.NewEncoder(fileWriter)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(manifest); err != nil {
+		return nil, err
+	}
+	if err = zipWriter.Close(); err != nil {
+		return nil, err
+	}
+	if errorCnt > 0 {
+		return manifest, fmt.Errorf("%d torrents failed to be backed up", errorCnt)
+	}
+	return manifest, nil
+}
+
+func backupTorrent(clientInstance client.Client, torrent *client.Torrent) (*TorrentRecord, []byte, error) {
+	contents, err := clientInstance.ExportTorrentFile(torrent.InfoHash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to export torrent: %w", err)
+	}
+	trackers, err := clientInstance.GetTorrentTrackers(torrent.InfoHash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get trackers: %w", err)
+	}
+	files, err := clientInstance.GetTorrentContents(torrent.InfoHash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get contents: %w", err)
+	}
+	record := &TorrentRecord{
+		InfoHash:           torrent.InfoHash,
+		Name:               torrent.Name,
+		SavePath:           torrent.SavePath,
+		Category:           torrent.Category,
+		Tags:               torrent.Tags,
+		Meta:               torrent.Meta,
+		Trackers:           util.Map(trackers, func(t client.TorrentTracker) string { return t.Url }),
+		Paused:             torrent.State == "paused" || torrent.State == "completed",
+		RatioLimit:         torrent.RatioLimit,
+		SeedingTimeLimit:   torrent.SeedingTimeLimit,
+		DownloadSpeedLimit: max(torrent.DownloadSpeedLimit, 0),
+		UploadSpeedLimit:   max(torrent.UploadedSpeedLimit, 0),
+		TorrentFile:        TORRENTS_DIR + "/" + torrent.InfoHash + ".torrent",
+	}
+	for _, file := range files {
+		if file.Priority != 1 {
+			if record.FilePriorities == nil {
+				record.FilePriorities = map[int64]int64{}
+			}
+			record.FilePriorities[file.Index] = file.Priority
+		}
+	}
+	return record, contents, nil
+}
+
+type RestoreOption struct {
+	SavePathMapper *util.PathMapper // map save path from backup client to target client. Optional
+	DryRun         bool
+}
+
+// Restore torrents in backup archive to client. Torrents that already exist in client are skipped.
+// Torrents are added with skip checking, so the content files must already exist in (mapped) save path.
+func Restore(clientInstance client.Client, archive *zip.Reader, option *RestoreOption) error {
+	manifest, err := ReadManifest(archive)
+	if err != nil {
+		return err
+	}
+	restoreCategories(clientInstance, manifest, option)
+	errorCnt := int64(0)
+	for _, record := range manifest.Torrents {
+		if torrent, err := clientInstance.GetTorrent(record.InfoHash); err != nil {
+			log.Errorf("Failed to get torrent %s: %v", record.InfoHash, err)
+			errorCnt++
+			continue
+		} else if torrent != nil {
+			fmt.Printf("Skip torrent %s (%s): already exists in client\n", record.InfoHash, record.Name)
+			continue
+		}
+		savePath := record.SavePath
+		if option.SavePathMapper != nil {
+			mappedSavePath, match := option.SavePathMapper.Before2After(savePath)
+			if !match {
+				log.Errorf("Failed to restore torrent %s (%s): save path %q can not be mapped",
+					record.InfoHash, record.Name, savePath)
+				errorCnt++
+				continue
+			}
+			savePath = mappedSavePath
+		}
+		if option.DryRun {
+			fmt.Printf("Would restore torrent %s (%s) to %q\n", record.InfoHash, record.Name, savePath)
+			continue
+		}
+		if err := restoreTorrent(clientInstance, archive, record, savePath); err != nil {
+			log.Errorf("Failed to restore torrent %s (%s): %v", record.InfoHash, record.Name, err)
+			errorCnt++
+			continue
+		}
+		fmt.Printf("Restored torrent %s (%s) to %q\n", record.InfoHash, record.Name, savePath)
+	}
+	if errorCnt > 0 {
+		return fmt.Errorf("%d errors", errorCnt)
+	}
+	return nil
+}
+
+// Create categories that do not exist in target client. Errors are logged but ignored,
+// as some clients do not support categories (or category save path).
+func restoreCategories(clientInstance client.Client, manifest *Manifest, option *RestoreOption) {
+	if len(manifest.Categories) == 0 {
+		return
+	}
+	categories, err := clientInstance.GetCategories()
+	if err != nil {
+		log.Warnf("Failed to get client categories: %v", err)
+		return
+	}
+	for _, category := range manifest.Categories {
+		if slices.ContainsFunc(categories, func(c *client.TorrentCategory) bool { return c.Name == category.Name }) {
+			continue
+		}
+		savePath := category.SavePath
+		if savePath != "" && option.SavePathMapper != nil {
+			savePath, _ = option.SavePathMapper.Before2After(savePath)
+		}
+		if option.DryRun {
+			fmt.Printf("Would create category %q (save path %q)\n", category.Name, savePath)
+			continue
+		}
+		if err := clientInstance.MakeCategory(category.Name, savePath); err != nil {
+			log.Warnf("Failed to create category %q: %v", category.Name, err)
+		}
+	}
+}
+
+func restoreTorrent(clientInstance client.Client, archive *zip.Reader, record *TorrentRecord,
+	savePath string) error {
+	contents, err := readArchiveFile(archive, record.TorrentFile)
+	if err != nil {
+		return err
+	}
+	err = clientInstance.AddTorrent(contents, &client.TorrentOption{
+		SavePath:           savePath,
+		Category:           record.Category,
+		Tags:               record.Tags,
+		RatioLimit:         record.RatioLimit,
+		SeedingTimeLimit:   record.SeedingTimeLimit,
+		DownloadSpeedLimit: record.DownloadSpeedLimit,
+		UploadSpeedLimit:   record.UploadSpeedLimit,
+		SkipChecking:       true,
+		Pause:              record.Paused,
+	}, record.Meta)
+	if err != nil {
+		return fmt.Errorf("failed to add torrent: %w", err)
+	}
+	// group files by priority, to reduce requests
+	priorityFiles := map[int64][]int64{}
+	for index, priority := range record.FilePriorities {
+		priorityFiles[priority] = append(priorityFiles[priority], index)
+	}
+	for priority, fileIndexes := range priorityFiles {
+		slices.Sort(fileIndexes)
+		if err := clientInstance.SetFilePriority(record.InfoHash, fileIndexes, priority); err != nil {
+			return fmt.Errorf("failed to set file priorities: %w", err)
+		}
+	}
+	return restoreTrackers(clientInstance, record)
+}
+
+// Make the trackers of torrent the same as recorded ones, which may differ from those in .torrent file.
+func restoreTrackers(clientInstance client.Client, record *TorrentRecord) error {
+	if len(record.Trackers) == 0 {
+		return nil
+	}
+	_, _, err := client.SetTorrentTrackers(clientInstance, record.InfoHash, record.Trackers)
+	return err
+}
+
+func ReadManifest(archive *zip.Reader) (*Manifest, error) {
+	data, err := readArchiveFile(archive, MANIFEST_FILENAME)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &Manifest{}
+	if err = json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if manifest.Version > MANIFEST_VERSION {
+		return nil, fmt.Errorf("unsupported manifest version %d", manifest.Version)
+	}
+	return manifest, nil
+}
+
+func readArchiveFile(archive *zip.Reader, name string) ([]byte, error) {
+	file, err := archive.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s in archive: %w", name, err)
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
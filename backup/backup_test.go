@@ -0,0 +1,58 @@
+package backup
+
+import (
+	"archive/zip"
+	"bytes"
+	"slices"
+	"testing"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/client/clienttest"
+	"github.com/sagan/ptool/util"
+)
+
+func testFiles(priorities ...int64) (files []*client.TorrentContentFile) {
+	for i, priority := range priorities {
+		files = append(files, &client.TorrentContentFile{Index: int64(i), Priority: priority})
+	}
+	return files
+}
+
+func TestBackupRestore(t *testing.T) {
+	srcClient := clienttest.New(&client.Torrent{
+		InfoHash: "a", Name: "A", SavePath: "/root/Downloads/movies", Category: "movies",
+		Tags: []string{"foo"}, State: "paused", RatioLimit: 2, SeedingTimeLimit: -1,
+	})
+	srcClient.Trackers["a"] = []string{"https://new.example.com/announce"}
+	srcClient.Files["a"] = testFiles(1, 0, 6)
+	buf := &bytes.Buffer{}
+	manifest, err := Backup(srcClient, buf)
+	if err != nil || len(manifest.Torrents) != 1 {
+		t.Fatalf("Backup() = %v, %v; want 1 torrent", manifest, err)
+	}
+
+	dstClient := clienttest.New()
+	// the trackers & files of .torrent file
+	dstClient.Trackers["a"] = []string{"https://tracker.example.com/announce"}
+	dstClient.Files["a"] = testFiles(1, 1, 1)
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("invalid archive: %v", err)
+	}
+	savePathMapper, _ := util.NewPathMapper([]string{"/root/Downloads|/downloads"})
+	if err := Restore(dstClient, archive, &RestoreOption{SavePathMapper: savePathMapper}); err != nil {
+		t.Fatalf("Restore error: %v", err)
+	}
+	option := dstClient.Added["a"]
+	if option == nil || option.SavePath != "/downloads/movies" || option.Category != "movies" ||
+		!slices.Equal(option.Tags, []string{"foo"}) || !option.Pause || !option.SkipChecking ||
+		option.RatioLimit != 2 || option.SeedingTimeLimit != -1 {
+		t.Errorf("restored torrent option = %+v, want the backed up state", option)
+	}
+	if files := dstClient.Files["a"]; files[1].Priority != 0 || files[2].Priority != 6 {
+		t.Errorf("restored file priorities = %d, %d; want 0, 6", files[1].Priority, files[2].Priority)
+	}
+	if want := []string{"https://new.example.com/announce"}; !slices.Equal(dstClient.Trackers["a"], want) {
+		t.Errorf("restored trackers = %v, want %v", dstClient.Trackers["a"], want)
+	}
+}
@@ -0,0 +1,215 @@
+// Package bandwidth implements the time-of-day bandwidth (global speed limits) schedule of clients.
+// The schedule is defined in "bandwidthSchedule" of client config, see config.BandwidthScheduleConfigStruct.
+package bandwidth
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/util"
+)
+
+// Speed limit value of a slot that does not change the client config.
+const KEEP = int64(-1)
+
+const (
+	DOWNLOAD_SPEED_LIMIT_KEY = "global_download_speed_limit"
+	UPLOAD_SPEED_LIMIT_KEY   = "global_upload_speed_limit"
+)
+
+var weekdays = map[string][]time.Weekday{
+	"sun":      {time.Sunday},
+	"mon":      {time.Monday},
+	"tue":      {time.Tuesday},
+	"wed":      {time.Wednesday},
+	"thu":      {time.Thursday},
+	"fri":      {time.Friday},
+	"sat":      {time.Saturday},
+	"weekdays": {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	"weekends": {time.Saturday, time.Sunday},
+}
+
+// A compiled time slot of schedule.
+type Slot struct {
+	*config.BandwidthScheduleConfigStruct
+	Index              int            // index (0-based) of slot in schedule
+	days               []time.Weekday // nil: every day
+	start              int            // minutes of day
+	end                int            // minutes of day. start == end means whole day
+	DownloadSpeedLimit int64          // KEEP: not set; 0: unlimited
+	UploadSpeedLimit   int64          // KEEP: not set; 0: unlimited
+}
+
+// A change of client global speed limit.
+type Change struct {
+	Key      string
+	OldValue int64
+	NewValue int64
+}
+
+func (c *Change) String() string {
+	return fmt.Sprintf("%s: %s => %s", c.Key, FormatSpeedLimit(c.OldValue), FormatSpeedLimit(c.NewValue))
+}
+
+func Compile(schedule []*config.BandwidthScheduleConfigStruct) ([]*Slot, error) {
+	var slots []*Slot
+	for i, slotConfig := range schedule {
+		slot, err := compileSlot(slotConfig)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bandwidth schedule slot #%d (%s): %w", i+1, slotConfig.Name, err)
+		}
+		slot.Index = i
+		slots = append(slots, slot)
+	}
+	return slots, nil
+}
+
+func compileSlot(slotConfig *config.BandwidthScheduleConfigStruct) (*Slot, error) {
+	slot := &Slot{BandwidthScheduleConfigStruct: slotConfig}
+	for _, day := range slotConfig.Days {
+		days, ok := weekdays[strings.ToLower(day)]
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q", day)
+		}
+		slot.days = append(slot.days, days...)
+	}
+	if slotConfig.Time != "" {
+		startStr, endStr, found := strings.Cut(slotConfig.Time, "-")
+		if !found {
+			return nil, fmt.Errorf("invalid time %q", slotConfig.Time)
+		}
+		var err error
+		if slot.start, err = parseTimeOfDay(startStr); err != nil {
+			return nil, err
+		}
+		if slot.end, err = parseTimeOfDay(endStr); err != nil {
+			return nil, err
+		}
+	}
+	for _, limit := range []struct {
+		name  string
+		value string
+		ptr   *int64
+	}{
+		{"downloadSpeedLimit", slotConfig.DownloadSpeedLimit, &slot.DownloadSpeedLimit},
+		{"uploadSpeedLimit", slotConfig.UploadSpeedLimit, &slot.UploadSpeedLimit},
+	} {
+		*limit.ptr = KEEP
+		if limit.value == "" {
+			continue
+		}
+		v, err := util.RAMInBytes(limit.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", limit.name, err)
+		}
+		*limit.ptr = max(v, 0)
+	}
+	return slot, nil
+}
+
+// Parse "HH:MM" time of day to minutes. "24:00" is allowed.
+func parseTimeOfDay(str string) (int, error) {
+	var hour, minute int
+	if n, err := fmt.Sscanf(strings.TrimSpace(str), "%d:%d", &hour, &minute); err != nil || n != 2 ||
+		hour < 0 || minute < 0 || minute >= 60 || hour*60+minute > 24*60 {
+		return 0, fmt.Errorf("invalid time of day %q", str)
+	}
+	return hour*60 + minute, nil
+}
+
+// Return true if the slot covers time t.
+func (slot *Slot) Match(t time.Time) bool {
+	minutes := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	switch {
+	case slot.start == slot.end:
+	case slot.start < slot.end:
+		if minutes < slot.start || minutes >= slot.end {
+			return false
+		}
+	default: // crosses midnight. The part after midnight belongs to the previous day
+		if minutes < slot.end {
+			day = (day + 6) % 7
+		} else if minutes < slot.start {
+			return false
+		}
+	}
+	if slot.days == nil {
+		return true
+	}
+	for _, d := range slot.days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the first slot that covers time t, or nil if none matches.
+func Match(slots []*Slot, t time.Time) *Slot {
+	for _, slot := range slots {
+		if slot.Match(t) {
+			return slot
+		}
+	}
+	return nil
+}
+
+// Return the slot of client bandwidth schedule that covers time t. Return nil if client has no schedule
+// or no slot matches.
+func GetClientSlot(clientConfig *config.ClientConfigStruct, t time.Time) (*Slot, error) {
+	if len(clientConfig.BandwidthSchedule) == 0 {
+		return nil, nil
+	}
+	slots, err := Compile(clientConfig.BandwidthSchedule)
+	if err != nil {
+		return nil, err
+	}
+	return Match(slots, t), nil
+}
+
+// Apply the speed limits of slot to client global config. Only the limits that differ from current ones are set.
+// If dryRun is true, return the changes without applying them.
+func Apply(clientInstance client.Client, slot *Slot, dryRun bool) (changes []*Change, err error) {
+	for _, limit := range []struct {
+		key   string
+		value int64
+	}{
+		{DOWNLOAD_SPEED_LIMIT_KEY, slot.DownloadSpeedLimit},
+		{UPLOAD_SPEED_LIMIT_KEY, slot.UploadSpeedLimit},
+	} {
+		if limit.value == KEEP {
+			continue
+		}
+		value, err := clientInstance.GetConfig(limit.key)
+		if err != nil {
+			return changes, fmt.Errorf("failed to get %s: %w", limit.key, err)
+		}
+		current := max(util.ParseInt(value), 0)
+		// some clients (e.g. transmission) store speed limits in KiB/s
+		if current == limit.value || current > 0 && limit.value > 0 && max(current, limit.value)-min(current, limit.value) < 1024 {
+			continue
+		}
+		if !dryRun {
+			if err := clientInstance.SetConfig(limit.key, fmt.Sprint(limit.value)); err != nil {
+				return changes, fmt.Errorf("failed to set %s: %w", limit.key, err)
+			}
+		}
+		changes = append(changes, &Change{Key: limit.key, OldValue: current, NewValue: limit.value})
+	}
+	return changes, nil
+}
+
+// Format speed limit value for display. <= 0 means unlimited.
+func FormatSpeedLimit(limit int64) string {
+	if limit == KEEP {
+		return "-"
+	}
+	if limit <= 0 {
+		return "unlimited"
+	}
+	return util.BytesSize(float64(limit)) + "/s"
+}
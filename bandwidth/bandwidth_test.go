@@ -0,0 +1,77 @@
+package bandwidth
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sagan/ptool/client/clienttest"
+	"github.com/sagan/ptool/config"
+)
+
+func TestMatch(t *testing.T) {
+	slots, err := Compile([]*config.BandwidthScheduleConfigStruct{
+		{Name: "work", Days: []string{"weekdays"}, Time: "09:00-18:00", UploadSpeedLimit: "2MiB"},
+		{Name: "friday-night", Days: []string{"Fri"}, Time: "23:00-07:00", UploadSpeedLimit: "5MiB"},
+		{Name: "default", UploadSpeedLimit: "-1"},
+	})
+	if err != nil {
+		t.Fatalf("Compile error: %v", err)
+	}
+	// 2024-01-05 is a Friday
+	cases := []struct {
+		time string
+		want string
+	}{
+		{"2024-01-05 09:00", "work"},
+		{"2024-01-05 17:59", "work"},
+		{"2024-01-05 18:00", "default"},
+		{"2024-01-05 23:30", "friday-night"},
+		{"2024-01-06 06:59", "friday-night"}, // Saturday morning belongs to Friday night
+		{"2024-01-06 07:00", "default"},
+		{"2024-01-06 10:00", "default"},
+		{"2024-01-05 06:00", "default"}, // Friday morning belongs to Thursday night
+	}
+	for _, c := range cases {
+		now, _ := time.ParseInLocation("2006-01-02 15:04", c.time, time.Local)
+		if slot := Match(slots, now); slot == nil || slot.Name != c.want {
+			t.Errorf("Match(%s) = %v, want %s", c.time, slot, c.want)
+		}
+	}
+	if slots[2].UploadSpeedLimit != 0 || slots[2].DownloadSpeedLimit != KEEP {
+		t.Errorf("unexpected default slot limits: %d, %d", slots[2].UploadSpeedLimit, slots[2].DownloadSpeedLimit)
+	}
+
+	for _, invalid := range []*config.BandwidthScheduleConfigStruct{
+		{Days: []string{"someday"}},
+		{Time: "09:00"},
+		{Time: "09:00-25:00"},
+		{UploadSpeedLimit: "fast"},
+	} {
+		if _, err := Compile([]*config.BandwidthScheduleConfigStruct{invalid}); err == nil {
+			t.Errorf("Compile(%+v) should fail", invalid)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	clientInstance := clienttest.New()
+	clientInstance.Settings[DOWNLOAD_SPEED_LIMIT_KEY] = "0"
+	clientInstance.Settings[UPLOAD_SPEED_LIMIT_KEY] = fmt.Sprint(10 << 20)
+	slots, _ := Compile([]*config.BandwidthScheduleConfigStruct{{UploadSpeedLimit: "2MiB", DownloadSpeedLimit: "-1"}})
+	changes, err := Apply(clientInstance, slots[0], true)
+	if err != nil || len(changes) != 1 || clientInstance.SetCnt != 0 {
+		t.Fatalf("Apply(dryRun) = %v, %v; sets = %d", changes, err, clientInstance.SetCnt)
+	}
+	changes, err = Apply(clientInstance, slots[0], false)
+	if err != nil || len(changes) != 1 || changes[0].NewValue != 2<<20 || changes[0].OldValue != 10<<20 {
+		t.Fatalf("Apply() = %v, %v", changes, err)
+	}
+	if clientInstance.Settings[UPLOAD_SPEED_LIMIT_KEY] != fmt.Sprint(2<<20) {
+		t.Errorf("upload speed limit = %s", clientInstance.Settings[UPLOAD_SPEED_LIMIT_KEY])
+	}
+	// already applied
+	if changes, _ = Apply(clientInstance, slots[0], false); len(changes) != 0 {
+		t.Errorf("Apply() again = %v, want no changes", changes)
+	}
+}
@@ -72,6 +72,12 @@ type Torrent struct {
 	Seeders            int64 // Cnt of seeders (including self client, if it's seeding), returned by tracker
 	Leechers           int64
 	Meta               map[string]int64
+	Client             string // client name. Only set when torrents of multiple clients are listed together
+
+	// Share limits, same values as SetTorrentsShareLimits args. 0: use global limit; -1: no limit.
+	// Only reported by clients that support it, otherwise 0.
+	RatioLimit       float64
+	SeedingTimeLimit int64 // seconds
 }
 
 type TorrentContentFile struct {
@@ -81,6 +87,7 @@ type TorrentContentFile struct {
 	Progress float64 // [0, 1]
 	Ignored  bool    // true if file is ignored (excluded from downloading)
 	Complete bool    // true if file is fullly downloaded
+	Priority int64   // qBittorrent style priority, see Client.SetFilePriority. 0 if ignored
 }
 
 type Status struct {
@@ -172,7 +179,8 @@ type Client interface {
 	EditTorrentTracker(infoHash string, oldTracker string, newTracker string, replaceHost bool) error
 	AddTorrentTrackers(infoHash string, trackers []string, oldTracker string, removeExisting bool) error
 	RemoveTorrentTrackers(infoHash string, trackers []string) error
-	// QB only, priority: 0	Do not download; 1	Normal priority; 6	High priority; 7	Maximal priority
+	// priority (qBittorrent values): 0	Do not download; 1	Normal priority; 6	High priority; 7	Maximal priority.
+	// Other clients map these values to their nearest priority levels
 	SetFilePriority(infoHash string, fileIndexes []int64, priority int64) error
 	Cached() bool
 	Close()
@@ -582,6 +590,15 @@ func PrintTorrents(output io.Writer, torrents []*Torrent, filter string, showSum
 	}
 	widthExcludingName := 105 // 40+6+5+6+6+5+5+16+8*2
 	widthName := width - widthExcludingName
+	// if torrents are from multiple clients, display an additional "Client" column
+	widthClient := 0
+	for _, torrent := range torrents {
+		widthClient = max(widthClient, len(torrent.Client))
+	}
+	if widthClient > 0 {
+		widthClient = max(widthClient, 6)
+		widthName -= widthClient + 2
+	}
 	cnt := int64(0)
 	var cntPaused, cntDownloading, cntSeeding, cntCompleted, cntOthers int64
 	size := int64(0)
@@ -589,6 +606,9 @@ func PrintTorrents(output io.Writer, torrents []*Torrent, filter string, showSum
 	largestSize := int64(-1)
 	sizeUnfinished := int64(0)
 	if showSum < 2 {
+		if widthClient > 0 {
+			fmt.Fprintf(output, "%-*s  ", widthClient, "Client")
+		}
 		fmt.Fprintf(output, "%-*s  %-40s  %-6s  %-5s  %-6s  %-6s  %-5s  %-5s  %-16s\n",
 			widthName, "Name", "InfoHash", "Size", "State", "↓S(/s)", "↑S(/s)", "Seeds", "Peers", "Tracker")
 	}
@@ -635,6 +655,9 @@ func PrintTorrents(output io.Writer, torrents []*Torrent, filter string, showSum
 				name += ` >` + torrent.ContentPath
 			}
 		}
+		if widthClient > 0 {
+			fmt.Fprintf(output, "%-*s  ", widthClient, torrent.Client)
+		}
 		remain := util.PrintStringInWidth(output, name, int64(widthName), true)
 		// 目前遇到的tracker域名最长的: "wintersakura.net"
 		trackerBaseDomain, _ := util.StringPrefixInWidth(torrent.TrackerBaseDomain, 16)
@@ -654,6 +677,9 @@ func PrintTorrents(output io.Writer, torrents []*Torrent, filter string, showSum
 				if remain == "" {
 					break
 				}
+				if widthClient > 0 {
+					fmt.Fprintf(output, "%*s  ", widthClient, "")
+				}
 				remain = util.PrintStringInWidth(output, remain, int64(widthName), true)
 				fmt.Fprintf(output, "\n")
 			}
@@ -721,7 +747,7 @@ func DeleteTorrentsAuto(clientInstance Client, infoHashes []string) (err error)
 	}
 	if len(torrents) > 0 {
 		infoHashes := util.Map(torrents, func(t *Torrent) string { return t.InfoHash })
-		err = clientInstance.DeleteTorrents(infoHashes, true)
+		err = DeleteOrTrashTorrents(clientInstance, infoHashes, true)
 		if err != nil {
 			return fmt.Errorf("failed to delete torrents: %w", err)
 		}
@@ -729,11 +755,44 @@ func DeleteTorrentsAuto(clientInstance Client, infoHashes []string) (err error)
 	return nil
 }
 
+// Make the trackers of torrent the same as trackers. The missing ones are added first,
+// then the extra ones are removed, as a torrent must have at least one tracker.
+// Return the number of added and removed trackers.
+func SetTorrentTrackers(clientInstance Client, infoHash string, trackers []string) (added, removed int, err error) {
+	torrentTrackers, err := clientInstance.GetTorrentTrackers(infoHash)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get trackers: %w", err)
+	}
+	currentTrackers := util.Map(torrentTrackers, func(t TorrentTracker) string { return t.Url })
+	addTrackers := util.Filter(trackers, func(tracker string) bool {
+		return !slices.Contains(currentTrackers, tracker)
+	})
+	removeTrackers := util.Filter(currentTrackers, func(tracker string) bool {
+		return !slices.Contains(trackers, tracker)
+	})
+	if len(addTrackers) > 0 {
+		if err = clientInstance.AddTorrentTrackers(infoHash, addTrackers, "", false); err != nil {
+			return 0, 0, fmt.Errorf("failed to add trackers: %w", err)
+		}
+	}
+	if len(removeTrackers) > 0 {
+		if err = clientInstance.RemoveTorrentTrackers(infoHash, removeTrackers); err != nil {
+			return len(addTrackers), 0, fmt.Errorf("failed to remove trackers: %w", err)
+		}
+	}
+	return len(addTrackers), len(removeTrackers), nil
+}
+
 // Parse and return torrents that meet criterion.
 // tag: comma-separated list, a torrent matches if it has any tag that in the list;
 // specially, "none" means untagged torrents.
-func QueryTorrents(clientInstance Client, category string, tag string, filter string,
+// where: query expression evaluated over torrent fields, see WhereVars.
+func QueryTorrents(clientInstance Client, category string, tag string, filter string, where string,
 	hashOrStateFilters ...string) ([]*Torrent, error) {
+	whereProgram, err := CompileWhere(where)
+	if err != nil {
+		return nil, err
+	}
 	isAll := len(hashOrStateFilters) == 0
 	for _, arg := range hashOrStateFilters {
 		if !IsValidInfoHashOrStateFilter(arg) {
@@ -747,7 +806,7 @@ func QueryTorrents(clientInstance Client, category string, tag string, filter st
 	if err != nil {
 		return nil, err
 	}
-	if category == "" && tag == "" && filter == "" && isAll {
+	if category == "" && tag == "" && filter == "" && where == "" && isAll {
 		return torrents, nil
 	}
 	torrents2 := []*Torrent{}
@@ -764,6 +823,11 @@ func QueryTorrents(clientInstance Client, category string, tag string, filter st
 		if filter != "" && !torrent.MatchFilter(filter) {
 			continue
 		}
+		if match, err := torrent.MatchWhere(whereProgram); err != nil {
+			return nil, fmt.Errorf("failed to evaluate where expression on torrent %s: %w", torrent.InfoHash, err)
+		} else if !match {
+			continue
+		}
 		if isAll {
 			torrents2 = append(torrents2, torrent)
 		} else {
@@ -786,9 +850,14 @@ func QueryTorrents(clientInstance Client, category string, tag string, filter st
 // category: "none" is a special value to select uncategoried torrents.
 // tag: comma-separated list, a torrent matches if it has any tag that in the list;
 // specially, "none" means untagged torrents.
-func SelectTorrents(clientInstance Client, category string, tag string, filter string,
+// where: query expression evaluated over torrent fields, see WhereVars.
+func SelectTorrents(clientInstance Client, category string, tag string, filter string, where string,
 	hashOrStateFilters ...string) ([]string, error) {
-	noCondition := category == "" && tag == "" && filter == ""
+	whereProgram, err := CompileWhere(where)
+	if err != nil {
+		return nil, err
+	}
+	noCondition := category == "" && tag == "" && filter == "" && where == ""
 	isAll := len(hashOrStateFilters) == 0
 	isPlainInfoHashes := true
 	for _, arg := range hashOrStateFilters {
@@ -829,6 +898,11 @@ func SelectTorrents(clientInstance Client, category string, tag string, filter s
 		if filter != "" && !torrent.MatchFilter(filter) {
 			continue
 		}
+		if match, err := torrent.MatchWhere(whereProgram); err != nil {
+			return nil, fmt.Errorf("failed to evaluate where expression on torrent %s: %w", torrent.InfoHash, err)
+		} else if !match {
+			continue
+		}
 		if isAll {
 			infoHashes = append(infoHashes, torrent.InfoHash)
 		} else {
@@ -0,0 +1,252 @@
+// Package clienttest implements an in-memory fake client.Client for tests.
+package clienttest
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+)
+
+// The prefix of fake .torrent file contents exported by Client. See TorrentFile.
+const TORRENT_FILE_PREFIX = "torrent:"
+
+// Client keeps torrents in memory. Only the methods used by tests are implemented,
+// calling other methods of client.Client panics.
+// Trackers and Files are keyed by info-hash and describe the torrent (as it's .torrent file does),
+// so they are kept after the torrent is deleted and used again if it's added back.
+type Client struct {
+	client.Client
+	Config     *config.ClientConfigStruct
+	Torrents   []*client.Torrent
+	Categories []*client.TorrentCategory
+	Trackers   map[string][]string                     // info-hash => tracker urls
+	Files      map[string][]*client.TorrentContentFile // info-hash => content files
+	Settings   map[string]string                       // GetConfig / SetConfig variables
+	SetCnt     int64                                   // number of SetConfig calls
+	Added      map[string]*client.TorrentOption        // info-hash => option of AddTorrent call
+	Errors     map[string]error                        // method name => error returned by it (modifying methods only)
+}
+
+// Return a new fake client named "local" with torrents.
+func New(torrents ...*client.Torrent) *Client {
+	return &Client{
+		Config:   &config.ClientConfigStruct{Name: "local", Type: "qbittorrent"},
+		Torrents: torrents,
+		Trackers: map[string][]string{},
+		Files:    map[string][]*client.TorrentContentFile{},
+		Settings: map[string]string{},
+		Added:    map[string]*client.TorrentOption{},
+		Errors:   map[string]error{},
+	}
+}
+
+// Return the fake .torrent file contents of infoHash, which can be added to Client.
+func TorrentFile(infoHash string) []byte {
+	return []byte(TORRENT_FILE_PREFIX + infoHash)
+}
+
+func (c *Client) GetName() string {
+	return c.Config.Name
+}
+
+func (c *Client) GetClientConfig() *config.ClientConfigStruct {
+	return c.Config
+}
+
+// category: "none" selects uncategoried torrents. stateFilter & showAll are ignored.
+func (c *Client) GetTorrents(stateFilter string, category string, showAll bool) ([]*client.Torrent, error) {
+	torrents := []*client.Torrent{}
+	for _, torrent := range c.Torrents {
+		if category == constants.NONE && torrent.Category != "" ||
+			category != "" && category != constants.NONE && torrent.Category != category {
+			continue
+		}
+		torrents = append(torrents, torrent)
+	}
+	return torrents, nil
+}
+
+// Return nil if torrent does not exist, same as real clients.
+func (c *Client) GetTorrent(infoHash string) (*client.Torrent, error) {
+	if index := c.index(infoHash); index >= 0 {
+		return c.Torrents[index], nil
+	}
+	return nil, nil
+}
+
+func (c *Client) GetTorrentsByContentPath(contentPath string) ([]*client.Torrent, error) {
+	torrents := []*client.Torrent{}
+	for _, torrent := range c.Torrents {
+		if torrent.ContentPath == contentPath {
+			torrents = append(torrents, torrent)
+		}
+	}
+	return torrents, nil
+}
+
+func (c *Client) ExportTorrentFile(infoHash string) ([]byte, error) {
+	if c.index(infoHash) < 0 {
+		return nil, fmt.Errorf("torrent %s not found", infoHash)
+	}
+	return TorrentFile(infoHash), nil
+}
+
+// torrentContent must be the contents returned by TorrentFile.
+func (c *Client) AddTorrent(torrentContent []byte, option *client.TorrentOption, meta map[string]int64) error {
+	if err := c.Errors["AddTorrent"]; err != nil {
+		return err
+	}
+	infoHash, ok := strings.CutPrefix(string(torrentContent), TORRENT_FILE_PREFIX)
+	if !ok {
+		return fmt.Errorf("invalid torrent contents")
+	}
+	if c.index(infoHash) >= 0 {
+		return fmt.Errorf("torrent %s already exists", infoHash)
+	}
+	state := "seeding"
+	if option.Pause {
+		state = "paused"
+	}
+	c.Torrents = append(c.Torrents, &client.Torrent{
+		InfoHash: infoHash,
+		Name:     option.Name,
+		SavePath: option.SavePath,
+		Category: option.Category,
+		Tags:     option.Tags,
+		State:    state,
+		Meta:     meta,
+	})
+	c.Added[infoHash] = option
+	return nil
+}
+
+func (c *Client) DeleteTorrents(infoHashes []string, deleteFiles bool) error {
+	if err := c.Errors["DeleteTorrents"]; err != nil {
+		return err
+	}
+	c.Torrents = slices.DeleteFunc(c.Torrents, func(t *client.Torrent) bool {
+		return slices.Contains(infoHashes, t.InfoHash)
+	})
+	return nil
+}
+
+func (c *Client) SetTorrentsCatetory(infoHashes []string, category string) error {
+	if err := c.Errors["SetTorrentsCatetory"]; err != nil {
+		return err
+	}
+	if category == constants.NONE {
+		category = ""
+	}
+	for _, infoHash := range infoHashes {
+		if index := c.index(infoHash); index >= 0 {
+			c.Torrents[index].Category = category
+		}
+	}
+	return nil
+}
+
+func (c *Client) SetTorrentsShareLimits(infoHashes []string, ratioLimit float64, seedingTimeLimit int64) error {
+	if err := c.Errors["SetTorrentsShareLimits"]; err != nil {
+		return err
+	}
+	for _, infoHash := range infoHashes {
+		if index := c.index(infoHash); index >= 0 {
+			c.Torrents[index].RatioLimit = ratioLimit
+			c.Torrents[index].SeedingTimeLimit = seedingTimeLimit
+		}
+	}
+	return nil
+}
+
+func (c *Client) GetCategories() ([]*client.TorrentCategory, error) {
+	return c.Categories, nil
+}
+
+func (c *Client) MakeCategory(category string, savePath string) error {
+	if err := c.Errors["MakeCategory"]; err != nil {
+		return err
+	}
+	for _, cat := range c.Categories {
+		if cat.Name == category {
+			cat.SavePath = savePath
+			return nil
+		}
+	}
+	c.Categories = append(c.Categories, &client.TorrentCategory{Name: category, SavePath: savePath})
+	return nil
+}
+
+func (c *Client) GetTorrentTrackers(infoHash string) (client.TorrentTrackers, error) {
+	var trackers client.TorrentTrackers
+	for _, url := range c.Trackers[infoHash] {
+		trackers = append(trackers, client.TorrentTracker{Url: url})
+	}
+	return trackers, nil
+}
+
+func (c *Client) AddTorrentTrackers(infoHash string, trackers []string, oldTracker string,
+	removeExisting bool) error {
+	if err := c.Errors["AddTorrentTrackers"]; err != nil {
+		return err
+	}
+	if removeExisting {
+		c.Trackers[infoHash] = nil
+	}
+	for _, tracker := range trackers {
+		if !slices.Contains(c.Trackers[infoHash], tracker) {
+			c.Trackers[infoHash] = append(c.Trackers[infoHash], tracker)
+		}
+	}
+	return nil
+}
+
+func (c *Client) RemoveTorrentTrackers(infoHash string, trackers []string) error {
+	if err := c.Errors["RemoveTorrentTrackers"]; err != nil {
+		return err
+	}
+	c.Trackers[infoHash] = slices.DeleteFunc(c.Trackers[infoHash], func(t string) bool {
+		return slices.Contains(trackers, t)
+	})
+	return nil
+}
+
+func (c *Client) GetTorrentContents(infoHash string) ([]*client.TorrentContentFile, error) {
+	return c.Files[infoHash], nil
+}
+
+func (c *Client) SetFilePriority(infoHash string, fileIndexes []int64, priority int64) error {
+	if err := c.Errors["SetFilePriority"]; err != nil {
+		return err
+	}
+	for _, file := range c.Files[infoHash] {
+		if slices.Contains(fileIndexes, file.Index) {
+			file.Priority = priority
+		}
+	}
+	return nil
+}
+
+func (c *Client) GetConfig(variable string) (string, error) {
+	return c.Settings[variable], nil
+}
+
+func (c *Client) SetConfig(variable string, value string) error {
+	if err := c.Errors["SetConfig"]; err != nil {
+		return err
+	}
+	c.Settings[variable] = value
+	c.SetCnt++
+	return nil
+}
+
+// No cache is used.
+func (c *Client) PurgeCache() {
+}
+
+func (c *Client) index(infoHash string) int {
+	return slices.IndexFunc(c.Torrents, func(t *client.Torrent) bool { return t.InfoHash == infoHash })
+}
@@ -0,0 +1,210 @@
+package deluge
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/util"
+)
+
+// Deluge Web JSON-RPC error code of "Not authenticated".
+const apiErrorNotAuthenticated = 1
+
+type apiRequest struct {
+	Method string `json:"method"`
+	Params []any  `json:"params"`
+	Id     int64  `json:"id"`
+}
+
+type apiError struct {
+	Message string `json:"message"`
+	Code    int64  `json:"code"`
+}
+
+type apiResponse struct {
+	Id     int64           `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *apiError       `json:"error"`
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("deluge rpc error (code=%d): %s", e.Code, e.Message)
+}
+
+type apiTorrentTracker struct {
+	Url  string `json:"url"`
+	Tier int64  `json:"tier"`
+}
+
+type apiTorrentFile struct {
+	Index  int64  `json:"index"`
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Offset int64  `json:"offset"`
+}
+
+// Keys of core.get_torrents_status that are fetched when syncing torrents list.
+var apiTorrentStatusKeys = []string{
+	"hash", "name", "state", "progress", "total_wanted", "total_size", "total_done", "all_time_download",
+	"total_uploaded", "download_payload_rate", "upload_payload_rate", "max_download_speed", "max_upload_speed",
+	"time_added", "completed_time", "time_since_transfer", "label", "save_path", "tracker", "trackers",
+	"total_seeds", "total_peers", "is_finished", "tracker_status",
+}
+
+// Torrent status returned by Deluge core.get_torrents_status / core.get_torrent_status.
+// Only fields that ptool uses are defined. See deluge/core/torrent.py for all available keys.
+type apiTorrentStatus struct {
+	Hash                string              `json:"hash"`
+	Name                string              `json:"name"`  // display name, could be changed by "name" torrent option
+	State               string              `json:"state"` // Checking|Downloading|Seeding|Paused|Error|Queued|Moving|Allocating
+	Progress            float64             `json:"progress"`
+	TotalWanted         int64               `json:"total_wanted"` // size of selected files
+	TotalSize           int64               `json:"total_size"`
+	TotalDone           int64               `json:"total_done"`
+	AllTimeDownload     int64               `json:"all_time_download"`
+	TotalUploaded       int64               `json:"total_uploaded"`
+	DownloadPayloadRate int64               `json:"download_payload_rate"`
+	UploadPayloadRate   int64               `json:"upload_payload_rate"`
+	MaxDownloadSpeed    float64             `json:"max_download_speed"` // KiB/s. -1 means no limit
+	MaxUploadSpeed      float64             `json:"max_upload_speed"`   // KiB/s. -1 means no limit
+	TimeAdded           float64             `json:"time_added"`
+	CompletedTime       float64             `json:"completed_time"`      // Deluge 2.0+. 0 if not completed
+	TimeSinceTransfer   int64               `json:"time_since_transfer"` // Deluge 2.0+. seconds. -1 if never
+	Label               string              `json:"label"`               // Label plugin
+	SavePath            string              `json:"save_path"`
+	Tracker             string              `json:"tracker"` // current tracker url
+	Trackers            []apiTorrentTracker `json:"trackers"`
+	TotalSeeds          int64               `json:"total_seeds"`
+	TotalPeers          int64               `json:"total_peers"`
+	IsFinished          bool                `json:"is_finished"`
+	TrackerStatus       string              `json:"tracker_status"` // e.g. "Announce OK", "Error: unregistered torrent"
+	// Below fields are not fetched when syncing torrents list.
+	Files          []apiTorrentFile `json:"files"`
+	FileProgress   []float64        `json:"file_progress"`
+	FilePriorities []int64          `json:"file_priorities"`
+}
+
+func (dt *apiTorrentStatus) ToTorrentState() string {
+	switch dt.State {
+	case "Downloading", "Allocating":
+		return "downloading"
+	case "Seeding":
+		return "seeding"
+	case "Paused":
+		if dt.IsFinished {
+			return "completed"
+		}
+		return "paused"
+	case "Queued", "Moving":
+		if dt.IsFinished {
+			return "seeding"
+		}
+		return "downloading"
+	case "Checking":
+		return "checking"
+	case "Error":
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// Return path sep (either '/' or '\') of this torrent.
+func (dt *apiTorrentStatus) Sep() string {
+	if !strings.Contains(dt.SavePath, `/`) && strings.Contains(dt.SavePath, `\`) {
+		return `\`
+	}
+	return `/`
+}
+
+func (dt *apiTorrentStatus) ContentPath() string {
+	name, _ := client.ParseMetaFromName(dt.Name)
+	return strings.TrimSuffix(dt.SavePath, dt.Sep()) + dt.Sep() + name
+}
+
+// now: the timestamp that the status was fetched. tags: the tags of torrent, stored by ptool.
+func (dt *apiTorrentStatus) ToTorrent(now int64, tags []string) *client.Torrent {
+	activityTime := int64(0)
+	if dt.TimeSinceTransfer >= 0 {
+		activityTime = now - dt.TimeSinceTransfer
+	}
+	torrent := &client.Torrent{
+		InfoHash:           dt.Hash,
+		Name:               dt.Name,
+		TrackerDomain:      util.ParseUrlHostname(dt.Tracker),
+		TrackerBaseDomain:  util.GetUrlDomain(dt.Tracker),
+		Tracker:            dt.Tracker,
+		State:              dt.ToTorrentState(),
+		LowLevelState:      dt.State,
+		Atime:              int64(dt.TimeAdded),
+		Ctime:              int64(dt.CompletedTime),
+		ActivityTime:       activityTime,
+		Downloaded:         dt.AllTimeDownload,
+		DownloadSpeed:      dt.DownloadPayloadRate,
+		DownloadSpeedLimit: fromSpeedLimit(dt.MaxDownloadSpeed),
+		Uploaded:           dt.TotalUploaded,
+		UploadSpeed:        dt.UploadPayloadRate,
+		UploadedSpeedLimit: fromSpeedLimit(dt.MaxUploadSpeed),
+		Category:           dt.Label,
+		SavePath:           dt.SavePath,
+		ContentPath:        dt.ContentPath(),
+		Tags:               append([]string{}, tags...),
+		Seeders:            dt.TotalSeeds,
+		Size:               dt.TotalWanted,
+		SizeCompleted:      dt.TotalDone,
+		SizeTotal:          dt.TotalSize,
+		Leechers:           dt.TotalPeers,
+	}
+	torrent.Name, torrent.Meta = client.ParseMetaFromName(torrent.Name)
+	if torrent.Meta == nil {
+		torrent.Meta = map[string]int64{}
+	}
+	return torrent
+}
+
+// Convert Deluge speed limit (KiB/s, -1 == unlimited) to ptool value (B/s, -1 == unlimited).
+func fromSpeedLimit(limit float64) int64 {
+	if limit < 0 {
+		return -1
+	}
+	return int64(limit * 1024)
+}
+
+// Convert ptool speed limit (B/s, <= 0 == unlimited) to Deluge value (KiB/s, -1 == unlimited).
+func toSpeedLimit(limit int64) float64 {
+	if limit <= 0 {
+		return -1
+	}
+	return float64(limit) / 1024
+}
+
+// Convert qBittorrent style file priority (0 - Do not download; 1 - Normal; 6 - High; 7 - Maximal)
+// to Deluge file priority (0 - Skip; 1 - Low; 4 - Normal; 7 - High).
+func toFilePriority(priority int64) int64 {
+	switch {
+	case priority <= 0:
+		return 0
+	case priority == 1:
+		return 4
+	case priority > 7:
+		return 7
+	default:
+		return priority
+	}
+}
+
+// Convert Deluge file priority to qBittorrent style file priority. Reverse of toFilePriority.
+func fromFilePriority(priority int64) int64 {
+	switch {
+	case priority <= 0:
+		return 0
+	case priority <= 4:
+		return 1
+	case priority >= 7:
+		return 7
+	default:
+		return 6
+	}
+}
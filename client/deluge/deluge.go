@@ -0,0 +1,1120 @@
+package deluge
+
+// Deluge client using Deluge Web UI JSON-RPC api (Deluge 2.0+).
+// protocol: https://deluge.readthedocs.io/en/latest/reference/webapi.html
+// The Label plugin must be enabled in Deluge to use categories. Each torrent in Deluge can only have one label,
+// which ptool uses as the torrent category. Tags are stored in a local meta file (see meta.go); operations that
+// would set or remove tags fail with ErrTagsUnsupported if the Label plugin is not enabled.
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+type Client struct {
+	Name                      string
+	ClientConfig              *config.ClientConfigStruct
+	Config                    *config.ConfigStruct
+	HttpClient                *http.Client
+	Logined                   bool
+	requestId                 int64
+	datatime                  int64
+	datatimeMeta              int64
+	torrents                  map[string]*apiTorrentStatus
+	meta                      *clientMeta // loaded with torrents
+	labels                    []string    // nil if not fetched
+	sessionStatus             map[string]float64
+	configValues              map[string]any
+	freeSpace                 int64
+	unfinishedSize            int64
+	unfinishedDownloadingSize int64
+	contentPathTorrents       map[string][]*apiTorrentStatus
+}
+
+var (
+	ErrTagsUnsupported = errors.New("unsupported: deluge tags require the Label plugin")
+)
+
+func (dclient *Client) apiUrl() string {
+	return strings.TrimSuffix(dclient.ClientConfig.Url, "/") + "/json"
+}
+
+// Do a raw JSON-RPC request, without trying to login first.
+func (dclient *Client) rpc(method string, result any, params ...any) error {
+	if params == nil {
+		params = []any{}
+	}
+	dclient.requestId++
+	req := &apiRequest{
+		Method: method,
+		Params: params,
+		Id:     dclient.requestId,
+	}
+	res := &apiResponse{}
+	if err := util.PostAndFetchJson(dclient.apiUrl(), req, res, nil, dclient.HttpClient); err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	if res.Error != nil {
+		return res.Error
+	}
+	if result != nil && len(res.Result) > 0 {
+		if err := json.Unmarshal(res.Result, result); err != nil {
+			return fmt.Errorf("%s: invalid result: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// Call a Deluge JSON-RPC method. Login automatically. If the web session expires, re-login and try again.
+func (dclient *Client) call(method string, result any, params ...any) error {
+	if err := dclient.login(); err != nil {
+		return fmt.Errorf("login error: %w", err)
+	}
+	err := dclient.rpc(method, result, params...)
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && apiErr.Code == apiErrorNotAuthenticated {
+		log.Debugf("deluge session expired, re-login")
+		dclient.Logined = false
+		if err := dclient.login(); err != nil {
+			return fmt.Errorf("login error: %w", err)
+		}
+		err = dclient.rpc(method, result, params...)
+	}
+	return err
+}
+
+func (dclient *Client) login() error {
+	if dclient.Logined {
+		return nil
+	}
+	password := dclient.ClientConfig.Password
+	// use deluge default
+	if password == "" {
+		password = "deluge"
+	}
+	ok := false
+	if err := dclient.rpc("auth.login", &ok, password); err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("incorrect password")
+	}
+	// the Web UI may be not connected to any daemon, in which case connect it to the first one.
+	connected := false
+	if err := dclient.rpc("web.connected", &connected); err != nil {
+		return err
+	}
+	if !connected {
+		var hosts [][]any
+		if err := dclient.rpc("web.get_hosts", &hosts); err != nil {
+			return err
+		}
+		if len(hosts) == 0 || len(hosts[0]) == 0 {
+			return fmt.Errorf("no deluge daemon host available in web ui")
+		}
+		if err := dclient.rpc("web.connect", nil, fmt.Sprint(hosts[0][0])); err != nil {
+			return fmt.Errorf("failed to connect to deluge daemon: %w", err)
+		}
+	}
+	dclient.Logined = true
+	return nil
+}
+
+func (dclient *Client) Cached() bool {
+	return dclient.datatime > 0
+}
+
+func (dclient *Client) sync() error {
+	if dclient.datatime > 0 {
+		return nil
+	}
+	now := util.Now()
+	torrents := map[string]*apiTorrentStatus{}
+	if err := dclient.call("core.get_torrents_status", &torrents, map[string]any{}, apiTorrentStatusKeys); err != nil {
+		return err
+	}
+	for infoHash, torrent := range torrents {
+		torrent.Hash = infoHash
+	}
+	meta, err := dclient.loadMeta()
+	if err != nil {
+		return err
+	}
+	dclient.datatime = now
+	dclient.torrents = torrents
+	dclient.meta = meta
+	dclient.buildDerivative()
+	return nil
+}
+
+func (dclient *Client) buildDerivative() {
+	unfinishedSize := int64(0)
+	unfinishedDownloadingSize := int64(0)
+	contentPathTorrents := map[string][]*apiTorrentStatus{}
+	for _, torrent := range dclient.torrents {
+		usize := torrent.TotalWanted - torrent.TotalDone
+		if usize < 0 {
+			usize = 0
+		}
+		unfinishedSize += usize
+		if torrent.State != "Paused" {
+			unfinishedDownloadingSize += usize
+		}
+		contentPath := torrent.ContentPath()
+		contentPathTorrents[contentPath] = append(contentPathTorrents[contentPath], torrent)
+	}
+	dclient.unfinishedSize = unfinishedSize
+	dclient.unfinishedDownloadingSize = unfinishedDownloadingSize
+	dclient.contentPathTorrents = contentPathTorrents
+}
+
+func (dclient *Client) syncMeta() error {
+	if dclient.datatimeMeta > 0 {
+		return nil
+	}
+	now := util.Now()
+	sessionStatus := map[string]float64{}
+	if err := dclient.call("core.get_session_status", &sessionStatus,
+		[]string{"payload_download_rate", "payload_upload_rate"}); err != nil {
+		return err
+	}
+	configValues := map[string]any{}
+	if err := dclient.call("core.get_config_values", &configValues,
+		[]string{"max_download_speed", "max_upload_speed", "download_location",
+			"stop_seed_at_ratio", "stop_seed_ratio"}); err != nil {
+		return err
+	}
+	freeSpace := int64(-1)
+	if err := dclient.call("core.get_free_space", &freeSpace); err != nil {
+		log.Debugf("failed to get deluge free space: %v", err)
+		freeSpace = -1
+	}
+	dclient.datatimeMeta = now
+	dclient.sessionStatus = sessionStatus
+	dclient.configValues = configValues
+	dclient.freeSpace = freeSpace
+	return nil
+}
+
+// Get Label plugin labels. Cached.
+func (dclient *Client) getLabels() ([]string, error) {
+	if dclient.labels != nil {
+		return dclient.labels, nil
+	}
+	labels := []string{}
+	if err := dclient.call("label.get_labels", &labels); err != nil {
+		return nil, fmt.Errorf("failed to get labels (is Label plugin enabled?): %w", err)
+	}
+	dclient.labels = labels
+	return labels, nil
+}
+
+// Create label if not exists.
+func (dclient *Client) ensureLabel(label string) error {
+	labels, err := dclient.getLabels()
+	if err != nil {
+		return err
+	}
+	if slices.Contains(labels, label) {
+		return nil
+	}
+	if err := dclient.call("label.add", nil, label); err != nil {
+		return err
+	}
+	dclient.labels = append(dclient.labels, label)
+	return nil
+}
+
+// Set label of torrents. Empty or "none" label removes existing label.
+func (dclient *Client) setLabel(infoHashes []string, label string) error {
+	if label == constants.NONE {
+		label = ""
+	}
+	// Label plugin only accepts lowercase labels.
+	label = strings.ToLower(label)
+	if label != "" {
+		if err := dclient.ensureLabel(label); err != nil {
+			return err
+		}
+	}
+	for _, infoHash := range infoHashes {
+		if dclient.torrents != nil && dclient.torrents[infoHash] != nil && dclient.torrents[infoHash].Label == label {
+			continue
+		}
+		if err := dclient.call("label.set_torrent", nil, infoHash, label); err != nil {
+			return err
+		}
+		if dclient.torrents != nil && dclient.torrents[infoHash] != nil {
+			dclient.torrents[infoHash].Label = label
+		}
+	}
+	return nil
+}
+
+// Get a torrent full status (including files) from rpc. return error if torrent not found.
+func (dclient *Client) getTorrent(infoHash string, keys ...string) (*apiTorrentStatus, error) {
+	if len(keys) == 0 {
+		keys = apiTorrentStatusKeys
+	}
+	var data json.RawMessage
+	if err := dclient.call("core.get_torrent_status", &data, infoHash, keys); err != nil {
+		return nil, err
+	}
+	// deluge returns an empty object if torrent does not exist
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil || len(fields) == 0 {
+		return nil, fmt.Errorf("torrent %s not found", infoHash)
+	}
+	torrent := &apiTorrentStatus{}
+	if err := json.Unmarshal(data, torrent); err != nil {
+		return nil, err
+	}
+	torrent.Hash = infoHash
+	return torrent, nil
+}
+
+func (dclient *Client) getAllInfoHashes() ([]string, error) {
+	if err := dclient.sync(); err != nil {
+		return nil, err
+	}
+	infoHashes := []string{}
+	for infoHash := range dclient.torrents {
+		infoHashes = append(infoHashes, infoHash)
+	}
+	return infoHashes, nil
+}
+
+func (dclient *Client) setTorrentsOptions(infoHashes []string, options map[string]any) error {
+	if len(infoHashes) == 0 || len(options) == 0 {
+		return nil
+	}
+	return dclient.call("core.set_torrent_options", nil, infoHashes, options)
+}
+
+func (dclient *Client) ExportTorrentFile(infoHash string) ([]byte, error) {
+	// Deluge stores .torrent files as "<infoHash>.torrent" in it's state dir.
+	if dclient.ClientConfig.LocalTorrentsPath != "" {
+		return os.ReadFile(filepath.Join(dclient.ClientConfig.LocalTorrentsPath, infoHash+".torrent"))
+	}
+	return nil, fmt.Errorf("unsupported")
+}
+
+func (dclient *Client) GetTorrent(infoHash string) (*client.Torrent, error) {
+	if err := dclient.sync(); err != nil {
+		return nil, err
+	}
+	torrent := dclient.torrents[infoHash]
+	if torrent == nil {
+		return nil, nil
+	}
+	return dclient.toTorrent(torrent), nil
+}
+
+func (dclient *Client) toTorrent(dtorrent *apiTorrentStatus) *client.Torrent {
+	return dtorrent.ToTorrent(dclient.datatime, dclient.meta.Torrents[dtorrent.Hash])
+}
+
+func (dclient *Client) GetTorrents(stateFilter string, category string, showAll bool) ([]*client.Torrent, error) {
+	if err := dclient.sync(); err != nil {
+		return nil, err
+	}
+	torrents := []*client.Torrent{}
+	for _, dtorrent := range dclient.torrents {
+		torrent := dclient.toTorrent(dtorrent)
+		if category != "" {
+			if category == constants.NONE {
+				if torrent.Category != "" {
+					continue
+				}
+			} else if category != torrent.Category {
+				continue
+			}
+		}
+		if !showAll && torrent.DownloadSpeed < 1024 && torrent.UploadSpeed < 1024 {
+			continue
+		}
+		if !torrent.MatchStateFilter(stateFilter) {
+			continue
+		}
+		torrents = append(torrents, torrent)
+	}
+	return torrents, nil
+}
+
+func (dclient *Client) GetTorrentsByContentPath(contentPath string) ([]*client.Torrent, error) {
+	if err := dclient.sync(); err != nil {
+		return nil, err
+	}
+	torrents := []*client.Torrent{}
+	for _, torrent := range dclient.contentPathTorrents[contentPath] {
+		torrents = append(torrents, dclient.toTorrent(torrent))
+	}
+	return torrents, nil
+}
+
+func (dclient *Client) AddTorrent(torrentContent []byte, option *client.TorrentOption, meta map[string]int64) error {
+	if len(option.Tags) > 0 {
+		if err := dclient.checkTags(); err != nil {
+			return fmt.Errorf("failed to add torrent with tags %v: %w", option.Tags, err)
+		}
+	}
+	options := map[string]any{
+		"add_paused": option.Pause,
+	}
+	if option.SavePath != "" {
+		options["download_location"] = option.SavePath
+	}
+	if option.DownloadSpeedLimit > 0 {
+		options["max_download_speed"] = toSpeedLimit(option.DownloadSpeedLimit)
+	}
+	if option.UploadSpeedLimit > 0 {
+		options["max_upload_speed"] = toSpeedLimit(option.UploadSpeedLimit)
+	}
+	if option.SkipChecking {
+		options["seed_mode"] = true
+	}
+	if option.SequentialDownload {
+		options["sequential_download"] = true
+	}
+	if option.RatioLimit > 0 {
+		options["stop_at_ratio"] = true
+		options["stop_ratio"] = option.RatioLimit
+	}
+	if option.SeedingTimeLimit > 0 {
+		log.Warnf("deluge does not support seeding time limit, ignore it")
+	}
+	infoHash := ""
+	name := ""
+	if util.IsTorrentUrl(string(torrentContent)) {
+		method := "core.add_torrent_url"
+		if util.IsPureTorrentUrl(string(torrentContent)) {
+			method = "core.add_torrent_magnet"
+		}
+		if err := dclient.call(method, &infoHash, string(torrentContent), options); err != nil {
+			return err
+		}
+		if option.Name != "" || len(meta) > 0 {
+			name = option.Name
+			if name == "" && infoHash != "" {
+				if torrent, err := dclient.getTorrent(infoHash, "name"); err == nil {
+					name = torrent.Name
+				}
+			}
+			name = client.GenerateNameWithMeta(name, meta)
+		}
+	} else {
+		tinfo, err := torrentutil.ParseTorrent(torrentContent)
+		if err != nil {
+			return fmt.Errorf("invalid torrent: %w", err)
+		}
+		if option.Name != "" || len(meta) > 0 {
+			name = option.Name
+			if name == "" {
+				name = tinfo.Info.Name
+			}
+			options["name"] = client.GenerateNameWithMeta(name, meta)
+		}
+		if err := dclient.call("core.add_torrent_file", &infoHash, tinfo.Info.Name+".torrent",
+			base64.StdEncoding.EncodeToString(torrentContent), options); err != nil {
+			return err
+		}
+		if infoHash == "" {
+			infoHash = tinfo.InfoHash
+		}
+		name = "" // already set by options
+	}
+	if infoHash == "" {
+		return nil
+	}
+	if name != "" {
+		if err := dclient.setTorrentsOptions([]string{infoHash}, map[string]any{"name": name}); err != nil {
+			log.Debugf("failed to set deluge torrent %s name: %v", infoHash, err)
+		}
+	}
+	if option.Category != "" && option.Category != constants.NONE {
+		if err := dclient.setLabel([]string{infoHash}, option.Category); err != nil {
+			return fmt.Errorf("torrent added but failed to set category: %w", err)
+		}
+	}
+	if len(option.Tags) > 0 {
+		if err := dclient.updateTorrentsTags([]string{infoHash}, option.Tags, nil); err != nil {
+			return fmt.Errorf("torrent added but failed to set tags: %w", err)
+		}
+	}
+	return nil
+}
+
+func (dclient *Client) ModifyTorrent(infoHash string, option *client.TorrentOption, meta map[string]int64) error {
+	if len(option.Tags) > 0 || len(option.RemoveTags) > 0 {
+		if err := dclient.checkTags(); err != nil {
+			return fmt.Errorf("failed to modify torrent tags %v / %v: %w", option.Tags, option.RemoveTags, err)
+		}
+	}
+	if err := dclient.sync(); err != nil {
+		return err
+	}
+	dtorrent := dclient.torrents[infoHash]
+	if dtorrent == nil {
+		return fmt.Errorf("torrent %s not exists", infoHash)
+	}
+	options := map[string]any{}
+	if option.Name != "" || len(meta) > 0 {
+		name := option.Name
+		if name == "" {
+			name, _ = client.ParseMetaFromName(dtorrent.Name)
+		}
+		name = client.GenerateNameWithMeta(name, meta)
+		if name != dtorrent.Name {
+			options["name"] = name
+		}
+	}
+	if option.DownloadSpeedLimit != 0 && option.DownloadSpeedLimit != fromSpeedLimit(dtorrent.MaxDownloadSpeed) {
+		options["max_download_speed"] = toSpeedLimit(option.DownloadSpeedLimit)
+	}
+	if option.UploadSpeedLimit != 0 && option.UploadSpeedLimit != fromSpeedLimit(dtorrent.MaxUploadSpeed) {
+		options["max_upload_speed"] = toSpeedLimit(option.UploadSpeedLimit)
+	}
+	if err := dclient.setTorrentsOptions([]string{infoHash}, options); err != nil {
+		return err
+	}
+	if option.Category != "" && option.Category != dtorrent.Label {
+		if err := dclient.setLabel([]string{infoHash}, option.Category); err != nil {
+			return err
+		}
+	}
+	if option.SavePath != "" && option.SavePath != dtorrent.SavePath {
+		if err := dclient.SetTorrentsSavePath([]string{infoHash}, option.SavePath); err != nil {
+			return err
+		}
+	}
+	if len(option.Tags) > 0 || len(option.RemoveTags) > 0 {
+		if err := dclient.updateTorrentsTags([]string{infoHash}, option.Tags, option.RemoveTags); err != nil {
+			return err
+		}
+	}
+	var err error
+	if option.Pause {
+		err = dclient.PauseTorrents([]string{infoHash})
+	} else if option.Resume {
+		err = dclient.ResumeTorrents([]string{infoHash})
+	}
+	return err
+}
+
+func (dclient *Client) DeleteTorrents(infoHashes []string, deleteFiles bool) error {
+	if len(infoHashes) == 0 {
+		return nil
+	}
+	// a list of [infoHash, errorMsg] tuples of torrents that failed to be removed
+	var failures [][]any
+	if err := dclient.call("core.remove_torrents", &failures, infoHashes, deleteFiles); err != nil {
+		return err
+	}
+	if dclient.Cached() {
+		for _, infoHash := range infoHashes {
+			delete(dclient.torrents, infoHash)
+		}
+		dclient.buildDerivative()
+	}
+	if err := dclient.removeTorrentsMeta(infoHashes); err != nil {
+		log.Warnf("Failed to remove tags of deleted torrents: %v", err)
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to delete %d torrents: %v", len(failures), failures)
+	}
+	return nil
+}
+
+func (dclient *Client) PauseTorrents(infoHashes []string) error {
+	return dclient.call("core.pause_torrents", nil, infoHashes)
+}
+
+func (dclient *Client) ResumeTorrents(infoHashes []string) error {
+	return dclient.call("core.resume_torrents", nil, infoHashes)
+}
+
+func (dclient *Client) RecheckTorrents(infoHashes []string) error {
+	return dclient.call("core.force_recheck", nil, infoHashes)
+}
+
+func (dclient *Client) ReannounceTorrents(infoHashes []string) error {
+	return dclient.call("core.force_reannounce", nil, infoHashes)
+}
+
+func (dclient *Client) AddTagsToTorrents(infoHashes []string, tags []string) error {
+	return dclient.updateTorrentsTags(infoHashes, tags, nil)
+}
+
+func (dclient *Client) RemoveTagsFromTorrents(infoHashes []string, tags []string) error {
+	return dclient.updateTorrentsTags(infoHashes, nil, tags)
+}
+
+func (dclient *Client) SetTorrentsSavePath(infoHashes []string, savePath string) error {
+	return dclient.call("core.move_storage", nil, infoHashes, savePath)
+}
+
+func (dclient *Client) PauseAllTorrents() error {
+	infoHashes, err := dclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return dclient.PauseTorrents(infoHashes)
+}
+
+func (dclient *Client) ResumeAllTorrents() error {
+	infoHashes, err := dclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return dclient.ResumeTorrents(infoHashes)
+}
+
+func (dclient *Client) RecheckAllTorrents() error {
+	infoHashes, err := dclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return dclient.RecheckTorrents(infoHashes)
+}
+
+func (dclient *Client) ReannounceAllTorrents() error {
+	infoHashes, err := dclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return dclient.ReannounceTorrents(infoHashes)
+}
+
+func (dclient *Client) AddTagsToAllTorrents(tags []string) error {
+	infoHashes, err := dclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return dclient.AddTagsToTorrents(infoHashes, tags)
+}
+
+func (dclient *Client) RemoveTagsFromAllTorrents(tags []string) error {
+	infoHashes, err := dclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return dclient.RemoveTagsFromTorrents(infoHashes, tags)
+}
+
+func (dclient *Client) SetAllTorrentsSavePath(savePath string) error {
+	infoHashes, err := dclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return dclient.SetTorrentsSavePath(infoHashes, savePath)
+}
+
+func (dclient *Client) GetTags() ([]string, error) {
+	if err := dclient.sync(); err != nil {
+		return nil, err
+	}
+	tags := slices.Clone(dclient.meta.Tags)
+	for infoHash, torrentTags := range dclient.meta.Torrents {
+		// tags of torrents that were deleted outside ptool are ignored
+		if dclient.torrents[infoHash] != nil {
+			tags = append(tags, torrentTags...)
+		}
+	}
+	tags = util.UniqueSlice(tags)
+	slices.Sort(tags)
+	return tags, nil
+}
+
+func (dclient *Client) CreateTags(tags ...string) error {
+	if err := dclient.checkTags(); err != nil {
+		return err
+	}
+	return dclient.updateMeta(func(meta *clientMeta) {
+		for _, tag := range tags {
+			if tag != "" && !slices.Contains(meta.Tags, tag) {
+				meta.Tags = append(meta.Tags, tag)
+			}
+		}
+	})
+}
+
+func (dclient *Client) DeleteTags(tags ...string) error {
+	if err := dclient.checkTags(); err != nil {
+		return err
+	}
+	return dclient.updateMeta(func(meta *clientMeta) {
+		meta.Tags = slices.DeleteFunc(meta.Tags, func(tag string) bool {
+			return slices.Contains(tags, tag)
+		})
+		for infoHash, torrentTags := range meta.Torrents {
+			torrentTags = slices.DeleteFunc(torrentTags, func(tag string) bool {
+				return slices.Contains(tags, tag)
+			})
+			if len(torrentTags) > 0 {
+				meta.Torrents[infoHash] = torrentTags
+			} else {
+				delete(meta.Torrents, infoHash)
+			}
+		}
+	})
+}
+
+// Create or edit a label. The savePath is set as the label's "move completed" path.
+func (dclient *Client) MakeCategory(category string, savePath string) error {
+	category = strings.ToLower(category)
+	if err := dclient.ensureLabel(category); err != nil {
+		return err
+	}
+	options := map[string]any{
+		"apply_move_completed": savePath != "",
+		"move_completed":       savePath != "",
+		"move_completed_path":  savePath,
+	}
+	return dclient.call("label.set_options", nil, category, options)
+}
+
+func (dclient *Client) DeleteCategories(categories []string) error {
+	labels, err := dclient.getLabels()
+	if err != nil {
+		return err
+	}
+	for _, category := range categories {
+		category = strings.ToLower(category)
+		if !slices.Contains(labels, category) {
+			continue
+		}
+		if err := dclient.call("label.remove", nil, category); err != nil {
+			return err
+		}
+	}
+	dclient.labels = nil
+	return nil
+}
+
+func (dclient *Client) GetCategories() ([]*client.TorrentCategory, error) {
+	labels, err := dclient.getLabels()
+	if err != nil {
+		return nil, err
+	}
+	cats := []*client.TorrentCategory{}
+	for _, label := range labels {
+		options := map[string]any{}
+		if err := dclient.call("label.get_options", &options, label); err != nil {
+			return nil, err
+		}
+		savePath := ""
+		if move, _ := options["move_completed"].(bool); move {
+			savePath, _ = options["move_completed_path"].(string)
+		}
+		cats = append(cats, &client.TorrentCategory{
+			Name:     label,
+			SavePath: savePath,
+		})
+	}
+	return cats, nil
+}
+
+func (dclient *Client) SetTorrentsCatetory(infoHashes []string, category string) error {
+	return dclient.setLabel(infoHashes, category)
+}
+
+func (dclient *Client) SetAllTorrentsCatetory(category string) error {
+	infoHashes, err := dclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return dclient.setLabel(infoHashes, category)
+}
+
+// ratioLimit: 0 - use global ratio limit; -1 - no limit; > 0 - stop seeding at this ratio.
+// Deluge does not support seeding time limit.
+func (dclient *Client) SetTorrentsShareLimits(infoHashes []string, ratioLimit float64, seedingTimeLimit int64) error {
+	if seedingTimeLimit > 0 {
+		log.Warnf("deluge does not support seeding time limit, ignore it")
+	}
+	options := map[string]any{}
+	if ratioLimit > 0 {
+		options["stop_at_ratio"] = true
+		options["stop_ratio"] = ratioLimit
+	} else if ratioLimit < 0 {
+		options["stop_at_ratio"] = false
+	} else {
+		if err := dclient.syncMeta(); err != nil {
+			return err
+		}
+		options["stop_at_ratio"] = dclient.configValues["stop_seed_at_ratio"]
+		options["stop_ratio"] = dclient.configValues["stop_seed_ratio"]
+	}
+	return dclient.setTorrentsOptions(infoHashes, options)
+}
+
+func (dclient *Client) SetAllTorrentsShareLimits(ratioLimit float64, seedingTimeLimit int64) error {
+	infoHashes, err := dclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return dclient.SetTorrentsShareLimits(infoHashes, ratioLimit, seedingTimeLimit)
+}
+
+func (dclient *Client) TorrentRootPathExists(rootFolder string) bool {
+	if rootFolder == "" {
+		return false
+	}
+	if err := dclient.sync(); err != nil {
+		return false
+	}
+	for _, torrent := range dclient.torrents {
+		if strings.HasSuffix(torrent.ContentPath(), torrent.Sep()+rootFolder) {
+			return true
+		}
+	}
+	return false
+}
+
+func (dclient *Client) GetTorrentContents(infoHash string) ([]*client.TorrentContentFile, error) {
+	torrent, err := dclient.getTorrent(infoHash, "files", "file_progress", "file_priorities")
+	if err != nil {
+		return nil, err
+	}
+	files := []*client.TorrentContentFile{}
+	for i, file := range torrent.Files {
+		progress := float64(0)
+		if i < len(torrent.FileProgress) {
+			progress = torrent.FileProgress[i]
+		}
+		priority := int64(1)
+		if i < len(torrent.FilePriorities) {
+			priority = fromFilePriority(torrent.FilePriorities[i])
+		}
+		files = append(files, &client.TorrentContentFile{
+			Index:    file.Index,
+			Path:     file.Path,
+			Size:     file.Size,
+			Progress: progress,
+			Ignored:  priority == 0,
+			Priority: priority,
+			Complete: progress == 1,
+		})
+	}
+	return files, nil
+}
+
+func (dclient *Client) PurgeCache() {
+	dclient.datatime = 0
+	dclient.datatimeMeta = 0
+	dclient.unfinishedSize = 0
+	dclient.unfinishedDownloadingSize = 0
+	dclient.torrents = nil
+	dclient.labels = nil
+	dclient.sessionStatus = nil
+	dclient.configValues = nil
+	dclient.contentPathTorrents = nil
+}
+
+func (dclient *Client) GetStatus() (*client.Status, error) {
+	if err := dclient.syncMeta(); err != nil {
+		return nil, err
+	}
+	status := &client.Status{
+		DownloadSpeed:      int64(dclient.sessionStatus["payload_download_rate"]),
+		UploadSpeed:        int64(dclient.sessionStatus["payload_upload_rate"]),
+		DownloadSpeedLimit: dclient.getSpeedLimitConfig("max_download_speed"),
+		UploadSpeedLimit:   dclient.getSpeedLimitConfig("max_upload_speed"),
+		FreeSpaceOnDisk:    dclient.freeSpace,
+	}
+	if err := dclient.sync(); err == nil {
+		status.UnfinishedSize = dclient.unfinishedSize
+		status.UnfinishedDownloadingSize = dclient.unfinishedDownloadingSize
+	}
+	if labels, err := dclient.getLabels(); err == nil {
+		status.NoAdd = slices.Contains(labels, config.NOADD_TAG)
+		status.NoDel = slices.Contains(labels, config.NODEL_TAG)
+	}
+	return status, nil
+}
+
+// Return global speed limit config in B/s. 0 means no limit.
+func (dclient *Client) getSpeedLimitConfig(key string) int64 {
+	limit, _ := dclient.configValues[key].(float64)
+	if limit <= 0 {
+		return 0
+	}
+	return int64(limit * 1024)
+}
+
+func (dclient *Client) GetName() string {
+	return dclient.Name
+}
+
+func (dclient *Client) GetClientConfig() *config.ClientConfigStruct {
+	return dclient.ClientConfig
+}
+
+func (dclient *Client) setConfigValue(key string, value any) error {
+	dclient.datatimeMeta = 0
+	return dclient.call("core.set_config", nil, map[string]any{key: value})
+}
+
+func (dclient *Client) SetConfig(variable string, value string) error {
+	if strings.HasPrefix(variable, "de_") && len(variable) > 3 {
+		argValue, kind := util.String2Any(value)
+		if kind != reflect.Int64 && kind != reflect.Bool && kind != reflect.String {
+			return fmt.Errorf("invalid value type: %v", kind)
+		}
+		return dclient.setConfigValue(variable[3:], argValue)
+	}
+	switch variable {
+	case "global_download_speed_limit":
+		return dclient.setConfigValue("max_download_speed", toSpeedLimit(util.ParseInt(value)))
+	case "global_upload_speed_limit":
+		return dclient.setConfigValue("max_upload_speed", toSpeedLimit(util.ParseInt(value)))
+	case "free_disk_space", "global_download_speed", "global_upload_speed":
+		return fmt.Errorf("%s is read-only", variable)
+	case "save_path":
+		return dclient.setConfigValue("download_location", value)
+	default:
+		return nil
+	}
+}
+
+func (dclient *Client) GetConfig(variable string) (string, error) {
+	if strings.HasPrefix(variable, "de_") && len(variable) > 3 {
+		var value any
+		if err := dclient.call("core.get_config_value", &value, variable[3:]); err != nil {
+			return "", err
+		}
+		return fmt.Sprint(value), nil
+	}
+	status, err := dclient.GetStatus()
+	if err != nil {
+		return "", err
+	}
+	switch variable {
+	case "global_download_speed_limit":
+		return fmt.Sprint(status.DownloadSpeedLimit), nil
+	case "global_upload_speed_limit":
+		return fmt.Sprint(status.UploadSpeedLimit), nil
+	case "free_disk_space":
+		return fmt.Sprint(status.FreeSpaceOnDisk), nil
+	case "global_download_speed":
+		return fmt.Sprint(status.DownloadSpeed), nil
+	case "global_upload_speed":
+		return fmt.Sprint(status.UploadSpeed), nil
+	case "save_path":
+		savePath, _ := dclient.configValues["download_location"].(string)
+		return savePath, nil
+	default:
+		return "", nil
+	}
+}
+
+// Deluge only reports the status of the current tracker of a torrent.
+func (dclient *Client) GetTorrentTrackers(infoHash string) (client.TorrentTrackers, error) {
+	torrent, err := dclient.getTorrent(infoHash, "tracker", "trackers", "tracker_status")
+	if err != nil {
+		return nil, err
+	}
+	trackers := client.TorrentTrackers{}
+	for _, tracker := range torrent.Trackers {
+		status := "notcontacted"
+		msg := ""
+		if tracker.Url == torrent.Tracker {
+			status, msg = parseTrackerStatus(torrent.TrackerStatus)
+		}
+		trackers = append(trackers, client.TorrentTracker{
+			Url:    tracker.Url,
+			Status: status,
+			Msg:    msg,
+		})
+	}
+	return trackers, nil
+}
+
+// Parse Deluge tracker_status, e.g. "Announce OK", "Announce Sent", "Warning: xxx", "Error: xxx".
+func parseTrackerStatus(trackerStatus string) (status string, msg string) {
+	switch {
+	case trackerStatus == "":
+		return "notcontacted", ""
+	case trackerStatus == "Announce OK":
+		return "working", ""
+	case trackerStatus == "Announce Sent":
+		return "updating", ""
+	case strings.HasPrefix(trackerStatus, "Warning:"):
+		return "working", strings.TrimSpace(strings.TrimPrefix(trackerStatus, "Warning:"))
+	case strings.HasPrefix(trackerStatus, "Error:"):
+		return "error", strings.TrimSpace(strings.TrimPrefix(trackerStatus, "Error:"))
+	default:
+		return "unknown", trackerStatus
+	}
+}
+
+func (dclient *Client) setTrackers(infoHash string, trackers []apiTorrentTracker) error {
+	if err := dclient.call("core.set_torrent_trackers", nil, infoHash, trackers); err != nil {
+		return err
+	}
+	if dclient.torrents != nil && dclient.torrents[infoHash] != nil {
+		dclient.torrents[infoHash].Trackers = trackers
+	}
+	return nil
+}
+
+func (dclient *Client) EditTorrentTracker(infoHash string, oldTracker string, newTracker string,
+	replaceHost bool) error {
+	torrent, err := dclient.getTorrent(infoHash, "trackers")
+	if err != nil {
+		return err
+	}
+	index := -1
+	newTrackerUrl := newTracker
+	directNewUrlMode := util.IsUrl(newTracker)
+	for i, tracker := range torrent.Trackers {
+		if replaceHost {
+			oldTrackerUrlObj, err := url.Parse(tracker.Url)
+			if err != nil {
+				continue
+			}
+			if oldTrackerUrlObj.Host == oldTracker {
+				index = i
+				if !directNewUrlMode {
+					oldTrackerUrlObj.Host = newTracker
+					newTrackerUrl = oldTrackerUrlObj.String()
+				}
+				break
+			}
+		} else if tracker.Url == oldTracker {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		return fmt.Errorf("torrent %s old tracker %s does NOT exist", infoHash, oldTracker)
+	}
+	if torrent.Trackers[index].Url == newTrackerUrl {
+		return nil
+	}
+	torrent.Trackers[index].Url = newTrackerUrl
+	return dclient.setTrackers(infoHash, torrent.Trackers)
+}
+
+func (dclient *Client) AddTorrentTrackers(infoHash string, trackers []string,
+	oldTracker string, removeExisting bool) error {
+	torrent, err := dclient.getTorrent(infoHash, "trackers")
+	if err != nil {
+		return err
+	}
+	if oldTracker != "" {
+		if !slices.ContainsFunc(torrent.Trackers, func(tracker apiTorrentTracker) bool {
+			return util.MatchUrlWithHostOrUrl(tracker.Url, oldTracker)
+		}) {
+			return nil
+		}
+	}
+	newTrackers := []apiTorrentTracker{}
+	tier := int64(0)
+	if !removeExisting {
+		newTrackers = torrent.Trackers
+		for _, tracker := range torrent.Trackers {
+			tier = max(tier, tracker.Tier+1)
+		}
+	}
+	added := false
+	for _, tracker := range trackers {
+		if slices.ContainsFunc(newTrackers, func(t apiTorrentTracker) bool { return t.Url == tracker }) {
+			continue
+		}
+		newTrackers = append(newTrackers, apiTorrentTracker{Url: tracker, Tier: tier})
+		tier++
+		added = true
+	}
+	if !added && !removeExisting {
+		return nil
+	}
+	return dclient.setTrackers(infoHash, newTrackers)
+}
+
+func (dclient *Client) RemoveTorrentTrackers(infoHash string, trackers []string) error {
+	torrent, err := dclient.getTorrent(infoHash, "trackers")
+	if err != nil {
+		return err
+	}
+	newTrackers := util.Filter(torrent.Trackers, func(tracker apiTorrentTracker) bool {
+		return !slices.Contains(trackers, tracker.Url)
+	})
+	if len(newTrackers) == len(torrent.Trackers) {
+		return nil
+	}
+	return dclient.setTrackers(infoHash, newTrackers)
+}
+
+// priority: qBittorrent style value, will be converted to Deluge file priority.
+func (dclient *Client) SetFilePriority(infoHash string, fileIndexes []int64, priority int64) error {
+	torrent, err := dclient.getTorrent(infoHash, "file_priorities")
+	if err != nil {
+		return err
+	}
+	priorities := torrent.FilePriorities
+	for _, index := range fileIndexes {
+		if index < 0 || index >= int64(len(priorities)) {
+			return fmt.Errorf("invalid file index %d", index)
+		}
+		priorities[index] = toFilePriority(priority)
+	}
+	return dclient.setTorrentsOptions([]string{infoHash}, map[string]any{"file_priorities": priorities})
+}
+
+func (dclient *Client) Close() {
+	dclient.PurgeCache()
+	if dclient.Logined {
+		dclient.rpc("auth.delete_session", nil)
+		dclient.Logined = false
+	}
+}
+
+func NewClient(name string, clientConfig *config.ClientConfigStruct, config *config.ConfigStruct) (
+	client.Client, error) {
+	urlObj, err := url.Parse(clientConfig.Url)
+	if err != nil || (urlObj.Scheme != "http" && urlObj.Scheme != "https") || urlObj.Host == "" {
+		return nil, fmt.Errorf("invalid deluge url: %s", clientConfig.Url)
+	}
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		Name:         name,
+		ClientConfig: clientConfig,
+		Config:       config,
+		HttpClient: &http.Client{
+			Jar: jar,
+		},
+	}, nil
+}
+
+func init() {
+	client.Register(&client.RegInfo{
+		Name:    "deluge",
+		Creator: NewClient,
+	})
+}
+
+var (
+	_ client.Client = (*Client)(nil)
+)
@@ -0,0 +1,213 @@
+package deluge_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/client/deluge"
+	"github.com/sagan/ptool/config"
+)
+
+// A minimal fake Deluge Web JSON-RPC server.
+type fakeDeluge struct {
+	password string
+	session  bool
+	labels   []string
+	noLabel  bool // Label plugin is not enabled
+	torrents map[string]map[string]any
+	calls    []string
+}
+
+func (fd *fakeDeluge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Method string `json:"method"`
+		Params []any  `json:"params"`
+		Id     int64  `json:"id"`
+	}
+	if r.URL.Path != "/json" || json.NewDecoder(r.Body).Decode(&req) != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	fd.calls = append(fd.calls, req.Method)
+	var result any
+	var rpcErr any
+	switch {
+	case req.Method == "auth.login":
+		fd.session = req.Params[0] == fd.password
+		result = fd.session
+	case !fd.session:
+		rpcErr = map[string]any{"message": "Not authenticated", "code": 1}
+	case req.Method == "web.connected":
+		result = true
+	case req.Method == "core.get_torrents_status":
+		result = fd.torrents
+	case strings.HasPrefix(req.Method, "label.") && fd.noLabel:
+		rpcErr = map[string]any{"message": "Unknown method", "code": 2}
+	case req.Method == "core.add_torrent_file":
+		fd.torrents["cccc"] = map[string]any{"hash": "cccc", "name": "foo", "state": "Paused",
+			"save_path": "/downloads", "label": ""}
+		result = "cccc"
+	case req.Method == "label.get_labels":
+		result = fd.labels
+	case req.Method == "label.add":
+		fd.labels = append(fd.labels, req.Params[0].(string))
+	case req.Method == "label.set_torrent":
+		fd.torrents[req.Params[0].(string)]["label"] = req.Params[1]
+	case req.Method == "core.remove_torrents":
+		for _, id := range req.Params[0].([]any) {
+			delete(fd.torrents, id.(string))
+		}
+		result = []any{}
+	default:
+		rpcErr = map[string]any{"message": "Unknown method", "code": 2}
+	}
+	json.NewEncoder(w).Encode(map[string]any{"id": req.Id, "result": result, "error": rpcErr})
+}
+
+func newTestClient(t *testing.T) (*deluge.Client, *fakeDeluge) {
+	config.ConfigDir = t.TempDir() // tags are stored in it
+	fd := &fakeDeluge{
+		password: "secret",
+		labels:   []string{},
+		torrents: map[string]map[string]any{
+			"aaaa": {
+				"hash": "aaaa", "name": "Foo__meta.id_123", "state": "Seeding", "save_path": "/downloads",
+				"total_wanted": 100, "total_done": 100, "is_finished": true, "label": "",
+				"max_download_speed": -1, "max_upload_speed": 10, "time_since_transfer": -1,
+				"tracker": "https://tracker.example.com/announce",
+			},
+			"bbbb": {
+				"hash": "bbbb", "name": "Bar", "state": "Downloading", "save_path": "/downloads",
+				"total_wanted": 100, "total_done": 40, "label": "",
+			},
+		},
+	}
+	return newTestClientOf(t, fd)
+}
+
+// Return a new client of fake server fd.
+func newTestClientOf(t *testing.T, fd *fakeDeluge) (*deluge.Client, *fakeDeluge) {
+	server := httptest.NewServer(fd)
+	t.Cleanup(server.Close)
+	clientInstance, err := deluge.NewClient("de", &config.ClientConfigStruct{
+		Type:     "deluge",
+		Url:      server.URL + "/",
+		Password: fd.password,
+	}, &config.ConfigStruct{})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	return clientInstance.(*deluge.Client), fd
+}
+
+func TestGetTorrents(t *testing.T) {
+	dclient, _ := newTestClient(t)
+	torrent, err := dclient.GetTorrent("aaaa")
+	if err != nil || torrent == nil {
+		t.Fatalf("GetTorrent error: %v", err)
+	}
+	if torrent.Name != "Foo" || torrent.Meta["id"] != 123 || torrent.State != "seeding" ||
+		torrent.ContentPath != "/downloads/Foo" || torrent.TrackerDomain != "tracker.example.com" ||
+		torrent.DownloadSpeedLimit != -1 || torrent.UploadedSpeedLimit != 10240 {
+		t.Errorf("unexpected torrent: %+v", torrent)
+	}
+	torrents, err := dclient.GetTorrents("_undone", "", true)
+	if err != nil {
+		t.Fatalf("GetTorrents error: %v", err)
+	}
+	if len(torrents) != 1 || torrents[0].InfoHash != "bbbb" {
+		t.Errorf("unexpected undone torrents: %v", torrents)
+	}
+	torrents, err = dclient.GetTorrentsByContentPath("/downloads/Bar")
+	if err != nil || len(torrents) != 1 {
+		t.Errorf("GetTorrentsByContentPath: got %v, err %v", torrents, err)
+	}
+}
+
+func TestReloginAndCategory(t *testing.T) {
+	dclient, fd := newTestClient(t)
+	if _, err := dclient.GetTorrents("", "", true); err != nil {
+		t.Fatalf("GetTorrents error: %v", err)
+	}
+	fd.session = false // web session expired
+	if err := dclient.SetTorrentsCatetory([]string{"aaaa"}, "Movie"); err != nil {
+		t.Fatalf("SetTorrentsCatetory error: %v", err)
+	}
+	if !slices.Contains(fd.labels, "movie") || fd.torrents["aaaa"]["label"] != "movie" {
+		t.Errorf("label not set: labels=%v, torrent=%v", fd.labels, fd.torrents["aaaa"])
+	}
+	if logins := len(slices.DeleteFunc(slices.Clone(fd.calls), func(m string) bool {
+		return m != "auth.login"
+	})); logins != 2 {
+		t.Errorf("expect re-login after session expired, calls: %v", fd.calls)
+	}
+	if err := dclient.DeleteTorrents([]string{"aaaa"}, false); err != nil {
+		t.Fatalf("DeleteTorrents error: %v", err)
+	}
+	if _, ok := fd.torrents["aaaa"]; ok {
+		t.Errorf("torrent not deleted")
+	}
+}
+
+func TestTags(t *testing.T) {
+	dclient, fd := newTestClient(t)
+	torrentContent := []byte("d4:infod6:lengthi1e4:name3:foo12:piece lengthi16384e6:pieces20:" +
+		strings.Repeat("a", 20) + "ee")
+
+	fd.noLabel = true
+	err := dclient.AddTorrent(torrentContent, &client.TorrentOption{Tags: []string{"_brush"}}, nil)
+	if !errors.Is(err, deluge.ErrTagsUnsupported) {
+		t.Errorf("AddTorrent with tags (no Label plugin) = %v, want ErrTagsUnsupported", err)
+	}
+	if slices.Contains(fd.calls, "core.add_torrent_file") {
+		t.Errorf("torrent with tags should not be added, calls: %v", fd.calls)
+	}
+	err = dclient.ModifyTorrent("aaaa", &client.TorrentOption{Category: "movie", RemoveTags: []string{"pt"}}, nil)
+	if !errors.Is(err, deluge.ErrTagsUnsupported) {
+		t.Errorf("ModifyTorrent with tags (no Label plugin) = %v, want ErrTagsUnsupported", err)
+	}
+	if fd.torrents["aaaa"]["label"] != "" {
+		t.Errorf("torrent should not be modified when tags are unsupported")
+	}
+
+	fd.noLabel = false
+	if err := dclient.AddTorrent(torrentContent, &client.TorrentOption{Tags: []string{"_brush", "site:foo"}},
+		nil); err != nil {
+		t.Fatalf("AddTorrent error: %v", err)
+	}
+	if err := dclient.AddTagsToTorrents([]string{"aaaa", "cccc"}, []string{"xseed"}); err != nil {
+		t.Fatalf("AddTagsToTorrents error: %v", err)
+	}
+	if err := dclient.ModifyTorrent("cccc", &client.TorrentOption{RemoveTags: []string{"_brush"}}, nil); err != nil {
+		t.Fatalf("ModifyTorrent error: %v", err)
+	}
+	// tags are persisted
+	dclient, _ = newTestClientOf(t, fd)
+	torrent, err := dclient.GetTorrent("cccc")
+	if err != nil || torrent == nil || !slices.Equal(torrent.Tags, []string{"site:foo", "xseed"}) {
+		t.Errorf("GetTorrent() = %+v, %v; want tags [site:foo xseed]", torrent, err)
+	}
+	if tags, err := dclient.GetTags(); err != nil || !slices.Equal(tags, []string{"site:foo", "xseed"}) {
+		t.Errorf("GetTags() = %v, %v", tags, err)
+	}
+
+	if err := dclient.DeleteTags("xseed"); err != nil {
+		t.Fatalf("DeleteTags error: %v", err)
+	}
+	if err := dclient.DeleteTorrents([]string{"cccc"}, false); err != nil {
+		t.Fatalf("DeleteTorrents error: %v", err)
+	}
+	dclient.PurgeCache()
+	if torrent, _ := dclient.GetTorrent("aaaa"); torrent == nil || len(torrent.Tags) != 0 {
+		t.Errorf("GetTorrent() = %+v, want no tags", torrent)
+	}
+	if tags, err := dclient.GetTags(); err != nil || len(tags) != 0 {
+		t.Errorf("GetTags() after delete = %v, %v", tags, err)
+	}
+}
@@ -0,0 +1,114 @@
+package deluge
+
+// Deluge has no tags, and the Label plugin only allows one (lowercase) label per torrent, which is used as category.
+// Tags are persisted to a local file "<config_dir>/deluge-<client>.json" (per torrent tags, and created but
+// not yet used tags), so they behave the same as in qBittorrent. Tags require the Label plugin to be enabled.
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+)
+
+type clientMeta struct {
+	Tags     []string            `json:"tags"`     // created tags
+	Torrents map[string][]string `json:"torrents"` // infoHash => tags
+}
+
+func (dclient *Client) metaFilename() string {
+	return filepath.Join(config.ConfigDir, fmt.Sprintf(config.DELUGE_META_FILE, dclient.Name))
+}
+
+// Load client meta from local file. Return an empty meta if file does not exist.
+func (dclient *Client) loadMeta() (*clientMeta, error) {
+	meta := &clientMeta{}
+	data, err := os.ReadFile(dclient.metaFilename())
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+	} else if err = json.Unmarshal(data, meta); err != nil {
+		err = fmt.Errorf("invalid deluge meta file %s: %w", dclient.metaFilename(), err)
+	}
+	if meta.Torrents == nil {
+		meta.Torrents = map[string][]string{}
+	}
+	return meta, err
+}
+
+func (dclient *Client) saveMeta(meta *clientMeta) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(config.ConfigDir, constants.PERM_DIR); err != nil {
+		return err
+	}
+	return os.WriteFile(dclient.metaFilename(), data, constants.PERM)
+}
+
+// Modify client meta and save it.
+func (dclient *Client) updateMeta(updater func(meta *clientMeta)) error {
+	meta, err := dclient.loadMeta()
+	if err != nil {
+		return err
+	}
+	updater(meta)
+	if err = dclient.saveMeta(meta); err != nil {
+		return err
+	}
+	if dclient.meta != nil {
+		dclient.meta = meta
+	}
+	return nil
+}
+
+// Remove tags of (deleted) torrents.
+func (dclient *Client) removeTorrentsMeta(infoHashes []string) error {
+	meta, err := dclient.loadMeta()
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(infoHashes, func(infoHash string) bool { return meta.Torrents[infoHash] != nil }) {
+		return nil
+	}
+	return dclient.updateMeta(func(meta *clientMeta) {
+		for _, infoHash := range infoHashes {
+			delete(meta.Torrents, infoHash)
+		}
+	})
+}
+
+// Return nil if tags are supported, that is, the Label plugin is enabled.
+func (dclient *Client) checkTags() error {
+	if _, err := dclient.getLabels(); err != nil {
+		return fmt.Errorf("%w: %w", ErrTagsUnsupported, err)
+	}
+	return nil
+}
+
+// Add tags to and remove removeTags from torrents.
+func (dclient *Client) updateTorrentsTags(infoHashes []string, tags []string, removeTags []string) error {
+	if err := dclient.checkTags(); err != nil {
+		return err
+	}
+	return dclient.updateMeta(func(meta *clientMeta) {
+		for _, infoHash := range infoHashes {
+			torrentTags := util.UniqueSlice(append(slices.Clone(meta.Torrents[infoHash]), tags...))
+			torrentTags = slices.DeleteFunc(torrentTags, func(tag string) bool {
+				return tag == "" || slices.Contains(removeTags, tag)
+			})
+			if len(torrentTags) > 0 {
+				meta.Torrents[infoHash] = torrentTags
+			} else {
+				delete(meta.Torrents, infoHash)
+			}
+		}
+	})
+}
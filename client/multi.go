@@ -0,0 +1,123 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/util"
+)
+
+// Parse a {client} arg, which is a client name or a client group name (including the built-in "_all" group),
+// return the client names.
+func ParseClientNames(name string) ([]string, error) {
+	names := config.ParseClientGroupAndOtherNames(name)
+	if len(names) == 0 {
+		return nil, fmt.Errorf("client group %s is empty", name)
+	}
+	for _, name := range names {
+		if !ClientExists(name) {
+			return nil, fmt.Errorf("client %s not existed", name)
+		}
+	}
+	return names, nil
+}
+
+// Create client instances of names, in the same order.
+func CreateClients(names []string) ([]Client, error) {
+	clientInstances := []Client{}
+	for _, name := range names {
+		clientInstance, err := CreateClient(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client %s: %w", name, err)
+		}
+		clientInstances = append(clientInstances, clientInstance)
+	}
+	return clientInstances, nil
+}
+
+// Parse a {client} arg (see ParseClientNames) and create the client instances.
+func CreateClientsFromArg(name string) ([]Client, error) {
+	names, err := ParseClientNames(name)
+	if err != nil {
+		return nil, err
+	}
+	return CreateClients(names)
+}
+
+// Run fn on each client concurrently and wait for all of them to finish.
+// If there is only one client, the error of fn is returned as it is.
+// Otherwise the error of each client is logged and a "<n> errors" error is returned if any one fails.
+func RunOnClients(clientInstances []Client, fn func(clientInstance Client) error) error {
+	if len(clientInstances) == 1 {
+		return fn(clientInstances[0])
+	}
+	errs := make([]error, len(clientInstances))
+	var wg sync.WaitGroup
+	for i, clientInstance := range clientInstances {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = fn(clientInstance)
+		}()
+	}
+	wg.Wait()
+	errorCnt := int64(0)
+	for i, err := range errs {
+		if err != nil {
+			log.Errorf("Client %s: %v", clientInstances[i].GetName(), err)
+			errorCnt++
+		}
+	}
+	if errorCnt > 0 {
+		return fmt.Errorf("%d errors", errorCnt)
+	}
+	return nil
+}
+
+// Query torrents of each client concurrently using fn, return all found torrents.
+// If there are multiple clients, the Client field of each returned torrent is set to the client name.
+func QueryClientsTorrents(clientInstances []Client,
+	fn func(clientInstance Client) ([]*Torrent, error)) ([]*Torrent, error) {
+	results := make([][]*Torrent, len(clientInstances))
+	err := RunOnClients(clientInstances, func(clientInstance Client) error {
+		torrents, err := fn(clientInstance)
+		if err != nil {
+			return err
+		}
+		if len(clientInstances) > 1 {
+			// torrents may be the client's cached objects, set Client field on copies
+			clientTorrents := make([]*Torrent, 0, len(torrents))
+			for _, torrent := range torrents {
+				clientTorrent := *torrent
+				clientTorrent.Client = clientInstance.GetName()
+				clientTorrents = append(clientTorrents, &clientTorrent)
+			}
+			torrents = clientTorrents
+		}
+		for i := range clientInstances {
+			if clientInstances[i] == clientInstance {
+				results[i] = torrents
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	torrents := []*Torrent{}
+	for _, result := range results {
+		torrents = append(torrents, result...)
+	}
+	return torrents, nil
+}
+
+// Return the torrents of clientInstance, from the torrents returned by QueryClientsTorrents(clientInstances, ...).
+func GetClientTorrents(clientInstances []Client, clientInstance Client, torrents []*Torrent) []*Torrent {
+	if len(clientInstances) == 1 {
+		return torrents
+	}
+	return util.Filter(torrents, func(t *Torrent) bool { return t.Client == clientInstance.GetName() })
+}
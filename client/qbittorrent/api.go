@@ -346,6 +346,15 @@ func (qbtorrent *apiTorrentInfo) ToTorrent() *client.Torrent {
 		Leechers:           qbtorrent.Num_incomplete,
 		Meta:               map[string]int64{},
 	}
+	// qb share limits: -2 means global limit, -1 means no limit. Seeding time limit is in minutes.
+	if qbtorrent.Ratio_limit >= 0 || qbtorrent.Ratio_limit == -1 {
+		torrent.RatioLimit = qbtorrent.Ratio_limit
+	}
+	if qbtorrent.Seeding_time_limit > 0 {
+		torrent.SeedingTimeLimit = qbtorrent.Seeding_time_limit * 60
+	} else if qbtorrent.Seeding_time_limit == -1 {
+		torrent.SeedingTimeLimit = -1
+	}
 	torrent.Name, torrent.Meta = client.ParseMetaFromName(torrent.Name)
 	return torrent
 }
@@ -69,11 +69,9 @@ func (qbclient *Client) SetTorrentsShareLimits(infoHashes []string, ratioLimit f
 		ratioLimit = -2 // use global limit
 	}
 	data.Add("ratioLimit", fmt.Sprint(ratioLimit))
-	seedingTimeLimitValue := seedingTimeLimit
+	seedingTimeLimitValue := seedingTimeLimitMinutes(seedingTimeLimit)
 	if seedingTimeLimitValue == 0 {
 		seedingTimeLimitValue = -2 //use global limit
-	} else if seedingTimeLimitValue > 0 {
-		seedingTimeLimitValue = seedingTimeLimitValue/60 + 1
 	}
 	data.Add("seedingTimeLimit", fmt.Sprint(seedingTimeLimitValue))
 	// The maximum amount of time (minutes) the torrent is allowed to seed while being inactive.
@@ -82,6 +80,15 @@ func (qbclient *Client) SetTorrentsShareLimits(infoHashes []string, ratioLimit f
 	return qbclient.apiPost("api/v2/torrents/setShareLimits", data)
 }
 
+// Convert seeding time limit (seconds) to qb value (minutes). Positive value is rounded up to minutes,
+// so that a limit read from qb (minutes * 60) is set back unchanged.
+func seedingTimeLimitMinutes(seconds int64) int64 {
+	if seconds > 0 {
+		return (seconds + 59) / 60
+	}
+	return seconds
+}
+
 func (qbclient *Client) apiPost(apiUrl string, data url.Values) error {
 	resp, err := qbclient.HttpClient.PostForm(qbclient.ClientConfig.Url+apiUrl, data)
 	if err != nil {
@@ -210,11 +217,7 @@ func (qbclient *Client) AddTorrent(torrentContent []byte, option *client.Torrent
 			mp.WriteField("ratioLimit", fmt.Sprint(option.RatioLimit))
 		}
 		if option.SeedingTimeLimit != 0 {
-			value := option.SeedingTimeLimit
-			if value > 0 {
-				value = value/60 + 1
-			}
-			mp.WriteField("seedingTimeLimit", fmt.Sprint(value))
+			mp.WriteField("seedingTimeLimit", fmt.Sprint(seedingTimeLimitMinutes(option.SeedingTimeLimit)))
 		}
 	}
 	mp.Close()
@@ -954,6 +957,7 @@ func (qbclient *Client) GetTorrentContents(infoHash string) ([]*client.TorrentCo
 			Path:     strings.ReplaceAll(qbTorrentContent.Name, `\`, "/"),
 			Size:     qbTorrentContent.Size,
 			Ignored:  qbTorrentContent.Priority == 0,
+			Priority: qbTorrentContent.Priority,
 			Complete: qbTorrentContent.Is_seed,
 			Progress: qbTorrentContent.Progress,
 		})
@@ -0,0 +1,27 @@
+package qbittorrent
+
+import "testing"
+
+func TestSeedingTimeLimitMinutes(t *testing.T) {
+	for _, c := range []struct {
+		seconds int64
+		want    int64
+	}{
+		{0, 0},
+		{-1, -1},
+		{-2, -2},
+		{1, 1},
+		{60, 1},
+		{61, 2},
+		{3600, 60},
+	} {
+		if got := seedingTimeLimitMinutes(c.seconds); got != c.want {
+			t.Errorf("seedingTimeLimitMinutes(%d) = %d, want %d", c.seconds, got, c.want)
+		}
+	}
+	// the limit read from qb is set back unchanged
+	qbtorrent := &apiTorrentInfo{Seeding_time_limit: 61, Ratio_limit: -2}
+	if got := seedingTimeLimitMinutes(qbtorrent.ToTorrent().SeedingTimeLimit); got != 61 {
+		t.Errorf("seeding time limit round trip = %d minutes, want 61", got)
+	}
+}
@@ -0,0 +1,1201 @@
+package rtorrent
+
+// rTorrent (0.9.x+) client using XML-RPC api. ruTorrent compatible.
+// protocol: https://rtorrent-docs.readthedocs.io/en/latest/cmd-ref.html
+// The torrent category is stored in "custom1" (the ruTorrent label, URI encoded).
+// Tags are stored as a comma-separated list in d.custom "ptool_tags" key.
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+)
+
+const (
+	TAGS_KEY    = "ptool_tags" // d.custom key of torrent tags
+	ADDTIME_KEY = "addtime"    // d.custom key of torrent added time (set by ruTorrent)
+)
+
+type Client struct {
+	Name                      string
+	ClientConfig              *config.ClientConfigStruct
+	Config                    *config.ConfigStruct
+	HttpClient                *http.Client
+	datatime                  int64
+	datatimeMeta              int64
+	torrents                  map[string]*rtTorrent
+	downloadRate              int64
+	uploadRate                int64
+	downloadRateLimit         int64
+	uploadRateLimit           int64
+	defaultDirectory          string
+	freeSpace                 int64
+	unfinishedSize            int64
+	unfinishedDownloadingSize int64
+	contentPathTorrents       map[string][]*rtTorrent
+}
+
+// rTorrent torrent (download) info.
+type rtTorrent struct {
+	Hash           string
+	Name           string
+	State          int64 // 0 - stopped; 1 - started
+	IsActive       bool  // false if started but paused
+	Complete       bool
+	IsHashChecking bool
+	IsMultiFile    bool
+	Size           int64
+	CompletedBytes int64
+	Downloaded     int64
+	Uploaded       int64
+	DownloadRate   int64
+	UploadRate     int64
+	Directory      string // for multi-file torrent, it's the content path
+	BasePath       string // empty if torrent is closed
+	Custom1        string
+	CustomTags     string
+	CustomAddtime  string
+	StartedTime    int64
+	FinishedTime   int64
+	Message        string
+	PeersComplete  int64
+	PeersAccounted int64
+	Tracker        string // the first enabled tracker url
+}
+
+// The d.multicall2 commands used to fetch torrents list. Must be in the same order as parsed in parseTorrent.
+var torrentFields = []string{
+	"d.hash=", "d.name=", "d.state=", "d.is_active=", "d.complete=", "d.is_hash_checking=", "d.is_multi_file=",
+	"d.size_bytes=", "d.completed_bytes=", "d.down.total=", "d.up.total=", "d.down.rate=", "d.up.rate=",
+	"d.directory=", "d.base_path=", "d.custom1=", "d.custom=" + TAGS_KEY, "d.custom=" + ADDTIME_KEY,
+	"d.timestamp.started=", "d.timestamp.finished=", "d.message=",
+	"d.peers_complete=", "d.peers_accounted=",
+}
+
+func parseTorrent(values []any) *rtTorrent {
+	if len(values) < len(torrentFields) {
+		return nil
+	}
+	return &rtTorrent{
+		Hash:           strings.ToLower(toString(values[0])),
+		Name:           toString(values[1]),
+		State:          toInt64(values[2]),
+		IsActive:       toInt64(values[3]) == 1,
+		Complete:       toInt64(values[4]) == 1,
+		IsHashChecking: toInt64(values[5]) == 1,
+		IsMultiFile:    toInt64(values[6]) == 1,
+		Size:           toInt64(values[7]),
+		CompletedBytes: toInt64(values[8]),
+		Downloaded:     toInt64(values[9]),
+		Uploaded:       toInt64(values[10]),
+		DownloadRate:   toInt64(values[11]),
+		UploadRate:     toInt64(values[12]),
+		Directory:      toString(values[13]),
+		BasePath:       toString(values[14]),
+		Custom1:        toString(values[15]),
+		CustomTags:     toString(values[16]),
+		CustomAddtime:  toString(values[17]),
+		StartedTime:    toInt64(values[18]),
+		FinishedTime:   toInt64(values[19]),
+		Message:        toString(values[20]),
+		PeersComplete:  toInt64(values[21]),
+		PeersAccounted: toInt64(values[22]),
+	}
+}
+
+func (rt *rtTorrent) ToTorrentState() string {
+	switch {
+	case rt.IsHashChecking:
+		return "checking"
+	case rt.State == 0 || !rt.IsActive:
+		if rt.Complete {
+			return "completed"
+		}
+		return "paused"
+	case rt.Message != "" && rt.DownloadRate == 0 && rt.UploadRate == 0 && !rt.Complete &&
+		!strings.HasPrefix(rt.Message, "Tracker: "):
+		return "error"
+	case rt.Complete:
+		return "seeding"
+	default:
+		return "downloading"
+	}
+}
+
+func (rt *rtTorrent) Sep() string {
+	if !strings.Contains(rt.Directory, `/`) && strings.Contains(rt.Directory, `\`) {
+		return `\`
+	}
+	return `/`
+}
+
+func (rt *rtTorrent) SavePath() string {
+	if rt.IsMultiFile {
+		dir := strings.TrimSuffix(rt.Directory, rt.Sep())
+		if i := strings.LastIndex(dir, rt.Sep()); i >= 0 {
+			return dir[:i]
+		}
+		return dir
+	}
+	return rt.Directory
+}
+
+func (rt *rtTorrent) ContentPath() string {
+	if rt.IsMultiFile {
+		return strings.TrimSuffix(rt.Directory, rt.Sep())
+	}
+	return strings.TrimSuffix(rt.Directory, rt.Sep()) + rt.Sep() + rt.Name
+}
+
+func (rt *rtTorrent) Category() string {
+	if category, err := url.PathUnescape(rt.Custom1); err == nil {
+		return category
+	}
+	return rt.Custom1
+}
+
+// Return raw tags (including substitute tags)
+func (rt *rtTorrent) Tags() []string {
+	return util.SplitCsv(rt.CustomTags)
+}
+
+// now: the timestamp that the torrent info was fetched.
+func (rt *rtTorrent) ToTorrent(now int64) *client.Torrent {
+	atime := rt.StartedTime
+	if addtime := util.ParseInt(strings.TrimSpace(rt.CustomAddtime)); addtime > 0 {
+		atime = addtime
+	}
+	// rtorrent does not record the latest activity time of torrent.
+	activityTime := max(atime, rt.FinishedTime)
+	if rt.DownloadRate > 0 || rt.UploadRate > 0 {
+		activityTime = now
+	}
+	torrent := &client.Torrent{
+		InfoHash:           rt.Hash,
+		Name:               rt.Name,
+		TrackerDomain:      util.ParseUrlHostname(rt.Tracker),
+		TrackerBaseDomain:  util.GetUrlDomain(rt.Tracker),
+		Tracker:            rt.Tracker,
+		State:              rt.ToTorrentState(),
+		LowLevelState:      fmt.Sprintf("state=%d,active=%t,complete=%t", rt.State, rt.IsActive, rt.Complete),
+		Atime:              atime,
+		Ctime:              rt.FinishedTime,
+		ActivityTime:       activityTime,
+		Category:           rt.Category(),
+		SavePath:           rt.SavePath(),
+		ContentPath:        rt.ContentPath(),
+		Tags:               rt.Tags(),
+		Downloaded:         rt.Downloaded,
+		DownloadSpeed:      rt.DownloadRate,
+		DownloadSpeedLimit: -1,
+		Uploaded:           rt.Uploaded,
+		UploadSpeed:        rt.UploadRate,
+		UploadedSpeedLimit: -1,
+		Size:               rt.Size,
+		SizeTotal:          rt.Size,
+		SizeCompleted:      rt.CompletedBytes,
+		Seeders:            rt.PeersComplete,
+		Leechers:           rt.PeersAccounted - rt.PeersComplete,
+	}
+	torrent.Meta = torrent.GetMetadataFromTags()
+	torrent.RemoveSubstituteTags()
+	return torrent
+}
+
+// Return the rTorrent target (download hash) of a torrent. rTorrent uses uppercase info-hash.
+func target(infoHash string) string {
+	return strings.ToUpper(infoHash)
+}
+
+// Call a rTorrent XML-RPC method.
+func (rtclient *Client) call(method string, params ...any) (any, error) {
+	body, err := encodeMethodCall(method, params...)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, rtclient.ClientConfig.Url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/xml")
+	if rtclient.ClientConfig.Username != "" || rtclient.ClientConfig.Password != "" {
+		req.SetBasicAuth(rtclient.ClientConfig.Username, rtclient.ClientConfig.Password)
+	}
+	res, err := util.HttpRequest(req, rtclient.HttpClient)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return nil, fmt.Errorf("%s: response error: status=%d", method, res.StatusCode)
+	}
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	result, err := decodeMethodResponse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+	return result, nil
+}
+
+// Call a torrent (download) method on each of the infoHashes.
+func (rtclient *Client) callEach(infoHashes []string, method string, params ...any) error {
+	if len(infoHashes) == 0 {
+		return nil
+	}
+	calls := []any{}
+	for _, infoHash := range infoHashes {
+		calls = append(calls, map[string]any{
+			"methodName": method,
+			"params":     append([]any{target(infoHash)}, params...),
+		})
+	}
+	return rtclient.multicall(calls)
+}
+
+// Execute multiple calls in one request via system.multicall. Return the first fault if any.
+func (rtclient *Client) multicall(calls []any) error {
+	result, err := rtclient.call("system.multicall", calls)
+	if err != nil {
+		return err
+	}
+	for _, item := range toSlice(result) {
+		if fault, ok := item.(map[string]any); ok {
+			return &xmlrpcFault{Code: toInt64(fault["faultCode"]), Message: toString(fault["faultString"])}
+		}
+	}
+	return nil
+}
+
+func (rtclient *Client) Cached() bool {
+	return rtclient.datatime > 0
+}
+
+func (rtclient *Client) sync() error {
+	if rtclient.datatime > 0 {
+		return nil
+	}
+	now := util.Now()
+	result, err := rtclient.call("d.multicall2", append([]any{"", "main"}, util.Map(torrentFields,
+		func(field string) any { return field })...)...)
+	if err != nil {
+		return err
+	}
+	torrents := map[string]*rtTorrent{}
+	hashes := []string{}
+	for _, item := range toSlice(result) {
+		if torrent := parseTorrent(toSlice(item)); torrent != nil {
+			torrents[torrent.Hash] = torrent
+			hashes = append(hashes, torrent.Hash)
+		}
+	}
+	// fetch trackers of all torrents in one request
+	if len(hashes) > 0 {
+		calls := []any{}
+		for _, infoHash := range hashes {
+			calls = append(calls, map[string]any{
+				"methodName": "t.multicall",
+				"params":     []any{target(infoHash), "", "t.url=", "t.is_enabled="},
+			})
+		}
+		result, err := rtclient.call("system.multicall", calls)
+		if err != nil {
+			return err
+		}
+		for i, item := range toSlice(result) {
+			if i >= len(hashes) {
+				break
+			}
+			// each successful result is wrapped in an one-element array
+			results := toSlice(item)
+			if len(results) == 0 {
+				continue
+			}
+			for _, tracker := range toSlice(results[0]) {
+				fields := toSlice(tracker)
+				if len(fields) >= 2 && toInt64(fields[1]) == 1 && util.IsUrl(toString(fields[0])) {
+					torrents[hashes[i]].Tracker = toString(fields[0])
+					break
+				}
+			}
+		}
+	}
+	rtclient.datatime = now
+	rtclient.torrents = torrents
+	rtclient.buildDerivative()
+	return nil
+}
+
+func (rtclient *Client) buildDerivative() {
+	unfinishedSize := int64(0)
+	unfinishedDownloadingSize := int64(0)
+	contentPathTorrents := map[string][]*rtTorrent{}
+	for _, torrent := range rtclient.torrents {
+		usize := torrent.Size - torrent.CompletedBytes
+		unfinishedSize += usize
+		if torrent.State == 1 && torrent.IsActive {
+			unfinishedDownloadingSize += usize
+		}
+		contentPath := torrent.ContentPath()
+		contentPathTorrents[contentPath] = append(contentPathTorrents[contentPath], torrent)
+	}
+	rtclient.unfinishedSize = unfinishedSize
+	rtclient.unfinishedDownloadingSize = unfinishedDownloadingSize
+	rtclient.contentPathTorrents = contentPathTorrents
+}
+
+func (rtclient *Client) syncMeta() error {
+	if rtclient.datatimeMeta > 0 {
+		return nil
+	}
+	now := util.Now()
+	values := []int64{}
+	for _, method := range []string{"throttle.global_down.rate", "throttle.global_up.rate",
+		"throttle.global_down.max_rate", "throttle.global_up.max_rate"} {
+		value, err := rtclient.call(method, "")
+		if err != nil {
+			return err
+		}
+		values = append(values, toInt64(value))
+	}
+	directory, err := rtclient.call("directory.default", "")
+	if err != nil {
+		return err
+	}
+	rtclient.datatimeMeta = now
+	rtclient.downloadRate = values[0]
+	rtclient.uploadRate = values[1]
+	rtclient.downloadRateLimit = values[2]
+	rtclient.uploadRateLimit = values[3]
+	rtclient.defaultDirectory = toString(directory)
+	rtclient.freeSpace = rtclient.getFreeSpace(rtclient.defaultDirectory)
+	return nil
+}
+
+// rTorrent does not provide a free disk space api, so run "df" on the rTorrent host. Return -1 if failed.
+func (rtclient *Client) getFreeSpace(dir string) int64 {
+	if dir == "" {
+		return -1
+	}
+	output, err := rtclient.call("execute.capture", "", "df", "-P", "-k", dir)
+	if err != nil {
+		log.Debugf("failed to get rtorrent free space: %v", err)
+		return -1
+	}
+	lines := strings.Split(strings.TrimSpace(toString(output)), "\n")
+	if len(lines) < 2 {
+		return -1
+	}
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) < 4 {
+		return -1
+	}
+	available, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return available * 1024
+}
+
+func (rtclient *Client) getAllInfoHashes() ([]string, error) {
+	if err := rtclient.sync(); err != nil {
+		return nil, err
+	}
+	infoHashes := []string{}
+	for infoHash := range rtclient.torrents {
+		infoHashes = append(infoHashes, infoHash)
+	}
+	return infoHashes, nil
+}
+
+func (rtclient *Client) getTorrent(infoHash string) (*rtTorrent, error) {
+	if err := rtclient.sync(); err != nil {
+		return nil, err
+	}
+	torrent := rtclient.torrents[infoHash]
+	if torrent == nil {
+		return nil, fmt.Errorf("torrent %s not found", infoHash)
+	}
+	return torrent, nil
+}
+
+// Export .torrent file from rTorrent session dir, where it's saved as "<INFOHASH>.torrent".
+// If localTorrentsPath is configured, read the file directly from it, otherwise read it via rTorrent "execute".
+func (rtclient *Client) ExportTorrentFile(infoHash string) ([]byte, error) {
+	filename := strings.ToUpper(infoHash) + ".torrent"
+	if rtclient.ClientConfig.LocalTorrentsPath != "" {
+		return os.ReadFile(filepath.Join(rtclient.ClientConfig.LocalTorrentsPath, filename))
+	}
+	sessionPath, err := rtclient.call("session.path", "")
+	if err != nil {
+		return nil, err
+	}
+	if toString(sessionPath) == "" {
+		return nil, fmt.Errorf("rtorrent session dir is not configured")
+	}
+	output, err := rtclient.call("execute.capture", "", "base64", "-w", "0",
+		path.Join(toString(sessionPath), filename))
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(toString(output)))
+}
+
+func (rtclient *Client) GetTorrent(infoHash string) (*client.Torrent, error) {
+	if err := rtclient.sync(); err != nil {
+		return nil, err
+	}
+	torrent := rtclient.torrents[infoHash]
+	if torrent == nil {
+		return nil, nil
+	}
+	return torrent.ToTorrent(rtclient.datatime), nil
+}
+
+func (rtclient *Client) GetTorrents(stateFilter string, category string, showAll bool) ([]*client.Torrent, error) {
+	if err := rtclient.sync(); err != nil {
+		return nil, err
+	}
+	torrents := []*client.Torrent{}
+	for _, rttorrent := range rtclient.torrents {
+		torrent := rttorrent.ToTorrent(rtclient.datatime)
+		if category != "" {
+			if category == constants.NONE {
+				if torrent.Category != "" {
+					continue
+				}
+			} else if category != torrent.Category {
+				continue
+			}
+		}
+		if !showAll && torrent.DownloadSpeed < 1024 && torrent.UploadSpeed < 1024 {
+			continue
+		}
+		if !torrent.MatchStateFilter(stateFilter) {
+			continue
+		}
+		torrents = append(torrents, torrent)
+	}
+	return torrents, nil
+}
+
+func (rtclient *Client) GetTorrentsByContentPath(contentPath string) ([]*client.Torrent, error) {
+	if err := rtclient.sync(); err != nil {
+		return nil, err
+	}
+	torrents := []*client.Torrent{}
+	for _, torrent := range rtclient.contentPathTorrents[contentPath] {
+		torrents = append(torrents, torrent.ToTorrent(rtclient.datatime))
+	}
+	return torrents, nil
+}
+
+func generateTags(tags []string, meta map[string]int64) string {
+	tags = util.CopySlice(tags)
+	for name, value := range meta {
+		tags = append(tags, client.GenerateTorrentTagFromMetadata(name, value))
+	}
+	return strings.Join(tags, ",")
+}
+
+func (rtclient *Client) AddTorrent(torrentContent []byte, option *client.TorrentOption, meta map[string]int64) error {
+	commands := []any{
+		fmt.Sprintf("d.custom.set=%s,%d", ADDTIME_KEY, util.Now()),
+	}
+	if option.SavePath != "" {
+		commands = append(commands, "d.directory.set="+quoteCommandArg(option.SavePath))
+	}
+	if option.Category != "" && option.Category != constants.NONE {
+		commands = append(commands, "d.custom1.set="+quoteCommandArg(url.PathEscape(option.Category)))
+	}
+	if tags := generateTags(option.Tags, meta); tags != "" {
+		commands = append(commands, fmt.Sprintf("d.custom.set=%s,%s", TAGS_KEY, quoteCommandArg(tags)))
+	}
+	if option.Name != "" {
+		log.Debugf("rtorrent does not support renaming torrent, ignore name %q", option.Name)
+	}
+	if option.DownloadSpeedLimit > 0 || option.UploadSpeedLimit > 0 || option.RatioLimit > 0 ||
+		option.SeedingTimeLimit > 0 {
+		log.Debugf("rtorrent does not support per-torrent speed / share limits, ignore them")
+	}
+	var err error
+	if util.IsTorrentUrl(string(torrentContent)) {
+		method := "load.start_verbose"
+		if option.Pause {
+			method = "load.verbose"
+		}
+		_, err = rtclient.call(method, append([]any{"", string(torrentContent)}, commands...)...)
+	} else {
+		method := "load.raw_start_verbose"
+		if option.Pause {
+			method = "load.raw_verbose"
+		}
+		_, err = rtclient.call(method, append([]any{"", torrentContent}, commands...)...)
+	}
+	return err
+}
+
+// Quote a rTorrent command argument.
+func quoteCommandArg(arg string) string {
+	if !strings.ContainsAny(arg, `,;" \{}`) {
+		return arg
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+func (rtclient *Client) setTags(infoHash string, tags string) error {
+	if _, err := rtclient.call("d.custom.set", target(infoHash), TAGS_KEY, tags); err != nil {
+		return err
+	}
+	if rtclient.torrents != nil && rtclient.torrents[infoHash] != nil {
+		rtclient.torrents[infoHash].CustomTags = tags
+	}
+	return nil
+}
+
+func (rtclient *Client) ModifyTorrent(infoHash string, option *client.TorrentOption, meta map[string]int64) error {
+	rttorrent, err := rtclient.getTorrent(infoHash)
+	if err != nil {
+		return err
+	}
+	torrent := rttorrent.ToTorrent(rtclient.datatime)
+	if option.Name != "" && option.Name != torrent.Name {
+		log.Debugf("rtorrent does not support renaming torrent, ignore name %q", option.Name)
+	}
+	if option.Category != "" && option.Category != torrent.Category {
+		if err := rtclient.SetTorrentsCatetory([]string{infoHash}, option.Category); err != nil {
+			return err
+		}
+	}
+	if len(option.Tags) > 0 || len(option.RemoveTags) > 0 || len(meta) > 0 {
+		tags := util.Filter(torrent.Tags, func(tag string) bool {
+			return !slices.Contains(option.RemoveTags, tag)
+		})
+		for _, tag := range option.Tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		if len(meta) == 0 {
+			meta = torrent.Meta
+		}
+		if err := rtclient.setTags(infoHash, generateTags(tags, meta)); err != nil {
+			return err
+		}
+	}
+	if option.SavePath != "" && option.SavePath != torrent.SavePath {
+		if err := rtclient.SetTorrentsSavePath([]string{infoHash}, option.SavePath); err != nil {
+			return err
+		}
+	}
+	if option.Pause {
+		err = rtclient.PauseTorrents([]string{infoHash})
+	} else if option.Resume {
+		err = rtclient.ResumeTorrents([]string{infoHash})
+	}
+	return err
+}
+
+// rTorrent d.erase does not delete downloaded files, so remove them by running "rm" on the rTorrent host.
+func (rtclient *Client) DeleteTorrents(infoHashes []string, deleteFiles bool) error {
+	if err := rtclient.sync(); err != nil {
+		return err
+	}
+	for _, infoHash := range infoHashes {
+		torrent := rtclient.torrents[infoHash]
+		if torrent == nil {
+			continue
+		}
+		if _, err := rtclient.call("d.erase", target(infoHash)); err != nil {
+			return err
+		}
+		if deleteFiles {
+			if _, err := rtclient.call("execute.throw", "", "rm", "-rf", "--", torrent.ContentPath()); err != nil {
+				return fmt.Errorf("torrent %s deleted but failed to delete files: %w", infoHash, err)
+			}
+		}
+		delete(rtclient.torrents, infoHash)
+	}
+	rtclient.buildDerivative()
+	return nil
+}
+
+func (rtclient *Client) PauseTorrents(infoHashes []string) error {
+	return rtclient.callEach(infoHashes, "d.stop")
+}
+
+func (rtclient *Client) ResumeTorrents(infoHashes []string) error {
+	return rtclient.callEach(infoHashes, "d.start")
+}
+
+func (rtclient *Client) RecheckTorrents(infoHashes []string) error {
+	return rtclient.callEach(infoHashes, "d.check_hash")
+}
+
+func (rtclient *Client) ReannounceTorrents(infoHashes []string) error {
+	return rtclient.callEach(infoHashes, "d.tracker_announce")
+}
+
+func (rtclient *Client) AddTagsToTorrents(infoHashes []string, tags []string) error {
+	for _, infoHash := range infoHashes {
+		if err := rtclient.ModifyTorrent(infoHash, &client.TorrentOption{Tags: tags}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rtclient *Client) RemoveTagsFromTorrents(infoHashes []string, tags []string) error {
+	for _, infoHash := range infoHashes {
+		if err := rtclient.ModifyTorrent(infoHash, &client.TorrentOption{RemoveTags: tags}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rTorrent does not move files when changing the directory of a torrent,
+// so stop the torrent, move the content by running "mv" on the rTorrent host, then set the new directory.
+func (rtclient *Client) SetTorrentsSavePath(infoHashes []string, savePath string) error {
+	if err := rtclient.sync(); err != nil {
+		return err
+	}
+	for _, infoHash := range infoHashes {
+		torrent := rtclient.torrents[infoHash]
+		if torrent == nil || torrent.SavePath() == savePath {
+			continue
+		}
+		started := torrent.State == 1
+		if _, err := rtclient.call("d.stop", target(infoHash)); err != nil {
+			return err
+		}
+		if _, err := rtclient.call("d.close", target(infoHash)); err != nil {
+			return err
+		}
+		if _, err := rtclient.call("execute.throw", "", "mkdir", "-p", "--", savePath); err != nil {
+			return err
+		}
+		if _, err := rtclient.call("execute.throw", "", "mv", "-f", "--", torrent.ContentPath(),
+			savePath+"/"); err != nil {
+			return fmt.Errorf("failed to move torrent %s files: %w", infoHash, err)
+		}
+		if _, err := rtclient.call("d.directory.set", target(infoHash), savePath); err != nil {
+			return err
+		}
+		if started {
+			if _, err := rtclient.call("d.start", target(infoHash)); err != nil {
+				return err
+			}
+		}
+		if torrent.IsMultiFile {
+			torrent.Directory = strings.TrimSuffix(savePath, torrent.Sep()) + torrent.Sep() + torrent.Name
+		} else {
+			torrent.Directory = savePath
+		}
+	}
+	rtclient.buildDerivative()
+	return nil
+}
+
+func (rtclient *Client) PauseAllTorrents() error {
+	infoHashes, err := rtclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return rtclient.PauseTorrents(infoHashes)
+}
+
+func (rtclient *Client) ResumeAllTorrents() error {
+	infoHashes, err := rtclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return rtclient.ResumeTorrents(infoHashes)
+}
+
+func (rtclient *Client) RecheckAllTorrents() error {
+	infoHashes, err := rtclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return rtclient.RecheckTorrents(infoHashes)
+}
+
+func (rtclient *Client) ReannounceAllTorrents() error {
+	infoHashes, err := rtclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return rtclient.ReannounceTorrents(infoHashes)
+}
+
+func (rtclient *Client) AddTagsToAllTorrents(tags []string) error {
+	infoHashes, err := rtclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return rtclient.AddTagsToTorrents(infoHashes, tags)
+}
+
+func (rtclient *Client) RemoveTagsFromAllTorrents(tags []string) error {
+	infoHashes, err := rtclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return rtclient.RemoveTagsFromTorrents(infoHashes, tags)
+}
+
+func (rtclient *Client) SetAllTorrentsSavePath(savePath string) error {
+	infoHashes, err := rtclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return rtclient.SetTorrentsSavePath(infoHashes, savePath)
+}
+
+func (rtclient *Client) GetTags() ([]string, error) {
+	if err := rtclient.sync(); err != nil {
+		return nil, err
+	}
+	tags := []string{}
+	for _, torrent := range rtclient.torrents {
+		for _, tag := range torrent.Tags() {
+			if !slices.Contains(tags, tag) && !client.IsSubstituteTag(tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags, nil
+}
+
+func (rtclient *Client) CreateTags(tags ...string) error {
+	return fmt.Errorf("unsupported")
+}
+
+func (rtclient *Client) DeleteTags(tags ...string) error {
+	return rtclient.RemoveTagsFromAllTorrents(tags)
+}
+
+func (rtclient *Client) MakeCategory(category string, savePath string) error {
+	return fmt.Errorf("unsupported")
+}
+
+func (rtclient *Client) DeleteCategories(categories []string) error {
+	return fmt.Errorf("unsupported")
+}
+
+func (rtclient *Client) GetCategories() ([]*client.TorrentCategory, error) {
+	if err := rtclient.sync(); err != nil {
+		return nil, err
+	}
+	cats := []*client.TorrentCategory{}
+	catsFlag := map[string]bool{}
+	for _, torrent := range rtclient.torrents {
+		cat := torrent.Category()
+		if cat != "" && !catsFlag[cat] {
+			cats = append(cats, &client.TorrentCategory{Name: cat})
+			catsFlag[cat] = true
+		}
+	}
+	return cats, nil
+}
+
+func (rtclient *Client) SetTorrentsCatetory(infoHashes []string, category string) error {
+	if category == constants.NONE {
+		category = ""
+	}
+	if err := rtclient.callEach(infoHashes, "d.custom1.set", url.PathEscape(category)); err != nil {
+		return err
+	}
+	for _, infoHash := range infoHashes {
+		if rtclient.torrents != nil && rtclient.torrents[infoHash] != nil {
+			rtclient.torrents[infoHash].Custom1 = url.PathEscape(category)
+		}
+	}
+	return nil
+}
+
+func (rtclient *Client) SetAllTorrentsCatetory(category string) error {
+	infoHashes, err := rtclient.getAllInfoHashes()
+	if err != nil {
+		return err
+	}
+	return rtclient.SetTorrentsCatetory(infoHashes, category)
+}
+
+func (rtclient *Client) SetTorrentsShareLimits(infoHashes []string, ratioLimit float64, seedingTimeLimit int64) error {
+	return fmt.Errorf("unsupported")
+}
+
+func (rtclient *Client) SetAllTorrentsShareLimits(ratioLimit float64, seedingTimeLimit int64) error {
+	return fmt.Errorf("unsupported")
+}
+
+func (rtclient *Client) TorrentRootPathExists(rootFolder string) bool {
+	if rootFolder == "" {
+		return false
+	}
+	if err := rtclient.sync(); err != nil {
+		return false
+	}
+	for _, torrent := range rtclient.torrents {
+		if torrent.Name == rootFolder {
+			return true
+		}
+	}
+	return false
+}
+
+func (rtclient *Client) GetTorrentContents(infoHash string) ([]*client.TorrentContentFile, error) {
+	torrent, err := rtclient.getTorrent(infoHash)
+	if err != nil {
+		return nil, err
+	}
+	result, err := rtclient.call("f.multicall", target(infoHash), "", "f.path=", "f.size_bytes=",
+		"f.completed_chunks=", "f.size_chunks=", "f.priority=")
+	if err != nil {
+		return nil, err
+	}
+	files := []*client.TorrentContentFile{}
+	for i, item := range toSlice(result) {
+		fields := toSlice(item)
+		if len(fields) < 5 {
+			continue
+		}
+		filePath := toString(fields[0])
+		if torrent.IsMultiFile {
+			filePath = torrent.Name + "/" + filePath
+		}
+		progress := float64(0)
+		if chunks := toInt64(fields[3]); chunks > 0 {
+			progress = float64(toInt64(fields[2])) / float64(chunks)
+		}
+		// rt file priority: 0 off, 1 normal, 2 high
+		priority := toInt64(fields[4])
+		if priority > 1 {
+			priority = 6
+		}
+		files = append(files, &client.TorrentContentFile{
+			Index:    int64(i),
+			Path:     filePath,
+			Size:     toInt64(fields[1]),
+			Progress: progress,
+			Ignored:  priority == 0,
+			Priority: priority,
+			Complete: progress == 1,
+		})
+	}
+	return files, nil
+}
+
+func (rtclient *Client) PurgeCache() {
+	rtclient.datatime = 0
+	rtclient.datatimeMeta = 0
+	rtclient.unfinishedSize = 0
+	rtclient.unfinishedDownloadingSize = 0
+	rtclient.torrents = nil
+	rtclient.contentPathTorrents = nil
+}
+
+func (rtclient *Client) GetStatus() (*client.Status, error) {
+	if err := rtclient.syncMeta(); err != nil {
+		return nil, err
+	}
+	status := &client.Status{
+		DownloadSpeed:      rtclient.downloadRate,
+		UploadSpeed:        rtclient.uploadRate,
+		DownloadSpeedLimit: rtclient.downloadRateLimit,
+		UploadSpeedLimit:   rtclient.uploadRateLimit,
+		FreeSpaceOnDisk:    rtclient.freeSpace,
+	}
+	if err := rtclient.sync(); err == nil {
+		status.UnfinishedSize = rtclient.unfinishedSize
+		status.UnfinishedDownloadingSize = rtclient.unfinishedDownloadingSize
+	}
+	return status, nil
+}
+
+func (rtclient *Client) GetName() string {
+	return rtclient.Name
+}
+
+func (rtclient *Client) GetClientConfig() *config.ClientConfigStruct {
+	return rtclient.ClientConfig
+}
+
+func (rtclient *Client) SetConfig(variable string, value string) error {
+	rtclient.datatimeMeta = 0
+	if strings.HasPrefix(variable, "rt_") && len(variable) > 3 {
+		var arg any = value
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			arg = i
+		}
+		_, err := rtclient.call(variable[3:]+".set", "", arg)
+		return err
+	}
+	switch variable {
+	case "global_download_speed_limit":
+		_, err := rtclient.call("throttle.global_down.max_rate.set", "", max(util.ParseInt(value), 0))
+		return err
+	case "global_upload_speed_limit":
+		_, err := rtclient.call("throttle.global_up.max_rate.set", "", max(util.ParseInt(value), 0))
+		return err
+	case "free_disk_space", "global_download_speed", "global_upload_speed":
+		return fmt.Errorf("%s is read-only", variable)
+	case "save_path":
+		_, err := rtclient.call("directory.default.set", "", value)
+		return err
+	default:
+		return nil
+	}
+}
+
+func (rtclient *Client) GetConfig(variable string) (string, error) {
+	if strings.HasPrefix(variable, "rt_") && len(variable) > 3 {
+		value, err := rtclient.call(variable[3:], "")
+		if err != nil {
+			return "", err
+		}
+		return toString(value), nil
+	}
+	status, err := rtclient.GetStatus()
+	if err != nil {
+		return "", err
+	}
+	switch variable {
+	case "global_download_speed_limit":
+		return fmt.Sprint(status.DownloadSpeedLimit), nil
+	case "global_upload_speed_limit":
+		return fmt.Sprint(status.UploadSpeedLimit), nil
+	case "free_disk_space":
+		return fmt.Sprint(status.FreeSpaceOnDisk), nil
+	case "global_download_speed":
+		return fmt.Sprint(status.DownloadSpeed), nil
+	case "global_upload_speed":
+		return fmt.Sprint(status.UploadSpeed), nil
+	case "save_path":
+		return rtclient.defaultDirectory, nil
+	default:
+		return "", nil
+	}
+}
+
+type rtTracker struct {
+	Url            string
+	Enabled        bool
+	SuccessCounter int64
+	FailedCounter  int64
+}
+
+func (rtclient *Client) getTrackers(infoHash string) ([]*rtTracker, error) {
+	result, err := rtclient.call("t.multicall", target(infoHash), "", "t.url=", "t.is_enabled=",
+		"t.success_counter=", "t.failed_counter=")
+	if err != nil {
+		return nil, err
+	}
+	trackers := []*rtTracker{}
+	for _, item := range toSlice(result) {
+		fields := toSlice(item)
+		if len(fields) < 4 {
+			continue
+		}
+		trackers = append(trackers, &rtTracker{
+			Url:            toString(fields[0]),
+			Enabled:        toInt64(fields[1]) == 1,
+			SuccessCounter: toInt64(fields[2]),
+			FailedCounter:  toInt64(fields[3]),
+		})
+	}
+	return trackers, nil
+}
+
+func (rtclient *Client) GetTorrentTrackers(infoHash string) (client.TorrentTrackers, error) {
+	torrent, err := rtclient.getTorrent(infoHash)
+	if err != nil {
+		return nil, err
+	}
+	rttrackers, err := rtclient.getTrackers(infoHash)
+	if err != nil {
+		return nil, err
+	}
+	trackers := client.TorrentTrackers{}
+	for _, rttracker := range rttrackers {
+		// rtorrent does not keep per-tracker message, use torrent message instead.
+		status := "notcontacted"
+		msg := ""
+		if !rttracker.Enabled {
+			status = "disabled"
+		} else if rttracker.SuccessCounter > 0 {
+			status = "working"
+		} else if rttracker.FailedCounter > 0 {
+			status = "error"
+			msg = strings.TrimPrefix(torrent.Message, "Tracker: ")
+		}
+		trackers = append(trackers, client.TorrentTracker{
+			Url:    rttracker.Url,
+			Status: status,
+			Msg:    msg,
+		})
+	}
+	return trackers, nil
+}
+
+// rTorrent XML-RPC does not support modifying tracker url, so disable old tracker and insert new one.
+// Trackers are addressed by index, and inserting a tracker shifts the indexes of trackers in later tiers,
+// so the old tracker must be disabled before inserting.
+func (rtclient *Client) EditTorrentTracker(infoHash string, oldTracker string, newTracker string,
+	replaceHost bool) error {
+	rttrackers, err := rtclient.getTrackers(infoHash)
+	if err != nil {
+		return err
+	}
+	index := -1
+	newTrackerUrl := newTracker
+	directNewUrlMode := util.IsUrl(newTracker)
+	for i, tracker := range rttrackers {
+		if replaceHost {
+			oldTrackerUrlObj, err := url.Parse(tracker.Url)
+			if err != nil {
+				continue
+			}
+			if oldTrackerUrlObj.Host == oldTracker {
+				index = i
+				if !directNewUrlMode {
+					oldTrackerUrlObj.Host = newTracker
+					newTrackerUrl = oldTrackerUrlObj.String()
+				}
+				break
+			}
+		} else if tracker.Url == oldTracker {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		return fmt.Errorf("torrent %s old tracker %s does NOT exist", infoHash, oldTracker)
+	}
+	if rttrackers[index].Url == newTrackerUrl {
+		return nil
+	}
+	if _, err := rtclient.call("t.disable", fmt.Sprintf("%s:t%d", target(infoHash), index)); err != nil {
+		return err
+	}
+	_, err = rtclient.call("d.tracker.insert", target(infoHash), "0", newTrackerUrl)
+	return err
+}
+
+func (rtclient *Client) AddTorrentTrackers(infoHash string, trackers []string,
+	oldTracker string, removeExisting bool) error {
+	rttrackers, err := rtclient.getTrackers(infoHash)
+	if err != nil {
+		return err
+	}
+	if oldTracker != "" {
+		if !slices.ContainsFunc(rttrackers, func(tracker *rtTracker) bool {
+			return util.MatchUrlWithHostOrUrl(tracker.Url, oldTracker)
+		}) {
+			return nil
+		}
+	}
+	// Disable existing trackers before inserting new ones, which would shift the indexes of existing trackers.
+	if removeExisting {
+		for i, rttracker := range rttrackers {
+			if rttracker.Enabled && !slices.Contains(trackers, rttracker.Url) {
+				if _, err := rtclient.call("t.disable", fmt.Sprintf("%s:t%d", target(infoHash), i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, tracker := range trackers {
+		if slices.ContainsFunc(rttrackers, func(t *rtTracker) bool {
+			return t.Url == tracker && (t.Enabled || !removeExisting)
+		}) {
+			continue
+		}
+		if _, err := rtclient.call("d.tracker.insert", target(infoHash), "0", tracker); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rTorrent does not support removing trackers, so disable them instead.
+func (rtclient *Client) RemoveTorrentTrackers(infoHash string, trackers []string) error {
+	rttrackers, err := rtclient.getTrackers(infoHash)
+	if err != nil {
+		return err
+	}
+	for i, rttracker := range rttrackers {
+		if rttracker.Enabled && slices.Contains(trackers, rttracker.Url) {
+			if _, err := rtclient.call("t.disable", fmt.Sprintf("%s:t%d", target(infoHash), i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// priority: qBittorrent style value. rTorrent file priority: 0 - off; 1 - normal; 2 - high.
+func (rtclient *Client) SetFilePriority(infoHash string, fileIndexes []int64, priority int64) error {
+	rtpriority := int64(1)
+	if priority <= 0 {
+		rtpriority = 0
+	} else if priority > 1 {
+		rtpriority = 2
+	}
+	for _, index := range fileIndexes {
+		if _, err := rtclient.call("f.priority.set", fmt.Sprintf("%s:f%d", target(infoHash), index), rtpriority); err != nil {
+			return err
+		}
+	}
+	_, err := rtclient.call("d.update_priorities", target(infoHash))
+	return err
+}
+
+func (rtclient *Client) Close() {
+	rtclient.PurgeCache()
+}
+
+func NewClient(name string, clientConfig *config.ClientConfigStruct, config *config.ConfigStruct) (
+	client.Client, error) {
+	urlObj, err := url.Parse(clientConfig.Url)
+	if err != nil || (urlObj.Scheme != "http" && urlObj.Scheme != "https") || urlObj.Host == "" {
+		return nil, fmt.Errorf("invalid rtorrent url: %s", clientConfig.Url)
+	}
+	return &Client{
+		Name:         name,
+		ClientConfig: clientConfig,
+		Config:       config,
+		HttpClient:   &http.Client{},
+	}, nil
+}
+
+func init() {
+	client.Register(&client.RegInfo{
+		Name:    "rtorrent",
+		Creator: NewClient,
+	})
+}
+
+var (
+	_ client.Client = (*Client)(nil)
+)
@@ -0,0 +1,241 @@
+package rtorrent
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sagan/ptool/config"
+)
+
+type fakeTracker struct {
+	url     string
+	group   int
+	enabled bool
+}
+
+type fakeDownload struct {
+	name      string
+	multiFile bool
+	directory string
+	state     int64
+	trackers  []*fakeTracker
+	closed    bool
+}
+
+// A minimal fake rTorrent XML-RPC server. Downloads are keyed by uppercase info-hash.
+type fakeRtorrent struct {
+	downloads map[string]*fakeDownload
+	calls     []string
+	executed  [][]string // commands run by execute.*
+}
+
+func (fr *fakeRtorrent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		MethodName string        `xml:"methodName"`
+		Params     []xmlrpcValue `xml:"params>param>value"`
+	}
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	params := []any{}
+	for i := range req.Params {
+		value, _ := req.Params[i].native()
+		params = append(params, value)
+	}
+	buf := &bytes.Buffer{}
+	buf.WriteString(`<?xml version="1.0"?><methodResponse><params><param>`)
+	encodeValue(buf, fr.handle(req.MethodName, params))
+	buf.WriteString(`</param></params></methodResponse>`)
+	w.Write(buf.Bytes())
+}
+
+func (fr *fakeRtorrent) handle(method string, params []any) any {
+	fr.calls = append(fr.calls, method)
+	download := func() *fakeDownload {
+		hash, _, _ := strings.Cut(toString(params[0]), ":")
+		return fr.downloads[hash]
+	}
+	switch method {
+	case "system.multicall":
+		results := []any{}
+		for _, call := range toSlice(params[0]) {
+			call := call.(map[string]any)
+			results = append(results, []any{fr.handle(toString(call["methodName"]), toSlice(call["params"]))})
+		}
+		return results
+	case "d.multicall2":
+		results := []any{}
+		for hash, d := range fr.downloads {
+			multiFile := int64(0)
+			if d.multiFile {
+				multiFile = 1
+			}
+			results = append(results, []any{hash, d.name, d.state, int64(1), int64(1), int64(0), multiFile,
+				int64(100), int64(100), int64(0), int64(0), int64(0), int64(0), d.directory, "", "", "", "",
+				int64(0), int64(0), "", int64(0), int64(0)})
+		}
+		return results
+	case "t.multicall":
+		results := []any{}
+		for _, t := range download().trackers {
+			enabled := int64(0)
+			if t.enabled {
+				enabled = 1
+			}
+			results = append(results, []any{t.url, enabled, int64(0), int64(0)})
+		}
+		return results
+	case "d.tracker.insert":
+		// rTorrent inserts the tracker at the end of it's group, shifting the trackers of later groups.
+		d := download()
+		group, _ := strconv.Atoi(toString(params[1]))
+		index := 0
+		for index < len(d.trackers) && d.trackers[index].group <= group {
+			index++
+		}
+		d.trackers = slices.Insert(d.trackers, index, &fakeTracker{url: toString(params[2]), group: group,
+			enabled: true})
+	case "t.disable":
+		_, index, _ := strings.Cut(toString(params[0]), ":t")
+		i, _ := strconv.Atoi(index)
+		download().trackers[i].enabled = false
+	case "d.erase":
+		delete(fr.downloads, toString(params[0]))
+	case "d.stop":
+		download().state = 0
+	case "d.start":
+		download().state = 1
+	case "d.close":
+		download().closed = true
+	case "d.directory.set":
+		download().directory = toString(params[1])
+	case "execute.throw", "execute.capture":
+		command := []string{}
+		for _, param := range params[1:] {
+			command = append(command, toString(param))
+		}
+		fr.executed = append(fr.executed, command)
+	}
+	return int64(0)
+}
+
+func newTestClient(t *testing.T) (*Client, *fakeRtorrent) {
+	fr := &fakeRtorrent{downloads: map[string]*fakeDownload{
+		"AAAA": {name: "Foo", multiFile: true, directory: "/downloads/Foo", state: 1, trackers: []*fakeTracker{
+			{url: "https://a.example.com/announce", group: 0, enabled: true},
+			{url: "https://b.example.com/announce", group: 1, enabled: true},
+			{url: "https://c.example.com/announce", group: 2, enabled: true},
+		}},
+		"BBBB": {name: "bar.mkv", directory: "/downloads", state: 0},
+	}}
+	server := httptest.NewServer(fr)
+	t.Cleanup(server.Close)
+	clientInstance, err := NewClient("rt", &config.ClientConfigStruct{Type: "rtorrent", Url: server.URL + "/RPC2"},
+		&config.ConfigStruct{})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	return clientInstance.(*Client), fr
+}
+
+func enabledTrackers(d *fakeDownload) (urls []string) {
+	for _, t := range d.trackers {
+		if t.enabled {
+			urls = append(urls, t.url)
+		}
+	}
+	return urls
+}
+
+func TestTrackers(t *testing.T) {
+	rtclient, fr := newTestClient(t)
+	d := fr.downloads["AAAA"]
+	// replace a tracker of a later tier: inserting into tier 0 shifts it's index.
+	if err := rtclient.EditTorrentTracker("aaaa", "https://c.example.com/announce",
+		"https://d.example.com/announce", false); err != nil {
+		t.Fatalf("EditTorrentTracker error: %v", err)
+	}
+	want := []string{"https://a.example.com/announce", "https://d.example.com/announce",
+		"https://b.example.com/announce"}
+	if got := enabledTrackers(d); !slices.Equal(got, want) {
+		t.Errorf("EditTorrentTracker: enabled trackers = %v, want %v", got, want)
+	}
+
+	err := rtclient.AddTorrentTrackers("aaaa", []string{"https://e.example.com/announce",
+		"https://b.example.com/announce"}, "", true)
+	if err != nil {
+		t.Fatalf("AddTorrentTrackers error: %v", err)
+	}
+	want = []string{"https://e.example.com/announce", "https://b.example.com/announce"}
+	if got := enabledTrackers(d); !slices.Equal(got, want) {
+		t.Errorf("AddTorrentTrackers(removeExisting): enabled trackers = %v, want %v", got, want)
+	}
+
+	if err := rtclient.RemoveTorrentTrackers("aaaa", []string{"https://b.example.com/announce"}); err != nil {
+		t.Fatalf("RemoveTorrentTrackers error: %v", err)
+	}
+	want = []string{"https://e.example.com/announce"}
+	if got := enabledTrackers(d); !slices.Equal(got, want) {
+		t.Errorf("RemoveTorrentTrackers: enabled trackers = %v, want %v", got, want)
+	}
+}
+
+func TestDeleteTorrents(t *testing.T) {
+	rtclient, fr := newTestClient(t)
+	if err := rtclient.DeleteTorrents([]string{"bbbb"}, false); err != nil {
+		t.Fatalf("DeleteTorrents error: %v", err)
+	}
+	if _, ok := fr.downloads["BBBB"]; ok || len(fr.executed) != 0 {
+		t.Errorf("DeleteTorrents(deleteFiles=false): downloads=%v, executed=%v", fr.downloads, fr.executed)
+	}
+	if err := rtclient.DeleteTorrents([]string{"aaaa"}, true); err != nil {
+		t.Fatalf("DeleteTorrents error: %v", err)
+	}
+	if _, ok := fr.downloads["AAAA"]; ok {
+		t.Errorf("torrent not deleted")
+	}
+	if len(fr.executed) != 1 || !slices.Equal(fr.executed[0], []string{"rm", "-rf", "--", "/downloads/Foo"}) {
+		t.Errorf("DeleteTorrents(deleteFiles=true) executed %v", fr.executed)
+	}
+	if torrents, err := rtclient.GetTorrents("", "", true); err != nil || len(torrents) != 0 {
+		t.Errorf("GetTorrents after delete = %v, %v", torrents, err)
+	}
+}
+
+func TestSetTorrentsSavePath(t *testing.T) {
+	rtclient, fr := newTestClient(t)
+	if err := rtclient.SetTorrentsSavePath([]string{"aaaa", "bbbb"}, "/data"); err != nil {
+		t.Fatalf("SetTorrentsSavePath error: %v", err)
+	}
+	wantExecuted := [][]string{
+		{"mkdir", "-p", "--", "/data"},
+		{"mv", "-f", "--", "/downloads/Foo", "/data/"},
+		{"mkdir", "-p", "--", "/data"},
+		{"mv", "-f", "--", "/downloads/bar.mkv", "/data/"},
+	}
+	// map iteration order of torrents is random, compare as sets of (mkdir, mv) pairs
+	if len(fr.executed) != 4 || !slices.ContainsFunc(fr.executed, func(c []string) bool {
+		return slices.Equal(c, wantExecuted[1])
+	}) || !slices.ContainsFunc(fr.executed, func(c []string) bool {
+		return slices.Equal(c, wantExecuted[3])
+	}) {
+		t.Errorf("SetTorrentsSavePath executed %v", fr.executed)
+	}
+	if fr.downloads["AAAA"].directory != "/data" || fr.downloads["BBBB"].directory != "/data" {
+		t.Errorf("directory not set: %q, %q", fr.downloads["AAAA"].directory, fr.downloads["BBBB"].directory)
+	}
+	if fr.downloads["AAAA"].state != 1 || fr.downloads["BBBB"].state != 0 {
+		t.Errorf("started state not restored: %d, %d", fr.downloads["AAAA"].state, fr.downloads["BBBB"].state)
+	}
+	torrent, err := rtclient.GetTorrent("aaaa")
+	if err != nil || torrent.SavePath != "/data" || torrent.ContentPath != "/data/Foo" {
+		t.Errorf("GetTorrent after move = %+v, %v", torrent, err)
+	}
+}
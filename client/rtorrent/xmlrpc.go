@@ -0,0 +1,241 @@
+package rtorrent
+
+// A minimal XML-RPC codec which is sufficient for talking to rTorrent.
+// spec: http://xmlrpc.com/spec.md . rTorrent also uses the <i8> extension type for 64-bit integers.
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type xmlrpcFault struct {
+	Code    int64
+	Message string
+}
+
+func (f *xmlrpcFault) Error() string {
+	return fmt.Sprintf("rtorrent xmlrpc fault (code=%d): %s", f.Code, f.Message)
+}
+
+type xmlrpcMember struct {
+	Name  string      `xml:"name"`
+	Value xmlrpcValue `xml:"value"`
+}
+
+type xmlrpcValue struct {
+	String   *string `xml:"string"`
+	Int      *string `xml:"int"`
+	I4       *string `xml:"i4"`
+	I8       *string `xml:"i8"`
+	Boolean  *string `xml:"boolean"`
+	Double   *string `xml:"double"`
+	Base64   *string `xml:"base64"`
+	DateTime *string `xml:"dateTime.iso8601"`
+	Array    *struct {
+		Values []xmlrpcValue `xml:"data>value"`
+	} `xml:"array"`
+	Struct *struct {
+		Members []xmlrpcMember `xml:"member"`
+	} `xml:"struct"`
+	Chardata string `xml:",chardata"` // a value without type element is a string
+}
+
+type xmlrpcMethodResponse struct {
+	Params []xmlrpcValue `xml:"params>param>value"`
+	Fault  *xmlrpcValue  `xml:"fault>value"`
+}
+
+// Convert a decoded xml-rpc value to native Go value:
+// string, int64, bool, float64, []byte, []any or map[string]any.
+func (v *xmlrpcValue) native() (any, error) {
+	switch {
+	case v.String != nil:
+		return *v.String, nil
+	case v.Int != nil, v.I4 != nil, v.I8 != nil:
+		str := v.Int
+		if str == nil {
+			str = v.I4
+		}
+		if str == nil {
+			str = v.I8
+		}
+		return strconv.ParseInt(strings.TrimSpace(*str), 10, 64)
+	case v.Boolean != nil:
+		return strings.TrimSpace(*v.Boolean) == "1", nil
+	case v.Double != nil:
+		return strconv.ParseFloat(strings.TrimSpace(*v.Double), 64)
+	case v.Base64 != nil:
+		return base64.StdEncoding.DecodeString(strings.TrimSpace(*v.Base64))
+	case v.DateTime != nil:
+		return *v.DateTime, nil
+	case v.Array != nil:
+		values := []any{}
+		for i := range v.Array.Values {
+			value, err := v.Array.Values[i].native()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case v.Struct != nil:
+		values := map[string]any{}
+		for i := range v.Struct.Members {
+			value, err := v.Struct.Members[i].Value.native()
+			if err != nil {
+				return nil, err
+			}
+			values[v.Struct.Members[i].Name] = value
+		}
+		return values, nil
+	default:
+		return v.Chardata, nil
+	}
+}
+
+// Encode a xml-rpc methodCall request body.
+// Supported param types: string, int, int64, bool, float64, []byte, []string, []any, map[string]any.
+func encodeMethodCall(method string, params ...any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString(`<?xml version="1.0"?><methodCall><methodName>`)
+	xml.EscapeText(buf, []byte(method))
+	buf.WriteString(`</methodName><params>`)
+	for _, param := range params {
+		buf.WriteString(`<param>`)
+		if err := encodeValue(buf, param); err != nil {
+			return nil, err
+		}
+		buf.WriteString(`</param>`)
+	}
+	buf.WriteString(`</params></methodCall>`)
+	return buf.Bytes(), nil
+}
+
+func encodeValue(buf *bytes.Buffer, value any) error {
+	buf.WriteString(`<value>`)
+	switch v := value.(type) {
+	case string:
+		buf.WriteString(`<string>`)
+		xml.EscapeText(buf, []byte(v))
+		buf.WriteString(`</string>`)
+	case int:
+		encodeInt(buf, int64(v))
+	case int64:
+		encodeInt(buf, v)
+	case bool:
+		if v {
+			buf.WriteString(`<boolean>1</boolean>`)
+		} else {
+			buf.WriteString(`<boolean>0</boolean>`)
+		}
+	case float64:
+		fmt.Fprintf(buf, `<double>%s</double>`, strconv.FormatFloat(v, 'f', -1, 64))
+	case []byte:
+		fmt.Fprintf(buf, `<base64>%s</base64>`, base64.StdEncoding.EncodeToString(v))
+	case []string:
+		buf.WriteString(`<array><data>`)
+		for _, item := range v {
+			if err := encodeValue(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteString(`</data></array>`)
+	case []any:
+		buf.WriteString(`<array><data>`)
+		for _, item := range v {
+			if err := encodeValue(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteString(`</data></array>`)
+	case map[string]any:
+		buf.WriteString(`<struct>`)
+		for name, item := range v {
+			buf.WriteString(`<member><name>`)
+			xml.EscapeText(buf, []byte(name))
+			buf.WriteString(`</name>`)
+			if err := encodeValue(buf, item); err != nil {
+				return err
+			}
+			buf.WriteString(`</member>`)
+		}
+		buf.WriteString(`</struct>`)
+	default:
+		return fmt.Errorf("unsupported xmlrpc param type %T", value)
+	}
+	buf.WriteString(`</value>`)
+	return nil
+}
+
+func encodeInt(buf *bytes.Buffer, v int64) {
+	if v >= math.MinInt32 && v <= math.MaxInt32 {
+		fmt.Fprintf(buf, `<i4>%d</i4>`, v)
+	} else {
+		fmt.Fprintf(buf, `<i8>%d</i8>`, v)
+	}
+}
+
+// Decode a xml-rpc methodResponse body, return the native value of the (first) response param.
+// If the response is a fault, return a *xmlrpcFault error.
+func decodeMethodResponse(data []byte) (any, error) {
+	res := &xmlrpcMethodResponse{}
+	if err := xml.Unmarshal(data, res); err != nil {
+		return nil, fmt.Errorf("invalid xmlrpc response: %w", err)
+	}
+	if res.Fault != nil {
+		fault, err := res.Fault.native()
+		if err != nil {
+			return nil, err
+		}
+		faultStruct, _ := fault.(map[string]any)
+		return nil, &xmlrpcFault{Code: toInt64(faultStruct["faultCode"]), Message: toString(faultStruct["faultString"])}
+	}
+	if len(res.Params) == 0 {
+		return nil, nil
+	}
+	return res.Params[0].native()
+}
+
+func toInt64(value any) int64 {
+	switch v := value.(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	case string:
+		i, _ := strconv.ParseInt(v, 10, 64)
+		return i
+	default:
+		return 0
+	}
+}
+
+func toString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func toSlice(value any) []any {
+	if v, ok := value.([]any); ok {
+		return v
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package rtorrent
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncodeMethodCall(t *testing.T) {
+	data, err := encodeMethodCall("d.custom1.set", "ABCD", "a<b", int64(1)<<40, []byte("x"))
+	if err != nil {
+		t.Fatalf("encodeMethodCall error: %v", err)
+	}
+	expected := `<?xml version="1.0"?><methodCall><methodName>d.custom1.set</methodName><params>` +
+		`<param><value><string>ABCD</string></value></param>` +
+		`<param><value><string>a&lt;b</string></value></param>` +
+		`<param><value><i8>1099511627776</i8></value></param>` +
+		`<param><value><base64>eA==</base64></value></param></params></methodCall>`
+	if string(data) != expected {
+		t.Errorf("encodeMethodCall: got %s, expected %s", data, expected)
+	}
+}
+
+func TestDecodeMethodResponse(t *testing.T) {
+	tests := []struct {
+		desc     string
+		response string
+		expected any
+		fault    string
+	}{
+		{
+			desc: "multicall",
+			response: `<?xml version="1.0" encoding="UTF-8"?><methodResponse><params><param><value><array><data>
+<value><array><data><value><string>ABCD</string></value><value><i8>5368709120</i8></value>
+<value>untyped</value><value><i4>-1</i4></value></data></array></value>
+</data></array></value></param></params></methodResponse>`,
+			expected: []any{[]any{"ABCD", int64(5368709120), "untyped", int64(-1)}},
+		},
+		{
+			desc: "struct",
+			response: `<methodResponse><params><param><value><struct><member><name>a</name>` +
+				`<value><boolean>1</boolean></value></member></struct></value></param></params></methodResponse>`,
+			expected: map[string]any{"a": true},
+		},
+		{
+			desc: "fault",
+			response: `<methodResponse><fault><value><struct>` +
+				`<member><name>faultCode</name><value><i4>-506</i4></value></member>` +
+				`<member><name>faultString</name><value><string>Method 'foo' not defined</string></value></member>` +
+				`</struct></value></fault></methodResponse>`,
+			fault: "Method 'foo' not defined",
+		},
+	}
+	for _, test := range tests {
+		result, err := decodeMethodResponse([]byte(test.response))
+		if test.fault != "" {
+			if err == nil || !strings.Contains(err.Error(), test.fault) {
+				t.Errorf("%s: expected fault %q, got %v", test.desc, test.fault, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: error: %v", test.desc, err)
+		} else if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("%s: got %#v, expected %#v", test.desc, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package transmission
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	transmissionrpc "github.com/hekmon/transmissionrpc/v2"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/site/tpl"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+// Transmission RPC does not provide the contents of .torrent file (and it's not possible to reconstruct it,
+// as piece hashes are not exposed), so it's fetched from the following sources in order:
+//  1. "<localTorrentsPath>/<infoHash>.torrent", if localTorrentsPath is configured.
+//     The file name of "torrentFile" returned by RPC is also tried (Transmission 3.0 names it "<name>.<hash16>.torrent").
+//  2. The "torrentFile" path returned by RPC, if ptool runs on the same host and the file is readable.
+//  3. Download from site. The site is determined by the "site:<name>" label of torrent or by the torrent trackers;
+//     the site torrent id is read from the "meta.id:<id>" label, or the "comment" field if it's a site url.
+//     The downloaded .torrent file must have the same info-hash as the client torrent.
+func (trclient *Client) ExportTorrentFile(infoHash string) ([]byte, error) {
+	if trclient.ClientConfig.LocalTorrentsPath != "" {
+		contents, err := os.ReadFile(filepath.Join(trclient.ClientConfig.LocalTorrentsPath, infoHash+".torrent"))
+		if err == nil || !os.IsNotExist(err) {
+			return contents, err
+		}
+	}
+	trtorrent, err := trclient.getTorrent(infoHash, true)
+	if err != nil {
+		return nil, err
+	}
+	errs := []error{}
+	if trtorrent.TorrentFile != nil && *trtorrent.TorrentFile != "" {
+		torrentFile := *trtorrent.TorrentFile
+		if trclient.ClientConfig.LocalTorrentsPath != "" {
+			// torrentFile path is in the file system of Transmission, which may be different from local one
+			torrentFile = filepath.Join(trclient.ClientConfig.LocalTorrentsPath, path.Base(util.ToSlash(torrentFile)))
+		}
+		contents, err := os.ReadFile(torrentFile)
+		if err == nil {
+			return contents, nil
+		}
+		errs = append(errs, fmt.Errorf("read torrent file: %w", err))
+	} else if trclient.ClientConfig.LocalTorrentsPath != "" {
+		errs = append(errs, fmt.Errorf("torrent file not found in localTorrentsPath"))
+	}
+	contents, err := trclient.downloadTorrentFromSite(trtorrent)
+	if err == nil {
+		return contents, nil
+	}
+	errs = append(errs, fmt.Errorf("download from site: %w", err))
+	return nil, fmt.Errorf("failed to export torrent: %w", errors.Join(errs...))
+}
+
+func (trclient *Client) downloadTorrentFromSite(trtorrent *transmissionrpc.Torrent) ([]byte, error) {
+	var err error
+	torrent := tr2Torrent(trtorrent)
+	sitename := torrent.GetSiteFromTag()
+	if sitename == "" || config.GetSiteConfig(sitename) == nil {
+		trackers := []string{}
+		for _, tracker := range trtorrent.Trackers {
+			trackers = append(trackers, tracker.Announce)
+		}
+		if sitename, err = tpl.GuessSiteByTrackers(trackers, ""); err != nil {
+			return nil, err
+		}
+	}
+	if sitename == "" {
+		return nil, fmt.Errorf("no site matches torrent trackers")
+	}
+	siteInstance, err := site.CreateSite(sitename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create site %s: %w", sitename, err)
+	}
+	torrentUrl := ""
+	if id := torrent.Meta["id"]; id > 0 {
+		torrentUrl = fmt.Sprintf("%s.%d", sitename, id)
+	} else if trtorrent.Comment != nil && util.IsUrl(*trtorrent.Comment) &&
+		util.GetUrlDomain(*trtorrent.Comment) == util.GetUrlDomain(siteInstance.GetSiteConfig().Url) {
+		torrentUrl = *trtorrent.Comment
+	} else {
+		return nil, fmt.Errorf("site torrent id of %s is unknown", sitename)
+	}
+	log.Debugf("Download torrent %s from site: %s", torrent.InfoHash, torrentUrl)
+	contents, _, _, err := siteInstance.DownloadTorrent(torrentUrl)
+	if err != nil {
+		return nil, err
+	}
+	tinfo, err := torrentutil.ParseTorrent(contents)
+	if err != nil {
+		return nil, fmt.Errorf("downloaded file is not a valid torrent: %w", err)
+	}
+	if tinfo.InfoHash != torrent.InfoHash {
+		return nil, fmt.Errorf("downloaded torrent (%s) info-hash mismatch: %s", torrentUrl, tinfo.InfoHash)
+	}
+	return contents, nil
+}
@@ -0,0 +1,74 @@
+package transmission
+
+// Transmission has no native categories or standalone tags: both are simulated using torrent labels.
+// Category save paths and created (but not yet used) tags are persisted to a local file
+// "<config_dir>/transmission-<client>.json", so they behave the same as in qBittorrent.
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+)
+
+type clientMeta struct {
+	Categories map[string]string `json:"categories"` // category => savePath ("" if not set)
+	Tags       []string          `json:"tags"`
+}
+
+func (trclient *Client) metaFilename() string {
+	return filepath.Join(config.ConfigDir, fmt.Sprintf(config.TRANSMISSION_META_FILE, trclient.Name))
+}
+
+// Load client meta from local file. Return an empty meta if file does not exist.
+func (trclient *Client) loadMeta() (*clientMeta, error) {
+	meta := &clientMeta{}
+	data, err := os.ReadFile(trclient.metaFilename())
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+	} else if err = json.Unmarshal(data, meta); err != nil {
+		err = fmt.Errorf("invalid transmission meta file %s: %w", trclient.metaFilename(), err)
+	}
+	if meta.Categories == nil {
+		meta.Categories = map[string]string{}
+	}
+	return meta, err
+}
+
+func (trclient *Client) saveMeta(meta *clientMeta) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(config.ConfigDir, constants.PERM_DIR); err != nil {
+		return err
+	}
+	return os.WriteFile(trclient.metaFilename(), data, constants.PERM)
+}
+
+// Modify client meta and save it.
+func (trclient *Client) updateMeta(updater func(meta *clientMeta)) error {
+	meta, err := trclient.loadMeta()
+	if err != nil {
+		return err
+	}
+	updater(meta)
+	return trclient.saveMeta(meta)
+}
+
+// Return the save path of category. Return empty string if category does not have a save path.
+func (trclient *Client) getCategorySavePath(category string) string {
+	if category == "" || category == constants.NONE {
+		return ""
+	}
+	meta, err := trclient.loadMeta()
+	if err != nil {
+		return ""
+	}
+	return meta.Categories[category]
+}
@@ -9,11 +9,10 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
-	"os"
-	"path/filepath"
 	"reflect"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/ettle/strcase"
 	transmissionrpc "github.com/hekmon/transmissionrpc/v2"
@@ -59,12 +58,53 @@ var (
 
 // SetAllTorrentsShareLimits implements client.Client.
 func (trclient *Client) SetAllTorrentsShareLimits(ratioLimit float64, seedingTimeLimit int64) error {
-	return ErrNotImplemented
+	if err := trclient.Sync(false); err != nil {
+		return err
+	}
+	return trclient.SetTorrentsShareLimits(trclient.getAllInfoHashes(), ratioLimit, seedingTimeLimit)
 }
 
 // SetTorrentsShareLimits implements client.Client.
+// ratioLimit / seedingTimeLimit (seconds): 0 or -2 == use global limit; -1 == no limit (same as qb).
+// Transmission does not have a seeding time limit, the seedingTimeLimit is applied as
+// seeding idle (inactivity) limit (minutes) instead, which is the closest equivalent.
 func (trclient *Client) SetTorrentsShareLimits(infoHashes []string, ratioLimit float64, seedingTimeLimit int64) error {
-	return ErrNotImplemented
+	if len(infoHashes) == 0 {
+		return nil
+	}
+	if err := trclient.Sync(false); err != nil {
+		return err
+	}
+	ids := trclient.getIds(infoHashes)
+	if len(ids) == 0 {
+		return nil
+	}
+	payload := transmissionrpc.TorrentSetPayload{
+		IDs: ids,
+	}
+	setShareLimitsPayload(&payload, ratioLimit, seedingTimeLimit)
+	return trclient.client.TorrentSet(context.TODO(), payload)
+}
+
+func setShareLimitsPayload(payload *transmissionrpc.TorrentSetPayload, ratioLimit float64, seedingTimeLimit int64) {
+	seedRatioMode := transmissionrpc.SeedRatioModeGlobal
+	if ratioLimit > 0 {
+		seedRatioMode = transmissionrpc.SeedRatioModeCustom
+		payload.SeedRatioLimit = &ratioLimit
+	} else if ratioLimit == -1 {
+		seedRatioMode = transmissionrpc.SeedRatioModeNoRatio
+	}
+	payload.SeedRatioMode = &seedRatioMode
+	seedIdleMode := int64(0) // 0: global; 1: single (torrent-level); 2: unlimited
+	if seedingTimeLimit > 0 {
+		seedIdleMode = 1
+		// round up to minutes, so a limit read back from torrent is the same
+		seedIdleLimit := time.Duration((seedingTimeLimit+59)/60) * time.Minute
+		payload.SeedIdleLimit = &seedIdleLimit
+	} else if seedingTimeLimit == -1 {
+		seedIdleMode = 2
+	}
+	payload.SeedIdleMode = &seedIdleMode
 }
 
 // get a torrent info from rpc. return error if torrent not found
@@ -130,6 +170,7 @@ func (trclient *Client) Sync(full bool) (err error) {
 			"activityDate", "addedDate", "doneDate", "downloadDir", "downloadedEver", "downloadLimit", "downloadLimited",
 			"hashString", "id", "labels", "name", "peersGettingFromUs", "peersSendingToUs", "percentDone", "rateDownload",
 			"rateUpload", "sizeWhenDone", "status", "trackers", "totalSize", "uploadedEver", "uploadLimit", "uploadLimited",
+			"seedRatioMode", "seedRatioLimit", "seedIdleMode", "seedIdleLimit",
 		}, nil)
 	}
 
@@ -190,13 +231,6 @@ func (trclient *Client) syncMeta() error {
 	return nil
 }
 
-func (trclient *Client) ExportTorrentFile(infoHash string) ([]byte, error) {
-	if trclient.ClientConfig.LocalTorrentsPath != "" {
-		return os.ReadFile(filepath.Join(trclient.ClientConfig.LocalTorrentsPath, infoHash+".torrent"))
-	}
-	return nil, fmt.Errorf("unsupported")
-}
-
 func (trclient *Client) GetTorrent(infoHash string) (*client.Torrent, error) {
 	if err := trclient.Sync(false); err != nil {
 		return nil, err
@@ -240,6 +274,8 @@ func (trclient *Client) AddTorrent(torrentContent []byte, option *client.Torrent
 	var downloadDir *string
 	if option.SavePath != "" {
 		downloadDir = &option.SavePath
+	} else if savePath := trclient.getCategorySavePath(option.Category); savePath != "" {
+		downloadDir = &savePath
 	}
 	payload := transmissionrpc.TorrentAddPayload{
 		Paused:      &option.Pause,
@@ -291,15 +327,19 @@ func (trclient *Client) AddTorrent(torrentContent []byte, option *client.Torrent
 		}
 		downloadLimited = true
 	}
-	if len(labels) > 0 || uploadLimited || downloadLimited {
-		err := transmissionbt.TorrentSet(context.TODO(), transmissionrpc.TorrentSetPayload{
+	if len(labels) > 0 || uploadLimited || downloadLimited || option.RatioLimit != 0 || option.SeedingTimeLimit != 0 {
+		payload := transmissionrpc.TorrentSetPayload{
 			IDs:             []int64{*torrent.ID},
 			Labels:          labels,
 			UploadLimited:   &uploadLimited,
 			UploadLimit:     &uploadLimit,
 			DownloadLimit:   &downloadLimit,
 			DownloadLimited: &downloadLimited,
-		})
+		}
+		if option.RatioLimit != 0 || option.SeedingTimeLimit != 0 {
+			setShareLimitsPayload(&payload, option.RatioLimit, option.SeedingTimeLimit)
+		}
+		err := transmissionbt.TorrentSet(context.TODO(), payload)
 		log.Tracef("set tr torrent err=%v", err)
 	}
 
@@ -325,14 +365,16 @@ func (trclient *Client) ModifyTorrent(infoHash string, option *client.TorrentOpt
 		IDs: []int64{*trtorrent.ID},
 	}
 
-	if (option.Category != "" && option.Category != constants.NONE) ||
+	// category "none": remove the category of torrent
+	removeCategory := option.Category == constants.NONE && torrent.Category != ""
+	if (option.Category != "" && option.Category != constants.NONE) || removeCategory ||
 		len(option.Tags) > 0 || len(option.RemoveTags) > 0 ||
 		len(meta) > 0 || len(torrent.Meta) > 0 {
 		labels := []string{}
-		if option.Category != "" && torrent.Category != option.Category {
+		if option.Category != "" && option.Category != constants.NONE && torrent.Category != option.Category {
 			categoryTag := client.GenerateTorrentTagFromCategory(option.Category)
 			labels = append(labels, categoryTag)
-		} else if torrent.Category != "" {
+		} else if torrent.Category != "" && !removeCategory {
 			categoryTag := client.GenerateTorrentTagFromCategory(torrent.Category)
 			labels = append(labels, categoryTag)
 		}
@@ -346,7 +388,7 @@ func (trclient *Client) ModifyTorrent(infoHash string, option *client.TorrentOpt
 				labels = append(labels, client.GenerateTorrentTagFromMetadata(name, value))
 			}
 		}
-		if len(labels) > 0 || len(option.RemoveTags) > 0 {
+		if len(labels) > 0 || len(option.RemoveTags) > 0 || removeCategory {
 			for _, tag := range torrent.Tags {
 				if !slices.Contains(option.RemoveTags, tag) {
 					labels = append(labels, tag)
@@ -360,7 +402,7 @@ func (trclient *Client) ModifyTorrent(infoHash string, option *client.TorrentOpt
 		downloadLimited := true
 		downloadLimit := int64(0)
 		if option.DownloadSpeedLimit > 0 {
-			downloadLimit := option.DownloadSpeedLimit / 1024
+			downloadLimit = option.DownloadSpeedLimit / 1024
 			if downloadLimit == 0 {
 				downloadLimit = 1
 			}
@@ -390,6 +432,9 @@ func (trclient *Client) ModifyTorrent(infoHash string, option *client.TorrentOpt
 	if option.SavePath != "" {
 		payload.Location = &option.SavePath
 	}
+	if option.RatioLimit != 0 || option.SeedingTimeLimit != 0 {
+		setShareLimitsPayload(&payload, option.RatioLimit, option.SeedingTimeLimit)
+	}
 
 	transmissionbt.TorrentSet(context.TODO(), payload)
 
@@ -524,8 +569,16 @@ func (trclient *Client) GetTags() ([]string, error) {
 	if err := trclient.Sync(false); err != nil {
 		return nil, err
 	}
+	meta, err := trclient.loadMeta()
+	if err != nil {
+		return nil, err
+	}
 	tags := []string{}
 	tagsFlag := map[string]bool{}
+	for _, tag := range meta.Tags {
+		tags = append(tags, tag)
+		tagsFlag[tag] = true
+	}
 	for _, trtorrent := range trclient.torrents {
 		for _, label := range trtorrent.Labels {
 			if label != "" && !tagsFlag[label] && !client.IsSubstituteTag(label) {
@@ -537,31 +590,83 @@ func (trclient *Client) GetTags() ([]string, error) {
 	return tags, nil
 }
 
+// Transmission labels only exist on torrents, so created tags are persisted in local meta file.
 func (trclient *Client) CreateTags(tags ...string) error {
-	return fmt.Errorf("unsupported")
+	return trclient.updateMeta(func(meta *clientMeta) {
+		for _, tag := range tags {
+			if tag != "" && !slices.Contains(meta.Tags, tag) {
+				meta.Tags = append(meta.Tags, tag)
+			}
+		}
+	})
 }
 
 func (trclient *Client) DeleteTags(tags ...string) error {
-	return trclient.RemoveTagsFromAllTorrents(tags)
+	if err := trclient.RemoveTagsFromAllTorrents(tags); err != nil {
+		return err
+	}
+	return trclient.updateMeta(func(meta *clientMeta) {
+		meta.Tags = slices.DeleteFunc(meta.Tags, func(tag string) bool {
+			return slices.Contains(tags, tag)
+		})
+	})
 }
 
+// Category is stored as a special label of torrent. The category save path is persisted in local meta file,
+// and is used as the default save path when adding torrent to that category.
 func (trclient *Client) MakeCategory(category string, savePath string) error {
-	return fmt.Errorf("unsupported")
+	if category == "" || category == constants.NONE {
+		return fmt.Errorf("invalid category name %q", category)
+	}
+	return trclient.updateMeta(func(meta *clientMeta) {
+		if savePath != constants.NONE {
+			meta.Categories[category] = savePath
+		} else if _, ok := meta.Categories[category]; !ok {
+			meta.Categories[category] = ""
+		}
+	})
 }
 
+// Delete categories. Torrents of deleted categories become uncategoried.
 func (trclient *Client) DeleteCategories(categories []string) error {
-	return fmt.Errorf("unsupported")
+	if err := trclient.Sync(false); err != nil {
+		return err
+	}
+	infoHashes := []string{}
+	for infoHash, trtorrent := range trclient.torrents {
+		if slices.Contains(categories, tr2Torrent(trtorrent).Category) {
+			infoHashes = append(infoHashes, infoHash)
+		}
+	}
+	if err := trclient.SetTorrentsCatetory(infoHashes, constants.NONE); err != nil {
+		return err
+	}
+	return trclient.updateMeta(func(meta *clientMeta) {
+		for _, category := range categories {
+			delete(meta.Categories, category)
+		}
+	})
 }
 
 func (trclient *Client) GetCategories() ([]*client.TorrentCategory, error) {
 	if err := trclient.Sync(false); err != nil {
 		return nil, err
 	}
+	meta, err := trclient.loadMeta()
+	if err != nil {
+		return nil, err
+	}
 	cats := []*client.TorrentCategory{}
 	catsFlag := map[string]bool{}
+	for cat, savePath := range meta.Categories {
+		cats = append(cats, &client.TorrentCategory{
+			Name:     cat,
+			SavePath: savePath,
+		})
+		catsFlag[cat] = true
+	}
 	for _, trtorrent := range trclient.torrents {
-		torrent := tr2Torrent(trtorrent)
-		cat := torrent.GetCategoryFromTag()
+		cat := tr2Torrent(trtorrent).Category
 		if cat != "" && !catsFlag[cat] {
 			cats = append(cats, &client.TorrentCategory{
 				Name: cat,
@@ -569,14 +674,20 @@ func (trclient *Client) GetCategories() ([]*client.TorrentCategory, error) {
 			catsFlag[cat] = true
 		}
 	}
+	slices.SortFunc(cats, func(a, b *client.TorrentCategory) int {
+		return strings.Compare(a.Name, b.Name)
+	})
 	return cats, nil
 }
 
 func (trclient *Client) SetTorrentsCatetory(infoHashes []string, category string) error {
 	for _, infoHash := range infoHashes {
-		trclient.ModifyTorrent(infoHash, &client.TorrentOption{
+		err := trclient.ModifyTorrent(infoHash, &client.TorrentOption{
 			Category: category,
 		}, nil)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -615,10 +726,20 @@ func (trclient *Client) GetTorrentContents(infoHash string) ([]*client.TorrentCo
 	}
 	files := []*client.TorrentContentFile{}
 	for i, trTorrentFile := range torrent.Files {
+		// tr file priority: -1 low, 0 normal, 1 high
+		priority := int64(0)
+		if torrent.FileStats[i].Wanted {
+			priority = 1
+			if torrent.FileStats[i].Priority > 0 {
+				priority = 6
+			}
+		}
 		files = append(files, &client.TorrentContentFile{
+			Index:    int64(i),
 			Path:     trTorrentFile.Name,
 			Size:     trTorrentFile.Length,
 			Ignored:  !torrent.FileStats[i].Wanted,
+			Priority: priority,
 			Complete: trTorrentFile.BytesCompleted == trTorrentFile.Length,
 			Progress: float64(trTorrentFile.BytesCompleted) / float64(trTorrentFile.Length),
 		})
@@ -904,8 +1025,34 @@ func (trclient *Client) RemoveTorrentTrackers(infoHash string, trackers []string
 	return nil
 }
 
+// Transmission file priorities are low / normal / high. The qBittorrent priority values are mapped:
+// 0 => do not download; 1-5 => normal; 6-7 => high.
 func (trclient *Client) SetFilePriority(infoHash string, fileIndexes []int64, priority int64) error {
-	return ErrNotImplemented
+	if len(fileIndexes) == 0 {
+		return fmt.Errorf("must provide at least fileIndex")
+	}
+	trtorrent, err := trclient.getTorrent(infoHash, false)
+	if err != nil {
+		return err
+	}
+	payload := transmissionrpc.TorrentSetPayload{
+		IDs: []int64{*trtorrent.ID},
+	}
+	if priority <= 0 {
+		payload.FilesUnwanted = fileIndexes
+	} else {
+		payload.FilesWanted = fileIndexes
+		if priority >= 6 {
+			payload.PriorityHigh = fileIndexes
+		} else {
+			payload.PriorityNormal = fileIndexes
+		}
+	}
+	if err = trclient.client.TorrentSet(context.TODO(), payload); err != nil {
+		return err
+	}
+	trclient.lastTorrent = nil
+	return nil
 }
 
 func (trclient *Client) Close() {
@@ -1041,6 +1188,26 @@ func tr2Torrent(trtorrent *transmissionrpc.Torrent) *client.Torrent {
 		Leechers:           *trtorrent.PeersGettingFromUs, // it's meaning is inconsistent with qb for now
 		Meta:               nil,
 	}
+	if trtorrent.SeedRatioMode != nil {
+		switch *trtorrent.SeedRatioMode {
+		case transmissionrpc.SeedRatioModeCustom:
+			if trtorrent.SeedRatioLimit != nil {
+				torrent.RatioLimit = *trtorrent.SeedRatioLimit
+			}
+		case transmissionrpc.SeedRatioModeNoRatio:
+			torrent.RatioLimit = -1
+		}
+	}
+	if trtorrent.SeedIdleMode != nil {
+		switch *trtorrent.SeedIdleMode {
+		case 1:
+			if trtorrent.SeedIdleLimit != nil {
+				torrent.SeedingTimeLimit = *trtorrent.SeedIdleLimit * 60 // minutes
+			}
+		case 2:
+			torrent.SeedingTimeLimit = -1
+		}
+	}
 	torrent.Meta = torrent.GetMetadataFromTags()
 	torrent.Category = torrent.GetCategoryFromTag()
 	torrent.RemoveSubstituteTags()
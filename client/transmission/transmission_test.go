@@ -0,0 +1,165 @@
+package transmission_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/client/transmission"
+	"github.com/sagan/ptool/config"
+)
+
+const testInfoHash = "0123456789abcdef0123456789abcdef01234567"
+
+// A minimal fake Transmission RPC server.
+type fakeTransmission struct {
+	torrentFile string
+	sets        []map[string]any // arguments of "torrent-set" calls
+}
+
+func (ft *fakeTransmission) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Transmission-Session-Id") != "test" {
+		w.Header().Set("X-Transmission-Session-Id", "test")
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	var req struct {
+		Method    string         `json:"method"`
+		Arguments map[string]any `json:"arguments"`
+		Tag       int            `json:"tag"`
+	}
+	if json.NewDecoder(r.Body).Decode(&req) != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	arguments := map[string]any{}
+	switch req.Method {
+	case "torrent-get":
+		arguments["torrents"] = []map[string]any{{
+			"id": 1, "hashString": testInfoHash, "name": "test", "labels": []string{"category:tv", "foo"},
+			"downloadDir": "/downloads", "status": 6, "addedDate": 1700000000, "doneDate": 1700000000,
+			"activityDate": 1700000000, "downloadedEver": 100, "uploadedEver": 200, "percentDone": 1,
+			"sizeWhenDone": 100, "totalSize": 100, "rateDownload": 0, "rateUpload": 0,
+			"uploadLimited": false, "downloadLimited": false, "uploadLimit": 0, "downloadLimit": 0,
+			"peersGettingFromUs": 0, "peersSendingToUs": 0, "trackers": []any{}, "torrentFile": ft.torrentFile,
+		}}
+	case "torrent-set":
+		ft.sets = append(ft.sets, req.Arguments)
+	}
+	json.NewEncoder(w).Encode(map[string]any{"result": "success", "arguments": arguments, "tag": req.Tag})
+}
+
+func newTestClient(t *testing.T) (client.Client, *fakeTransmission) {
+	config.ConfigDir = t.TempDir()
+	ft := &fakeTransmission{}
+	server := httptest.NewServer(ft)
+	t.Cleanup(server.Close)
+	clientInstance, err := transmission.NewClient("tr", &config.ClientConfigStruct{
+		Type: "transmission",
+		Url:  server.URL + "/transmission/rpc",
+	}, &config.ConfigStruct{})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return clientInstance, ft
+}
+
+func TestSetTorrentsShareLimits(t *testing.T) {
+	clientInstance, ft := newTestClient(t)
+	if err := clientInstance.SetTorrentsShareLimits([]string{testInfoHash}, 2, 3600); err != nil {
+		t.Fatalf("SetTorrentsShareLimits error: %v", err)
+	}
+	if err := clientInstance.SetTorrentsShareLimits([]string{testInfoHash}, -1, 3601); err != nil {
+		t.Fatalf("SetTorrentsShareLimits error: %v", err)
+	}
+	if err := clientInstance.SetTorrentsShareLimits([]string{testInfoHash}, -2, -1); err != nil {
+		t.Fatalf("SetTorrentsShareLimits error: %v", err)
+	}
+	if err := clientInstance.SetTorrentsShareLimits([]string{testInfoHash}, 0, -2); err != nil {
+		t.Fatalf("SetTorrentsShareLimits error: %v", err)
+	}
+	want := []map[string]any{
+		{"ids": []any{1.0}, "seedRatioMode": 1.0, "seedRatioLimit": 2.0, "seedIdleMode": 1.0, "seedIdleLimit": 60.0},
+		{"ids": []any{1.0}, "seedRatioMode": 2.0, "seedIdleMode": 1.0, "seedIdleLimit": 61.0},
+		{"ids": []any{1.0}, "seedRatioMode": 0.0, "seedIdleMode": 2.0},
+		{"ids": []any{1.0}, "seedRatioMode": 0.0, "seedIdleMode": 0.0},
+	}
+	if !reflect.DeepEqual(ft.sets, want) {
+		t.Errorf("torrent-set calls = %v, want %v", ft.sets, want)
+	}
+}
+
+func TestSetFilePriority(t *testing.T) {
+	clientInstance, ft := newTestClient(t)
+	if err := clientInstance.SetFilePriority(testInfoHash, []int64{0, 2}, 0); err != nil {
+		t.Fatalf("SetFilePriority error: %v", err)
+	}
+	if err := clientInstance.SetFilePriority(testInfoHash, []int64{1}, 7); err != nil {
+		t.Fatalf("SetFilePriority error: %v", err)
+	}
+	want := []map[string]any{
+		{"ids": []any{1.0}, "files-unwanted": []any{0.0, 2.0}},
+		{"ids": []any{1.0}, "files-wanted": []any{1.0}, "priority-high": []any{1.0}},
+	}
+	if !reflect.DeepEqual(ft.sets, want) {
+		t.Errorf("torrent-set calls = %v, want %v", ft.sets, want)
+	}
+}
+
+func TestCategories(t *testing.T) {
+	clientInstance, ft := newTestClient(t)
+	if err := clientInstance.MakeCategory("movies", "/data/movies"); err != nil {
+		t.Fatalf("MakeCategory error: %v", err)
+	}
+	categories, err := clientInstance.GetCategories()
+	if err != nil {
+		t.Fatalf("GetCategories error: %v", err)
+	}
+	want := []*client.TorrentCategory{{Name: "movies", SavePath: "/data/movies"}, {Name: "tv"}}
+	if !reflect.DeepEqual(categories, want) {
+		t.Errorf("GetCategories() = %v, want %v", categories, want)
+	}
+	if err := clientInstance.DeleteCategories([]string{"movies", "tv"}); err != nil {
+		t.Fatalf("DeleteCategories error: %v", err)
+	}
+	wantSets := []map[string]any{{"ids": []any{1.0}, "labels": []any{"foo"}}}
+	if !reflect.DeepEqual(ft.sets, wantSets) {
+		t.Errorf("torrent-set calls = %v, want %v", ft.sets, wantSets)
+	}
+	if err := clientInstance.CreateTags("bar"); err != nil {
+		t.Fatalf("CreateTags error: %v", err)
+	}
+	tags, err := clientInstance.GetTags()
+	if err != nil {
+		t.Fatalf("GetTags error: %v", err)
+	}
+	if !reflect.DeepEqual(tags, []string{"bar", "foo"}) {
+		t.Errorf("GetTags() = %v, want %v", tags, []string{"bar", "foo"})
+	}
+}
+
+func TestExportTorrentFile(t *testing.T) {
+	clientInstance, ft := newTestClient(t)
+	// Transmission runs in a container, where torrents dir is mounted to local torrentsDir
+	torrentsDir := t.TempDir()
+	ft.torrentFile = "/config/torrents/test.0123456789abcdef.torrent"
+	if err := os.WriteFile(filepath.Join(torrentsDir, "test.0123456789abcdef.torrent"), []byte("d4:infoe"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := clientInstance.ExportTorrentFile(testInfoHash); err == nil {
+		t.Errorf("ExportTorrentFile should fail if torrent file is not accessible")
+	}
+	clientInstance.GetClientConfig().LocalTorrentsPath = torrentsDir
+	contents, err := clientInstance.ExportTorrentFile(testInfoHash)
+	if err != nil {
+		t.Fatalf("ExportTorrentFile error: %v", err)
+	}
+	if string(contents) != "d4:infoe" {
+		t.Errorf("ExportTorrentFile() = %q, want %q", contents, "d4:infoe")
+	}
+}
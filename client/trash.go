@@ -0,0 +1,295 @@
+package client
+
+// Trash mode: if "trashPath" of client is configured, deleting torrents with files (DeleteOrTrashTorrents)
+// does NOT delete the content files. Instead, the .torrent file is exported, the content files are moved into
+// the trash (which must be on the same device as them), then the torrent is deleted from client (preserving files).
+// Each trashed torrent is an item dir:
+// "<trashPath>/<time>.<infoHash>/", which contains "item.json" (metadata), "<infoHash>.torrent",
+// and "data/" (moved content files, with the same structure as in save path).
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/natefinch/atomic"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/osutil"
+)
+
+const (
+	TRASH_ITEM_META = "item.json"
+	TRASH_ITEM_DATA = "data"
+)
+
+type TrashItem struct {
+	Id       string           `json:"id"` // item dir name
+	Client   string           `json:"client"`
+	InfoHash string           `json:"infoHash"`
+	Name     string           `json:"name"`
+	SavePath string           `json:"savePath"` // client save path
+	Category string           `json:"category"`
+	Tags     []string         `json:"tags"`
+	Meta     map[string]int64 `json:"meta,omitempty"`
+	Size     int64            `json:"size"`
+	Time     int64            `json:"time"`  // trashed time
+	Files    []string         `json:"files"` // root files / folders (relative to save path) moved to trash
+	Dir      string           `json:"-"`     // local path of item dir
+}
+
+// Delete torrents from client. If deleteFiles is true and trash mode is enabled for client,
+// move the content files to trash instead of deleting them.
+func DeleteOrTrashTorrents(clientInstance Client, infoHashes []string, deleteFiles bool) error {
+	if !deleteFiles || clientInstance.GetClientConfig().TrashPath == "" {
+		return clientInstance.DeleteTorrents(infoHashes, deleteFiles)
+	}
+	return TrashTorrents(clientInstance, infoHashes)
+}
+
+// Delete torrents from client and move their content files to trash. Torrents not found in client are ignored.
+func TrashTorrents(clientInstance Client, infoHashes []string) error {
+	trashPath := clientInstance.GetClientConfig().TrashPath
+	if trashPath == "" {
+		return fmt.Errorf("trash mode is not enabled for client %s", clientInstance.GetName())
+	}
+	savePathMapper, err := getTrashSavePathMapper(clientInstance)
+	if err != nil {
+		return err
+	}
+	errorCnt := int64(0)
+	for _, infoHash := range infoHashes {
+		if err := trashTorrent(clientInstance, trashPath, savePathMapper, infoHash); err != nil {
+			log.Errorf("Failed to trash torrent %s: %v", infoHash, err)
+			errorCnt++
+		}
+	}
+	if errorCnt > 0 {
+		return fmt.Errorf("%d errors", errorCnt)
+	}
+	return nil
+}
+
+func trashTorrent(clientInstance Client, trashPath string, savePathMapper *util.PathMapper, infoHash string) error {
+	torrent, err := clientInstance.GetTorrent(infoHash)
+	if err != nil {
+		return err
+	}
+	if torrent == nil {
+		return nil
+	}
+	contents, err := clientInstance.ExportTorrentFile(infoHash)
+	if err != nil {
+		return fmt.Errorf("failed to export torrent: %w", err)
+	}
+	files, err := clientInstance.GetTorrentContents(infoHash)
+	if err != nil {
+		return fmt.Errorf("failed to get torrent contents: %w", err)
+	}
+	rootFiles := util.UniqueSlice(util.Map(files, func(f *TorrentContentFile) string {
+		rootFile, _, _ := strings.Cut(f.Path, "/")
+		return rootFile
+	}))
+	localRootFiles := map[string]string{}
+	for _, rootFile := range rootFiles {
+		if localRootFiles[rootFile], err = mapTrashFilePath(savePathMapper, torrent.SavePath, rootFile); err != nil {
+			return err
+		}
+	}
+	// Content files are moved to trash by renaming, which requires them to be on the same device as trash.
+	if err = os.MkdirAll(trashPath, constants.PERM_DIR); err != nil {
+		return err
+	}
+	for _, rootFile := range rootFiles {
+		src := localRootFiles[rootFile]
+		if !util.FileExists(src) {
+			continue
+		}
+		if sameDevice, err := osutil.SameDevice(src, trashPath); err != nil {
+			return err
+		} else if !sameDevice {
+			return fmt.Errorf("%q is not on the same device as trash path %q", src, trashPath)
+		}
+	}
+	item := &TrashItem{
+		Id:       fmt.Sprintf("%d.%s", util.Now(), infoHash),
+		Client:   clientInstance.GetName(),
+		InfoHash: infoHash,
+		Name:     torrent.Name,
+		SavePath: torrent.SavePath,
+		Category: torrent.Category,
+		Tags:     torrent.Tags,
+		Meta:     torrent.Meta,
+		Size:     torrent.Size,
+		Time:     util.Now(),
+	}
+	item.Dir = filepath.Join(trashPath, item.Id)
+	if err = os.MkdirAll(filepath.Join(item.Dir, TRASH_ITEM_DATA), constants.PERM_DIR); err != nil {
+		return err
+	}
+	if err = atomic.WriteFile(filepath.Join(item.Dir, infoHash+".torrent"), bytes.NewReader(contents)); err != nil {
+		os.RemoveAll(item.Dir)
+		return err
+	}
+	// Move the content files before deleting torrent from client. If anything fails,
+	// the moved files are moved back, so the torrent is left intact in client.
+	rollback := func() {
+		for _, rootFile := range item.Files {
+			if err := os.Rename(filepath.Join(item.Dir, TRASH_ITEM_DATA, rootFile),
+				localRootFiles[rootFile]); err != nil {
+				log.Errorf("Failed to move %q back from trash: %v", localRootFiles[rootFile], err)
+				return // keep the item in trash
+			}
+		}
+		os.RemoveAll(item.Dir)
+	}
+	for _, rootFile := range rootFiles {
+		src := localRootFiles[rootFile]
+		if !util.FileExists(src) {
+			continue // e.g. another xseed torrent of the same contents was trashed previously
+		}
+		if err = os.Rename(src, filepath.Join(item.Dir, TRASH_ITEM_DATA, rootFile)); err != nil {
+			rollback()
+			return fmt.Errorf("failed to move %q to trash: %w", src, err)
+		}
+		item.Files = append(item.Files, rootFile)
+	}
+	if err = item.save(); err != nil {
+		rollback()
+		return err
+	}
+	if err = clientInstance.DeleteTorrents([]string{infoHash}, false); err != nil {
+		rollback()
+		return fmt.Errorf("failed to delete torrent: %w", err)
+	}
+	return nil
+}
+
+// Return the trashed items of client, oldest first.
+func GetTrashItems(clientInstance Client) ([]*TrashItem, error) {
+	trashPath := clientInstance.GetClientConfig().TrashPath
+	if trashPath == "" {
+		return nil, fmt.Errorf("trash mode is not enabled for client %s", clientInstance.GetName())
+	}
+	entries, err := os.ReadDir(trashPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var items []*TrashItem
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(trashPath, entry.Name())
+		data, err := os.ReadFile(filepath.Join(dir, TRASH_ITEM_META))
+		if err != nil {
+			continue
+		}
+		item := &TrashItem{}
+		if err = json.Unmarshal(data, item); err != nil {
+			log.Warnf("Invalid trash item %q: %v", dir, err)
+			continue
+		}
+		if item.Client != clientInstance.GetName() {
+			continue
+		}
+		item.Dir = dir
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Time < items[j].Time })
+	return items, nil
+}
+
+// Move the content files back to original save path, then re-add the torrent to client with skip checking.
+// The item is removed from trash if succeeds.
+func RestoreTrashItem(clientInstance Client, item *TrashItem) error {
+	contents, err := os.ReadFile(filepath.Join(item.Dir, item.InfoHash+".torrent"))
+	if err != nil {
+		return fmt.Errorf("failed to read torrent file: %w", err)
+	}
+	savePathMapper, err := getTrashSavePathMapper(clientInstance)
+	if err != nil {
+		return err
+	}
+	localRootFiles := map[string]string{}
+	for _, rootFile := range item.Files {
+		dst, err := mapTrashFilePath(savePathMapper, item.SavePath, rootFile)
+		if err != nil {
+			return err
+		}
+		if util.FileExists(dst) {
+			return fmt.Errorf("failed to restore %q: target already exists", dst)
+		}
+		localRootFiles[rootFile] = dst
+	}
+	for _, rootFile := range item.Files {
+		dst := localRootFiles[rootFile]
+		if err = os.MkdirAll(filepath.Dir(dst), constants.PERM_DIR); err != nil {
+			return err
+		}
+		if err = os.Rename(filepath.Join(item.Dir, TRASH_ITEM_DATA, rootFile), dst); err != nil {
+			return fmt.Errorf("failed to move %q back to save path: %w", rootFile, err)
+		}
+	}
+	err = clientInstance.AddTorrent(contents, &TorrentOption{
+		SavePath:     item.SavePath,
+		Category:     item.Category,
+		Tags:         item.Tags,
+		SkipChecking: true,
+	}, item.Meta)
+	if err != nil {
+		// Content files are already moved back, so only the torrent needs to be re-added manually.
+		item.Files = nil
+		item.save()
+		return fmt.Errorf("failed to add torrent to client: %w", err)
+	}
+	return os.RemoveAll(item.Dir)
+}
+
+// Permanently delete the item (including content files) from trash.
+func PurgeTrashItem(item *TrashItem) error {
+	return os.RemoveAll(item.Dir)
+}
+
+func (item *TrashItem) save() error {
+	data, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomic.WriteFile(filepath.Join(item.Dir, TRASH_ITEM_META), bytes.NewReader(data))
+}
+
+func getTrashSavePathMapper(clientInstance Client) (*util.PathMapper, error) {
+	rules := clientInstance.GetClientConfig().TrashMapSavePaths
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	savePathMapper, err := util.NewPathMapper(rules)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trashMapSavePaths of client %s: %w", clientInstance.GetName(), err)
+	}
+	return savePathMapper, nil
+}
+
+// Return the local path of the root file (or folder) of torrent in client save path.
+func mapTrashFilePath(savePathMapper *util.PathMapper, savePath string, rootFile string) (string, error) {
+	clientPath := path.Join(util.ToSlash(savePath), rootFile)
+	if savePathMapper == nil {
+		return filepath.FromSlash(clientPath), nil
+	}
+	localPath, match := savePathMapper.After2Before(clientPath)
+	if !match {
+		return "", fmt.Errorf("save path %q can not be mapped to local path", savePath)
+	}
+	return filepath.FromSlash(localPath), nil
+}
@@ -0,0 +1,84 @@
+package client_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/client/clienttest"
+	"github.com/sagan/ptool/config"
+)
+
+const testInfoHash = "0123456789abcdef0123456789abcdef01234567"
+
+func newTrashTestClient(t *testing.T) (clientInstance *clienttest.Client, localSavePath string) {
+	dir := t.TempDir()
+	localSavePath = filepath.Join(dir, "downloads")
+	if err := os.MkdirAll(filepath.Join(localSavePath, "Movie"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(localSavePath, "Movie", "a.mkv"), []byte("a"), 0600)
+	clientInstance = clienttest.New(&client.Torrent{InfoHash: testInfoHash, Name: "Movie", SavePath: "/downloads",
+		Category: "movies"})
+	clientInstance.Config = &config.ClientConfigStruct{
+		Name:              "local",
+		TrashPath:         filepath.Join(dir, "trash"),
+		TrashMapSavePaths: []string{localSavePath + "|/downloads"},
+	}
+	clientInstance.Files[testInfoHash] = []*client.TorrentContentFile{{Path: "Movie/a.mkv"}, {Path: "Movie/b.srt"}}
+	return clientInstance, localSavePath
+}
+
+func TestTrash(t *testing.T) {
+	clientInstance, localSavePath := newTrashTestClient(t)
+	if err := client.DeleteOrTrashTorrents(clientInstance, []string{testInfoHash}, true); err != nil {
+		t.Fatalf("DeleteOrTrashTorrents error: %v", err)
+	}
+	if torrent, _ := clientInstance.GetTorrent(testInfoHash); torrent != nil {
+		t.Errorf("torrent is not deleted from client")
+	}
+	if _, err := os.Stat(filepath.Join(localSavePath, "Movie")); !os.IsNotExist(err) {
+		t.Errorf("content files are not moved to trash")
+	}
+	items, err := client.GetTrashItems(clientInstance)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("GetTrashItems() = %d items, %v; want 1 item", len(items), err)
+	}
+	if items[0].InfoHash != testInfoHash || len(items[0].Files) != 1 || items[0].Files[0] != "Movie" {
+		t.Errorf("trashed item = %+v, want the trashed torrent with files [Movie]", items[0])
+	}
+
+	if err := client.RestoreTrashItem(clientInstance, items[0]); err != nil {
+		t.Fatalf("RestoreTrashItem error: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(localSavePath, "Movie", "a.mkv")); err != nil || string(data) != "a" {
+		t.Errorf("content files are not restored: %v", err)
+	}
+	option := clientInstance.Added[testInfoHash]
+	if option == nil || option.SavePath != "/downloads" || option.Category != "movies" || !option.SkipChecking {
+		t.Errorf("restored torrent option = %+v, want original save path & category with skip checking", option)
+	}
+	if items, _ := client.GetTrashItems(clientInstance); len(items) != 0 {
+		t.Errorf("restored item is not removed from trash")
+	}
+}
+
+// If the torrent fails to be deleted from client, the content files are moved back.
+func TestTrashDeleteFailure(t *testing.T) {
+	clientInstance, localSavePath := newTrashTestClient(t)
+	clientInstance.Errors["DeleteTorrents"] = errors.New("network error")
+	if err := client.TrashTorrents(clientInstance, []string{testInfoHash}); err == nil {
+		t.Fatalf("TrashTorrents should fail")
+	}
+	if torrent, _ := clientInstance.GetTorrent(testInfoHash); torrent == nil {
+		t.Errorf("torrent is deleted from client")
+	}
+	if data, err := os.ReadFile(filepath.Join(localSavePath, "Movie", "a.mkv")); err != nil || string(data) != "a" {
+		t.Errorf("content files are not moved back: %v", err)
+	}
+	if items, err := client.GetTrashItems(clientInstance); err != nil || len(items) != 0 {
+		t.Errorf("GetTrashItems() = %d items, %v; want no items", len(items), err)
+	}
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/expr"
+)
+
+// Variables of client torrent that can be used in "--where" query expression.
+var WhereVars = []string{
+	"name", "infohash", "category", "tags", "site", "tracker", "trackerDomain", "state", "savePath", "contentPath",
+	"size", "sizeTotal", "sizeCompleted", "progress", "complete", "downloaded", "uploaded", "ratio",
+	"downloadSpeed", "uploadSpeed", "seeders", "leechers",
+	"atime", "ctime", "activityTime", "age", "seedingTime", "inactive",
+}
+
+// Compile a "--where" query expression. Return nil if where is empty.
+func CompileWhere(where string) (*expr.Program, error) {
+	if where == "" {
+		return nil, nil
+	}
+	program, err := expr.Compile(where)
+	if err != nil {
+		return nil, fmt.Errorf("invalid where expression: %w", err)
+	}
+	return program, nil
+}
+
+// Return the upload / download ratio of torrent.
+// For torrents that have not downloaded anything (e.g. xseed torrents), return uploaded / size instead.
+func (torrent *Torrent) Ratio() float64 {
+	if torrent.Downloaded > 0 {
+		return float64(torrent.Uploaded) / float64(torrent.Downloaded)
+	}
+	if torrent.Size > 0 {
+		return float64(torrent.Uploaded) / float64(torrent.Size)
+	}
+	return 0
+}
+
+// Return variables of torrent for evaluating "--where" query expression. See WhereVars.
+// Time variables (atime / ctime / activityTime) are unix timestamps (0 if not available);
+// age / seedingTime / inactive are durations in seconds since added / completed / last activity.
+func (torrent *Torrent) WhereEnv(now int64) map[string]any {
+	progress := float64(0)
+	if torrent.Size > 0 {
+		progress = float64(torrent.SizeCompleted) / float64(torrent.Size)
+	}
+	ctime := max(torrent.Ctime, 0)
+	seedingTime := int64(0)
+	if ctime > 0 {
+		seedingTime = now - ctime
+	}
+	inactive := int64(0)
+	if torrent.ActivityTime > 0 {
+		inactive = now - torrent.ActivityTime
+	}
+	tags := torrent.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return map[string]any{
+		"name":          torrent.Name,
+		"infohash":      torrent.InfoHash,
+		"category":      torrent.Category,
+		"tags":          tags,
+		"site":          torrent.GetSiteFromTag(),
+		"tracker":       torrent.Tracker,
+		"trackerDomain": torrent.TrackerDomain,
+		"state":         torrent.State,
+		"savePath":      torrent.SavePath,
+		"contentPath":   torrent.ContentPath,
+		"size":          torrent.Size,
+		"sizeTotal":     torrent.SizeTotal,
+		"sizeCompleted": torrent.SizeCompleted,
+		"progress":      progress,
+		"complete":      torrent.IsComplete(),
+		"downloaded":    torrent.Downloaded,
+		"uploaded":      torrent.Uploaded,
+		"ratio":         torrent.Ratio(),
+		"downloadSpeed": torrent.DownloadSpeed,
+		"uploadSpeed":   torrent.UploadSpeed,
+		"seeders":       torrent.Seeders,
+		"leechers":      torrent.Leechers,
+		"atime":         torrent.Atime,
+		"ctime":         ctime,
+		"activityTime":  torrent.ActivityTime,
+		"age":           now - torrent.Atime,
+		"seedingTime":   seedingTime,
+		"inactive":      inactive,
+	}
+}
+
+// Return true if torrent matches the compiled "--where" query expression. A nil program matches all torrents.
+func (torrent *Torrent) MatchWhere(program *expr.Program) (bool, error) {
+	if program == nil {
+		return true, nil
+	}
+	return program.EvalBool(torrent.WhereEnv(util.Now()))
+}
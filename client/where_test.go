@@ -0,0 +1,53 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/util"
+)
+
+func TestMatchWhere(t *testing.T) {
+	now := util.Now()
+	torrent := &client.Torrent{
+		Name:          "Test.S01.1080p",
+		Tracker:       "https://tracker.m-team.cc/announce",
+		TrackerDomain: "tracker.m-team.cc",
+		State:         "seeding",
+		Tags:          []string{"site:mteam", "keep"},
+		Size:          20 << 30,
+		SizeCompleted: 20 << 30,
+		Downloaded:    20 << 30,
+		Uploaded:      10 << 30,
+		Seeders:       2,
+		Atime:         now - 86400*10,
+		Ctime:         now - 86400*9,
+		ActivityTime:  now - 3600,
+	}
+	tests := []struct {
+		where string
+		want  bool
+	}{
+		{`size > 10GiB && seeders < 3 && tracker =~ "m-team" && ratio < 1 && !("nodel" in tags)`, true},
+		{`"keep" in tags`, true},
+		{`site == "mteam" && complete && progress == 1`, true},
+		{`seedingTime > 7d && age < 11d && inactive < 2h`, true},
+		{`ratio >= 1`, false},
+		{`name =~ "(?i)s02"`, false},
+	}
+	for _, tt := range tests {
+		program, err := client.CompileWhere(tt.where)
+		if err != nil {
+			t.Fatalf("CompileWhere(%q) error: %v", tt.where, err)
+		}
+		got, err := torrent.MatchWhere(program)
+		if err != nil {
+			t.Errorf("MatchWhere(%q) error: %v", tt.where, err)
+		} else if got != tt.want {
+			t.Errorf("MatchWhere(%q) = %t, want %t", tt.where, got, tt.want)
+		}
+	}
+	if _, err := client.CompileWhere("size >"); err == nil {
+		t.Errorf("CompileWhere should fail on invalid expression")
+	}
+}
@@ -12,7 +12,6 @@ import (
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
-	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/site/public"
@@ -138,9 +137,9 @@ func add(cmd *cobra.Command, args []string) error {
 		SeedingTimeLimit:   seedingTimeLimit,
 	}
 	fixedTags := util.SplitCsv(addTags)
-	var savePathMapper *common.PathMapper
+	var savePathMapper *util.PathMapper
 	if len(mapSavePaths) > 0 {
-		savePathMapper, err = common.NewPathMapper(mapSavePaths)
+		savePathMapper, err = util.NewPathMapper(mapSavePaths)
 		if err != nil {
 			return fmt.Errorf("invalid map-save-path(s): %w", err)
 		}
@@ -18,8 +18,9 @@ var command = &cobra.Command{
 	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "addtags"},
 	Short:       "Add tags to torrents in client.",
 	Long: fmt.Sprintf(`Add tags to torrents in client.
+%s.
 First arg ({tags}) is comma-seperated tags list. The following args is the args list.
-%s.`, constants.HELP_INFOHASH_ARGS),
+%s.`, constants.HELP_CLIENT_GROUP_ARG, constants.HELP_INFOHASH_ARGS),
 	Args: cobra.MatchAll(cobra.MinimumNArgs(2), cobra.OnlyValidArgs),
 	RunE: addtags,
 }
@@ -28,10 +29,12 @@ var (
 	category = ""
 	tag      = ""
 	filter   = ""
+	where    = ""
 )
 
 func init() {
 	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&where, "where", "", "", constants.HELP_ARG_WHERE_TORRENT)
 	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
 	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
 	cmd.RootCmd.AddCommand(command)
@@ -41,32 +44,33 @@ func addtags(cmd *cobra.Command, args []string) error {
 	clientName := args[0]
 	tags := util.SplitCsv(args[1])
 	infoHashes := args[2:]
-	if category == "" && tag == "" && filter == "" {
+	if category == "" && tag == "" && filter == "" && where == "" {
 		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
 			return err
 		} else {
 			infoHashes = _infoHashes
 		}
 	}
-	clientInstance, err := client.CreateClient(clientName)
-	if err != nil {
-		return fmt.Errorf("failed to create client: %w", err)
-	}
-
-	infoHashes, err = client.SelectTorrents(clientInstance, category, tag, filter, infoHashes...)
+	clientInstances, err := client.CreateClientsFromArg(clientName)
 	if err != nil {
 		return err
 	}
-	if infoHashes == nil {
-		err = clientInstance.AddTagsToAllTorrents(tags)
+	return client.RunOnClients(clientInstances, func(clientInstance client.Client) error {
+		infoHashes, err := client.SelectTorrents(clientInstance, category, tag, filter, where, infoHashes...)
 		if err != nil {
 			return err
 		}
-	} else if len(infoHashes) > 0 {
-		err = clientInstance.AddTagsToTorrents(infoHashes, tags)
-		if err != nil {
-			return err
+		if infoHashes == nil {
+			err = clientInstance.AddTagsToAllTorrents(tags)
+			if err != nil {
+				return err
+			}
+		} else if len(infoHashes) > 0 {
+			err = clientInstance.AddTagsToTorrents(infoHashes, tags)
+			if err != nil {
+				return err
+			}
 		}
-	}
-	return nil
+		return nil
+	})
 }
@@ -41,6 +41,7 @@ var (
 	category       = ""
 	tag            = ""
 	filter         = ""
+	where          = ""
 	oldTracker     = ""
 	trackers       = []string{}
 )
@@ -50,6 +51,7 @@ func init() {
 		`Remove all existing trackers. The added tracker(s) will become the only (sole) tracker(s) of torrent`)
 	command.Flags().BoolVarP(&force, "force", "", false, "Force updating trackers. Do NOT prompt for confirm")
 	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&where, "where", "", "", constants.HELP_ARG_WHERE_TORRENT)
 	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
 	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
 	command.Flags().StringVarP(&oldTracker, "old-tracker", "", "",
@@ -62,7 +64,7 @@ func init() {
 func addtrackers(cmd *cobra.Command, args []string) error {
 	clientName := args[0]
 	infoHashes := args[1:]
-	if category == "" && tag == "" && filter == "" {
+	if category == "" && tag == "" && filter == "" && where == "" {
 		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
 			return err
 		} else {
@@ -82,7 +84,7 @@ func addtrackers(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
 
-	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, infoHashes...)
+	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, where, infoHashes...)
 	if err != nil {
 		return err
 	}
@@ -6,14 +6,19 @@ import (
 	_ "github.com/sagan/ptool/cmd/addtags"
 	_ "github.com/sagan/ptool/cmd/addtrackers"
 	_ "github.com/sagan/ptool/cmd/alias"
+	_ "github.com/sagan/ptool/cmd/autorule"
+	_ "github.com/sagan/ptool/cmd/backupcmd"
+	_ "github.com/sagan/ptool/cmd/bandwidthcmd"
 	_ "github.com/sagan/ptool/cmd/batchdl"
 	_ "github.com/sagan/ptool/cmd/brush"
+	_ "github.com/sagan/ptool/cmd/brush/simulate"
 	_ "github.com/sagan/ptool/cmd/checktag"
 	_ "github.com/sagan/ptool/cmd/clientctl"
 	_ "github.com/sagan/ptool/cmd/configcmd/all"
 	_ "github.com/sagan/ptool/cmd/cookiecloud/all"
 	_ "github.com/sagan/ptool/cmd/createcategory"
 	_ "github.com/sagan/ptool/cmd/createtags"
+	_ "github.com/sagan/ptool/cmd/daemon"
 	_ "github.com/sagan/ptool/cmd/delete"
 	_ "github.com/sagan/ptool/cmd/deletecategories"
 	_ "github.com/sagan/ptool/cmd/deletetags"
@@ -34,16 +39,20 @@ import (
 	_ "github.com/sagan/ptool/cmd/parsetorrent"
 	_ "github.com/sagan/ptool/cmd/partialdownload"
 	_ "github.com/sagan/ptool/cmd/pause"
+	_ "github.com/sagan/ptool/cmd/prune"
 	_ "github.com/sagan/ptool/cmd/publish"
 	_ "github.com/sagan/ptool/cmd/reannounce"
 	_ "github.com/sagan/ptool/cmd/recheck"
 	_ "github.com/sagan/ptool/cmd/removetags"
 	_ "github.com/sagan/ptool/cmd/removetrackers"
 	_ "github.com/sagan/ptool/cmd/renametag"
+	_ "github.com/sagan/ptool/cmd/repair"
 	_ "github.com/sagan/ptool/cmd/reseed/all"
+	_ "github.com/sagan/ptool/cmd/restore"
 	_ "github.com/sagan/ptool/cmd/resume"
 	_ "github.com/sagan/ptool/cmd/run"
 	_ "github.com/sagan/ptool/cmd/search"
+	_ "github.com/sagan/ptool/cmd/serve"
 	_ "github.com/sagan/ptool/cmd/setcategory"
 	_ "github.com/sagan/ptool/cmd/setsavepath"
 	_ "github.com/sagan/ptool/cmd/shell"
@@ -54,6 +63,8 @@ import (
 	_ "github.com/sagan/ptool/cmd/status"
 	_ "github.com/sagan/ptool/cmd/tidyup"
 	_ "github.com/sagan/ptool/cmd/transfertorrent"
+	_ "github.com/sagan/ptool/cmd/trash"
+	_ "github.com/sagan/ptool/cmd/undo"
 	_ "github.com/sagan/ptool/cmd/verifytorrent"
 	_ "github.com/sagan/ptool/cmd/versioncmd"
 	_ "github.com/sagan/ptool/cmd/xseedadd"
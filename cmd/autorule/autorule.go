@@ -0,0 +1,112 @@
+package autorule
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/autorule"
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+)
+
+var command = &cobra.Command{
+	Use:         "autorule {client} [--rule name]... [--dry-run]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "autorule"},
+	Short:       "Apply auto tagging / categorization rules to torrents of client.",
+	Long: fmt.Sprintf(`Apply auto tagging / categorization rules to torrents of client.
+%s.
+
+Rules are defined in "autorules" of ptool.toml, and are matched against every torrent in order.
+A rule matches a torrent if all it's conditions are met:
+  sites, trackers, nameRegex, minSize, maxSize, extensions, savePaths, where (a "--where" query expression).
+The actions of all matched rules are merged and applied to the torrent; later rules override earlier ones:
+  setCategory, addTags, removeTags, setSavePath, ratioLimit, seedingTimeLimit, uploadSpeedLimit, downloadSpeedLimit.
+If a matched rule has "final = true", subsequent rules are not matched for that torrent.
+Only the actions that differ from current state of torrent are applied, so it's safe to run it repeatedly.
+
+Use "--dry-run" flag to show the changes without actually modifying torrents.
+Use "--rule name" flag to only apply specific rules (even if they are disabled).`, constants.HELP_CLIENT_GROUP_ARG),
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: autorulecmd,
+}
+
+var (
+	dryRun   = false
+	rules    []string
+	filter   = ""
+	where    = ""
+	category = ""
+	tag      = ""
+)
+
+func init() {
+	command.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "Dry run. Do NOT actually modify torrents to client")
+	command.Flags().StringArrayVarP(&rules, "rule", "", nil, "Only apply the rule of this name")
+	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&where, "where", "", "", constants.HELP_ARG_WHERE_TORRENT)
+	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
+	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
+	cmd.RootCmd.AddCommand(command)
+}
+
+func autorulecmd(cmd *cobra.Command, args []string) error {
+	if len(config.Get().Autorules) == 0 {
+		return fmt.Errorf(`no rule defined in "autorules" of config file`)
+	}
+	clientInstances, err := client.CreateClientsFromArg(args[0])
+	if err != nil {
+		return err
+	}
+	errorCnt := int64(0)
+	for _, clientInstance := range clientInstances {
+		if err := applyRules(clientInstance); err != nil {
+			log.Errorf("Client %s: %v", clientInstance.GetName(), err)
+			errorCnt++
+		}
+	}
+	if errorCnt > 0 {
+		return fmt.Errorf("%d errors", errorCnt)
+	}
+	return nil
+}
+
+func applyRules(clientInstance client.Client) error {
+	engine, err := autorule.New(clientInstance.GetName(), config.Get().Autorules, rules)
+	if err != nil {
+		return err
+	}
+	if len(engine.Rules()) == 0 {
+		fmt.Printf("Client %s: no rule applies\n", clientInstance.GetName())
+		return nil
+	}
+	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, where)
+	if err != nil {
+		return fmt.Errorf("failed to get torrents: %w", err)
+	}
+	changes, err := engine.Plan(clientInstance, torrents)
+	if err != nil {
+		return err
+	}
+	errorCnt := int64(0)
+	for i, change := range changes {
+		fmt.Printf("Modify (%d/%d) torrent %s - %s: rules=%v; %s\n", i+1, len(changes),
+			change.Torrent.InfoHash, change.Torrent.Name, change.Rules, change)
+		if dryRun {
+			continue
+		}
+		if err := autorule.Apply(clientInstance, change); err != nil {
+			log.Errorf("Failed to modify torrent %s: %v", change.Torrent.InfoHash, err)
+			errorCnt++
+		}
+	}
+	fmt.Printf("Client %s: %d torrents checked, %d torrents modified (dry-run = %t)\n",
+		clientInstance.GetName(), len(torrents), len(changes)-int(errorCnt), dryRun)
+	if errorCnt > 0 {
+		return fmt.Errorf("%d errors", errorCnt)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package backupcmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/natefinch/atomic"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/backup"
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/util"
+)
+
+var command = &cobra.Command{
+	Use:   "backup {client} --output {file.zip}",
+	Short: "Backup all torrents of client to a zip archive.",
+	Long: `Backup all torrents of client to a zip archive.
+For every torrent of client, it stores the .torrent file, as well as save path, category, tags, trackers,
+share limits, speed limits, paused state and file priorities into the manifest of archive.
+The categories of client are also stored.
+Content files of torrents are NOT included.
+
+Use "ptool restore" to restore the backup to any (supported type) client.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: backupcmd,
+}
+
+var (
+	output = ""
+	force  = false
+)
+
+func init() {
+	command.Flags().StringVarP(&output, "output", "o", "", "Output zip archive filename")
+	command.Flags().BoolVarP(&force, "force", "", false, "Overwrite existing output file")
+	command.MarkFlagRequired("output")
+	cmd.RootCmd.AddCommand(command)
+}
+
+func backupcmd(cmd *cobra.Command, args []string) error {
+	if !force && util.FileExists(output) {
+		return fmt.Errorf("output file %q already exists. Use --force to overwrite it", output)
+	}
+	clientInstance, err := client.CreateClient(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	// Write the archive to a temp file in the same dir, then rename it to output.
+	file, err := os.CreateTemp(filepath.Dir(output), ".ptool-backup-*.zip")
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	tmpfile := file.Name()
+	defer os.Remove(tmpfile)
+	manifest, err := backup.Backup(clientInstance, file)
+	if closeErr := file.Close(); manifest != nil && closeErr != nil {
+		return fmt.Errorf("failed to write output file: %w", closeErr)
+	}
+	if manifest == nil {
+		return err
+	}
+	if err != nil {
+		log.Errorf("%v", err)
+	}
+	if err := atomic.ReplaceFile(tmpfile, output); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Backed up %d torrents of client %s to %q\n",
+		len(manifest.Torrents), clientInstance.GetName(), output)
+	return err
+}
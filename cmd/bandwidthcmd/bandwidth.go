@@ -0,0 +1,123 @@
+package bandwidthcmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/bandwidth"
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/constants"
+)
+
+var command = &cobra.Command{
+	Use:         "bandwidth {client} [--dry-run] [--show]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "bandwidth"},
+	Short:       "Apply bandwidth schedule (global speed limits) to client.",
+	Long: fmt.Sprintf(`Apply bandwidth schedule (global speed limits) to client.
+%s.
+
+The schedule is defined in "bandwidthSchedule" of client config. Each slot has optional "days"
+(e.g. ["mon", "tue"], or "weekdays" / "weekends") and "time" (e.g. "09:00-18:00", may cross midnight) conditions,
+and "downloadSpeedLimit" / "uploadSpeedLimit" ("-1" means unlimited; empty means do not change).
+The first slot that covers current (local) time is applied. If no slot matches, nothing is changed.
+Only the limits that differ from current client config are set, so it's safe to run it repeatedly,
+e.g. as a "[[schedules]]" task of "ptool daemon".
+
+Use "--show" flag to display the schedule and current slot without applying it.`, constants.HELP_CLIENT_GROUP_ARG),
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: bandwidthcmd,
+}
+
+var (
+	dryRun = false
+	show   = false
+)
+
+func init() {
+	command.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "Dry run. Do NOT actually modify client config")
+	command.Flags().BoolVarP(&show, "show", "", false, "Show bandwidth schedule of client and exit")
+	cmd.RootCmd.AddCommand(command)
+}
+
+func bandwidthcmd(cmd *cobra.Command, args []string) error {
+	clientInstances, err := client.CreateClientsFromArg(args[0])
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	errorCnt := int64(0)
+	for _, clientInstance := range clientInstances {
+		var err error
+		if show {
+			err = showSchedule(clientInstance, now)
+		} else {
+			err = applySchedule(clientInstance, now)
+		}
+		if err != nil {
+			log.Errorf("Client %s: %v", clientInstance.GetName(), err)
+			errorCnt++
+		}
+	}
+	if errorCnt > 0 {
+		return fmt.Errorf("%d errors", errorCnt)
+	}
+	return nil
+}
+
+func applySchedule(clientInstance client.Client, now time.Time) error {
+	if len(clientInstance.GetClientConfig().BandwidthSchedule) == 0 {
+		return fmt.Errorf("no bandwidthSchedule defined in client config")
+	}
+	slot, err := bandwidth.GetClientSlot(clientInstance.GetClientConfig(), now)
+	if err != nil {
+		return err
+	}
+	if slot == nil {
+		fmt.Printf("Client %s: no bandwidth schedule slot matches current time\n", clientInstance.GetName())
+		return nil
+	}
+	changes, err := bandwidth.Apply(clientInstance, slot, dryRun)
+	if len(changes) == 0 && err == nil {
+		fmt.Printf("Client %s: slot #%d (%s) is already applied\n", clientInstance.GetName(), slot.Index+1, slot.Name)
+		return nil
+	}
+	for _, change := range changes {
+		fmt.Printf("Client %s: slot #%d (%s): %s (dry-run = %t)\n",
+			clientInstance.GetName(), slot.Index+1, slot.Name, change, dryRun)
+	}
+	return err
+}
+
+func showSchedule(clientInstance client.Client, now time.Time) error {
+	slots, err := bandwidth.Compile(clientInstance.GetClientConfig().BandwidthSchedule)
+	if err != nil {
+		return err
+	}
+	current := bandwidth.Match(slots, now)
+	fmt.Printf("Client %s bandwidth schedule (current time: %s)\n", clientInstance.GetName(),
+		now.Format("2006-01-02 15:04 Mon"))
+	fmt.Printf("%-3s  %-3s  %-20s  %-30s  %-12s  %-14s  %-14s\n",
+		"", "#", "Name", "Days", "Time", "Download", "Upload")
+	for _, slot := range slots {
+		mark := ""
+		if slot == current {
+			mark = "=>"
+		}
+		days := strings.Join(slot.Days, ",")
+		if days == "" {
+			days = "*"
+		}
+		timeRange := slot.Time
+		if timeRange == "" {
+			timeRange = "*"
+		}
+		fmt.Printf("%-3s  %-3d  %-20s  %-30s  %-12s  %-14s  %-14s\n", mark, slot.Index+1, slot.Name, days, timeRange,
+			bandwidth.FormatSpeedLimit(slot.DownloadSpeedLimit), bandwidth.FormatSpeedLimit(slot.UploadSpeedLimit))
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package bandwidthcmd
+
+import (
+	"github.com/c-bata/go-prompt"
+
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/shell/suggest"
+)
+
+func init() {
+	cmd.AddShellCompletion("bandwidth", func(document *prompt.Document) []prompt.Suggest {
+		info := suggest.Parse(document)
+		if info.LastArgIndex < 1 {
+			return nil
+		}
+		if info.LastArgIsFlag {
+			return nil
+		}
+		if info.LastArgIndex != 1 {
+			return nil
+		}
+		return suggest.ClientOrGroupArg(info.MatchingPrefix)
+	})
+}
@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"math/rand"
 	"path/filepath"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
+	"github.com/sagan/ptool/bandwidth"
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
 	"github.com/sagan/ptool/cmd/brush/strategy"
@@ -18,7 +20,7 @@ import (
 	"github.com/sagan/ptool/util/torrentutil"
 )
 
-var command = &cobra.Command{
+var Command = &cobra.Command{
 	Use:         "brush {client} {site | group}...",
 	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "brush"},
 	Short:       "Brush sites using client.",
@@ -32,16 +34,20 @@ var (
 	addPaused = false
 	ordered   = false
 	force     = false
+	record    = false
 	maxSites  = int64(0)
 )
 
 func init() {
-	command.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "Dry run. Do not actually controlling client")
-	command.Flags().BoolVarP(&addPaused, "add-paused", "", false, "Add torrents to client in paused state")
-	command.Flags().BoolVarP(&ordered, "ordered", "", false, "Brush sites provided in order")
-	command.Flags().BoolVarP(&force, "force", "", false, `Force mode. Ignore "`+config.NOADD_TAG+`" flag tag in client`)
-	command.Flags().Int64VarP(&maxSites, "max-sites", "", -1, "Allowed max succcess sites number, -1 == no limit")
-	cmd.RootCmd.AddCommand(command)
+	Command.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "Dry run. Do not actually controlling client")
+	Command.Flags().BoolVarP(&addPaused, "add-paused", "", false, "Add torrents to client in paused state")
+	Command.Flags().BoolVarP(&ordered, "ordered", "", false, "Brush sites provided in order")
+	Command.Flags().BoolVarP(&force, "force", "", false, `Force mode. Ignore "`+config.NOADD_TAG+`" flag tag in client`)
+	Command.Flags().Int64VarP(&maxSites, "max-sites", "", -1, "Allowed max succcess sites number, -1 == no limit")
+	Command.Flags().BoolVarP(&record, "record", "", false, `Save site torrents, client torrents & status `+
+		`and brush decision of each site to snapshot file in "<config_dir>/`+config.BRUSH_RECORDS_DIR+
+		`/<client>" dir, which can be replayed by "brush simulate" command`)
+	cmd.RootCmd.AddCommand(Command)
 }
 
 func brush(cmd *cobra.Command, args []string) (err error) {
@@ -59,6 +65,11 @@ func brush(cmd *cobra.Command, args []string) (err error) {
 	if clientInstance.GetClientConfig().Type == "transmission" {
 		log.Warnf("Warning: brush function of transmission client has NOT been tested")
 	}
+	brushClientOption := strategy.GetBrushClientOptions(clientInstance)
+	brushStrategy, err := strategy.Get(brushClientOption.Strategy)
+	if err != nil {
+		return fmt.Errorf("invalid brushStrategy of client %s: %w", clientInstance.GetName(), err)
+	}
 	if !ordered {
 		rand.Shuffle(len(sitenames), func(i, j int) { sitenames[i], sitenames[j] = sitenames[j], sitenames[i] })
 	}
@@ -74,6 +85,14 @@ func brush(cmd *cobra.Command, args []string) (err error) {
 			log.Warnf("Failed to create stats db: %v.", err)
 		}
 	}
+	// Sample client brush torrents once per run, before any site is processed (or skipped).
+	if statDb != nil && !dryRun {
+		if clientTorrents, err := clientInstance.GetTorrents("", config.BRUSH_CAT, true); err != nil {
+			log.Warnf("Failed to get client %s torrents for stats samples: %v", clientInstance.GetName(), err)
+		} else if err := statDb.AddTorrentSamples(util.Now(), clientInstance.GetName(), clientTorrents); err != nil {
+			log.Warnf("Failed to add torrent samples to stats db: %v", err)
+		}
+	}
 
 	for i, sitename := range sitenames {
 		siteInstance, err := site.CreateSite(sitename)
@@ -87,10 +106,10 @@ func brush(cmd *cobra.Command, args []string) (err error) {
 			log.Printf("Failed to get client %s status: %v", clientInstance.GetName(), err)
 			continue
 		}
+		status = applyBandwidthSchedule(clientInstance, status)
 		noadd := !force && status.NoAdd
 		var siteTorrents []*site.Torrent
-		if status.UploadSpeedLimit > 0 && (status.UploadSpeedLimit < strategy.SLOW_UPLOAD_SPEED ||
-			(float64(status.UploadSpeed)/float64(status.UploadSpeedLimit)) >= strategy.BANDWIDTH_FULL_PERCENT) {
+		if strategy.IsBandwidthFull(status, brushClientOption) {
 			log.Printf(
 				"Client %s upload bandwidth is already full (Up speed/limit: %s/s/%s/s). Do not fetch site new torrents\n",
 				clientInstance.GetName(),
@@ -124,10 +143,10 @@ func brush(cmd *cobra.Command, args []string) (err error) {
 		brushSiteOption.AllowAddTorrents = brushMaxTorrents - int64(currentTorrents)
 		log.Printf("Site %s already have %d torrents, max %d, allow %d", sitename,
 			len(getTorrentsOfSite(clientTorrents, sitename)), brushMaxTorrents, brushSiteOption.AllowAddTorrents)
-		brushClientOption := strategy.GetBrushClientOptions(clientInstance)
 		log.Printf(
-			"Brush Options: minDiskSpace=%v, slowUploadSpeedTier=%v, torrentUploadSpeedLimit=%v/s,"+
+			"Brush Options: strategy=%s, minDiskSpace=%v, slowUploadSpeedTier=%v, torrentUploadSpeedLimit=%v/s,"+
 				" maxDownloadingTorrents=%d, maxTorrents=%d, minRatio=%f",
+			brushClientOption.Strategy,
 			util.BytesSize(float64(brushClientOption.MinDiskSpace)),
 			util.BytesSize(float64(brushClientOption.SlowUploadSpeedTier)),
 			util.BytesSize(float64(brushSiteOption.TorrentUploadSpeedLimit)),
@@ -135,7 +154,27 @@ func brush(cmd *cobra.Command, args []string) (err error) {
 			brushClientOption.MaxTorrents,
 			brushClientOption.MinRatio,
 		)
-		result := strategy.Decide(status, clientTorrents, siteTorrents, brushSiteOption, brushClientOption)
+		result := brushStrategy.Decide(status, clientTorrents, siteTorrents, brushSiteOption, brushClientOption)
+		if !dryRun {
+			recordResultMetrics(clientInstance.GetName(), sitename, result)
+		}
+		if record {
+			filename, err := saveSnapshot(&Snapshot{
+				Client:         clientInstance.GetName(),
+				Site:           sitename,
+				ClientStatus:   status,
+				ClientTorrents: clientTorrents,
+				SiteTorrents:   siteTorrents,
+				SiteOption:     brushSiteOption,
+				ClientOption:   brushClientOption,
+				Result:         result,
+			})
+			if err != nil {
+				log.Errorf("Failed to save brush snapshot: %v", err)
+			} else {
+				log.Printf("Saved brush snapshot to %s", filename)
+			}
+		}
 		log.Printf(
 			"Current client %s torrents: %d; Download speed / limit: %s/s / %s/s; "+
 				"Upload speed / limit: %s/s / %s/s;Free disk space: %s;",
@@ -333,6 +372,26 @@ func brush(cmd *cobra.Command, args []string) (err error) {
 	return nil
 }
 
+// If client has bandwidth schedule, use the upload speed limit of current time slot as client upload speed limit,
+// regardless whether "ptool bandwidth" has already applied it to client.
+func applyBandwidthSchedule(clientInstance client.Client, status *client.Status) *client.Status {
+	slot, err := bandwidth.GetClientSlot(clientInstance.GetClientConfig(), time.Now())
+	if err != nil {
+		log.Warnf("Client %s: %v", clientInstance.GetName(), err)
+		return status
+	}
+	if slot == nil || slot.UploadSpeedLimit == bandwidth.KEEP ||
+		slot.UploadSpeedLimit == max(status.UploadSpeedLimit, 0) {
+		return status
+	}
+	log.Printf("Client %s: use upload speed limit %s of bandwidth schedule slot #%d (%s) (current: %s)",
+		clientInstance.GetName(), bandwidth.FormatSpeedLimit(slot.UploadSpeedLimit), slot.Index+1, slot.Name,
+		bandwidth.FormatSpeedLimit(status.UploadSpeedLimit))
+	scheduledStatus := *status
+	scheduledStatus.UploadSpeedLimit = slot.UploadSpeedLimit
+	return &scheduledStatus
+}
+
 func getTorrentsOfSite(torrents []*client.Torrent, siteName string) []*client.Torrent {
 	var ret []*client.Torrent
 	for _, torrent := range torrents {
@@ -0,0 +1,28 @@
+package brush
+
+import (
+	"github.com/sagan/ptool/cmd/brush/strategy"
+	"github.com/sagan/ptool/util/metrics"
+)
+
+// Brush metrics of current process. Exported by the metrics endpoint of daemon.
+var (
+	brushRunsMetric = metrics.NewCounter("ptool_brush_runs_total",
+		"Count of brush algorithm runs (excluding dry runs).", "client", "site")
+	brushResultsMetric = metrics.NewCounter("ptool_brush_result_torrents_total",
+		"Count of torrents in brush algorithm results (excluding dry runs), by operation: "+
+			"add|modify|stall|resume|delete.", "client", "site", "operation")
+)
+
+func init() {
+	metrics.Register(brushRunsMetric, brushResultsMetric)
+}
+
+func recordResultMetrics(clientName string, sitename string, result *strategy.AlgorithmResult) {
+	brushRunsMetric.Add(1, clientName, sitename)
+	brushResultsMetric.Add(float64(len(result.AddTorrents)), clientName, sitename, "add")
+	brushResultsMetric.Add(float64(len(result.ModifyTorrents)), clientName, sitename, "modify")
+	brushResultsMetric.Add(float64(len(result.StallTorrents)), clientName, sitename, "stall")
+	brushResultsMetric.Add(float64(len(result.ResumeTorrents)), clientName, sitename, "resume")
+	brushResultsMetric.Add(float64(len(result.DeleteTorrents)), clientName, sitename, "delete")
+}
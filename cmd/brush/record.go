@@ -0,0 +1,104 @@
+package brush
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd/brush/strategy"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/site"
+)
+
+// A recorded brush run of one client & site, which can be replayed by "brush simulate".
+type Snapshot struct {
+	Client         string
+	Site           string
+	ClientStatus   *client.Status
+	ClientTorrents []*client.Torrent
+	SiteTorrents   []*site.Torrent
+	SiteOption     *strategy.BrushSiteOptionStruct
+	ClientOption   *strategy.BrushClientOptionStruct
+	Result         *strategy.AlgorithmResult // the actual decision made when recording
+	Filename       string                    `json:"-"`
+}
+
+func GetRecordsDir() string {
+	return filepath.Join(config.ConfigDir, config.BRUSH_RECORDS_DIR)
+}
+
+// Save snapshot to "<config_dir>/brush_records/<client>/<timestamp>-<site>.json".
+func saveSnapshot(snapshot *Snapshot) (filename string, err error) {
+	dir := filepath.Join(GetRecordsDir(), snapshot.Client)
+	if err = os.MkdirAll(dir, constants.PERM_DIR); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+	filename = filepath.Join(dir, fmt.Sprintf("%d-%s.json", snapshot.SiteOption.Now, snapshot.Site))
+	if err = os.WriteFile(filename, data, constants.PERM); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
+
+func LoadSnapshot(filename string) (*Snapshot, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &Snapshot{}
+	if err = json.Unmarshal(data, snapshot); err != nil {
+		return nil, fmt.Errorf("invalid snapshot file %s: %w", filename, err)
+	}
+	if snapshot.SiteOption == nil || snapshot.ClientOption == nil || snapshot.ClientStatus == nil {
+		return nil, fmt.Errorf("invalid snapshot file %s: incomplete data", filename)
+	}
+	snapshot.Filename = filename
+	return snapshot, nil
+}
+
+// Load snapshots from files or dirs (all *.json files inside it, recursively), sorted by time.
+func LoadSnapshots(paths []string) (snapshots []*Snapshot, err error) {
+	var filenames []string
+	for _, path := range paths {
+		stat, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !stat.IsDir() {
+			filenames = append(filenames, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(path string, entry os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+				filenames = append(filenames, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, filename := range filenames {
+		snapshot, err := LoadSnapshot(filename)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].SiteOption.Now < snapshots[j].SiteOption.Now
+	})
+	return snapshots, nil
+}
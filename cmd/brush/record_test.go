@@ -0,0 +1,41 @@
+package brush
+
+import (
+	"testing"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd/brush/strategy"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util/expr"
+)
+
+func TestSnapshotSaveLoad(t *testing.T) {
+	config.ConfigDir = t.TempDir()
+	snapshot := &Snapshot{
+		Client:         "local",
+		Site:           "mt",
+		ClientStatus:   &client.Status{FreeSpaceOnDisk: 100},
+		ClientTorrents: []*client.Torrent{{InfoHash: "abc", Meta: map[string]int64{"stt": 1}}},
+		SiteTorrents:   []*site.Torrent{{Name: "foo", Tags: []string{"_hr"}}},
+		SiteOption:     &strategy.BrushSiteOptionStruct{Now: 1700000000, Score: expr.MustCompile("score * 2")},
+		ClientOption:   &strategy.BrushClientOptionStruct{Strategy: strategy.DEFAULT_STRATEGY},
+		Result:         &strategy.AlgorithmResult{CanAddMore: true},
+	}
+	filename, err := saveSnapshot(snapshot)
+	if err != nil {
+		t.Fatalf("saveSnapshot error: %v", err)
+	}
+	snapshots, err := LoadSnapshots([]string{GetRecordsDir()})
+	if err != nil || len(snapshots) != 1 {
+		t.Fatalf("LoadSnapshots() = %v, %v; want 1 snapshot", snapshots, err)
+	}
+	loaded := snapshots[0]
+	if loaded.Filename != filename || loaded.Site != "mt" || loaded.ClientTorrents[0].Meta["stt"] != 1 ||
+		loaded.SiteTorrents[0].Tags[0] != "_hr" || !loaded.Result.CanAddMore {
+		t.Errorf("loaded snapshot mismatch: %+v", loaded)
+	}
+	if loaded.SiteOption.Score == nil || loaded.SiteOption.Score.String() != "score * 2" {
+		t.Errorf("loaded score expression = %v, want %q", loaded.SiteOption.Score, "score * 2")
+	}
+}
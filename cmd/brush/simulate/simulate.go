@@ -0,0 +1,310 @@
+package simulate
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/cmd/brush"
+	"github.com/sagan/ptool/cmd/brush/strategy"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/expr"
+)
+
+var command = &cobra.Command{
+	Use:   "simulate [{snapshot-file | dir}...]",
+	Short: `Replay recorded brush snapshots through brush strategy with different options.`,
+	Long: `Replay recorded brush snapshots through brush strategy with different options.
+Snapshots are recorded by "brush --record" command. Args are snapshot files or dirs that contains them,
+if none is provided, use "<config_dir>/` + config.BRUSH_RECORDS_DIR + `" dir.
+
+By default the options recorded in snapshot are used. Use --use-config flag to use current options
+of the client & site in config file instead. Options set by flags override both of them.
+It reports what torrents would have been added, stalled, resumed and deleted by the strategy,
+along with the numbers of the actual (recorded) decision, e.g. "Add 3 (2)" means 3 torrents would be added
+in simulation and 2 were added in the recorded run. Nothing is sent to any client or site.
+
+Like brush, if the recorded client upload speed reaches the (recorded) upload speed limit * bandwidth full percent,
+no site torrents are added in simulation. Note snapshots recorded when bandwidth was full do not contain site torrents,
+so a higher --bandwidth-full-percent can not make them add new torrents.`,
+	RunE: simulate,
+}
+
+var (
+	useConfig                    = false
+	showDetails                  = false
+	showJson                     = false
+	strategyName                 = ""
+	score                        = ""
+	allowNoneFree                = false
+	allowPaid                    = false
+	allowHr                      = false
+	allowZeroSeeders             = false
+	acceptAnyFree                = false
+	torrentMinSizeLimit          = ""
+	torrentMaxSizeLimit          = ""
+	torrentUploadSpeedLimit      = ""
+	minDiskSpace                 = ""
+	slowUploadSpeedTier          = ""
+	maxDownloadingTorrents       = int64(0)
+	maxTorrents                  = int64(0)
+	minRatio                     = float64(0)
+	defaultUploadSpeedLimit      = ""
+	bandwidthFullPercent         = float64(0)
+	newTorrentsTimespan          = ""
+	slowTorrentsCheckTimespan    = ""
+	stallTorrentDeletionTimespan = ""
+)
+
+func init() {
+	command.Flags().BoolVarP(&useConfig, "use-config", "", false,
+		"Use current brush options of the client & site in config file instead of the recorded ones")
+	command.Flags().BoolVarP(&showDetails, "details", "", false, "Show details of each torrent operation")
+	command.Flags().BoolVarP(&showJson, "json", "", false, "Show output in json format")
+	command.Flags().StringVarP(&strategyName, "strategy", "", "", "Brush strategy")
+	command.Flags().StringVarP(&score, "score", "", "", `Site torrent score expression. E.g. "score * 2"`)
+	command.Flags().BoolVarP(&allowNoneFree, "allow-none-free", "", false, "Allow none-free site torrents")
+	command.Flags().BoolVarP(&allowPaid, "allow-paid", "", false, "Allow paid site torrents")
+	command.Flags().BoolVarP(&allowHr, "allow-hr", "", false, "Allow HnR site torrents")
+	command.Flags().BoolVarP(&allowZeroSeeders, "allow-zero-seeders", "", false, "Allow site torrents with 0 seeders")
+	command.Flags().BoolVarP(&acceptAnyFree, "accept-any-free", "", false, "Accept any free site torrents")
+	command.Flags().StringVarP(&torrentMinSizeLimit, "torrent-min-size-limit", "", "", "Site torrent min size limit")
+	command.Flags().StringVarP(&torrentMaxSizeLimit, "torrent-max-size-limit", "", "", "Site torrent max size limit")
+	command.Flags().StringVarP(&torrentUploadSpeedLimit, "torrent-upload-speed-limit", "", "",
+		"Site torrent upload speed limit (/s)")
+	command.Flags().StringVarP(&minDiskSpace, "min-disk-space", "", "", "Client min disk space")
+	command.Flags().StringVarP(&slowUploadSpeedTier, "slow-upload-speed-tier", "", "", "Slow upload speed tier (/s)")
+	command.Flags().Int64VarP(&maxDownloadingTorrents, "max-downloading-torrents", "", 0, "Max downloading torrents")
+	command.Flags().Int64VarP(&maxTorrents, "max-torrents", "", 0, "Max torrents")
+	command.Flags().Float64VarP(&minRatio, "min-ratio", "", 0, "Min ratio")
+	command.Flags().StringVarP(&defaultUploadSpeedLimit, "default-upload-speed-limit", "", "",
+		"Default client upload speed limit (/s)")
+	command.Flags().Float64VarP(&bandwidthFullPercent, "bandwidth-full-percent", "", 0, "Bandwidth full percent (0, 1]")
+	command.Flags().StringVarP(&newTorrentsTimespan, "new-torrents-timespan", "", "", `New torrents timespan. E.g. "15m"`)
+	command.Flags().StringVarP(&slowTorrentsCheckTimespan, "slow-torrents-check-timespan", "", "",
+		`Slow torrents check timespan. E.g. "15m"`)
+	command.Flags().StringVarP(&stallTorrentDeletionTimespan, "stall-torrent-deletion-timespan", "", "",
+		`Stall torrent deletion timespan. E.g. "30m"`)
+	brush.Command.AddCommand(command)
+}
+
+type SimulateResult struct {
+	File           string
+	Client         string
+	Site           string
+	Time           int64
+	SiteTorrents   int
+	ClientTorrents int
+	Result         *strategy.AlgorithmResult
+	RecordedResult *strategy.AlgorithmResult
+}
+
+func simulate(cmd *cobra.Command, args []string) (err error) {
+	if len(args) == 0 {
+		args = []string{brush.GetRecordsDir()}
+	}
+	snapshots, err := brush.LoadSnapshots(args)
+	if err != nil {
+		return fmt.Errorf("failed to load snapshots: %w", err)
+	}
+	if len(snapshots) == 0 {
+		return fmt.Errorf("no snapshot found")
+	}
+	var scoreProgram *expr.Program
+	if score != "" {
+		if scoreProgram, err = expr.Compile(score); err != nil {
+			return fmt.Errorf("invalid score expression: %w", err)
+		}
+	}
+	var results []*SimulateResult
+	for _, snapshot := range snapshots {
+		siteOption := snapshot.SiteOption
+		clientOption := snapshot.ClientOption
+		if useConfig {
+			clientConfig := config.GetClientConfig(snapshot.Client)
+			siteConfig := config.GetSiteConfig(snapshot.Site)
+			if clientConfig == nil || siteConfig == nil {
+				return fmt.Errorf("snapshot %s: client %s or site %s not found in config",
+					snapshot.Filename, snapshot.Client, snapshot.Site)
+			}
+			newSiteOption := strategy.GetBrushSiteOptionsFromConfig(siteConfig, siteOption.Now)
+			newSiteOption.AllowAddTorrents = siteOption.AllowAddTorrents
+			siteOption = newSiteOption
+			clientOption = strategy.GetBrushClientOptionsFromConfig(clientConfig)
+		}
+		if err = applyFlags(cmd, siteOption, clientOption, scoreProgram); err != nil {
+			return err
+		}
+		brushStrategy, err := strategy.Get(clientOption.Strategy)
+		if err != nil {
+			return err
+		}
+		siteTorrents := snapshot.SiteTorrents
+		if strategy.IsBandwidthFull(snapshot.ClientStatus, clientOption) {
+			siteTorrents = nil // same as brush, which does not fetch site torrents in this case
+		}
+		result := brushStrategy.Decide(snapshot.ClientStatus, snapshot.ClientTorrents, siteTorrents,
+			siteOption, clientOption)
+		results = append(results, &SimulateResult{
+			File:           snapshot.Filename,
+			Client:         snapshot.Client,
+			Site:           snapshot.Site,
+			Time:           siteOption.Now,
+			SiteTorrents:   len(snapshot.SiteTorrents),
+			ClientTorrents: len(snapshot.ClientTorrents),
+			Result:         result,
+			RecordedResult: snapshot.Result,
+		})
+	}
+	if showJson {
+		return util.PrintJson(os.Stdout, results)
+	}
+	printResults(results)
+	return nil
+}
+
+// Override options with flags which are explicitly set.
+func applyFlags(cmd *cobra.Command, siteOption *strategy.BrushSiteOptionStruct,
+	clientOption *strategy.BrushClientOptionStruct, scoreProgram *expr.Program) (err error) {
+	flags := cmd.Flags()
+	if flags.Changed("strategy") {
+		clientOption.Strategy = strategyName
+	}
+	if scoreProgram != nil {
+		siteOption.Score = scoreProgram
+	}
+	if flags.Changed("allow-none-free") {
+		siteOption.AllowNoneFree = allowNoneFree
+	}
+	if flags.Changed("allow-paid") {
+		siteOption.AllowPaid = allowPaid
+	}
+	if flags.Changed("allow-hr") {
+		siteOption.AllowHr = allowHr
+	}
+	if flags.Changed("allow-zero-seeders") {
+		siteOption.AllowZeroSeeders = allowZeroSeeders
+	}
+	if flags.Changed("accept-any-free") {
+		siteOption.AcceptAnyFree = acceptAnyFree
+	}
+	if flags.Changed("max-downloading-torrents") {
+		clientOption.MaxDownloadingTorrents = maxDownloadingTorrents
+	}
+	if flags.Changed("max-torrents") {
+		clientOption.MaxTorrents = maxTorrents
+	}
+	if flags.Changed("min-ratio") {
+		clientOption.MinRatio = minRatio
+	}
+	if flags.Changed("bandwidth-full-percent") {
+		if bandwidthFullPercent <= 0 || bandwidthFullPercent > 1 {
+			return fmt.Errorf("invalid --bandwidth-full-percent value %v, should between (0, 1]", bandwidthFullPercent)
+		}
+		clientOption.BandwidthFullPercent = bandwidthFullPercent
+	}
+	for _, size := range []struct {
+		flag  string
+		value string
+		ptr   *int64
+	}{
+		{"torrent-min-size-limit", torrentMinSizeLimit, &siteOption.TorrentMinSizeLimit},
+		{"torrent-max-size-limit", torrentMaxSizeLimit, &siteOption.TorrentMaxSizeLimit},
+		{"torrent-upload-speed-limit", torrentUploadSpeedLimit, &siteOption.TorrentUploadSpeedLimit},
+		{"min-disk-space", minDiskSpace, &clientOption.MinDiskSpace},
+		{"slow-upload-speed-tier", slowUploadSpeedTier, &clientOption.SlowUploadSpeedTier},
+		{"default-upload-speed-limit", defaultUploadSpeedLimit, &clientOption.DefaultUploadSpeedLimit},
+	} {
+		if flags.Changed(size.flag) {
+			if *size.ptr, err = util.RAMInBytes(size.value); err != nil {
+				return fmt.Errorf("invalid --%s value: %w", size.flag, err)
+			}
+		}
+	}
+	for _, timespan := range []struct {
+		flag  string
+		value string
+		ptr   *int64
+	}{
+		{"new-torrents-timespan", newTorrentsTimespan, &clientOption.NewTorrentsTimespan},
+		{"slow-torrents-check-timespan", slowTorrentsCheckTimespan, &clientOption.SlowTorrentsCheckTimespan},
+		{"stall-torrent-deletion-timespan", stallTorrentDeletionTimespan, &clientOption.StallTorrentDeletionTimespan},
+	} {
+		if flags.Changed(timespan.flag) {
+			if *timespan.ptr, err = util.ParseTimeDuration(timespan.value); err != nil || *timespan.ptr <= 0 {
+				return fmt.Errorf("invalid --%s value %q", timespan.flag, timespan.value)
+			}
+		}
+	}
+	return nil
+}
+
+func printResults(results []*SimulateResult) {
+	total := &strategy.AlgorithmResult{}
+	recordedTotal := &strategy.AlgorithmResult{}
+	for _, r := range results {
+		fmt.Printf("%s  client=%s site=%s  site torrents: %d, client torrents: %d  (%s)\n",
+			time.Unix(r.Time, 0).Format("2006-01-02 15:04:05"), r.Client, r.Site, r.SiteTorrents, r.ClientTorrents, r.File)
+		fmt.Printf("  %s\n", formatCounts(r.Result, r.RecordedResult))
+		mergeResult(total, r.Result)
+		mergeResult(recordedTotal, r.RecordedResult)
+		if !showDetails {
+			continue
+		}
+		for _, torrent := range r.Result.AddTorrents {
+			fmt.Printf("  + Add     %s : %s\n", torrent.Name, torrent.Msg)
+		}
+		for _, torrent := range r.Result.StallTorrents {
+			fmt.Printf("  ~ Stall   %s (%s) : %s\n", torrent.Name, torrent.InfoHash, torrent.Msg)
+		}
+		for _, torrent := range r.Result.ResumeTorrents {
+			fmt.Printf("  > Resume  %s (%s) : %s\n", torrent.Name, torrent.InfoHash, torrent.Msg)
+		}
+		for _, torrent := range r.Result.DeleteTorrents {
+			fmt.Printf("  - Delete  %s (%s) : %s\n", torrent.Name, torrent.InfoHash, torrent.Msg)
+		}
+		if r.Result.Msg != "" {
+			fmt.Printf("  Msg: %s\n", r.Result.Msg)
+		}
+	}
+	fmt.Printf("\nTotal %d snapshots: %s\n", len(results), formatCounts(total, recordedTotal))
+}
+
+func formatCounts(result *strategy.AlgorithmResult, recorded *strategy.AlgorithmResult) string {
+	counts := func(r *strategy.AlgorithmResult) []int {
+		if r == nil {
+			return nil
+		}
+		return []int{len(r.AddTorrents), len(r.StallTorrents), len(r.ResumeTorrents),
+			len(r.ModifyTorrents), len(r.DeleteTorrents)}
+	}
+	names := []string{"Add", "Stall", "Resume", "Modify", "Delete"}
+	simulated := counts(result)
+	actual := counts(recorded)
+	str := ""
+	for i, name := range names {
+		if i > 0 {
+			str += ", "
+		}
+		if actual != nil {
+			str += fmt.Sprintf("%s %d (%d)", name, simulated[i], actual[i])
+		} else {
+			str += fmt.Sprintf("%s %d", name, simulated[i])
+		}
+	}
+	return str
+}
+
+func mergeResult(total *strategy.AlgorithmResult, result *strategy.AlgorithmResult) {
+	if result == nil {
+		return
+	}
+	total.AddTorrents = append(total.AddTorrents, result.AddTorrents...)
+	total.StallTorrents = append(total.StallTorrents, result.StallTorrents...)
+	total.ResumeTorrents = append(total.ResumeTorrents, result.ResumeTorrents...)
+	total.ModifyTorrents = append(total.ModifyTorrents, result.ModifyTorrents...)
+	total.DeleteTorrents = append(total.DeleteTorrents, result.DeleteTorrents...)
+}
@@ -0,0 +1,106 @@
+package strategy
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/site"
+)
+
+const DEFAULT_STRATEGY = "default"
+
+// Brush strategy: decide which site torrents to add and which client torrents to delete / stall.
+type Strategy interface {
+	// Rate a site torrent. score <= 0 means the torrent should NOT be added.
+	// clientStatus may be nil if unknown (e.g. when only displaying site torrent scores).
+	RateSiteTorrent(siteTorrent *site.Torrent, siteOption *BrushSiteOptionStruct, clientStatus *client.Status) (
+		score float64, predictionUploadSpeed int64, note string)
+	Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteTorrents []*site.Torrent,
+		siteOption *BrushSiteOptionStruct, clientOption *BrushClientOptionStruct) *AlgorithmResult
+}
+
+type defaultStrategy struct {
+}
+
+// The default (builtin) brush strategy.
+var Default Strategy = &defaultStrategy{}
+
+var registry = map[string]Strategy{
+	DEFAULT_STRATEGY: Default,
+}
+
+// Register a brush strategy with name. Should be called in init().
+func Register(name string, strategy Strategy) {
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("brush strategy %s already registered", name))
+	}
+	registry[name] = strategy
+}
+
+// Get brush strategy by name. Empty name returns the default strategy.
+func Get(name string) (Strategy, error) {
+	if name == "" {
+		name = DEFAULT_STRATEGY
+	}
+	if strategy, ok := registry[name]; ok {
+		return strategy, nil
+	}
+	return nil, fmt.Errorf("brush strategy %s not found. available strategies: %v", name, Names())
+}
+
+// Return names of all registered strategies.
+func Names() []string {
+	names := []string{}
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Return the variables of site torrent and client status, used to evaluate user defined brush score expression.
+// clientStatus may be nil, in which case client related variables are -1.
+func SiteTorrentEnv(siteTorrent *site.Torrent, siteOption *BrushSiteOptionStruct,
+	clientStatus *client.Status) map[string]any {
+	tags := siteTorrent.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	env := map[string]any{
+		"name":                     siteTorrent.Name,
+		"description":              siteTorrent.Description,
+		"id":                       siteTorrent.Id,
+		"size":                     siteTorrent.Size,
+		"seeders":                  siteTorrent.Seeders,
+		"leechers":                 siteTorrent.Leechers,
+		"snatched":                 siteTorrent.Snatched,
+		"time":                     siteTorrent.Time,
+		"age":                      siteOption.Now - siteTorrent.Time,
+		"now":                      siteOption.Now,
+		"free":                     siteTorrent.DownloadMultiplier == 0,
+		"downloadMultiplier":       siteTorrent.DownloadMultiplier,
+		"uploadMultiplier":         siteTorrent.UploadMultiplier,
+		"discountEndTime":          siteTorrent.DiscountEndTime,
+		"hr":                       siteTorrent.HasHnR,
+		"paid":                     siteTorrent.Paid,
+		"bought":                   siteTorrent.Bought,
+		"neutral":                  siteTorrent.Neutral,
+		"tags":                     tags,
+		"score":                    float64(0),
+		"predictionUploadSpeed":    int64(0),
+		"clientUploadSpeed":        int64(-1),
+		"clientDownloadSpeed":      int64(-1),
+		"clientUploadSpeedLimit":   int64(-1),
+		"clientDownloadSpeedLimit": int64(-1),
+		"clientFreeSpace":          int64(-1),
+	}
+	if clientStatus != nil {
+		env["clientUploadSpeed"] = clientStatus.UploadSpeed
+		env["clientDownloadSpeed"] = clientStatus.DownloadSpeed
+		env["clientUploadSpeedLimit"] = clientStatus.UploadSpeedLimit
+		env["clientDownloadSpeedLimit"] = clientStatus.DownloadSpeedLimit
+		env["clientFreeSpace"] = clientStatus.FreeSpaceOnDisk
+	}
+	return env
+}
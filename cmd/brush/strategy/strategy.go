@@ -8,23 +8,26 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/site"
 	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/expr"
 )
 
+// Default values of the tunable parameters are defined in config package (DEFAULT_CLIENT_BRUSH_*).
 const (
 	// new torrents timespan during which will NOT be examined at all
-	NEW_TORRENTS_TIMESPAN = int64(15 * 60)
+	NEW_TORRENTS_TIMESPAN = config.DEFAULT_CLIENT_BRUSH_NEW_TORRENTS_TIMESPAN
 	// new torrents timespan during which will NOT be stalled
 	NEW_TORRENTS_STALL_EXEMPTION_TIMESPAN = int64(30 * 60)
 	NO_PROCESS_TORRENT_DELETEION_TIMESPAN = int64(30 * 60)
 	STALL_DOWNLOAD_SPEED                  = int64(10 * 1024)
 	SLOW_UPLOAD_SPEED                     = int64(100 * 1024)
 	RATIO_CHECK_MIN_DOWNLOAD_SPEED        = int64(100 * 1024)
-	SLOW_TORRENTS_CHECK_TIMESPAN          = int64(15 * 60)
+	SLOW_TORRENTS_CHECK_TIMESPAN          = config.DEFAULT_CLIENT_BRUSH_SLOW_TORRENTS_CHECK_TIMESPAN
 	// stalled torrent will be deleted after this time passed
-	STALL_TORRENT_DELETEION_TIMESPAN     = int64(30 * 60)
-	BANDWIDTH_FULL_PERCENT               = float64(0.8)
+	STALL_TORRENT_DELETEION_TIMESPAN     = config.DEFAULT_CLIENT_BRUSH_STALL_TORRENT_DELETION_TIMESPAN
+	BANDWIDTH_FULL_PERCENT               = config.DEFAULT_CLIENT_BRUSH_BANDWIDTH_FULL_PERCENT
 	DELETE_TORRENT_IMMEDIATELY_SCORE     = float64(99999)
 	RESUME_TORRENTS_FREE_DISK_SPACE_TIER = int64(5 * 1024 * 1024 * 1024)  // 5GB
 	DELETE_TORRENTS_FREE_DISK_SPACE_TIER = int64(10 * 1024 * 1024 * 1024) // 10GB
@@ -43,6 +46,7 @@ type BrushSiteOptionStruct struct {
 	ExcludeTags             []string
 	AllowAddTorrents        int64
 	AcceptAnyFree           bool
+	Score                   *expr.Program // user defined score expression. nil == use the strategy score
 }
 
 type BrushClientOptionStruct struct {
@@ -52,6 +56,12 @@ type BrushClientOptionStruct struct {
 	MaxTorrents             int64
 	MinRatio                float64
 	DefaultUploadSpeedLimit int64
+	Strategy                string // strategy name
+	// tunable parameters of default strategy
+	BandwidthFullPercent         float64
+	NewTorrentsTimespan          int64
+	SlowTorrentsCheckTimespan    int64
+	StallTorrentDeletionTimespan int64
 }
 
 type AlgorithmAddTorrent struct {
@@ -110,9 +120,9 @@ type clientTorrentInfoStruct struct {
 	DeleteFlag          bool
 }
 
-func countAsDownloading(torrent *client.Torrent, now int64) bool {
+func countAsDownloading(torrent *client.Torrent, now int64, newTorrentsTimespan int64) bool {
 	return !torrent.IsComplete() && torrent.Meta["stt"] == 0 &&
-		(torrent.DownloadSpeed >= STALL_DOWNLOAD_SPEED || now-torrent.Atime <= NEW_TORRENTS_TIMESPAN)
+		(torrent.DownloadSpeed >= STALL_DOWNLOAD_SPEED || now-torrent.Atime <= newTorrentsTimespan)
 }
 
 func canStallTorrent(torrent *client.Torrent) bool {
@@ -142,8 +152,9 @@ func isTorrentStalled(torrent *client.Torrent) bool {
  * Also：
  *   * Use the current seeders / leechers info of torrent when make decisions
  */
-func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteTorrents []*site.Torrent,
-	siteOption *BrushSiteOptionStruct, clientOption *BrushClientOptionStruct) (result *AlgorithmResult) {
+func (s *defaultStrategy) Decide(clientStatus *client.Status, clientTorrents []*client.Torrent,
+	siteTorrents []*site.Torrent, siteOption *BrushSiteOptionStruct, clientOption *BrushClientOptionStruct) (
+	result *AlgorithmResult) {
 	result = &AlgorithmResult{}
 
 	cntTorrents := int64(len(clientTorrents))
@@ -176,7 +187,7 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 	}
 
 	for _, siteTorrent := range siteTorrents {
-		score, predictionUploadSpeed, _ := RateSiteTorrent(siteTorrent, siteOption)
+		score, predictionUploadSpeed, _ := s.RateSiteTorrent(siteTorrent, siteOption, clientStatus)
 		if score > 0 {
 			candidateTorrent := candidateTorrentStruct{
 				Name:                  siteTorrent.Name,
@@ -198,7 +209,7 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 
 	// mark torrents
 	for _, torrent := range clientTorrents {
-		if countAsDownloading(torrent, siteOption.Now) {
+		if countAsDownloading(torrent, siteOption.Now, clientOption.NewTorrentsTimespan) {
 			cntDownloadingTorrents++
 		}
 
@@ -218,7 +229,7 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 		}
 
 		// skip new added torrents
-		if siteOption.Now-torrent.Atime <= NEW_TORRENTS_TIMESPAN {
+		if siteOption.Now-torrent.Atime <= clientOption.NewTorrentsTimespan {
 			continue
 		}
 
@@ -245,7 +256,7 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 		} else if torrent.UploadSpeed < clientOption.SlowUploadSpeedTier {
 			// check slow torrents, add it to watch list first time and mark as deleteCandidate second time
 			if torrent.Meta["sct"] > 0 { // second encounter on slow torrent
-				if siteOption.Now-torrent.Meta["sct"] >= SLOW_TORRENTS_CHECK_TIMESPAN {
+				if siteOption.Now-torrent.Meta["sct"] >= clientOption.SlowTorrentsCheckTimespan {
 					averageUploadSpeedSinceSct := (torrent.Uploaded - torrent.Meta["sctu"]) /
 						(siteOption.Now - torrent.Meta["sct"])
 					if averageUploadSpeedSinceSct < clientOption.SlowUploadSpeedTier {
@@ -330,7 +341,7 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 			shouldDelete = true
 		} else if torrent.Ctime <= 0 &&
 			torrent.Meta["stt"] > 0 &&
-			siteOption.Now-torrent.Meta["stt"] >= STALL_TORRENT_DELETEION_TIMESPAN {
+			siteOption.Now-torrent.Meta["stt"] >= clientOption.StallTorrentDeletionTimespan {
 			shouldDelete = true
 		}
 		if !shouldDelete {
@@ -344,7 +355,7 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 		freespaceChange += torrent.SizeCompleted
 		estimateUploadSpeed -= torrent.UploadSpeed
 		clientTorrentsMap[torrent.InfoHash].DeleteFlag = true
-		if countAsDownloading(torrent, siteOption.Now) {
+		if countAsDownloading(torrent, siteOption.Now, clientOption.NewTorrentsTimespan) {
 			cntDownloadingTorrents--
 		}
 		cntTorrents--
@@ -364,7 +375,7 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 			freespaceChange += torrent.SizeCompleted
 			estimateUploadSpeed -= torrent.UploadSpeed
 			clientTorrentsMap[torrent.InfoHash].DeleteFlag = true
-			if countAsDownloading(torrent, siteOption.Now) {
+			if countAsDownloading(torrent, siteOption.Now, clientOption.NewTorrentsTimespan) {
 				cntDownloadingTorrents--
 			}
 			cntTorrents--
@@ -387,7 +398,7 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 			freespaceChange += torrent.SizeCompleted
 			estimateUploadSpeed -= torrent.UploadSpeed
 			clientTorrentsMap[torrent.InfoHash].DeleteFlag = true
-			if countAsDownloading(torrent, siteOption.Now) {
+			if countAsDownloading(torrent, siteOption.Now, clientOption.NewTorrentsTimespan) {
 				cntDownloadingTorrents--
 			}
 			cntTorrents--
@@ -440,7 +451,8 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 			continue
 		}
 		result.StallTorrents = append(result.StallTorrents, stallTorrent)
-		if countAsDownloading(clientTorrentsMap[stallTorrent.InfoHash].Torrent, siteOption.Now) {
+		if countAsDownloading(clientTorrentsMap[stallTorrent.InfoHash].Torrent, siteOption.Now,
+			clientOption.NewTorrentsTimespan) {
 			cntDownloadingTorrents--
 		}
 	}
@@ -452,7 +464,8 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 			continue
 		}
 		result.ResumeTorrents = append(result.ResumeTorrents, resumeTorrent)
-		if !countAsDownloading(clientTorrentsMap[resumeTorrent.InfoHash].Torrent, siteOption.Now) {
+		if !countAsDownloading(clientTorrentsMap[resumeTorrent.InfoHash].Torrent, siteOption.Now,
+			clientOption.NewTorrentsTimespan) {
 			cntDownloadingTorrents++
 		}
 	}
@@ -499,8 +512,20 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 	return
 }
 
+// Rate site torrent using default strategy. clientStatus is unknown (nil).
 func RateSiteTorrent(siteTorrent *site.Torrent, siteOption *BrushSiteOptionStruct) (
 	score float64, predictionUploadSpeed int64, note string) {
+	return Default.RateSiteTorrent(siteTorrent, siteOption, nil)
+}
+
+// Decide using default strategy.
+func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteTorrents []*site.Torrent,
+	siteOption *BrushSiteOptionStruct, clientOption *BrushClientOptionStruct) (result *AlgorithmResult) {
+	return Default.Decide(clientStatus, clientTorrents, siteTorrents, siteOption, clientOption)
+}
+
+func (s *defaultStrategy) RateSiteTorrent(siteTorrent *site.Torrent, siteOption *BrushSiteOptionStruct,
+	clientStatus *client.Status) (score float64, predictionUploadSpeed int64, note string) {
 	if log.GetLevel() >= log.TraceLevel {
 		defer func() {
 			log.Tracef("rateSiteTorrent score=%0.0f name=%s, free=%t, rtime=%d, seeders=%d, leechers=%d, note=%s",
@@ -514,6 +539,29 @@ func RateSiteTorrent(siteTorrent *site.Torrent, siteOption *BrushSiteOptionStruc
 			)
 		}()
 	}
+	if ok, filterNote := FilterSiteTorrent(siteTorrent, siteOption); !ok {
+		note = filterNote
+		return
+	}
+	score, predictionUploadSpeed = s.rate(siteTorrent, siteOption)
+	if siteOption.Score != nil {
+		env := SiteTorrentEnv(siteTorrent, siteOption, clientStatus)
+		env["score"] = score
+		env["predictionUploadSpeed"] = predictionUploadSpeed
+		var err error
+		if score, err = siteOption.Score.EvalNumber(env); err != nil {
+			log.Warnf("Failed to evaluate brush score expression %q of torrent %s: %v",
+				siteOption.Score, siteTorrent.Name, err)
+			score = 0
+		}
+		note = "score expression"
+	}
+	return
+}
+
+// Return false if site torrent does not meet the hard requirements of site brush options
+// (e.g. HR, none-free, size limits, excludes), which should never be added whatever the score is.
+func FilterSiteTorrent(siteTorrent *site.Torrent, siteOption *BrushSiteOptionStruct) (ok bool, note string) {
 	if siteTorrent.IsActive || siteTorrent.UploadMultiplier == 0 ||
 		(!siteOption.AllowHr && siteTorrent.HasHnR) ||
 		(!siteOption.AllowNoneFree && siteTorrent.DownloadMultiplier != 0) ||
@@ -521,29 +569,30 @@ func RateSiteTorrent(siteTorrent *site.Torrent, siteOption *BrushSiteOptionStruc
 		siteTorrent.Size < siteOption.TorrentMinSizeLimit ||
 		siteTorrent.Size > siteOption.TorrentMaxSizeLimit ||
 		(siteTorrent.DiscountEndTime > 0 && siteTorrent.DiscountEndTime-siteOption.Now < 3600) ||
-		(!siteOption.AllowZeroSeeders && siteTorrent.Seeders == 0) ||
-		((!siteOption.AcceptAnyFree || siteTorrent.DownloadMultiplier != 0) && siteTorrent.Leechers <= siteTorrent.Seeders) {
-		score = 0
-		return
+		(!siteOption.AllowZeroSeeders && siteTorrent.Seeders == 0) {
+		return false, ""
 	}
 	if siteTorrent.MatchFiltersOr(siteOption.Excludes) {
-		score = 0
-		note = "brush excludes match"
-		return
+		return false, "brush excludes match"
 	}
 	if siteTorrent.HasAnyTag(siteOption.ExcludeTags) {
-		score = 0
-		note = "brush exclude tags match"
+		return false, "brush exclude tags match"
+	}
+	return true, ""
+}
+
+// The default score formula. Torrent has already passed FilterSiteTorrent.
+func (s *defaultStrategy) rate(siteTorrent *site.Torrent, siteOption *BrushSiteOptionStruct) (
+	score float64, predictionUploadSpeed int64) {
+	if (!siteOption.AcceptAnyFree || siteTorrent.DownloadMultiplier != 0) && siteTorrent.Leechers <= siteTorrent.Seeders {
 		return
 	}
 	// 部分站点定期将旧种重新置顶免费。这类种子仍然可以获得很好的上传速度。
 	if !siteOption.AcceptAnyFree && siteOption.Now-siteTorrent.Time <= 86400*30 {
 		if siteOption.Now-siteTorrent.Time >= 86400 {
-			score = 0
 			return
 		} else if siteOption.Now-siteTorrent.Time >= 7200 {
 			if siteTorrent.Leechers < 500 {
-				score = 0
 				return
 			}
 		}
@@ -594,28 +643,54 @@ func RateSiteTorrent(siteTorrent *site.Torrent, siteOption *BrushSiteOptionStruc
 }
 
 func GetBrushSiteOptions(siteInstance site.Site, ts int64) *BrushSiteOptionStruct {
+	return GetBrushSiteOptionsFromConfig(siteInstance.GetSiteConfig(), ts)
+}
+
+func GetBrushSiteOptionsFromConfig(siteConfig *config.SiteConfigStruct, ts int64) *BrushSiteOptionStruct {
 	return &BrushSiteOptionStruct{
-		TorrentMinSizeLimit:     siteInstance.GetSiteConfig().BrushTorrentMinSizeLimitValue,
-		TorrentMaxSizeLimit:     siteInstance.GetSiteConfig().BrushTorrentMaxSizeLimitValue,
-		TorrentUploadSpeedLimit: siteInstance.GetSiteConfig().TorrentUploadSpeedLimitValue,
-		AllowNoneFree:           siteInstance.GetSiteConfig().BrushAllowNoneFree,
-		AcceptAnyFree:           siteInstance.GetSiteConfig().BrushAcceptAnyFree,
-		AllowPaid:               siteInstance.GetSiteConfig().BrushAllowPaid,
-		AllowHr:                 siteInstance.GetSiteConfig().BrushAllowHr,
-		AllowZeroSeeders:        siteInstance.GetSiteConfig().BrushAllowZeroSeeders,
-		Excludes:                siteInstance.GetSiteConfig().BrushExcludes,
-		ExcludeTags:             siteInstance.GetSiteConfig().BrushExcludeTags,
+		TorrentMinSizeLimit:     siteConfig.BrushTorrentMinSizeLimitValue,
+		TorrentMaxSizeLimit:     siteConfig.BrushTorrentMaxSizeLimitValue,
+		TorrentUploadSpeedLimit: siteConfig.TorrentUploadSpeedLimitValue,
+		AllowNoneFree:           siteConfig.BrushAllowNoneFree,
+		AcceptAnyFree:           siteConfig.BrushAcceptAnyFree,
+		AllowPaid:               siteConfig.BrushAllowPaid,
+		AllowHr:                 siteConfig.BrushAllowHr,
+		AllowZeroSeeders:        siteConfig.BrushAllowZeroSeeders,
+		Excludes:                siteConfig.BrushExcludes,
+		ExcludeTags:             siteConfig.BrushExcludeTags,
+		Score:                   siteConfig.BrushScoreProgram,
 		Now:                     ts,
 	}
 }
 
 func GetBrushClientOptions(clientInstance client.Client) *BrushClientOptionStruct {
+	return GetBrushClientOptionsFromConfig(clientInstance.GetClientConfig())
+}
+
+func GetBrushClientOptionsFromConfig(clientConfig *config.ClientConfigStruct) *BrushClientOptionStruct {
+	strategy := clientConfig.BrushStrategy
+	if strategy == "" {
+		strategy = DEFAULT_STRATEGY
+	}
 	return &BrushClientOptionStruct{
-		MinDiskSpace:            clientInstance.GetClientConfig().BrushMinDiskSpaceValue,
-		SlowUploadSpeedTier:     clientInstance.GetClientConfig().BrushSlowUploadSpeedTierValue,
-		MaxDownloadingTorrents:  clientInstance.GetClientConfig().BrushMaxDownloadingTorrents,
-		MaxTorrents:             clientInstance.GetClientConfig().BrushMaxTorrents,
-		MinRatio:                clientInstance.GetClientConfig().BrushMinRatio,
-		DefaultUploadSpeedLimit: clientInstance.GetClientConfig().BrushDefaultUploadSpeedLimitValue,
+		MinDiskSpace:            clientConfig.BrushMinDiskSpaceValue,
+		SlowUploadSpeedTier:     clientConfig.BrushSlowUploadSpeedTierValue,
+		MaxDownloadingTorrents:  clientConfig.BrushMaxDownloadingTorrents,
+		MaxTorrents:             clientConfig.BrushMaxTorrents,
+		MinRatio:                clientConfig.BrushMinRatio,
+		DefaultUploadSpeedLimit: clientConfig.BrushDefaultUploadSpeedLimitValue,
+		Strategy:                strategy,
+
+		BandwidthFullPercent:         clientConfig.BrushBandwidthFullPercent,
+		NewTorrentsTimespan:          clientConfig.BrushNewTorrentsTimespanValue,
+		SlowTorrentsCheckTimespan:    clientConfig.BrushSlowTorrentsCheckTimespanValue,
+		StallTorrentDeletionTimespan: clientConfig.BrushStallTorrentDeletionTimespanValue,
 	}
 }
+
+// Return true if client upload bandwidth is already full (or too low), in which case new site torrents
+// should not be added.
+func IsBandwidthFull(clientStatus *client.Status, clientOption *BrushClientOptionStruct) bool {
+	return clientStatus.UploadSpeedLimit > 0 && (clientStatus.UploadSpeedLimit < SLOW_UPLOAD_SPEED ||
+		float64(clientStatus.UploadSpeed)/float64(clientStatus.UploadSpeedLimit) >= clientOption.BandwidthFullPercent)
+}
@@ -0,0 +1,68 @@
+package strategy_test
+
+import (
+	"testing"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd/brush/strategy"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util/expr"
+)
+
+func TestRateSiteTorrentScoreExpression(t *testing.T) {
+	now := int64(1700000000)
+	siteOption := &strategy.BrushSiteOptionStruct{
+		TorrentUploadSpeedLimit: 10 * 1024 * 1024,
+		TorrentMaxSizeLimit:     1 << 50,
+		Now:                     now,
+	}
+	torrent := &site.Torrent{
+		Name:             "Foo.S01E01.1080p",
+		Size:             2 * 1024 * 1024 * 1024,
+		Seeders:          1,
+		Leechers:         10,
+		Time:             now - 600,
+		UploadMultiplier: 1,
+	}
+	defaultScore, _, _ := strategy.RateSiteTorrent(torrent, siteOption)
+	if defaultScore != 120 {
+		t.Fatalf("default score = %v, want 120", defaultScore)
+	}
+
+	siteOption.Score = expr.MustCompile("age < 1h && seeders <= 1 ? score * 2 : 0")
+	if score, _, _ := strategy.RateSiteTorrent(torrent, siteOption); score != 240 {
+		t.Errorf("expression score = %v, want 240", score)
+	}
+
+	// hard filters are applied before the expression
+	torrent.HasHnR = true
+	siteOption.Score = expr.MustCompile("1000")
+	if score, _, _ := strategy.RateSiteTorrent(torrent, siteOption); score != 0 {
+		t.Errorf("hr torrent score = %v, want 0", score)
+	}
+
+	if _, err := strategy.Get("nonexistent"); err == nil {
+		t.Errorf("Get(nonexistent) expect error")
+	}
+	if s, err := strategy.Get(""); err != nil || s != strategy.Default {
+		t.Errorf("Get(\"\") should return default strategy")
+	}
+}
+
+func TestIsBandwidthFull(t *testing.T) {
+	clientOption := &strategy.BrushClientOptionStruct{BandwidthFullPercent: 0.8}
+	for _, tt := range []struct {
+		speed, limit int64
+		want         bool
+	}{
+		{10 << 20, 0, false},
+		{7 << 20, 10 << 20, false},
+		{8 << 20, 10 << 20, true},
+		{0, 50 * 1024, true},
+	} {
+		status := &client.Status{UploadSpeed: tt.speed, UploadSpeedLimit: tt.limit}
+		if got := strategy.IsBandwidthFull(status, clientOption); got != tt.want {
+			t.Errorf("IsBandwidthFull(%d/%d) = %v, want %v", tt.speed, tt.limit, got, tt.want)
+		}
+	}
+}
@@ -5,9 +5,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path"
-	"slices"
-	"strings"
 
 	"github.com/natefinch/atomic"
 	"github.com/sagan/ptool/client"
@@ -92,63 +89,6 @@ func (ts *TorrentsStatistics) Print(output io.Writer) {
 	fmt.Fprintf(output, "Invalid torrents: %d\n", ts.InvalidCnt)
 }
 
-type PathMapper struct {
-	mapper  map[string]string
-	befores []string
-}
-
-func (spm *PathMapper) Before2After(beforePath string) (afterPath string, match bool) {
-	beforePath = path.Clean(util.ToSlash(beforePath))
-	for _, before := range spm.befores {
-		if before == "/" {
-			if strings.HasPrefix(beforePath, before) {
-				return spm.mapper[before] + strings.TrimPrefix(beforePath, before), true
-			}
-		} else if strings.HasPrefix(beforePath, before+"/") {
-			return spm.mapper[before] + strings.TrimPrefix(beforePath, before), true
-		}
-	}
-	return beforePath, false
-}
-
-func (spm *PathMapper) After2Before(afterPath string) (beforePath string, match bool) {
-	afterPath = path.Clean(util.ToSlash(afterPath))
-	for _, before := range spm.befores {
-		after := spm.mapper[before]
-		if after == "/" {
-			if strings.HasPrefix(afterPath, after) {
-				return before + strings.TrimPrefix(afterPath, after), true
-			}
-		} else if strings.HasPrefix(afterPath, after+"/") {
-			return before + strings.TrimPrefix(afterPath, after), true
-		}
-	}
-	return afterPath, false
-}
-
-func NewPathMapper(rules []string) (*PathMapper, error) {
-	pm := &PathMapper{
-		mapper: map[string]string{},
-	}
-	for _, rule := range rules {
-		sep := "|"
-		// use ":" as sep only when "|" not exists and no Windows abs path (e.g. "E:\Downloads") exists
-		if !strings.Contains(rule, "|") && !strings.Contains(rule, `:\`) {
-			sep = ":"
-		}
-		before, after, found := strings.Cut(rule, sep)
-		if !found || before == "" || after == "" {
-			return nil, fmt.Errorf("invalid path mapper rule %q", rule)
-		}
-		before = path.Clean(util.ToSlash(before))
-		after = path.Clean(util.ToSlash(after))
-		pm.mapper[before] = after
-		pm.befores = append(pm.befores, before)
-	}
-	slices.SortFunc(pm.befores, func(a, b string) int { return len(b) - len(a) }) // longest first
-	return pm, nil
-}
-
 // Export a client torrent's metainfo (".torrent" file contents), return it's byte contents and parsed info.
 // If outputPath is not empty, it also writes the contents to that path; if it's "-", write to stdout.
 // If useCommentMeta is true, encode the client torrents' category / tag / savePath in exported
@@ -7,6 +7,7 @@ import (
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/constants"
 )
 
 var command = &cobra.Command{
@@ -14,9 +15,10 @@ var command = &cobra.Command{
 	Aliases:     []string{"createtag"},
 	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "createtags"},
 	Short:       "Create tags in client.",
-	Long:        `Create tags in client.`,
-	Args:        cobra.MatchAll(cobra.MinimumNArgs(2), cobra.OnlyValidArgs),
-	RunE:        createtags,
+	Long: `Create tags in client.
+` + constants.HELP_CLIENT_GROUP_ARG + ".",
+	Args: cobra.MatchAll(cobra.MinimumNArgs(2), cobra.OnlyValidArgs),
+	RunE: createtags,
 }
 
 func init() {
@@ -26,14 +28,14 @@ func init() {
 func createtags(cmd *cobra.Command, args []string) error {
 	clientName := args[0]
 	tags := args[1:]
-	clientInstance, err := client.CreateClient(clientName)
+	clientInstances, err := client.CreateClientsFromArg(clientName)
 	if err != nil {
-		return fmt.Errorf("failed to create client: %w", err)
+		return err
 	}
-
-	err = clientInstance.CreateTags(tags...)
-	if err != nil {
-		return fmt.Errorf("Failed to create tags: %w", err)
-	}
-	return nil
+	return client.RunOnClients(clientInstances, func(clientInstance client.Client) error {
+		if err := clientInstance.CreateTags(tags...); err != nil {
+			return fmt.Errorf("Failed to create tags: %w", err)
+		}
+		return nil
+	})
 }
@@ -0,0 +1,286 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/shlex"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/status"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/metrics"
+)
+
+const DEFAULT_INTERVAL = 10 * time.Minute
+
+var command = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run in daemon mode, executing scheduled jobs defined in config file periodically.",
+	Long: `Run in daemon mode, executing scheduled jobs defined in config file periodically.
+
+Jobs are defined in "schedules" section of ptool.toml config file, e.g.:
+
+  [[schedules]]
+  name = 'brush'
+  cmd = 'brush local mteam hdsky'
+  interval = '10m'
+
+  [[schedules]]
+  name = 'xseed'
+  cmd = 'iyuu xseed local'
+  interval = '1h'
+
+Each job is a ptool cmdline (without the leading "ptool"), which will be executed in the daemon process.
+Jobs run one by one. The first run of each job happens when the daemon starts.
+Clients and sites instances are kept during the whole daemon session, but their cache are purged between runs.
+The "<config_dir>/` + config.GLOBAL_LOCK_FILE + `" file is locked during each run,
+so it's safe to run other ptool instances with "--global-lock" flag at the same time.
+If the lock is held by another ptool instance, the run fails and the job is tried again at it's next schedule.
+If a job fails, the error is logged and the daemon keeps going.
+Stop the daemon by sending a SIGINT / SIGTERM signal to it, it will exit after the current running job finishes.
+
+If "--metrics-addr" flag is set, the daemon also serves Prometheus metrics on "http://<addr>/metrics", including:
+- Status of clients: speeds & limits, free disk space, unfinished size, torrents count & size of each tracker.
+- Status of sites: user uploaded & downloaded, seeding & leeching torrents count.
+- Counts of brush algorithm results of brush jobs run by the daemon.
+- Runs & failures count of each job.
+Clients & sites status are collected every "--metrics-interval" as an internal job (which runs like other jobs).
+By default all enabled clients and sites are collected, use "--metrics" flag to set the list.
+The metrics endpoint does not require authentication, so do NOT expose it to public network.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(0), cobra.OnlyValidArgs),
+	RunE: daemon,
+}
+
+var (
+	once            = false
+	metricsAddr     = ""
+	metricsInterval = ""
+	metricsNames    = ""
+)
+
+func init() {
+	command.Flags().BoolVarP(&once, "once", "", false, "Run each job only once then exit")
+	command.Flags().StringVarP(&metricsAddr, "metrics-addr", "", "",
+		`Serve Prometheus metrics on this address. E.g. "127.0.0.1:9090". Empty == disabled`)
+	command.Flags().StringVarP(&metricsInterval, "metrics-interval", "", "1m",
+		"Interval of collecting clients & sites status metrics")
+	command.Flags().StringVarP(&metricsNames, "metrics", "", "",
+		"Comma-separated list of clients, sites or groups to collect status metrics of. Empty == all")
+	metrics.Register(jobRunsMetric, jobFailsMetric)
+	cmd.RootCmd.AddCommand(command)
+}
+
+type job struct {
+	name     string
+	args     []string
+	fn       func() error // internal job, runs fn instead of args
+	interval time.Duration
+	nextRun  time.Time
+	runs     int64
+	fails    int64
+}
+
+var (
+	jobRunsMetric  = metrics.NewCounter("ptool_daemon_job_runs_total", "Count of daemon job runs.", "job")
+	jobFailsMetric = metrics.NewCounter("ptool_daemon_job_failures_total", "Count of daemon job failures.", "job")
+	// rendered clients & sites status metrics of latest collection
+	metricsSnapshot   []byte
+	metricsSnapshotMu sync.Mutex
+)
+
+func daemon(command *cobra.Command, args []string) error {
+	if config.InShell {
+		return fmt.Errorf(`you cann't run "daemon" command in shell`)
+	}
+	if config.LockFile != "" {
+		return fmt.Errorf("--lock or --global-lock flag can NOT be used with daemon")
+	}
+	jobs, err := parseJobs(config.Get().Schedules)
+	if err != nil {
+		return err
+	}
+	if len(jobs) == 0 && metricsAddr == "" {
+		return fmt.Errorf(`no job defined. Add "[[schedules]]" section to config file`)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if metricsAddr != "" {
+		interval, err := time.ParseDuration(metricsInterval)
+		if err != nil || interval <= 0 {
+			return fmt.Errorf("invalid metrics-interval '%s'", metricsInterval)
+		}
+		jobs = append(jobs, &job{name: "_metrics", fn: collectMetrics, interval: interval})
+		server := &http.Server{Addr: metricsAddr, Handler: http.HandlerFunc(serveMetrics),
+			ReadHeaderTimeout: 30 * time.Second}
+		go func() {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Errorf("Metrics server error: %v", err)
+				stop()
+			}
+		}()
+		defer server.Close()
+		log.Warnf("Serving metrics on http://%s/metrics", metricsAddr)
+	}
+	log.Warnf("Daemon started with %d jobs", len(jobs))
+	now := time.Now()
+	for _, job := range jobs {
+		job.nextRun = now
+		log.Warnf("Job %s: interval=%v, cmd=%v", job.name, job.interval, job.args)
+	}
+	for {
+		var next *job
+		for _, job := range jobs {
+			if (!once || job.runs == 0) && (next == nil || job.nextRun.Before(next.nextRun)) {
+				next = job
+			}
+		}
+		if next == nil {
+			break
+		}
+		if wait := time.Until(next.nextRun); wait > 0 {
+			log.Infof("Next job %s will run at %s", next.name, next.nextRun.Format(time.DateTime))
+			select {
+			case <-ctx.Done():
+			case <-time.After(wait):
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		runJob(next)
+		next.nextRun = time.Now().Add(next.interval)
+	}
+	for _, job := range jobs {
+		log.Warnf("Job %s: %d runs, %d fails", job.name, job.runs, job.fails)
+	}
+	log.Warnf("Daemon exited")
+	return nil
+}
+
+func parseJobs(schedules []*config.ScheduleConfigStruct) ([]*job, error) {
+	jobs := []*job{}
+	names := map[string]bool{}
+	for i, schedule := range schedules {
+		if schedule.Disabled {
+			continue
+		}
+		name := schedule.Name
+		if name == "" {
+			name = fmt.Sprintf("job%d", i+1)
+		}
+		if strings.HasPrefix(name, "_") {
+			return nil, fmt.Errorf("invalid schedule name %s: names starting with '_' are reserved", name)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("duplicate schedule name %s", name)
+		}
+		names[name] = true
+		args, err := shlex.Split(schedule.Cmd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse schedule %s cmd '%s': %w", name, schedule.Cmd, err)
+		}
+		if len(args) == 0 {
+			return nil, fmt.Errorf("schedule %s does not have cmd", name)
+		}
+		if args[0] == "daemon" || args[0] == "shell" {
+			return nil, fmt.Errorf("schedule %s: %s command can NOT be scheduled", name, args[0])
+		}
+		interval := DEFAULT_INTERVAL
+		if schedule.Interval != "" {
+			if interval, err = time.ParseDuration(schedule.Interval); err != nil || interval <= 0 {
+				return nil, fmt.Errorf("schedule %s has invalid interval '%s'", name, schedule.Interval)
+			}
+		}
+		jobs = append(jobs, &job{
+			name:     name,
+			args:     args,
+			interval: interval,
+		})
+	}
+	return jobs, nil
+}
+
+// Run a job. Return error if the job failed.
+func runJob(job *job) (err error) {
+	job.runs++
+	jobRunsMetric.Add(1, job.name)
+	start := time.Now()
+	logf := log.Warnf
+	if job.fn != nil {
+		logf = log.Infof // internal jobs are not logged by default
+	}
+	logf("Run job %s (#%d): %v", job.name, job.runs, job.args)
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+		if err != nil {
+			job.fails++
+			jobFailsMetric.Add(1, job.name)
+			log.Errorf("Job %s failed in %v: %v", job.name, time.Since(start), err)
+		} else {
+			logf("Job %s finished in %v", job.name, time.Since(start))
+		}
+	}()
+	lock, err := config.LockConfigDirFile(config.GLOBAL_LOCK_FILE)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	// clients and sites instances are kept, but data cached in previous run must be purged.
+	client.Purge("")
+	site.Purge("")
+	if job.fn != nil {
+		return job.fn()
+	}
+	args := job.args
+	if jobCmd, _, err := cmd.RootCmd.Find(args); err != nil || jobCmd == cmd.RootCmd {
+		// try to parse cmd as alias
+		args = append([]string{"alias"}, args...)
+	} else {
+		cmd.ResetFlags(jobCmd)
+	}
+	os.Args = append([]string{os.Args[0]}, args...)
+	return cmd.RootCmd.Execute()
+}
+
+func collectMetrics() error {
+	buf := &bytes.Buffer{}
+	collectTime := metrics.NewGauge("ptool_metrics_collect_timestamp_seconds",
+		"Unix timestamp of latest clients & sites status metrics collection.")
+	collectTime.Set(float64(util.Now()))
+	if err := metrics.Write(buf, append(status.CollectMetrics(util.SplitCsv(metricsNames)), collectTime)...); err != nil {
+		return err
+	}
+	metricsSnapshotMu.Lock()
+	defer metricsSnapshotMu.Unlock()
+	metricsSnapshot = buf.Bytes()
+	return nil
+}
+
+func serveMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/metrics" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", metrics.CONTENT_TYPE)
+	metricsSnapshotMu.Lock()
+	w.Write(metricsSnapshot)
+	metricsSnapshotMu.Unlock()
+	metrics.WriteRegistered(w)
+}
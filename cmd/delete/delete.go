@@ -3,6 +3,7 @@ package delete
 import (
 	"fmt"
 	"os"
+	"slices"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -10,6 +11,7 @@ import (
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
 	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/journal"
 	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/helper"
 )
@@ -21,8 +23,15 @@ var command = &cobra.Command{
 	Short:       "Delete torrents from client.",
 	Long: fmt.Sprintf(`Delete torrents from client.
 %s.
+%s.
+
+It will ask for confirmation of deletion, unless --force flag is set.
+
+Before deletion, the .torrent files and save path / category / tags of torrents are recorded to journal,
+so the deletion can be undone by "ptool undo" (deleted content files can NOT be restored, though).
 
-It will ask for confirmation of deletion, unless --force flag is set.`, constants.HELP_INFOHASH_ARGS),
+If "trashPath" of client is configured (trash mode), the content files are moved to trash instead of
+being deleted, and can be restored later by "ptool trash restore". Use --no-trash flag to delete them directly.`, constants.HELP_CLIENT_GROUP_ARG, constants.HELP_INFOHASH_ARGS),
 	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
 	RunE: delete,
 }
@@ -31,8 +40,10 @@ var (
 	showSum           = false
 	preserve          = false
 	preserveXseed     = false
+	noTrash           = false
 	force             = false
 	filter            = ""
+	where             = ""
 	category          = ""
 	tag               = ""
 	tracker           = ""
@@ -46,8 +57,11 @@ func init() {
 		"Preserve (don't delete) torrent content files on the disk")
 	command.Flags().BoolVarP(&preserveXseed, "preserve-if-xseed-exist", "P", false,
 		"Preserve (don't delete) torrent content files on the disk if other xseed torrents exist")
+	command.Flags().BoolVarP(&noTrash, "no-trash", "", false,
+		"Delete content files directly even if trash mode is enabled for client")
 	command.Flags().BoolVarP(&force, "force", "", false, "Force deletion. Do NOT prompt for confirm")
 	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&where, "where", "", "", constants.HELP_ARG_WHERE_TORRENT)
 	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
 	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
 	command.Flags().StringVarP(&tracker, "tracker", "", "", constants.HELP_ARG_TRACKER)
@@ -62,14 +76,15 @@ func delete(cmd *cobra.Command, args []string) error {
 	}
 	clientName := args[0]
 	infoHashes := args[1:]
-	clientInstance, err := client.CreateClient(clientName)
+	clientInstances, err := client.CreateClientsFromArg(clientName)
 	if err != nil {
-		return fmt.Errorf("failed to create client: %w", err)
+		return err
 	}
 	minTorrentSize, _ := util.RAMInBytes(minTorrentSizeStr)
 	maxTorrentSize, _ := util.RAMInBytes(maxTorrentSizeStr)
 	infohashesOnly := true
-	if category != "" || tag != "" || filter != "" || tracker != "" || minTorrentSize >= 0 || maxTorrentSize >= 0 {
+	if category != "" || tag != "" || filter != "" || where != "" || tracker != "" ||
+		minTorrentSize >= 0 || maxTorrentSize >= 0 {
 		infohashesOnly = false
 	} else {
 		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
@@ -91,15 +106,35 @@ func delete(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("no torrent to delete")
 		}
 		if force {
-			if err = clientInstance.DeleteTorrents(infoHashes, !preserve); err != nil {
-				return fmt.Errorf("failed to delete torrents: %w", err)
+			journalEntry := journal.New(journal.OP_DELETE)
+			journalEntry.DeleteFiles = !preserve
+			for _, clientInstance := range clientInstances {
+				if err := journalEntry.AddInfoHashes(clientInstance, infoHashes); err != nil {
+					log.Warnf("Client %s: failed to record journal, the deletion can not be fully undone: %v",
+						clientInstance.GetName(), err)
+				}
+			}
+			if err := journalEntry.Save(); err != nil {
+				return fmt.Errorf("failed to save journal: %w", err)
 			}
-			return nil
+			return client.RunOnClients(clientInstances, func(clientInstance client.Client) error {
+				if err := deleteTorrents(clientInstance, infoHashes, !preserve); err != nil {
+					return fmt.Errorf("failed to delete torrents: %w", err)
+				}
+				return nil
+			})
 		}
 	}
-	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, infoHashes...)
+	torrents, err := client.QueryClientsTorrents(clientInstances,
+		func(clientInstance client.Client) ([]*client.Torrent, error) {
+			torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, where, infoHashes...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch client torrents: %w", err)
+			}
+			return torrents, nil
+		})
 	if err != nil {
-		return fmt.Errorf("failed to fetch client torrents: %w", err)
+		return err
 	}
 	if tracker != "" || minTorrentSize >= 0 || maxTorrentSize >= 0 {
 		torrents = util.Filter(torrents, func(t *client.Torrent) bool {
@@ -114,10 +149,17 @@ func delete(cmd *cobra.Command, args []string) error {
 	// if preserve-xseed flag is set, the torrents which contains other-not-delete xseed torrents
 	var torrentsWithXseed []*client.Torrent
 	if preserveXseed {
-		torrents, torrentsWithXseed, err = client.FilterTorrentsXseed(clientInstance, torrents)
-		if err != nil {
-			return err
+		var torrentsNoXseed []*client.Torrent
+		for _, clientInstance := range clientInstances {
+			noXseed, xseed, err := client.FilterTorrentsXseed(clientInstance,
+				client.GetClientTorrents(clientInstances, clientInstance, torrents))
+			if err != nil {
+				return err
+			}
+			torrentsNoXseed = append(torrentsNoXseed, noXseed...)
+			torrentsWithXseed = append(torrentsWithXseed, xseed...)
 		}
+		torrents = torrentsNoXseed
 	}
 	if len(torrents) == 0 && len(torrentsWithXseed) == 0 {
 		log.Infof("No matched torrents found")
@@ -143,21 +185,48 @@ func delete(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("abort")
 		}
 	}
-	if len(torrentsWithXseed) > 0 {
-		infoHashes := util.Map(torrentsWithXseed, func(t *client.Torrent) string { return t.InfoHash })
-		err = clientInstance.DeleteTorrents(infoHashes, false)
+	journalEntry := journal.New(journal.OP_DELETE)
+	journalEntry.DeleteFiles = !preserve
+	for _, clientInstance := range clientInstances {
+		err := journalEntry.AddTorrents(clientInstance, slices.Concat(
+			client.GetClientTorrents(clientInstances, clientInstance, torrents),
+			client.GetClientTorrents(clientInstances, clientInstance, torrentsWithXseed)))
 		if err != nil {
-			return fmt.Errorf("failed to delete torrents: %w", err)
+			log.Warnf("Client %s: failed to record journal, the deletion can not be fully undone: %v",
+				clientInstance.GetName(), err)
 		}
-		fmt.Printf("%d torrents deleted (delete files = false).\n", len(torrentsWithXseed))
 	}
-	if len(torrents) > 0 {
-		infoHashes := util.Map(torrents, func(t *client.Torrent) string { return t.InfoHash })
-		err = clientInstance.DeleteTorrents(infoHashes, !preserve)
-		if err != nil {
-			return fmt.Errorf("failed to delete torrents: %w", err)
+	if err := journalEntry.Save(); err != nil {
+		return fmt.Errorf("failed to save journal: %w", err)
+	}
+	return client.RunOnClients(clientInstances, func(clientInstance client.Client) error {
+		prefix := ""
+		if len(clientInstances) > 1 {
+			prefix = fmt.Sprintf("Client %s: ", clientInstance.GetName())
 		}
-		fmt.Printf("%d torrents deleted (delete files = %t).\n", len(torrents), !preserve)
+		torrentsWithXseed := client.GetClientTorrents(clientInstances, clientInstance, torrentsWithXseed)
+		if len(torrentsWithXseed) > 0 {
+			infoHashes := util.Map(torrentsWithXseed, func(t *client.Torrent) string { return t.InfoHash })
+			if err := clientInstance.DeleteTorrents(infoHashes, false); err != nil {
+				return fmt.Errorf("failed to delete torrents: %w", err)
+			}
+			fmt.Printf("%s%d torrents deleted (delete files = false).\n", prefix, len(torrentsWithXseed))
+		}
+		torrents := client.GetClientTorrents(clientInstances, clientInstance, torrents)
+		if len(torrents) > 0 {
+			infoHashes := util.Map(torrents, func(t *client.Torrent) string { return t.InfoHash })
+			if err := deleteTorrents(clientInstance, infoHashes, !preserve); err != nil {
+				return fmt.Errorf("failed to delete torrents: %w", err)
+			}
+			fmt.Printf("%s%d torrents deleted (delete files = %t).\n", prefix, len(torrents), !preserve)
+		}
+		return nil
+	})
+}
+
+func deleteTorrents(clientInstance client.Client, infoHashes []string, deleteFiles bool) error {
+	if noTrash {
+		return clientInstance.DeleteTorrents(infoHashes, deleteFiles)
 	}
-	return nil
+	return client.DeleteOrTrashTorrents(clientInstance, infoHashes, deleteFiles)
 }
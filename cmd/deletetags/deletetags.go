@@ -7,6 +7,7 @@ import (
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/constants"
 )
 
 var command = &cobra.Command{
@@ -14,9 +15,10 @@ var command = &cobra.Command{
 	Aliases:     []string{"deletetag", "deltags", "deltag"},
 	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "deletetags"},
 	Short:       "Delete tags from client.",
-	Long:        `Delete tags from client.`,
-	Args:        cobra.MatchAll(cobra.MinimumNArgs(2), cobra.OnlyValidArgs),
-	RunE:        deletetags,
+	Long: `Delete tags from client.
+` + constants.HELP_CLIENT_GROUP_ARG + ".",
+	Args: cobra.MatchAll(cobra.MinimumNArgs(2), cobra.OnlyValidArgs),
+	RunE: deletetags,
 }
 
 func init() {
@@ -24,16 +26,17 @@ func init() {
 }
 
 func deletetags(cmd *cobra.Command, args []string) error {
-	clientInstance, err := client.CreateClient(args[0])
+	clientInstances, err := client.CreateClientsFromArg(args[0])
 	if err != nil {
-		return fmt.Errorf("failed to create client: %w", err)
+		return err
 	}
 
 	tags := args[1:]
 
-	err = clientInstance.DeleteTags(tags...)
-	if err != nil {
-		return fmt.Errorf("failed to delete tags: %w", err)
-	}
-	return nil
+	return client.RunOnClients(clientInstances, func(clientInstance client.Client) error {
+		if err := clientInstance.DeleteTags(tags...); err != nil {
+			return fmt.Errorf("failed to delete tags: %w", err)
+		}
+		return nil
+	})
 }
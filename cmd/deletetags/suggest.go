@@ -19,6 +19,6 @@ func init() {
 		if info.LastArgIndex != 1 {
 			return nil
 		}
-		return suggest.ClientArg(info.MatchingPrefix)
+		return suggest.ClientOrGroupArg(info.MatchingPrefix)
 	})
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
 	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/journal"
 	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/helper"
 )
@@ -42,6 +43,7 @@ var (
 	category    = ""
 	tag         = ""
 	filter      = ""
+	where       = ""
 	oldTracker  = ""
 	newTracker  = ""
 )
@@ -52,6 +54,7 @@ func init() {
 		"Replace host mode. If set, --old-tracker should be the old host (hostname[:port]) instead of url, "+
 			"the --new-tracker can either be a host or url")
 	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&where, "where", "", "", constants.HELP_ARG_WHERE_TORRENT)
 	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
 	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
 	command.Flags().StringVarP(&oldTracker, "old-tracker", "", "", "Set the old tracker")
@@ -67,7 +70,7 @@ func edittracker(cmd *cobra.Command, args []string) error {
 	if !replaceHost && (!util.IsUrl(oldTracker) || !util.IsUrl(newTracker)) {
 		return fmt.Errorf("both --old-tracker and --new-tracker MUST be valid URL ( 'http(s)://...' )")
 	}
-	if category == "" && tag == "" && filter == "" {
+	if category == "" && tag == "" && filter == "" && where == "" {
 		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
 			return err
 		} else {
@@ -79,7 +82,7 @@ func edittracker(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
 
-	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, infoHashes...)
+	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, where, infoHashes...)
 	if err != nil {
 		return err
 	}
@@ -102,6 +105,13 @@ If a torrent does NOT has the old tracker, it will NOT be updated`,
 			return fmt.Errorf("abort")
 		}
 	}
+	journalEntry := journal.New(journal.OP_EDITTRACKER)
+	if err := journalEntry.AddTorrents(clientInstance, torrents); err != nil {
+		log.Warnf("Failed to record journal, the operation can not be fully undone: %v", err)
+	}
+	if err := journalEntry.Save(); err != nil {
+		return fmt.Errorf("failed to save journal: %w", err)
+	}
 	errorCnt := int64(0)
 	for i, torrent := range torrents {
 		fmt.Printf("(%d/%d) ", i+1, len(torrents))
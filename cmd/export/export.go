@@ -57,6 +57,7 @@ var (
 	category       = ""
 	tag            = ""
 	filter         = ""
+	where          = ""
 	downloadDir    = ""
 	rename         = ""
 )
@@ -69,6 +70,7 @@ func init() {
 	command.Flags().BoolVarP(&useCommentMeta, "use-comment-meta", "", false,
 		`Export torrent category, tags, save path and other infos to "comment" field of .torrent file`)
 	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&where, "where", "", "", constants.HELP_ARG_WHERE_TORRENT)
 	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
 	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
 	command.Flags().StringVarP(&downloadDir, "download-dir", "", ".", `Set the download dir of exported torrents. `+
@@ -82,7 +84,7 @@ func init() {
 func export(cmd *cobra.Command, args []string) error {
 	clientName := args[0]
 	infoHashes := args[1:]
-	if category == "" && tag == "" && filter == "" {
+	if category == "" && tag == "" && filter == "" && where == "" {
 		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
 			return err
 		} else {
@@ -101,7 +103,7 @@ func export(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
 
-	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, infoHashes...)
+	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, where, infoHashes...)
 	if err != nil {
 		return err
 	}
@@ -14,7 +14,6 @@ import (
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
-	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/helper"
@@ -92,9 +91,9 @@ func findalone(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
-	var savePathMapper *common.PathMapper
+	var savePathMapper *util.PathMapper
 	if len(mapSavePaths) > 0 {
-		savePathMapper, err = common.NewPathMapper(mapSavePaths)
+		savePathMapper, err = util.NewPathMapper(mapSavePaths)
 		if err != nil {
 			return fmt.Errorf("invalid map-save-path(s): %w", err)
 		}
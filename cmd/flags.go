@@ -1,11 +1,14 @@
 package cmd
 
 import (
+	"encoding/csv"
 	"errors"
 	"fmt"
 	"slices"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // an enum of string type
@@ -86,3 +89,24 @@ func AddEnumFlagP(command *cobra.Command, value *string, name string, shorthand
 		return vv.cobraCompletion(), cobra.ShellCompDirectiveDefault
 	})
 }
+
+// Reset all local (non-inherited) flags of command that have been set back to their default values.
+// It's used when executing multiple commands in the same process (e.g. daemon mode).
+func ResetFlags(command *cobra.Command) {
+	command.NonInheritedFlags().VisitAll(func(flag *pflag.Flag) {
+		if !flag.Changed {
+			return
+		}
+		if sv, ok := flag.Value.(pflag.SliceValue); ok {
+			// flag.DefValue is "[" + writeAsCSV(values) + "]"
+			var values []string
+			if defValue := strings.TrimSuffix(strings.TrimPrefix(flag.DefValue, "["), "]"); defValue != "" {
+				values, _ = csv.NewReader(strings.NewReader(defValue)).Read()
+			}
+			sv.Replace(values)
+		} else {
+			flag.Value.Set(flag.DefValue)
+		}
+		flag.Changed = false
+	})
+}
@@ -0,0 +1,152 @@
+package maketorrent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shibumi/go-pathspec"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/helper"
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+const (
+	BATCH_STATUS_OK      = "ok"
+	BATCH_STATUS_SKIPPED = "skipped" // output .torrent file already exists
+	BATCH_STATUS_ERROR   = "error"
+)
+
+// Result of making torrent for a top-level entry of save path in batch mode.
+type BatchResult struct {
+	Name        string
+	ContentPath string
+	Output      string
+	Status      string
+	Error       string `json:",omitempty"`
+	InfoHash    string `json:",omitempty"`
+	Size        int64
+}
+
+type BatchSummary struct {
+	SavePath string
+	Profile  string
+	Ts       int64
+	Total    int64
+	Ok       int64
+	Skipped  int64
+	Errors   int64
+	Results  []*BatchResult
+}
+
+// Parse a "site" or "site/name" profile, return the make torrent profile config.
+func getProfile(profile string) (*config.MakeTorrentProfileConfigStruct, error) {
+	siteName, name, _ := strings.Cut(profile, "/")
+	siteConfig := config.GetSiteConfig(siteName)
+	if siteConfig == nil {
+		return nil, fmt.Errorf("site %q not found", siteName)
+	}
+	profileConfig := siteConfig.GetMakeTorrentProfile(name)
+	if profileConfig == nil {
+		return nil, fmt.Errorf("make torrent profile %q not found in site %s config", name, siteName)
+	}
+	return profileConfig, nil
+}
+
+// Apply profile to make torrent options. Options set by flags take precedence.
+// The profile's Comment is a note of config; it's TorrentComment is the "comment" field of created torrent.
+func applyProfile(options *torrentutil.TorrentMakeOptions, profile *config.MakeTorrentProfileConfigStruct,
+	filenameLengthLimitSet bool) {
+	options.Trackers = util.UniqueSlice(append(slices.Clone(profile.Trackers), options.Trackers...))
+	options.Private = options.Private || profile.Private
+	if options.Comment == "" {
+		options.Comment = profile.TorrentComment
+	}
+	if options.Source == "" {
+		options.Source = profile.Source
+	}
+	options.Excludes = append(options.Excludes, profile.Excludes...)
+	if profile.FilenameLengthLimit != 0 && !filenameLengthLimitSet {
+		options.FilenameLengthLimit = profile.FilenameLengthLimit
+	}
+}
+
+// Return names of top-level entries (files or folders) of savePath that torrents will be made for.
+// Entries matching excludes (gitignore style) and .torrent files are skipped.
+func batchEntries(savePath string, excludes []string) (names []string, err error) {
+	entries, err := os.ReadDir(savePath)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasSuffix(strings.ToLower(name), ".torrent") {
+			continue
+		}
+		if len(excludes) > 0 {
+			if ignore, _ := pathspec.GitIgnore(excludes, name); ignore {
+				log.Debugf("Ignore %s", name)
+				continue
+			}
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// Make a torrent for each top-level entry of savePath, output to "{outputDir}/{name}.torrent".
+// options is the template of all made torrents.
+func makeBatch(savePath string, outputDir string, options *torrentutil.TorrentMakeOptions) (*BatchSummary, error) {
+	excludes := options.Excludes
+	if !options.All {
+		excludes = append(slices.Clone(excludes), constants.DefaultIgnorePatterns...)
+	}
+	names, err := batchEntries(savePath, excludes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read save path: %w", err)
+	}
+	summary := &BatchSummary{SavePath: savePath, Ts: time.Now().Unix()}
+	for _, name := range names {
+		result := &BatchResult{
+			Name:        name,
+			ContentPath: filepath.Join(savePath, name),
+			Output:      filepath.Join(outputDir, name+".torrent"),
+		}
+		summary.Results = append(summary.Results, result)
+		summary.Total++
+		if !options.Force && util.FileExists(result.Output) {
+			log.Warnf("Skip %q: output file %q already exists", name, result.Output)
+			result.Status = BATCH_STATUS_SKIPPED
+			summary.Skipped++
+			continue
+		}
+		entryOptions := *options
+		entryOptions.ContentPath = result.ContentPath
+		entryOptions.Output = result.Output
+		entryOptions.Excludes = slices.Clone(options.Excludes)
+		entryOptions.Trackers = slices.Clone(options.Trackers)
+		progress := helper.NewProgress(name)
+		entryOptions.Progress = progress.Update
+		tinfo, err := torrentutil.MakeTorrent(&entryOptions)
+		progress.Done()
+		if err != nil {
+			log.Errorf("Failed to make torrent for %q: %v", name, err)
+			result.Status = BATCH_STATUS_ERROR
+			result.Error = err.Error()
+			summary.Errors++
+			continue
+		}
+		result.Status = BATCH_STATUS_OK
+		result.InfoHash = tinfo.InfoHash
+		result.Size = tinfo.Size
+		summary.Ok++
+	}
+	return summary, nil
+}
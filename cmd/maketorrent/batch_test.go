@@ -0,0 +1,89 @@
+package maketorrent
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+func TestMakeBatch(t *testing.T) {
+	savePath := t.TempDir()
+	for name, size := range map[string]int{
+		"Release.A/a.mkv":   40000,
+		"Release.A/a.nfo":   100,
+		"Release.A/.hidden": 10,
+		"Release.B.mkv":     20000,
+		"Release.C/c.txt":   100, // all files excluded by profile
+		".DS_Store":         10,
+		"old.torrent":       10,
+	} {
+		filename := filepath.Join(savePath, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	profile := &config.MakeTorrentProfileConfigStruct{
+		Trackers:       []string{"https://tracker.example.com/announce"},
+		Private:        true,
+		Source:         "EXAMPLE",
+		TorrentComment: "comment",
+		Excludes:       []string{"*.txt"},
+	}
+	options := &torrentutil.TorrentMakeOptions{
+		PieceLengthStr: "16KiB",
+		Trackers:       []string{"https://tracker2.example.com/announce", "https://tracker.example.com/announce"},
+		Comment:        "my comment",
+	}
+	applyProfile(options, profile, false)
+	if !slices.Equal(options.Trackers, []string{"https://tracker.example.com/announce",
+		"https://tracker2.example.com/announce"}) || !options.Private || options.Comment != "my comment" ||
+		options.Source != "EXAMPLE" || !slices.Equal(options.Excludes, []string{"*.txt"}) {
+		t.Fatalf("applyProfile result unexpected: %+v", options)
+	}
+
+	outputDir := t.TempDir()
+	summary, err := makeBatch(savePath, outputDir, options)
+	if err != nil {
+		t.Fatalf("makeBatch error: %v", err)
+	}
+	if summary.Total != 3 || summary.Ok != 2 || summary.Errors != 1 || summary.Skipped != 0 {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+	for _, result := range summary.Results {
+		if result.Name == "Release.C" {
+			if result.Status != BATCH_STATUS_ERROR {
+				t.Errorf("Release.C status = %s, want error", result.Status)
+			}
+			continue
+		}
+		contents, err := os.ReadFile(result.Output)
+		if err != nil {
+			t.Fatalf("failed to read output of %s: %v", result.Name, err)
+		}
+		tinfo, err := torrentutil.ParseTorrent(contents)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Status != BATCH_STATUS_OK || tinfo.InfoHash != result.InfoHash || tinfo.Info.Source != "EXAMPLE" ||
+			!tinfo.IsPrivate() || len(tinfo.Trackers) != 2 {
+			t.Errorf("unexpected created torrent of %s: result=%+v, source=%q, trackers=%v", result.Name, result,
+				tinfo.Info.Source, tinfo.Trackers)
+		}
+		if result.Name == "Release.A" && len(tinfo.Files) != 2 {
+			t.Errorf("Release.A torrent has %d files, want 2", len(tinfo.Files))
+		}
+	}
+
+	// existing outputs are skipped
+	summary, err = makeBatch(savePath, outputDir, options)
+	if err != nil || summary.Skipped != 2 || summary.Errors != 1 {
+		t.Errorf("re-run makeBatch = %v, summary: %+v", err, summary)
+	}
+}
@@ -2,16 +2,20 @@
 package maketorrent
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/natefinch/atomic"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
 	"github.com/sagan/ptool/cmd"
 	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/helper"
 	"github.com/sagan/ptool/util/torrentutil"
 )
 
@@ -24,7 +28,17 @@ var command = &cobra.Command{
 By default, it saves created torrent to "{content-name}.torrent" file,
 where "{content-name}" is is folder or file name of "{content-path}".
 To manually set the output .torrent filename, use "--output" flag; set it to "-" to directly output to stdout.
-It creates BitTorrent v1 format torrent. V2 or hybrid format is NOT supported at this time.
+By default it creates BitTorrent v1 format torrent.
+Use "--v2" flag to create BitTorrent v2 (BEP 52) only torrent, or "--hybrid" flag to create hybrid (v1 + v2) torrent,
+which is compatible with both v1 and v2 clients. Hybrid torrent pads each file (except the last one) to piece boundary
+with BEP 47 padding files in v1 "files" list. The piece length of v2 / hybrid torrent must be a power of 2 and >= 16KiB.
+Set "--piece-length auto" to choose piece length automatically based on total contents size:
+the smallest power of 2 (16KiB - 16MiB) that makes the torrent have at most 2000 pieces.
+
+Pieces are hashed by multiple workers concurrently (the number of CPUs by default, set by "--workers" flag).
+To make a large torrent resumable, set "--checkpoint file" flag: the hashing state is periodically saved to that file,
+and if the making is interrupted, run the same command again to resume from it.
+The checkpoint file is deleted after the making completes.
 
 Examples:
   ptool maketorrent ./MyVideos # output: ./MyVideos.torrent
@@ -40,7 +54,19 @@ It's possible to provide your own customized exclude-pattern(s) using "--exclude
 
 If "--public" flag is set, ptool will add the following open trackers to created .torrent file:
   %s
-To create a private torrent (for uploading to Private Trackers site), set "--private" flag.`,
+To create a private torrent (for uploading to Private Trackers site), set "--private" flag.
+
+Use "--profile site" (or "--profile site/name") flag to apply a make torrent profile defined in the site config
+("makeTorrentProfiles"): trackers, private flag, "info.source" field, torrent comment ("torrentComment"), excludes
+and filename length limit. The "comment" of profile is only a note in config and is NOT written to torrent.
+Trackers and excludes of profile are merged with the ones set by flags; other flags take precedence over profile.
+
+Batch mode: if "--batch" flag is set, the arg is treated as a save path, and a torrent is created for each top-level
+entry (file or folder) of it, which is output to "{output-dir}/{entry-name}.torrent".
+Entries whose output .torrent file already exists are skipped, unless "--force" flag is set.
+A summary JSON of the batch (the result of each entry) is written to stdout, or to the file set by "--summary" flag.
+E.g.:
+  ptool maketorrent --batch ./Releases --profile mysite --output-dir ./torrents --summary summary.json`,
 		strings.Join(constants.DefaultIgnorePatterns, " ; "), strings.Join(constants.OpenTrackers, "\n  ")),
 	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
 	RunE: maketorrent,
@@ -51,9 +77,17 @@ var (
 	private                           = false
 	public                            = false
 	force                             = false
+	v2                                = false
+	hybrid                            = false
 	allowFilenameRestrictedCharacters = false
 	filenameLengthLimit               = int64(0)
+	workers                           = 0
 	pieceLengthStr                    = ""
+	checkpoint                        = ""
+	batch                             = false
+	profile                           = ""
+	outputDir                         = ""
+	summaryFile                       = ""
 	infoName                          = ""
 	comment                           = ""
 	output                            = ""
@@ -74,6 +108,8 @@ func init() {
 	command.Flags().BoolVarP(&public, "public", "P", false, `Mark created torrent as public (non-private), `+
 		`ptool will automatically add common pre-defined open trackers to it`)
 	command.Flags().BoolVarP(&force, "force", "", false, "Force overwrite existing output .torrent file in disk")
+	command.Flags().BoolVarP(&v2, "v2", "", false, "Create BitTorrent v2 only torrent")
+	command.Flags().BoolVarP(&hybrid, "hybrid", "", false, "Create hybrid (BitTorrent v1 + v2) torrent")
 	command.Flags().Int64VarP(&filenameLengthLimit, "filename-length-limit", "",
 		constants.TORRENT_CONTENT_FILENAME_LENGTH_LIMIT,
 		"By default, ptool refuses to make torrent which contents contain any file which name's length "+
@@ -81,9 +117,22 @@ func init() {
 			"Too long name files could cause problems when downloading / "+
 			"seeding the torrent. Set to -1 to lift the restriction")
 	command.Flags().StringVarP(&pieceLengthStr, "piece-length", "", constants.TORRENT_DEFAULT_PIECE_LENGTH,
-		`Set the piece length ("info"."piece length" field) of created .torrent`)
+		`Set the piece length ("info"."piece length" field) of created .torrent. `+
+			`Set to "`+constants.TORRENT_PIECE_LENGTH_AUTO+`" to choose it automatically based on contents size`)
+	command.Flags().IntVarP(&workers, "workers", "", 0,
+		"Number of concurrent pieces hashing workers. 0 means the number of CPUs")
+	command.Flags().StringVarP(&checkpoint, "checkpoint", "", "",
+		"Save pieces hashing state to this file periodically, and resume from it if it exists")
 	command.Flags().StringVarP(&output, "output", "", "", `Set the output .torrent filename. `+
 		`Use "-" to output to stdout`)
+	command.Flags().BoolVarP(&batch, "batch", "", false,
+		"Batch mode. Treat the arg as a save path and create a torrent for each top-level entry of it")
+	command.Flags().StringVarP(&profile, "profile", "", "",
+		`Apply the make torrent profile of site config. Format: "site" (the first profile) or "site/name"`)
+	command.Flags().StringVarP(&outputDir, "output-dir", "", ".",
+		`Used with "--batch". The dir to output created .torrent files`)
+	command.Flags().StringVarP(&summaryFile, "summary", "", "",
+		`Used with "--batch". Write the summary JSON to this file instead of stdout`)
 	command.Flags().StringVarP(&infoName, "info-name", "", "", `Manually set the "info.name" field of created torrent`)
 	command.Flags().StringVarP(&comment, "comment", "", "", `Set the "comment" field of created torrent`)
 	command.Flags().StringVarP(&createdBy, "created-by", "", "",
@@ -106,6 +155,12 @@ func maketorrent(cmd *cobra.Command, args []string) (err error) {
 	if private && public {
 		return fmt.Errorf("--private and --public flags are NOT compatible")
 	}
+	if v2 && hybrid {
+		return fmt.Errorf("--v2 and --hybrid flags are NOT compatible")
+	}
+	if batch && output != "" {
+		return fmt.Errorf("--batch and --output flags are NOT compatible")
+	}
 	contentPath, err := filepath.Abs(args[0])
 	if err != nil {
 		return fmt.Errorf("failed to get abs path of %q: %w", args[0], err)
@@ -127,13 +182,51 @@ func maketorrent(cmd *cobra.Command, args []string) (err error) {
 		CreationDate:                  creationDate,
 		AllowRestrictedCharInFilename: allowFilenameRestrictedCharacters,
 		FilenameLengthLimit:           filenameLengthLimit,
+		V2:                            v2,
+		Hybrid:                        hybrid,
+		Workers:                       workers,
+		Checkpoint:                    checkpoint,
+	}
+	if profile != "" {
+		profileConfig, err := getProfile(profile)
+		if err != nil {
+			return fmt.Errorf("invalid profile: %w", err)
+		}
+		applyProfile(optoins, profileConfig, cmd.Flags().Changed("filename-length-limit"))
 	}
 	if len(optoins.Trackers) == 0 && !optoins.Public {
 		log.Warnf(`Warning: the created .torrent file will NOT have any trackers. ` +
 			`Use "--tracker" flag to add a tracker; ` +
 			`For public (non-private) torrent, use "--public" to add pre-defined open trackers`)
 	}
+	if batch {
+		summary, err := makeBatch(contentPath, outputDir, optoins)
+		if err != nil {
+			return err
+		}
+		summary.Profile = profile
+		fmt.Fprintf(os.Stderr, "\nBatch done: %d entries, %d ok, %d skipped, %d errors\n",
+			summary.Total, summary.Ok, summary.Skipped, summary.Errors)
+		if summaryFile == "" {
+			err = util.PrintJson(os.Stdout, summary)
+		} else {
+			buf := &bytes.Buffer{}
+			if err = util.PrintJson(buf, summary); err == nil {
+				err = atomic.WriteFile(summaryFile, buf)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write summary: %w", err)
+		}
+		if summary.Errors > 0 {
+			return fmt.Errorf("%d errors", summary.Errors)
+		}
+		return nil
+	}
+	progress := helper.NewProgress(filepath.Base(contentPath))
+	optoins.Progress = progress.Update
 	tinfo, err := torrentutil.MakeTorrent(optoins)
+	progress.Done()
 	if err != nil {
 		return err
 	}
@@ -50,11 +50,13 @@ var (
 	category  = ""
 	tag       = ""
 	filter    = ""
+	where     = ""
 	tagPrefix = ""
 )
 
 func init() {
 	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&where, "where", "", "", constants.HELP_ARG_WHERE_TORRENT)
 	command.Flags().StringVarP(&tagPrefix, "tag-prefix", "", config.INVALID_TRACKER_TAG_PREFIX,
 		"Mark found invalid tracker with tags of this prefix")
 	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
@@ -67,7 +69,7 @@ func init() {
 func markinvalidtracker(cmd *cobra.Command, args []string) error {
 	clientName := args[0]
 	infoHashes := args[1:]
-	if category == "" && tag == "" && filter == "" {
+	if category == "" && tag == "" && filter == "" && where == "" {
 		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
 			return err
 		} else {
@@ -84,7 +86,7 @@ func markinvalidtracker(cmd *cobra.Command, args []string) error {
 	if trClient, ok := clientInstance.(*transmission.Client); ok {
 		trClient.Sync(true)
 	}
-	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, infoHashes...)
+	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, where, infoHashes...)
 	if err != nil {
 		return fmt.Errorf("failed to query client torrents: %w", err)
 	}
@@ -45,6 +45,7 @@ var (
 	category         = ""
 	tag              = ""
 	filter           = ""
+	where            = ""
 	setCategory      = ""
 	setSavePath      = ""
 	addTags          = ""
@@ -53,13 +54,14 @@ var (
 
 func init() {
 	command.Flags().Int64VarP(&seedingTimeLimit, "seeding-time-limit", "", 0,
-		`(qBittorrent only) If != 0, set seeding time share limit of torrents. `+
+		`If != 0, set seeding time share limit of torrents. `+
 			`Positive value: the max amount of time (seconds) the torrent should be seeded. `+
 			`Negative value has special meaning`)
 	command.Flags().Float64VarP(&ratioLimit, "ratio-limit", "", 0,
-		`(qBittorrent only) If != 0, set ratio share limit of torrents. `+
+		`If != 0, set ratio share limit of torrents. `+
 			`Positive value: the max ratio (Up/Dl) the torrent should be seeded until. Negative value has special meaning`)
 	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&where, "where", "", "", constants.HELP_ARG_WHERE_TORRENT)
 	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
 	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
 	command.Flags().StringVarP(&setCategory, "set-category", "", "", `Modify category of torrents. `+
@@ -76,7 +78,7 @@ func modifytorrent(cmd *cobra.Command, args []string) error {
 	}
 	clientName := args[0]
 	infoHashes := args[1:]
-	if category == "" && tag == "" && filter == "" {
+	if category == "" && tag == "" && filter == "" && where == "" {
 		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
 			return err
 		} else {
@@ -90,7 +92,7 @@ func modifytorrent(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
 
-	infoHashes, err = client.SelectTorrents(clientInstance, category, tag, filter, infoHashes...)
+	infoHashes, err = client.SelectTorrents(clientInstance, category, tag, filter, where, infoHashes...)
 	if err != nil {
 		return err
 	}
@@ -16,6 +16,7 @@ import (
 	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/journal"
 	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/helper"
 	"github.com/sagan/ptool/util/torrentutil"
@@ -94,9 +95,9 @@ func movesavepath(cmd *cobra.Command, args []string) (err error) {
 	if newSavePathStat, err := os.Stat(newSavePath); err != nil || !newSavePathStat.IsDir() {
 		return fmt.Errorf("new-save-path does not exists or is not dir (err: %w)", err)
 	}
-	var savePathMapper *common.PathMapper
+	var savePathMapper *util.PathMapper
 	if len(mapSavePaths) > 0 {
-		savePathMapper, err = common.NewPathMapper(mapSavePaths)
+		savePathMapper, err = util.NewPathMapper(mapSavePaths)
 		if err != nil {
 			return fmt.Errorf("invalid map-save-path(s): %w", err)
 		}
@@ -194,6 +195,13 @@ If any error happens, you can safely re-run the same command to re-try and resum
 		}
 	}
 
+	journalEntry := journal.New(journal.OP_MOVESAVEPATH)
+	if err := journalEntry.AddTorrents(clientInstance, clientTorrents); err != nil {
+		log.Warnf("Failed to record journal, the operation can not be fully undone: %v", err)
+	}
+	if err := journalEntry.Save(); err != nil {
+		return fmt.Errorf("failed to save journal: %w", err)
+	}
 	var clientTorrentInfoHashes []string
 	for _, torrent := range clientTorrents {
 		exportFilename := filepath.Join(tmppath, torrent.InfoHash+".torrent")
@@ -36,8 +36,9 @@ which includes all fields of "TorrentMeta" type:
 
 // https://github.com/sagan/ptool/blob/master/util/torrentutil/torrent.go
 type TorrentMeta struct {
-	InfoHash          string
-	PiecesHash        string // sha1(torrent.info.pieces)
+	InfoHash          string // v1 info-hash. For v2-only torrent, it's the truncated (first 20 bytes) v2 info-hash
+	InfoHashV2        string // v2 (SHA-256) info-hash. Empty for v1-only torrent
+	PiecesHash        string // sha1(torrent.info.pieces). For v2-only torrent, sha1 of all files' "pieces root"
 	Trackers          []string
 	Size              int64
 	SingleFileTorrent bool
@@ -48,9 +49,12 @@ type TorrentMeta struct {
 	Info              *metainfo.Info     // always non-nil in a parsed *TorrentMeta
 }
 
+BitTorrent v2 and hybrid (v1 + v2) torrents are supported.
+
 Some additionally fields are also available:
 - Index : number. current torrent index
 - Torrent : string. current torrent filename.
+- Version : string. BitTorrent protocol version of torrent: "v1", "v2" or "hybrid".
 
 The template render result will be trim spaced.
 If the renderring throws any error, the torrent will be treated as fail.
@@ -95,7 +99,7 @@ func init() {
 	command.Flags().BoolVarP(&dedupe, "dedupe", "", false,
 		"Treat duplicate torrent (has the same info-hash as previous parsed torrent) as fail (error)")
 	command.Flags().BoolVarP(&showAll, "all", "a", false, "Show all info")
-	command.Flags().BoolVarP(&showInfoHashOnly, "show-info-hash-only", "", false, "Output torrents info hash only")
+	command.Flags().BoolVarP(&showInfoHashOnly, "show-info-hash-only", "", false, "Output torrents info hash only (also output v2 info hash for v2 / hybrid torrent)")
 	command.Flags().BoolVarP(&showJson, "json", "", false, "Show output in json format")
 	command.Flags().BoolVarP(&forceLocal, "force-local", "", false, "Force treat all arg as local torrent filename")
 	command.Flags().BoolVarP(&showSum, "sum", "", false, "Show torrents summary only")
@@ -155,6 +159,9 @@ func parsetorrent(cmd *cobra.Command, args []string) error {
 			statistics.UpdateTinfo(common.TORRENT_INVALID, nil)
 		} else {
 			_, exists := parsedTorrents[tinfo.InfoHash]
+			if tinfo.InfoHashV2 != "" && !exists {
+				_, exists = parsedTorrents[tinfo.InfoHashV2]
+			}
 			if minTorrentSize >= 0 && tinfo.Size < minTorrentSize {
 				err = fmt.Errorf("torrent is too small: %s (%d)", util.BytesSize(float64(tinfo.Size)), tinfo.Size)
 			} else if maxTorrentSize >= 0 && tinfo.Size > maxTorrentSize {
@@ -173,6 +180,7 @@ func parsetorrent(cmd *cobra.Command, args []string) error {
 				data := util.StructToMap(*tinfo, false, false)
 				data["Index"] = i
 				data["Torrent"] = torrent
+				data["Version"] = tinfo.Version()
 				if err = outputTemplate.Execute(buf, data); err != nil {
 					err = fmt.Errorf("failed to render torrent %v: %v", tinfo, err)
 				} else {
@@ -201,6 +209,9 @@ func parsetorrent(cmd *cobra.Command, args []string) error {
 			continue
 		}
 		parsedTorrents[tinfo.InfoHash] = struct{}{}
+		if tinfo.InfoHashV2 != "" {
+			parsedTorrents[tinfo.InfoHashV2] = struct{}{}
+		}
 		statistics.UpdateTinfo(common.TORRENT_SUCCESS, tinfo)
 		if showSum {
 			continue
@@ -212,7 +223,13 @@ func parsetorrent(cmd *cobra.Command, args []string) error {
 			}
 			continue
 		} else if showInfoHashOnly {
-			fmt.Printf("%s\n", tinfo.InfoHash)
+			if tinfo.InfoHashV2 != "" && tinfo.Info.HasV1() {
+				fmt.Printf("%s %s\n", tinfo.InfoHash, tinfo.InfoHashV2)
+			} else if tinfo.InfoHashV2 != "" {
+				fmt.Printf("%s\n", tinfo.InfoHashV2)
+			} else {
+				fmt.Printf("%s\n", tinfo.InfoHash)
+			}
 			continue
 		} else if outputTemplate != nil {
 			fmt.Println(customOutput)
@@ -0,0 +1,128 @@
+package prune
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/util/expr"
+)
+
+// Default value score of a torrent: recent uploaded per GiB of size, plus a bonus for scarcity (few seeders),
+// minus a small penalty for age. Lower score torrents are deleted first.
+const DEFAULT_SCORE = "recentUploaded / max(size, 1GiB) + 1 / (seeders + 1) - age / 1y"
+
+// Reasons of torrent being protected from pruning.
+const (
+	PROTECT_NODEL = "nodel"
+	PROTECT_HNR   = "hnr"
+	PROTECT_XSEED = "xseed"
+)
+
+type PlanOption struct {
+	Now            int64
+	Window         int64            // seconds. The period of "recentUploaded"
+	RecentUploaded map[string]int64 // infoHash => uploaded in window, from stats. Not sampled torrents are estimated
+	HnRSeedingTime int64            // seconds. Minimal seeding time of HnR torrents
+	IsHnR          func(torrent *client.Torrent) bool
+	Score          *expr.Program // nil: use DEFAULT_SCORE
+	Free           int64         // target size to free
+}
+
+type Candidate struct {
+	Torrent        *client.Torrent
+	RecentUploaded int64
+	Score          float64
+}
+
+type Plan struct {
+	Candidates []*Candidate     // all deletable torrents, least valuable first
+	Selected   []*Candidate     // torrents to delete, a prefix of Candidates
+	Protected  map[string]int64 // reason => count of protected torrents
+	Size       int64            // total size of selected torrents
+}
+
+// Return true if torrent has a HnR (hit and run) requirement: the "_hr" tag, or it's site is "globalHnR".
+func IsHnR(torrent *client.Torrent) bool {
+	if torrent.HasTag(config.HR_TAG) {
+		return true
+	}
+	siteConfig := config.GetSiteConfig(torrent.GetSiteFromTag())
+	return siteConfig != nil && siteConfig.GlobalHnR
+}
+
+// Rank the seeding torrents of client by value, and select the least valuable ones until their total size
+// reaches option.Free. Incomplete torrents are ignored. Protected torrents are never selected.
+func MakePlan(clientInstance client.Client, torrents []*client.Torrent, option *PlanOption) (*Plan, error) {
+	scoreProgram := option.Score
+	if scoreProgram == nil {
+		scoreProgram = expr.MustCompile(DEFAULT_SCORE)
+	}
+	isHnR := option.IsHnR
+	if isHnR == nil {
+		isHnR = func(torrent *client.Torrent) bool { return torrent.HasTag(config.HR_TAG) }
+	}
+	plan := &Plan{Protected: map[string]int64{}}
+	// contentPath => whether it's shared by multiple torrents. Query client only once per content path
+	xseedContentPaths := map[string]bool{}
+	for _, torrent := range torrents {
+		if !torrent.IsComplete() {
+			continue
+		}
+		if torrent.HasTag(config.TORRENT_NODEL_TAG) || torrent.HasTag(config.NODEL_TAG) {
+			plan.Protected[PROTECT_NODEL]++
+			continue
+		}
+		if isHnR(torrent) && (torrent.Ctime <= 0 || option.Now-torrent.Ctime < option.HnRSeedingTime) {
+			plan.Protected[PROTECT_HNR]++
+			continue
+		}
+		xseed, ok := xseedContentPaths[torrent.ContentPath]
+		if !ok {
+			_, torrentsXseed, err := client.FilterTorrentsXseed(clientInstance, []*client.Torrent{torrent})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get xseed torrents of %s: %w", torrent.InfoHash, err)
+			}
+			xseed = len(torrentsXseed) > 0
+			xseedContentPaths[torrent.ContentPath] = xseed
+		}
+		if xseed {
+			plan.Protected[PROTECT_XSEED]++
+			continue
+		}
+		candidate := &Candidate{Torrent: torrent}
+		if recentUploaded, ok := option.RecentUploaded[torrent.InfoHash]; ok {
+			candidate.RecentUploaded = recentUploaded
+		} else {
+			candidate.RecentUploaded = estimateRecentUploaded(torrent, option.Now, option.Window)
+		}
+		env := torrent.WhereEnv(option.Now)
+		env["recentUploaded"] = candidate.RecentUploaded
+		var err error
+		if candidate.Score, err = scoreProgram.EvalNumber(env); err != nil {
+			return nil, fmt.Errorf("failed to calculate score of %s: %w", torrent.InfoHash, err)
+		}
+		plan.Candidates = append(plan.Candidates, candidate)
+	}
+	sort.SliceStable(plan.Candidates, func(i, j int) bool {
+		return plan.Candidates[i].Score < plan.Candidates[j].Score
+	})
+	for _, candidate := range plan.Candidates {
+		if plan.Size >= option.Free {
+			break
+		}
+		plan.Selected = append(plan.Selected, candidate)
+		plan.Size += candidate.Torrent.Size
+	}
+	return plan, nil
+}
+
+// Without stats samples, assume the torrent uploaded evenly over it's lifetime.
+func estimateRecentUploaded(torrent *client.Torrent, now int64, window int64) int64 {
+	age := now - torrent.Atime
+	if age <= window || age <= 0 {
+		return torrent.Uploaded
+	}
+	return int64(float64(torrent.Uploaded) * float64(window) / float64(age))
+}
@@ -0,0 +1,101 @@
+package prune
+
+import (
+	"testing"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/client/clienttest"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/util/expr"
+)
+
+func TestMakePlan(t *testing.T) {
+	const now = int64(1700000000)
+	const day = int64(86400)
+	gib := int64(1 << 30)
+	torrents := []*client.Torrent{
+		// uploads a lot recently
+		{InfoHash: "hot", Size: 10 * gib, State: "seeding", ContentPath: "/d/hot", Atime: now - 100*day,
+			Ctime: now - 100*day, Seeders: 10},
+		// uploads nothing and has many seeders: least valuable
+		{InfoHash: "cold", Size: 20 * gib, State: "seeding", ContentPath: "/d/cold", Atime: now - 300*day,
+			Ctime: now - 300*day, Seeders: 50},
+		// somewhat valuable
+		{InfoHash: "warm", Size: 5 * gib, State: "completed", ContentPath: "/d/warm", Atime: now - 200*day,
+			Ctime: now - 200*day, Seeders: 5},
+		{InfoHash: "nodel", Size: 5 * gib, State: "seeding", ContentPath: "/d/nodel", Atime: now - 300*day,
+			Ctime: now - 300*day, Tags: []string{config.TORRENT_NODEL_TAG}},
+		// HnR requirement not met yet
+		{InfoHash: "hr", Size: 5 * gib, State: "seeding", ContentPath: "/d/hr", Atime: now - 3*day,
+			Ctime: now - 3*day, Tags: []string{config.HR_TAG}},
+		// share content with xseed2
+		{InfoHash: "xseed1", Size: 5 * gib, State: "seeding", ContentPath: "/d/xseed", Atime: now - 300*day,
+			Ctime: now - 300*day},
+		{InfoHash: "xseed2", Size: 5 * gib, State: "seeding", ContentPath: "/d/xseed", Atime: now - 300*day,
+			Ctime: now - 300*day},
+		{InfoHash: "downloading", Size: 5 * gib, State: "downloading", ContentPath: "/d/downloading"},
+	}
+	for _, torrent := range torrents {
+		if torrent.State != "downloading" {
+			torrent.SizeCompleted = torrent.Size
+		}
+	}
+	clientInstance := clienttest.New(torrents...)
+	option := &PlanOption{
+		Now:    now,
+		Window: 30 * day,
+		// "cold" is not sampled, it's estimated from it's total uploaded (0)
+		RecentUploaded: map[string]int64{"hot": 100 * gib, "warm": gib},
+		HnRSeedingTime: 7 * day,
+		Free:           22 * gib,
+	}
+	plan, err := MakePlan(clientInstance, torrents, option)
+	if err != nil {
+		t.Fatalf("MakePlan error: %v", err)
+	}
+	if len(plan.Candidates) != 3 {
+		t.Fatalf("MakePlan() = %d candidates, want 3", len(plan.Candidates))
+	}
+	if plan.Protected[PROTECT_NODEL] != 1 || plan.Protected[PROTECT_HNR] != 1 || plan.Protected[PROTECT_XSEED] != 2 {
+		t.Errorf("unexpected protected: %v", plan.Protected)
+	}
+	if len(plan.Selected) != 2 || plan.Selected[0].Torrent.InfoHash != "cold" ||
+		plan.Selected[1].Torrent.InfoHash != "warm" || plan.Size != 25*gib {
+		t.Errorf("unexpected selected: %d torrents, size %d", len(plan.Selected), plan.Size)
+	}
+
+	option.Score = expr.MustCompile("-size")
+	option.Free = gib
+	if plan, err = MakePlan(clientInstance, torrents, option); err != nil {
+		t.Fatalf("MakePlan error: %v", err)
+	}
+	if len(plan.Selected) != 1 || plan.Selected[0].Torrent.InfoHash != "cold" {
+		t.Errorf("unexpected selected with custom score: %v", plan.Selected)
+	}
+
+	// a not sampled torrent which uploaded a lot is estimated as valuable
+	torrents[1].Uploaded = 1000 * gib
+	option.Score = nil
+	option.Free = 22 * gib
+	if plan, err = MakePlan(clientInstance, torrents, option); err != nil {
+		t.Fatalf("MakePlan error: %v", err)
+	}
+	for _, candidate := range plan.Candidates {
+		if candidate.Torrent.InfoHash == "cold" && candidate.RecentUploaded != 100*gib {
+			t.Errorf("estimated recentUploaded of cold = %d, want %d", candidate.RecentUploaded, 100*gib)
+		}
+	}
+	if len(plan.Selected) == 0 || plan.Selected[0].Torrent.InfoHash != "warm" {
+		t.Errorf("unexpected selected with estimated recentUploaded: %v", plan.Selected)
+	}
+}
+
+func TestEstimateRecentUploaded(t *testing.T) {
+	torrent := &client.Torrent{Atime: 1000, Uploaded: 1000}
+	if got := estimateRecentUploaded(torrent, 2000, 100); got != 100 {
+		t.Errorf("estimateRecentUploaded() = %d, want 100", got)
+	}
+	if got := estimateRecentUploaded(torrent, 1050, 100); got != 1000 {
+		t.Errorf("estimateRecentUploaded() = %d, want 1000", got)
+	}
+}
@@ -0,0 +1,172 @@
+package prune
+
+import (
+	"fmt"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/journal"
+	"github.com/sagan/ptool/stats"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/expr"
+)
+
+var command = &cobra.Command{
+	Use:         "prune {client} --free size [--yes]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "prune"},
+	Short:       "Delete the least valuable seeding torrents of client to free disk space.",
+	Long: fmt.Sprintf(`Delete the least valuable seeding torrents of client to free disk space.
+
+It ranks all complete torrents of client by a value score, then selects the least valuable ones
+until their total size reaches the --free target. By default it only shows the plan,
+use --yes flag to actually delete selected torrents (with their content files).
+
+The following torrents are protected and will never be deleted:
+  - torrents with %q tag.
+  - HnR torrents (with %q tag, or of a site with "globalHnR = true") which are seeded less than --hnr-seeding-time.
+  - torrents that share content files with other (xseed) torrents in client.
+
+The score is calculated by the --score expression (same syntax as "--where" flag), default:
+  %s
+Besides all the "--where" variables of torrent, "recentUploaded" variable is the uploaded size
+in recent --days days. It's read from statistics samples if "brushEnableStats = true" is set in config file
+and the torrent was sampled, otherwise it's estimated assuming torrent uploads evenly over it's lifetime.
+
+The deletion is recorded to journal, but the trash mode of client is NOT used, as the purpose is freeing space.`,
+		config.TORRENT_NODEL_TAG, config.HR_TAG, DEFAULT_SCORE),
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: prune,
+}
+
+var (
+	yes               = false
+	noStats           = false
+	freeStr           = ""
+	days              = int64(0)
+	hnrSeedingTimeStr = ""
+	score             = ""
+	filter            = ""
+	where             = ""
+	category          = ""
+	tag               = ""
+)
+
+func init() {
+	command.Flags().BoolVarP(&yes, "yes", "y", false,
+		"Actually delete the selected torrents. Otherwise only show the plan")
+	command.Flags().BoolVarP(&noStats, "no-stats", "", false,
+		`Do not read "recentUploaded" from statistics samples, always estimate it`)
+	command.Flags().StringVarP(&freeStr, "free", "", "", `Required. Target size to free. E.g. "500GiB"`)
+	command.Flags().Int64VarP(&days, "days", "", 30, `The period (days) of "recentUploaded" variable`)
+	command.Flags().StringVarP(&hnrSeedingTimeStr, "hnr-seeding-time", "", "7d",
+		"Minimal seeding time of HnR torrents. Those seeded less than this are protected")
+	command.Flags().StringVarP(&score, "score", "", "",
+		`Score expression of torrent value. Lower score torrents are deleted first. Default: "`+DEFAULT_SCORE+`"`)
+	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&where, "where", "", "", constants.HELP_ARG_WHERE_TORRENT)
+	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
+	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
+	command.MarkFlagRequired("free")
+	cmd.RootCmd.AddCommand(command)
+}
+
+func prune(cmd *cobra.Command, args []string) error {
+	free, err := util.RAMInBytes(freeStr)
+	if err != nil || free <= 0 {
+		return fmt.Errorf("invalid free: %q", freeStr)
+	}
+	if days <= 0 {
+		return fmt.Errorf("invalid days: %d", days)
+	}
+	hnrSeedingTime, err := util.ParseTimeDuration(hnrSeedingTimeStr)
+	if err != nil {
+		return fmt.Errorf("invalid hnr-seeding-time: %w", err)
+	}
+	option := &PlanOption{
+		Now:            util.Now(),
+		Window:         days * 86400,
+		HnRSeedingTime: hnrSeedingTime,
+		IsHnR:          IsHnR,
+		Free:           free,
+	}
+	if score != "" {
+		if option.Score, err = expr.Compile(score); err != nil {
+			return fmt.Errorf("invalid score: %w", err)
+		}
+	}
+	clientInstance, err := client.CreateClient(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	if config.Get().BrushEnableStats && !noStats {
+		statDb, err := stats.NewDb(filepath.Join(config.ConfigDir, config.STATS_FILENAME))
+		if err != nil {
+			return fmt.Errorf("failed to create stats db: %w", err)
+		}
+		recentUploaded, err := statDb.GetTorrentsUploadedSince(clientInstance.GetName(), option.Now-option.Window)
+		if err != nil {
+			return fmt.Errorf("failed to read stats samples: %w", err)
+		}
+		if len(recentUploaded) > 0 {
+			option.RecentUploaded = recentUploaded
+		} else {
+			log.Warnf("No stats samples of client found, recentUploaded will be estimated")
+		}
+	}
+	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, where)
+	if err != nil {
+		return fmt.Errorf("failed to get torrents: %w", err)
+	}
+	plan, err := MakePlan(clientInstance, torrents, option)
+	if err != nil {
+		return err
+	}
+	printPlan(plan, option)
+	if len(plan.Selected) == 0 {
+		return nil
+	}
+	if !yes {
+		fmt.Printf("Plan only. Use --yes flag to actually delete above torrents\n")
+		return nil
+	}
+	selectedTorrents := util.Map(plan.Selected, func(c *Candidate) *client.Torrent { return c.Torrent })
+	journalEntry := journal.New(journal.OP_DELETE)
+	journalEntry.DeleteFiles = true
+	if err := journalEntry.AddTorrents(clientInstance, selectedTorrents); err != nil {
+		log.Warnf("Failed to record journal, the deletion can not be fully undone: %v", err)
+	}
+	if err := journalEntry.Save(); err != nil {
+		return fmt.Errorf("failed to save journal: %w", err)
+	}
+	infoHashes := util.Map(selectedTorrents, func(t *client.Torrent) string { return t.InfoHash })
+	if err := clientInstance.DeleteTorrents(infoHashes, true); err != nil {
+		return fmt.Errorf("failed to delete torrents: %w", err)
+	}
+	fmt.Printf("%d torrents deleted, %s freed\n", len(infoHashes), util.BytesSize(float64(plan.Size)))
+	return nil
+}
+
+func printPlan(plan *Plan, option *PlanOption) {
+	fmt.Printf("%-40s  %10s  %10s  %10s  %8s  %10s  %s\n",
+		"InfoHash", "Score", "Size", "RecentUp", "Seeders", "Age", "Name")
+	for _, candidate := range plan.Selected {
+		torrent := candidate.Torrent
+		name, _ := util.StringPrefixInWidth(torrent.Name, 40)
+		fmt.Printf("%-40s  %10.4f  %10s  %10s  %8d  %10s  %s\n", torrent.InfoHash, candidate.Score,
+			util.BytesSize(float64(torrent.Size)), util.BytesSize(float64(candidate.RecentUploaded)),
+			torrent.Seeders, util.FormatDuration(option.Now-torrent.Atime), name)
+	}
+	fmt.Printf("\n%d / %d deletable torrents selected, total size %s (target %s). "+
+		"Protected: nodel=%d, hnr=%d, xseed=%d\n", len(plan.Selected), len(plan.Candidates),
+		util.BytesSize(float64(plan.Size)), util.BytesSize(float64(option.Free)),
+		plan.Protected[PROTECT_NODEL], plan.Protected[PROTECT_HNR], plan.Protected[PROTECT_XSEED])
+	if plan.Size < option.Free {
+		fmt.Printf("Warning: not enough deletable torrents to reach the target\n")
+	}
+}
@@ -18,7 +18,6 @@ import (
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
-	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/site"
@@ -174,9 +173,9 @@ func publish(cmd *cobra.Command, args []string) (err error) {
 	mustTags := util.SplitCsv(mustTag)
 	metaArrayKeys := util.SplitCsv(metaArrayKeysStr)
 
-	var savePathMapper *common.PathMapper
+	var savePathMapper *util.PathMapper
 	if len(mapSavePaths) > 0 {
-		savePathMapper, err = common.NewPathMapper(mapSavePaths)
+		savePathMapper, err = util.NewPathMapper(mapSavePaths)
 		if err != nil {
 			return fmt.Errorf("invalid map-save-path(s): %w", err)
 		}
@@ -329,7 +328,7 @@ func parseMetadataFile(metadataFile string, arrayKeys []string) (metadata url.Va
 }
 
 func publicTorrent(siteInstance site.Site, clientInstance client.Client, contentPath string, otherFields url.Values,
-	mustMetadataFile bool, checkExisting bool, savePathMapper *common.PathMapper, minTorrentSize int64,
+	mustMetadataFile bool, checkExisting bool, savePathMapper *util.PathMapper, minTorrentSize int64,
 	imageFiles []string, moveOk string, dryRun bool, mustTags []string, metaArrayKeys []string,
 	addCategory string, addTags []string) (
 	id string, tinfo *torrentutil.TorrentMeta, err error) {
@@ -576,7 +575,7 @@ func publicTorrent(siteInstance site.Site, clientInstance client.Client, content
 
 // Download published torrent to local, optionaly add it to local client to start seeding.
 func downloadPublishedTorrent(siteInstance site.Site, clientInstance client.Client,
-	contentPath string, savePathMapper *common.PathMapper, addCategory string, addTags []string) (err error) {
+	contentPath string, savePathMapper *util.PathMapper, addCategory string, addTags []string) (err error) {
 	sitename := siteInstance.GetName()
 	torrentFilename := filepath.Join(contentPath, fmt.Sprintf(PUBLISHED_TORRENT_FILENAME, sitename))
 	var torrentContents []byte
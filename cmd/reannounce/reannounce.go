@@ -16,7 +16,8 @@ var command = &cobra.Command{
 	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "reannounce"},
 	Short:       "Reannounce torrents of client.",
 	Long: fmt.Sprintf(`Reannounce torrents of client.
-%s.`, constants.HELP_INFOHASH_ARGS),
+%s.
+%s.`, constants.HELP_CLIENT_GROUP_ARG, constants.HELP_INFOHASH_ARGS),
 	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
 	RunE: reannounce,
 }
@@ -25,10 +26,12 @@ var (
 	category = ""
 	tag      = ""
 	filter   = ""
+	where    = ""
 )
 
 func init() {
 	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&where, "where", "", "", constants.HELP_ARG_WHERE_TORRENT)
 	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
 	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
 	cmd.RootCmd.AddCommand(command)
@@ -37,32 +40,33 @@ func init() {
 func reannounce(cmd *cobra.Command, args []string) error {
 	clientName := args[0]
 	infoHashes := args[1:]
-	if category == "" && tag == "" && filter == "" {
+	if category == "" && tag == "" && filter == "" && where == "" {
 		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
 			return err
 		} else {
 			infoHashes = _infoHashes
 		}
 	}
-	clientInstance, err := client.CreateClient(clientName)
-	if err != nil {
-		return fmt.Errorf("failed to create client: %w", err)
-	}
-
-	infoHashes, err = client.SelectTorrents(clientInstance, category, tag, filter, infoHashes...)
+	clientInstances, err := client.CreateClientsFromArg(clientName)
 	if err != nil {
 		return err
 	}
-	if infoHashes == nil {
-		err = clientInstance.ReannounceAllTorrents()
+	return client.RunOnClients(clientInstances, func(clientInstance client.Client) error {
+		infoHashes, err := client.SelectTorrents(clientInstance, category, tag, filter, where, infoHashes...)
 		if err != nil {
 			return err
 		}
-	} else if len(infoHashes) > 0 {
-		err = clientInstance.ReannounceTorrents(infoHashes)
-		if err != nil {
-			return err
+		if infoHashes == nil {
+			err = clientInstance.ReannounceAllTorrents()
+			if err != nil {
+				return err
+			}
+		} else if len(infoHashes) > 0 {
+			err = clientInstance.ReannounceTorrents(infoHashes)
+			if err != nil {
+				return err
+			}
 		}
-	}
-	return nil
+		return nil
+	})
 }
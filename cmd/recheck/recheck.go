@@ -18,7 +18,8 @@ var command = &cobra.Command{
 	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "recheck"},
 	Short:       "Recheck torrents of client.",
 	Long: fmt.Sprintf(`Recheck torrents of client.
-%s.`, constants.HELP_INFOHASH_ARGS),
+%s.
+%s.`, constants.HELP_CLIENT_GROUP_ARG, constants.HELP_INFOHASH_ARGS),
 	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
 	RunE: recheck,
 }
@@ -27,11 +28,13 @@ var (
 	category = ""
 	tag      = ""
 	filter   = ""
+	where    = ""
 	force    = false
 )
 
 func init() {
 	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&where, "where", "", "", constants.HELP_ARG_WHERE_TORRENT)
 	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
 	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
 	command.Flags().BoolVarP(&force, "force", "", false, "Do recheck torrents without asking for confirm")
@@ -42,7 +45,7 @@ func recheck(cmd *cobra.Command, args []string) error {
 	clientName := args[0]
 	infoHashes := args[1:]
 	infohashesOnly := true
-	if category != "" || tag != "" || filter != "" {
+	if category != "" || tag != "" || filter != "" || where != "" {
 		infohashesOnly = false
 	} else {
 		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
@@ -57,25 +60,34 @@ func recheck(cmd *cobra.Command, args []string) error {
 			}
 		}
 	}
-	clientInstance, err := client.CreateClient(clientName)
+	clientInstances, err := client.CreateClientsFromArg(clientName)
 	if err != nil {
-		return fmt.Errorf("failed to create client: %w", err)
+		return err
 	}
 	if infohashesOnly {
 		if len(infoHashes) == 0 {
 			return fmt.Errorf("no torrent to recheck")
 		}
 		if force {
-			if err = clientInstance.RecheckTorrents(infoHashes); err != nil {
-				return fmt.Errorf("failed to recheck torrents: %w", err)
-			}
-			return nil
+			return client.RunOnClients(clientInstances, func(clientInstance client.Client) error {
+				if err := clientInstance.RecheckTorrents(infoHashes); err != nil {
+					return fmt.Errorf("failed to recheck torrents: %w", err)
+				}
+				return nil
+			})
 		}
 	}
 
-	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, infoHashes...)
+	torrents, err := client.QueryClientsTorrents(clientInstances,
+		func(clientInstance client.Client) ([]*client.Torrent, error) {
+			torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, where, infoHashes...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch client torrents: %w", err)
+			}
+			return torrents, nil
+		})
 	if err != nil {
-		return fmt.Errorf("failed to fetch client torrents: %w", err)
+		return err
 	}
 	if len(torrents) == 0 {
 		log.Infof("No matched torrents found")
@@ -92,6 +104,12 @@ func recheck(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("abort")
 		}
 	}
-	infoHashes = util.Map(torrents, func(t *client.Torrent) string { return t.InfoHash })
-	return clientInstance.RecheckTorrents(infoHashes)
+	return client.RunOnClients(clientInstances, func(clientInstance client.Client) error {
+		torrents := client.GetClientTorrents(clientInstances, clientInstance, torrents)
+		if len(torrents) == 0 {
+			return nil
+		}
+		infoHashes := util.Map(torrents, func(t *client.Torrent) string { return t.InfoHash })
+		return clientInstance.RecheckTorrents(infoHashes)
+	})
 }
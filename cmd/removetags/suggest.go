@@ -17,7 +17,7 @@ func init() {
 			return nil
 		}
 		if info.LastArgIndex == 1 {
-			return suggest.ClientArg(info.MatchingPrefix)
+			return suggest.ClientOrGroupArg(info.MatchingPrefix)
 		}
 		if info.LastArgIndex >= 3 {
 			return suggest.InfoHashOrFilterArg(info.MatchingPrefix, info.Args[1])
@@ -11,6 +11,7 @@ import (
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
 	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/journal"
 	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/helper"
 )
@@ -37,12 +38,14 @@ var (
 	category = ""
 	tag      = ""
 	filter   = ""
+	where    = ""
 	trackers = []string{}
 )
 
 func init() {
 	command.Flags().BoolVarP(&force, "force", "", false, "Force updating trackers. Do NOT prompt for confirm")
 	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&where, "where", "", "", constants.HELP_ARG_WHERE_TORRENT)
 	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
 	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
 	command.Flags().StringArrayVarP(&trackers, "tracker", "", nil, "Set the tracker to remove. Can be set multiple times")
@@ -53,7 +56,7 @@ func init() {
 func removetrackers(cmd *cobra.Command, args []string) error {
 	clientName := args[0]
 	infoHashes := args[1:]
-	if category == "" && tag == "" && filter == "" {
+	if category == "" && tag == "" && filter == "" && where == "" {
 		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
 			return err
 		} else {
@@ -73,7 +76,7 @@ func removetrackers(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
 
-	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, infoHashes...)
+	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, where, infoHashes...)
 	if err != nil {
 		return err
 	}
@@ -93,6 +96,13 @@ func removetrackers(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("abort")
 		}
 	}
+	journalEntry := journal.New(journal.OP_REMOVETRACKERS)
+	if err := journalEntry.AddTorrents(clientInstance, torrents); err != nil {
+		log.Warnf("Failed to record journal, the operation can not be fully undone: %v", err)
+	}
+	if err := journalEntry.Save(); err != nil {
+		return fmt.Errorf("failed to save journal: %w", err)
+	}
 	errorCnt := int64(0)
 	for i, torrent := range torrents {
 		fmt.Printf("(%d/%d) ", i+1, len(torrents))
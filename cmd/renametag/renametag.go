@@ -37,7 +37,7 @@ func renametag(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
-	torrents, err := client.QueryTorrents(clientInstance, "", oldTag, "")
+	torrents, err := client.QueryTorrents(clientInstance, "", oldTag, "", "")
 	if err != nil {
 		return fmt.Errorf("failed to query client torrents of old-tag: %w", err)
 	}
@@ -0,0 +1,130 @@
+package repair
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+// A damaged file of torrent that will be re-downloaded.
+type RepairFile struct {
+	*torrentutil.VerifyFileResult
+	Index    int64 // file index in client
+	Priority int64 // original priority in client
+}
+
+type Plan struct {
+	Files []*RepairFile // damaged files to re-download
+	// damaged files which are ignored (marked as no-download) in client. They are left as it
+	Ignored []*torrentutil.VerifyFileResult
+	// partially checked files which share unchecked pieces with damaged files. They are not re-downloaded,
+	// but also not marked as no-download, so that client re-downloads the shared pieces
+	Partial []*torrentutil.VerifyFileResult
+	// indexes of other files, which will be marked as no-download during repairing
+	Others []int64
+	// original priorities of all files in client. index => priority
+	Priorities map[int64]int64
+	Size       int64 // total size of files to re-download
+}
+
+// Return the path of client torrent file relative to torrent root folder.
+func clientRelativePath(path string, hasRootDir bool) string {
+	path = util.ToSlash(path)
+	if hasRootDir {
+		if _, relativePath, found := strings.Cut(path, "/"); found {
+			return relativePath
+		}
+	}
+	return path
+}
+
+// Make the repair plan from local verification report of torrent and the files of client torrent.
+func MakePlan(tinfo *torrentutil.TorrentMeta, report *torrentutil.VerifyReport,
+	clientFiles []*client.TorrentContentFile) (*Plan, error) {
+	plan := &Plan{Priorities: map[int64]int64{}}
+	clientFilesMap := map[string]*client.TorrentContentFile{}
+	for _, clientFile := range clientFiles {
+		plan.Priorities[clientFile.Index] = clientFile.Priority
+		if tinfo.SingleFileTorrent {
+			clientFilesMap[tinfo.Files[0].Path] = clientFile
+		} else {
+			clientFilesMap[clientRelativePath(clientFile.Path, tinfo.RootDir != "")] = clientFile
+		}
+	}
+	if len(clientFilesMap) != len(tinfo.Files) {
+		return nil, fmt.Errorf("client torrent has %d files, but torrent has %d files", len(clientFilesMap),
+			len(tinfo.Files))
+	}
+	keepIndexes := map[int64]struct{}{} // files not marked as no-download during repairing
+	for _, file := range report.Files {
+		clientFile := clientFilesMap[file.Path]
+		if clientFile == nil {
+			return nil, fmt.Errorf("file %q does not exist in client torrent", file.Path)
+		}
+		if file.Status == torrentutil.VERIFY_FILE_OK {
+			continue
+		}
+		if file.Status == torrentutil.VERIFY_FILE_PARTIAL {
+			plan.Partial = append(plan.Partial, file)
+			keepIndexes[clientFile.Index] = struct{}{}
+			continue
+		}
+		if clientFile.Ignored || clientFile.Priority == 0 {
+			plan.Ignored = append(plan.Ignored, file)
+			continue
+		}
+		plan.Files = append(plan.Files, &RepairFile{
+			VerifyFileResult: file,
+			Index:            clientFile.Index,
+			Priority:         clientFile.Priority,
+		})
+		plan.Size += file.Size
+		keepIndexes[clientFile.Index] = struct{}{}
+	}
+	for _, clientFile := range clientFiles {
+		if _, ok := keepIndexes[clientFile.Index]; !ok && clientFile.Priority != 0 {
+			plan.Others = append(plan.Others, clientFile.Index)
+		}
+	}
+	return plan, nil
+}
+
+// Mark all other files as no-download, so that only damaged files will be re-downloaded.
+func (plan *Plan) Apply(clientInstance client.Client, infoHash string) error {
+	if len(plan.Others) == 0 {
+		return nil
+	}
+	return clientInstance.SetFilePriority(infoHash, plan.Others, 0)
+}
+
+// Restore original priorities of other files.
+func (plan *Plan) Restore(clientInstance client.Client, infoHash string) error {
+	indexesByPriority := map[int64][]int64{}
+	for _, index := range plan.Others {
+		priority := plan.Priorities[index]
+		indexesByPriority[priority] = append(indexesByPriority[priority], index)
+	}
+	for priority, indexes := range indexesByPriority {
+		if err := clientInstance.SetFilePriority(infoHash, indexes, priority); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Return true if all files to repair are complete in client.
+func (plan *Plan) IsComplete(clientFiles []*client.TorrentContentFile) bool {
+	complete := map[int64]bool{}
+	for _, clientFile := range clientFiles {
+		complete[clientFile.Index] = clientFile.Complete
+	}
+	for _, file := range plan.Files {
+		if !complete[file.Index] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+package repair
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/anacrolix/torrent/metainfo"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/client/clienttest"
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+func TestMakePlan(t *testing.T) {
+	tinfo := &torrentutil.TorrentMeta{
+		RootDir: "Root",
+		Files: []*torrentutil.TorrentMetaFile{
+			{Path: "a.mkv", Size: 100},
+			{Path: "b.mkv", Size: 200},
+			{Path: "sub/c.nfo", Size: 10},
+			{Path: "sub/d.mkv", Size: 300},
+			{Path: "sub/e.mkv", Size: 400},
+		},
+		Info: &metainfo.Info{},
+	}
+	report := &torrentutil.VerifyReport{Files: []*torrentutil.VerifyFileResult{
+		{Path: "a.mkv", Size: 100, Status: torrentutil.VERIFY_FILE_OK},
+		{Path: "b.mkv", Size: 200, Status: torrentutil.VERIFY_FILE_BAD, BadPieces: []int64{3}},
+		{Path: "sub/c.nfo", Size: 10, Status: torrentutil.VERIFY_FILE_MISSING},
+		{Path: "sub/d.mkv", Size: 300, Status: torrentutil.VERIFY_FILE_WRONG_SIZE},
+		{Path: "sub/e.mkv", Size: 400, Status: torrentutil.VERIFY_FILE_PARTIAL, UncheckedPieces: []int64{5}},
+	}}
+	// root folder is renamed in client, and file order differs
+	clientFiles := []*client.TorrentContentFile{
+		{Index: 0, Path: "Renamed/a.mkv", Size: 100, Priority: 1, Complete: true},
+		{Index: 1, Path: "Renamed/b.mkv", Size: 200, Priority: 6, Complete: true},
+		{Index: 2, Path: "Renamed/sub/d.mkv", Size: 300, Priority: 1, Complete: true},
+		{Index: 3, Path: "Renamed/sub/c.nfo", Size: 10, Priority: 0, Ignored: true},
+		{Index: 4, Path: "Renamed/sub/e.mkv", Size: 400, Priority: 1, Complete: true},
+	}
+	plan, err := MakePlan(tinfo, report, clientFiles)
+	if err != nil {
+		t.Fatalf("MakePlan error: %v", err)
+	}
+	if len(plan.Files) != 2 || plan.Files[0].Index != 1 || plan.Files[1].Index != 2 || plan.Size != 500 {
+		t.Errorf("unexpected plan files: %v", plan.Files)
+	}
+	if len(plan.Ignored) != 1 || plan.Ignored[0].Path != "sub/c.nfo" {
+		t.Errorf("unexpected plan ignored files: %v", plan.Ignored)
+	}
+	if len(plan.Partial) != 1 || plan.Partial[0].Path != "sub/e.mkv" {
+		t.Errorf("unexpected plan partial files: %v", plan.Partial)
+	}
+	// partially checked file is kept downloadable
+	if !slices.Equal(plan.Others, []int64{0}) {
+		t.Errorf("plan.Others = %v, want [0]", plan.Others)
+	}
+	if !plan.IsComplete(clientFiles) {
+		t.Errorf("IsComplete() = false, want true")
+	}
+	clientFiles[2].Complete = false
+	if plan.IsComplete(clientFiles) {
+		t.Errorf("IsComplete() = true, want false")
+	}
+
+	clientInstance := clienttest.New()
+	clientInstance.Files["a"] = clientFiles
+	if err := plan.Apply(clientInstance, "a"); err != nil || clientFiles[0].Priority != 0 ||
+		clientFiles[1].Priority != 6 || clientFiles[4].Priority != 1 {
+		t.Errorf("Apply() = %v, files = %v", err, clientFiles)
+	}
+	if err := plan.Restore(clientInstance, "a"); err != nil || clientFiles[0].Priority != 1 ||
+		clientFiles[1].Priority != 6 || clientFiles[3].Priority != 0 {
+		t.Errorf("Restore() = %v, files = %v", err, clientFiles)
+	}
+
+	clientFiles = append(clientFiles[:3], &client.TorrentContentFile{Index: 3, Path: "Renamed/sub/e.nfo"})
+	if _, err := MakePlan(tinfo, report, clientFiles); err == nil {
+		t.Errorf("MakePlan() with mismatched client files should fail")
+	}
+}
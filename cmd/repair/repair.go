@@ -0,0 +1,263 @@
+package repair
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/helper"
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+var command = &cobra.Command{
+	Use:         "repair {client} {infoHash} [--map-save-path local_path|client_path] [--dry-run]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "repair"},
+	Short:       "Repair damaged contents of a client torrent by re-downloading only the damaged files.",
+	Long: `Repair damaged contents of a client torrent by re-downloading only the damaged files.
+
+It verifies the torrent contents in local disk by hash checking (see "ptool verifytorrent"),
+and finds out the damaged files: missing, wrong size or having bad pieces.
+Then it marks all other files of torrent as no-download in client, rechecks and resumes the torrent,
+so that client re-downloads only the damaged files. After the damaged files are completely downloaded,
+the original download priorities of other files are restored.
+Damaged files which are already marked as no-download in client are ignored.
+Files which share unchecked pieces with missing or wrong size files (partially checked) are not
+re-downloaded, but are also not marked as no-download, so that client can re-download the shared pieces.
+
+The {client} must be a client in local, or you will have to set the "--map-save-path local_path|client_path" flags.
+
+By default it waits for the re-downloading to finish. The wait can be interrupted by Ctrl+C or limited by "--timeout",
+in which case the original file priorities are also restored (client will still re-download the damaged pieces,
+as they are marked as missing by recheck).
+
+Use "--dry-run" flag to only display the verification result and the files that would be re-downloaded.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(2), cobra.OnlyValidArgs),
+	RunE: repair,
+}
+
+var (
+	dryRun            = false
+	force             = false
+	checkQuick        = false
+	workers           = 0
+	intervalStr       = ""
+	timeoutStr        = ""
+	checkMinLengthStr = ""
+	mapSavePaths      []string
+)
+
+func init() {
+	command.Flags().BoolVarP(&dryRun, "dry-run", "d", false,
+		"Dry run. Only verify torrent contents and display the files that would be re-downloaded")
+	command.Flags().BoolVarP(&force, "force", "", false, "Do repair without asking for confirm")
+	command.Flags().BoolVarP(&checkQuick, "check-quick", "", false,
+		"Do quick hash checking, only the head and tail pieces of each file will be checked")
+	command.Flags().IntVarP(&workers, "workers", "", 0,
+		"Number of concurrent hash checking workers. 0 means the number of CPUs")
+	command.Flags().StringVarP(&checkMinLengthStr, "check-min-length", "", "0",
+		`Used with "--check-quick". If > 0, the min size of each file's head & tail that must be hash checked`)
+	command.Flags().StringVarP(&intervalStr, "interval", "", "10s",
+		"The interval of checking the re-downloading status of torrent in client")
+	command.Flags().StringVarP(&timeoutStr, "timeout", "", "",
+		`Timeout of waiting for re-downloading to finish. E.g. "12h". Default is no timeout`)
+	command.Flags().StringArrayVarP(&mapSavePaths, "map-save-path", "", nil,
+		`Map save path that ptool sees to the one that the BitTorrent client sees. `+
+			`Format: "local_save_path|client_save_path". `+constants.HELP_ARG_PATH_MAPPERS)
+	cmd.RootCmd.AddCommand(command)
+}
+
+func repair(cmd *cobra.Command, args []string) (err error) {
+	clientName := args[0]
+	infoHash := args[1]
+	interval, err := util.ParseDuration(intervalStr)
+	if err != nil || interval <= 0 {
+		return fmt.Errorf("invalid interval %q: %w", intervalStr, err)
+	}
+	var timeout time.Duration
+	if timeoutStr != "" {
+		if timeout, err = util.ParseDuration(timeoutStr); err != nil {
+			return fmt.Errorf("invalid timeout: %w", err)
+		}
+	}
+	checkMinLength, err := util.RAMInBytes(checkMinLengthStr)
+	if err != nil {
+		return fmt.Errorf("invalid check-min-length: %w", err)
+	}
+	var savePathMapper *util.PathMapper
+	if len(mapSavePaths) > 0 {
+		if savePathMapper, err = util.NewPathMapper(mapSavePaths); err != nil {
+			return fmt.Errorf("invalid map-save-path(s): %w", err)
+		}
+	}
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	torrent, err := clientInstance.GetTorrent(infoHash)
+	if err != nil {
+		return fmt.Errorf("failed to get client torrent: %w", err)
+	}
+	if torrent == nil {
+		return fmt.Errorf("torrent %s not found in client", infoHash)
+	}
+	torrentContents, err := clientInstance.ExportTorrentFile(torrent.InfoHash)
+	if err != nil {
+		return fmt.Errorf("failed to export client torrent: %w", err)
+	}
+	tinfo, err := torrentutil.ParseTorrent(torrentContents)
+	if err != nil {
+		return fmt.Errorf("failed to parse client torrent: %w", err)
+	}
+	// Use client torrent content path, as the root folder (or single file) may be renamed in client
+	savePath, contentPath := "", ""
+	if tinfo.SingleFileTorrent || tinfo.RootDir != "" {
+		contentPath = util.ToSlash(torrent.ContentPath)
+	} else {
+		savePath = util.ToSlash(torrent.SavePath)
+	}
+	if savePathMapper != nil {
+		path, match := savePathMapper.After2Before(savePath + contentPath)
+		if !match {
+			return fmt.Errorf("torrent content path %q does not match with any map-save-path rule", savePath+contentPath)
+		}
+		if contentPath != "" {
+			contentPath = path
+		} else {
+			savePath = path
+		}
+	}
+
+	checkMode := int64(99)
+	if checkQuick {
+		checkMode = 1
+	}
+	fmt.Printf("Verifying torrent %s (%s) contents in %q\n", torrent.Name, torrent.InfoHash, savePath+contentPath)
+	progress := helper.NewProgress(torrent.Name)
+	report, err := tinfo.VerifyDetailed(&torrentutil.VerifyOptions{
+		SavePath:       savePath,
+		ContentPath:    contentPath,
+		CheckHash:      checkMode,
+		CheckMinLength: checkMinLength,
+		Workers:        workers,
+		Progress:       progress.Update,
+	})
+	progress.Done()
+	if err != nil {
+		return fmt.Errorf("failed to verify torrent: %w", err)
+	}
+	report.FprintFiles(os.Stdout, false)
+	clientFiles, err := clientInstance.GetTorrentContents(torrent.InfoHash)
+	if err != nil {
+		return fmt.Errorf("failed to get client torrent files: %w", err)
+	}
+	plan, err := MakePlan(tinfo, report, clientFiles)
+	if err != nil {
+		return err
+	}
+	for _, file := range plan.Ignored {
+		fmt.Printf("Ignore damaged file %q: it's marked as no-download in client\n", file.Path)
+	}
+	for _, file := range plan.Partial {
+		fmt.Printf("Keep partially checked file %q downloadable: it shares unchecked pieces with damaged files\n",
+			file.Path)
+	}
+	if len(plan.Files) == 0 {
+		fmt.Printf("No damaged file to repair\n")
+		return nil
+	}
+	fmt.Printf("Will re-download %d damaged files (%s), and mark other %d files as no-download during repairing\n",
+		len(plan.Files), util.BytesSize(float64(plan.Size)), len(plan.Others))
+	if dryRun {
+		return nil
+	}
+	if !force && !helper.AskYesNoConfirm(fmt.Sprintf("Repair client torrent %s", torrent.InfoHash)) {
+		return fmt.Errorf("abort")
+	}
+
+	if err = plan.Apply(clientInstance, torrent.InfoHash); err != nil {
+		return fmt.Errorf("failed to set file priorities: %w", err)
+	}
+	defer func() {
+		log.Infof("Restoring original file priorities")
+		if restoreErr := plan.Restore(clientInstance, torrent.InfoHash); restoreErr != nil {
+			err = fmt.Errorf("failed to restore file priorities: %w", restoreErr)
+		} else {
+			fmt.Printf("Restored original priorities of %d files\n", len(plan.Others))
+		}
+	}()
+	if err = clientInstance.RecheckTorrents([]string{torrent.InfoHash}); err != nil {
+		return fmt.Errorf("failed to recheck torrent: %w", err)
+	}
+	if err = clientInstance.ResumeTorrents([]string{torrent.InfoHash}); err != nil {
+		return fmt.Errorf("failed to resume torrent: %w", err)
+	}
+	fmt.Printf("Torrent is rechecked and resumed. Waiting for damaged files to be re-downloaded...\n")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return waitRepaired(ctx, clientInstance, torrent, plan, interval)
+}
+
+// Wait until all files to repair are complete in client. oldTorrent is the torrent before recheck.
+// Recheck is asynchronous in client, the files status is not trusted until the recheck is seen to take effect:
+// torrent is in checking state, it's state changes, some files to repair become incomplete,
+// or it's downloaded / completed size changes (the recheck and re-downloading may both finish between two polls).
+func waitRepaired(ctx context.Context, clientInstance client.Client, oldTorrent *client.Torrent,
+	plan *Plan, interval time.Duration) error {
+	infoHash := oldTorrent.InfoHash
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	rechecked := false
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stop waiting for re-downloading: %w", ctx.Err())
+		case <-ticker.C:
+		}
+		clientInstance.PurgeCache()
+		torrent, err := clientInstance.GetTorrent(infoHash)
+		if err != nil || torrent == nil {
+			log.Warnf("Failed to get client torrent: %v", err)
+			continue
+		}
+		if torrent.State == "checking" {
+			log.Infof("Torrent is being checked")
+			rechecked = true
+			continue
+		}
+		if torrent.State != oldTorrent.State || torrent.Downloaded != oldTorrent.Downloaded ||
+			torrent.SizeCompleted != oldTorrent.SizeCompleted {
+			rechecked = true
+		}
+		clientFiles, err := clientInstance.GetTorrentContents(infoHash)
+		if err != nil {
+			log.Warnf("Failed to get client torrent files: %v", err)
+			continue
+		}
+		if plan.IsComplete(clientFiles) {
+			if rechecked {
+				fmt.Printf("All damaged files are re-downloaded\n")
+				return nil
+			}
+			log.Infof("Waiting for torrent recheck to start")
+			continue
+		}
+		rechecked = true
+		log.Infof("Torrent state: %s, progress: %.1f%%", torrent.State,
+			float64(torrent.SizeCompleted)*100/float64(max(torrent.Size, 1)))
+	}
+}
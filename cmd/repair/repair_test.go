@@ -0,0 +1,73 @@
+package repair
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/client/clienttest"
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+// recheckClient returns the torrent in states one by one on each GetTorrent call, the last one is kept.
+// If downloaded is set, the torrent downloaded size is also changed to it from the second call.
+type recheckClient struct {
+	*clienttest.Client
+	states     []string
+	downloaded int64
+	calls      int
+}
+
+func (c *recheckClient) GetTorrent(infoHash string) (*client.Torrent, error) {
+	torrent, err := c.Client.GetTorrent(infoHash)
+	if torrent != nil {
+		torrent.State = c.states[0]
+		if len(c.states) > 1 {
+			c.states = c.states[1:]
+		}
+		if c.calls++; c.calls > 1 && c.downloaded > 0 {
+			torrent.Downloaded = c.downloaded
+		}
+	}
+	return torrent, err
+}
+
+func TestWaitRepaired(t *testing.T) {
+	plan := &Plan{Files: []*RepairFile{{VerifyFileResult: &torrentutil.VerifyFileResult{Path: "a.mkv"}, Index: 0}}}
+	newClient := func(states ...string) *recheckClient {
+		clientInstance := clienttest.New(&client.Torrent{InfoHash: "a", Downloaded: 100})
+		// the damaged file is still complete in client before the recheck takes effect
+		clientInstance.Files["a"] = []*client.TorrentContentFile{{Index: 0, Path: "a.mkv", Complete: true}}
+		return &recheckClient{Client: clientInstance, states: states}
+	}
+
+	oldTorrent := &client.Torrent{InfoHash: "a", State: "seeding", Downloaded: 100}
+
+	// recheck is never seen
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := waitRepaired(ctx, newClient("seeding"), oldTorrent, plan, time.Millisecond); err == nil {
+		t.Errorf("waitRepaired() before recheck = nil, want timeout error")
+	}
+
+	for _, states := range [][]string{
+		{"seeding", "checking", "seeding"},
+		{"seeding", "downloading", "seeding"}, // recheck finished between polls
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := waitRepaired(ctx, newClient(states...), oldTorrent, plan, time.Millisecond); err != nil {
+			t.Errorf("waitRepaired() with states %v = %v", states, err)
+		}
+	}
+
+	// recheck and re-downloading both finished between polls, only the downloaded size changes
+	clientInstance := newClient("seeding")
+	clientInstance.downloaded = 200
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := waitRepaired(ctx, clientInstance, oldTorrent, plan, time.Millisecond); err != nil {
+		t.Errorf("waitRepaired() with downloaded size changed = %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package restore
+
+import (
+	"archive/zip"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/backup"
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+)
+
+var command = &cobra.Command{
+	Use:   "restore {client} {file.zip}",
+	Short: "Restore torrents of client from a backup zip archive.",
+	Long: fmt.Sprintf(`Restore torrents of client from a backup zip archive created by "ptool backup".
+The {client} can be of any supported type, not necessarily the same type as the backed up client.
+
+It re-adds every torrent in the backup to client, with the original save path, category, tags, trackers,
+share limits, speed limits, paused state and file priorities. Torrents are added with skip checking,
+so the content files should already exist in the save path. Torrents that already exist in client are skipped.
+Categories that do not exist in client are also created.
+
+If {client} has a different file system than the backed up client, use "--map-save-path" flag
+to map the save paths. E.g. "/root/Downloads|/downloads". %s.`, constants.HELP_ARG_PATH_MAPPERS),
+	Args: cobra.MatchAll(cobra.ExactArgs(2), cobra.OnlyValidArgs),
+	RunE: restore,
+}
+
+var (
+	dryRun       = false
+	mapSavePaths []string
+)
+
+func init() {
+	command.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "Dry run. Do NOT actually modify client")
+	command.Flags().StringArrayVarP(&mapSavePaths, "map-save-path", "", nil,
+		`Map save path from backed up client to {client}. Format: "backup_client_path|client_path". `+
+			constants.HELP_ARG_PATH_MAPPERS)
+	cmd.RootCmd.AddCommand(command)
+}
+
+func restore(cmd *cobra.Command, args []string) (err error) {
+	option := &backup.RestoreOption{
+		DryRun: dryRun,
+	}
+	if len(mapSavePaths) > 0 {
+		if option.SavePathMapper, err = util.NewPathMapper(mapSavePaths); err != nil {
+			return fmt.Errorf("invalid map-save-path(s): %w", err)
+		}
+	}
+	archive, err := zip.OpenReader(args[1])
+	if err != nil {
+		return fmt.Errorf("failed to open backup archive: %w", err)
+	}
+	defer archive.Close()
+	clientInstance, err := client.CreateClient(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	return backup.Restore(clientInstance, &archive.Reader, option)
+}
@@ -17,7 +17,8 @@ var command = &cobra.Command{
 	Aliases:     []string{"start"},
 	Short:       "Resume torrents of client.",
 	Long: fmt.Sprintf(`Resume torrents of client.
-%s.`, constants.HELP_INFOHASH_ARGS),
+%s.
+%s.`, constants.HELP_CLIENT_GROUP_ARG, constants.HELP_INFOHASH_ARGS),
 	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
 	RunE: resume,
 }
@@ -26,10 +27,12 @@ var (
 	category = ""
 	tag      = ""
 	filter   = ""
+	where    = ""
 )
 
 func init() {
 	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&where, "where", "", "", constants.HELP_ARG_WHERE_TORRENT)
 	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
 	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
 	cmd.RootCmd.AddCommand(command)
@@ -38,32 +41,33 @@ func init() {
 func resume(cmd *cobra.Command, args []string) error {
 	clientName := args[0]
 	infoHashes := args[1:]
-	if category == "" && tag == "" && filter == "" {
+	if category == "" && tag == "" && filter == "" && where == "" {
 		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
 			return err
 		} else {
 			infoHashes = _infoHashes
 		}
 	}
-	clientInstance, err := client.CreateClient(clientName)
-	if err != nil {
-		return fmt.Errorf("failed to create client: %w", err)
-	}
-
-	infoHashes, err = client.SelectTorrents(clientInstance, category, tag, filter, infoHashes...)
+	clientInstances, err := client.CreateClientsFromArg(clientName)
 	if err != nil {
 		return err
 	}
-	if infoHashes == nil {
-		err = clientInstance.ResumeAllTorrents()
+	return client.RunOnClients(clientInstances, func(clientInstance client.Client) error {
+		infoHashes, err := client.SelectTorrents(clientInstance, category, tag, filter, where, infoHashes...)
 		if err != nil {
 			return err
 		}
-	} else if len(infoHashes) > 0 {
-		err = clientInstance.ResumeTorrents(infoHashes)
-		if err != nil {
-			return err
+		if infoHashes == nil {
+			err = clientInstance.ResumeAllTorrents()
+			if err != nil {
+				return err
+			}
+		} else if len(infoHashes) > 0 {
+			err = clientInstance.ResumeTorrents(infoHashes)
+			if err != nil {
+				return err
+			}
 		}
-	}
-	return nil
+		return nil
+	})
 }
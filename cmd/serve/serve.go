@@ -0,0 +1,535 @@
+package serve
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/cmd/show"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/helper"
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+var command = &cobra.Command{
+	Use:   "serve",
+	Short: "Run a http server that exposes clients and sites operations as REST API.",
+	Long: `Run a http server that exposes clients and sites operations as REST API.
+
+All requests must be authenticated with the token, which is set by "--token" flag or "serveToken" config item.
+Put it in "Authorization: Bearer <token>" request header, or "token" url query parameter.
+All responses are in json format. The json of torrents is the same as the "--json" output of "show" / "search" cmds.
+If a request fails, the response is '{"error": "<msg>"}' with a non-2xx http status code.
+
+Client API:
+  GET    /api/clients : list clients
+  GET    /api/clients/{client}/status : client status
+  GET    /api/clients/{client}/torrents : query torrents
+  POST   /api/clients/{client}/torrents : add torrent
+  PATCH  /api/clients/{client}/torrents : modify category / tags of torrents
+  DELETE /api/clients/{client}/torrents : delete torrents
+  GET    /api/clients/{client}/torrents/{infoHash} : torrent details
+  GET    /api/clients/{client}/torrents/{infoHash}/trackers : torrent trackers
+  GET    /api/clients/{client}/torrents/{infoHash}/files : torrent content files
+
+The GET / PATCH / DELETE "torrents" endpoints select torrents using url query parameters,
+which have the same names and meanings as the flags of "show" cmd:
+  hashes (comma-separated info-hash or state filter list, the args of "show"), all, category, tag, filter, where,
+  exclude-tag, tracker, save-path, save-path-prefix, content-path, min-torrent-size, max-torrent-size,
+  max-total-size, max-torrents, added-after, completed-before, active-since, not-active-since,
+  partial, exclude, sort, order.
+Unlike "show", PATCH and DELETE require at least one condition (or "hashes" / "all") to be set.
+PATCH request body: {"category": "...", "addTags": ["..."], "removeTags": ["..."]}.
+A "category" of "" means leaving category unchanged; use "` + constants.NONE + `" to remove the category.
+DELETE parameters: preserve=1 (don't delete files on disk).
+
+POST "torrents" (add) request body is the .torrent file contents. Alternatively, set the "torrent" parameter
+to a site torrent id or url (e.g. "mteam.12345") or a magnet link. Other parameters:
+  category, tags (comma-separated), save-path, rename, paused=1, skip-checking=1.
+
+Site API:
+  GET    /api/sites : list sites
+  GET    /api/sites/{site}/status : site user status
+  GET    /api/sites/{site}/search?q=keyword : search site torrents
+  GET    /api/sites/{site}/latest?full=1 : latest site torrents
+
+Requests are processed one by one. Clients and sites data are always freshly fetched for each request.
+Stop the server by sending a SIGINT / SIGTERM signal to it.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(0), cobra.OnlyValidArgs),
+	RunE: serve,
+}
+
+var (
+	addr  = ""
+	token = ""
+)
+
+func init() {
+	command.Flags().StringVarP(&addr, "addr", "", "127.0.0.1:8090", "Http server listen address")
+	command.Flags().StringVarP(&token, "token", "", "",
+		`Api access token. If not set, use the "serveToken" of config file`)
+	cmd.RootCmd.AddCommand(command)
+}
+
+// All api calls are serialized as client / site instances are not goroutine-safe.
+var mu sync.Mutex
+
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string {
+	return e.err.Error()
+}
+
+func badRequest(format string, a ...any) error {
+	return &httpError{status: http.StatusBadRequest, err: fmt.Errorf(format, a...)}
+}
+
+func notFound(format string, a ...any) error {
+	return &httpError{status: http.StatusNotFound, err: fmt.Errorf(format, a...)}
+}
+
+type handlerFunc func(r *http.Request) (any, error)
+
+func serve(command *cobra.Command, args []string) error {
+	if config.InShell {
+		return fmt.Errorf(`you cann't run "serve" command in shell`)
+	}
+	if token == "" {
+		token = config.Get().ServeToken
+	}
+	if token == "" {
+		return fmt.Errorf(`api token is not set. Use "--token" flag or set "serveToken" in config file`)
+	}
+	mux := http.NewServeMux()
+	handle := func(pattern string, handler handlerFunc) {
+		mux.Handle(pattern, apiHandler(handler))
+	}
+	handle("GET /api/clients", listClients)
+	handle("GET /api/clients/{client}/status", clientStatus)
+	handle("GET /api/clients/{client}/torrents", queryTorrents)
+	handle("POST /api/clients/{client}/torrents", addTorrent)
+	handle("PATCH /api/clients/{client}/torrents", modifyTorrents)
+	handle("DELETE /api/clients/{client}/torrents", deleteTorrents)
+	handle("GET /api/clients/{client}/torrents/{infoHash}", getTorrent)
+	handle("GET /api/clients/{client}/torrents/{infoHash}/trackers", getTorrentTrackers)
+	handle("GET /api/clients/{client}/torrents/{infoHash}/files", getTorrentFiles)
+	handle("GET /api/sites", listSites)
+	handle("GET /api/sites/{site}/status", siteStatus)
+	handle("GET /api/sites/{site}/search", searchSite)
+	handle("GET /api/sites/{site}/latest", latestSiteTorrents)
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	log.Warnf("Serving api on http://%s/api", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	log.Warnf("Server exited")
+	return nil
+}
+
+func apiHandler(handler handlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestToken := r.URL.Query().Get("token")
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			requestToken, _ = strings.CutPrefix(auth, "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(requestToken), []byte(token)) != 1 {
+			writeJson(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		start := time.Now()
+		data, err := handler(r)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if httpErr, ok := err.(*httpError); ok {
+				status = httpErr.status
+			}
+			log.Errorf("%s %s: %v (%v)", r.Method, r.URL.Path, err, time.Since(start))
+			writeJson(w, status, map[string]string{"error": err.Error()})
+			return
+		}
+		log.Infof("%s %s (%v)", r.Method, r.URL.Path, time.Since(start))
+		writeJson(w, http.StatusOK, data)
+	})
+}
+
+func writeJson(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	util.PrintJson(w, data)
+}
+
+// Create client instance of the {client} path value, with cache purged.
+func getClient(r *http.Request) (client.Client, error) {
+	name := r.PathValue("client")
+	if config.GetClientConfig(name) == nil {
+		return nil, notFound("client %s not found", name)
+	}
+	client.Purge(name)
+	return client.CreateClient(name)
+}
+
+// Create site instance of the {site} path value, with cache purged.
+func getSite(r *http.Request) (site.Site, error) {
+	name := r.PathValue("site")
+	if config.GetSiteConfig(name) == nil {
+		return nil, notFound("site %s not found", name)
+	}
+	site.Purge(name)
+	return site.CreateSite(name)
+}
+
+func listClients(r *http.Request) (any, error) {
+	type clientInfo struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	}
+	clients := []*clientInfo{}
+	for _, clientConfig := range config.Get().ClientsEnabled {
+		clients = append(clients, &clientInfo{Name: clientConfig.Name, Type: clientConfig.Type})
+	}
+	return clients, nil
+}
+
+func listSites(r *http.Request) (any, error) {
+	type siteInfo struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+		Url  string `json:"url"`
+	}
+	sites := []*siteInfo{}
+	for _, siteConfig := range config.Get().SitesEnabled {
+		sites = append(sites, &siteInfo{Name: siteConfig.GetName(), Type: siteConfig.Type, Url: siteConfig.Url})
+	}
+	return sites, nil
+}
+
+func clientStatus(r *http.Request) (any, error) {
+	clientInstance, err := getClient(r)
+	if err != nil {
+		return nil, err
+	}
+	return clientInstance.GetStatus()
+}
+
+// Parse the "show" cmd equivalent torrents query from request url query parameters.
+func parseTorrentsQuery(values url.Values) (*show.TorrentsQuery, error) {
+	query := &show.TorrentsQuery{
+		All:             parseBool(values.Get("all")),
+		InfoHashes:      util.SplitCsv(values.Get("hashes")),
+		Category:        values.Get("category"),
+		Tag:             values.Get("tag"),
+		Filter:          values.Get("filter"),
+		Where:           values.Get("where"),
+		ExcludeTag:      values.Get("exclude-tag"),
+		Tracker:         values.Get("tracker"),
+		SavePath:        values.Get("save-path"),
+		SavePathPrefix:  values.Get("save-path-prefix"),
+		ContentPath:     values.Get("content-path"),
+		MinTorrentSize:  values.Get("min-torrent-size"),
+		MaxTorrentSize:  values.Get("max-torrent-size"),
+		MaxTotalSize:    values.Get("max-total-size"),
+		MaxTorrents:     -1,
+		AddedAfter:      values.Get("added-after"),
+		CompletedBefore: values.Get("completed-before"),
+		ActiveSince:     values.Get("active-since"),
+		NotActiveSince:  values.Get("not-active-since"),
+		Partial:         parseBool(values.Get("partial")),
+		Excludes:        values.Get("exclude"),
+		Sort:            values.Get("sort"),
+		Order:           values.Get("order"),
+	}
+	if value := values.Get("max-torrents"); value != "" {
+		maxTorrents, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, badRequest("invalid max-torrents: %w", err)
+		}
+		query.MaxTorrents = maxTorrents
+	}
+	if query.Sort == "" {
+		query.Sort = common.ClientTorrentSortFlag.Options[0][0]
+	} else if !slices.ContainsFunc(common.ClientTorrentSortFlag.Options, func(option [2]string) bool {
+		return option[0] == query.Sort
+	}) {
+		return nil, badRequest("invalid sort: %s", query.Sort)
+	}
+	if query.Order != "" && query.Order != "asc" && query.Order != "desc" {
+		return nil, badRequest("invalid order: %s", query.Order)
+	}
+	if err := query.Prepare(); err != nil {
+		return nil, badRequest("%w", err)
+	}
+	return query, nil
+}
+
+func parseBool(value string) bool {
+	b, _ := strconv.ParseBool(value)
+	return b
+}
+
+func queryTorrents(r *http.Request) (any, error) {
+	query, err := parseTorrentsQuery(r.URL.Query())
+	if err != nil {
+		return nil, err
+	}
+	clientInstance, err := getClient(r)
+	if err != nil {
+		return nil, err
+	}
+	return query.Query(clientInstance)
+}
+
+// Select torrents for modification / deletion. It requires at least one condition to be set.
+func selectTorrents(r *http.Request) (client.Client, []*client.Torrent, error) {
+	query, err := parseTorrentsQuery(r.URL.Query())
+	if err != nil {
+		return nil, nil, err
+	}
+	if !query.All && query.NoConditions() && len(query.InfoHashes) == 0 {
+		return nil, nil, badRequest("no condition set. Set at least one of hashes, all or other conditions")
+	}
+	clientInstance, err := getClient(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	torrents, err := query.Query(clientInstance)
+	if err != nil {
+		return nil, nil, err
+	}
+	return clientInstance, torrents, nil
+}
+
+type modifyRequest struct {
+	Category   string   `json:"category"`
+	AddTags    []string `json:"addTags"`
+	RemoveTags []string `json:"removeTags"`
+}
+
+type modifyResponse struct {
+	InfoHashes []string `json:"infoHashes"`
+}
+
+func modifyTorrents(r *http.Request) (any, error) {
+	req := &modifyRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return nil, badRequest("invalid request body: %w", err)
+	}
+	if req.Category == "" && len(req.AddTags) == 0 && len(req.RemoveTags) == 0 {
+		return nil, badRequest("nothing to modify")
+	}
+	clientInstance, torrents, err := selectTorrents(r)
+	if err != nil {
+		return nil, err
+	}
+	infoHashes := util.Map(torrents, func(t *client.Torrent) string { return t.InfoHash })
+	if len(infoHashes) > 0 {
+		if req.Category != "" {
+			category := req.Category
+			if category == constants.NONE {
+				category = ""
+			}
+			if err = clientInstance.SetTorrentsCatetory(infoHashes, category); err != nil {
+				return nil, fmt.Errorf("failed to set category: %w", err)
+			}
+		}
+		if len(req.RemoveTags) > 0 {
+			if err = clientInstance.RemoveTagsFromTorrents(infoHashes, req.RemoveTags); err != nil {
+				return nil, fmt.Errorf("failed to remove tags: %w", err)
+			}
+		}
+		if len(req.AddTags) > 0 {
+			if err = clientInstance.AddTagsToTorrents(infoHashes, req.AddTags); err != nil {
+				return nil, fmt.Errorf("failed to add tags: %w", err)
+			}
+		}
+	}
+	return &modifyResponse{InfoHashes: infoHashes}, nil
+}
+
+func deleteTorrents(r *http.Request) (any, error) {
+	preserve := parseBool(r.URL.Query().Get("preserve"))
+	clientInstance, torrents, err := selectTorrents(r)
+	if err != nil {
+		return nil, err
+	}
+	infoHashes := util.Map(torrents, func(t *client.Torrent) string { return t.InfoHash })
+	if len(infoHashes) > 0 {
+		if err = client.DeleteOrTrashTorrents(clientInstance, infoHashes, !preserve); err != nil {
+			return nil, fmt.Errorf("failed to delete torrents: %w", err)
+		}
+	}
+	return &modifyResponse{InfoHashes: infoHashes}, nil
+}
+
+type addResponse struct {
+	InfoHash string `json:"infoHash"` // empty if added torrent is a magnet link
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	Site     string `json:"site"`
+}
+
+func addTorrent(r *http.Request) (any, error) {
+	values := r.URL.Query()
+	option := &client.TorrentOption{
+		Name:         values.Get("rename"),
+		Category:     values.Get("category"),
+		SavePath:     values.Get("save-path"),
+		Tags:         util.SplitCsv(values.Get("tags")),
+		Pause:        parseBool(values.Get("paused")),
+		SkipChecking: parseBool(values.Get("skip-checking")),
+	}
+	torrent := values.Get("torrent")
+	var content []byte
+	var err error
+	if torrent == "" {
+		if content, err = io.ReadAll(io.LimitReader(r.Body, constants.BIG_FILE_SIZE)); err != nil {
+			return nil, badRequest("failed to read request body: %w", err)
+		}
+		if !util.BytesHasAnyStringPrefix(content, constants.TorrentFileMagicNumbers...) {
+			return nil, badRequest(`request body is not a valid .torrent file and "torrent" parameter is not set`)
+		}
+	} else if util.IsPureTorrentUrl(torrent) {
+		content = []byte(torrent)
+	}
+	clientInstance, err := getClient(r)
+	if err != nil {
+		return nil, err
+	}
+	res := &addResponse{}
+	if content != nil && torrent != "" {
+		// magnet link. All magnet: link should be public torrent.
+		option.Tags = append(option.Tags, config.PUBLIC_TAG)
+		option.RatioLimit = config.Get().PublicTorrentRatioLimit
+	} else {
+		var tinfo *torrentutil.TorrentMeta
+		var siteInstance site.Site
+		if content == nil {
+			// forceRemote: never read local files of server
+			content, tinfo, siteInstance, res.Site, _, _, _, err = helper.GetTorrentContent(torrent, "",
+				false, true, nil, false, nil)
+			if err != nil {
+				return nil, badRequest("failed to get torrent %s: %w", torrent, err)
+			}
+		} else if tinfo, err = torrentutil.ParseTorrent(content); err != nil {
+			return nil, badRequest("invalid torrent: %w", err)
+		}
+		res.InfoHash, res.Name, res.Size = tinfo.InfoHash, tinfo.Info.Name, tinfo.Size
+		if tinfo.IsPrivate() {
+			option.Tags = append(option.Tags, config.PRIVATE_TAG)
+		} else {
+			option.Tags = append(option.Tags, config.PUBLIC_TAG)
+			option.RatioLimit = config.Get().PublicTorrentRatioLimit
+		}
+		if res.Site != "" {
+			option.Tags = append(option.Tags, client.GenerateTorrentTagFromSite(res.Site))
+		}
+		if siteInstance != nil && siteInstance.GetSiteConfig().GlobalHnR {
+			option.Tags = append(option.Tags, config.HR_TAG)
+		}
+	}
+	if err = clientInstance.AddTorrent(content, option, nil); err != nil {
+		return nil, fmt.Errorf("failed to add torrent to client: %w", err)
+	}
+	return res, nil
+}
+
+func getTorrent(r *http.Request) (any, error) {
+	infoHash := r.PathValue("infoHash")
+	if !client.IsValidInfoHash(infoHash) {
+		return nil, badRequest("%s is not a valid infoHash", infoHash)
+	}
+	clientInstance, err := getClient(r)
+	if err != nil {
+		return nil, err
+	}
+	torrent, err := clientInstance.GetTorrent(infoHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get torrent %s details: %w", infoHash, err)
+	}
+	if torrent == nil {
+		return nil, notFound("torrent %s not found", infoHash)
+	}
+	return torrent, nil
+}
+
+func getTorrentTrackers(r *http.Request) (any, error) {
+	if _, err := getTorrent(r); err != nil {
+		return nil, err
+	}
+	clientInstance, _ := client.CreateClient(r.PathValue("client"))
+	return clientInstance.GetTorrentTrackers(r.PathValue("infoHash"))
+}
+
+func getTorrentFiles(r *http.Request) (any, error) {
+	if _, err := getTorrent(r); err != nil {
+		return nil, err
+	}
+	clientInstance, _ := client.CreateClient(r.PathValue("client"))
+	return clientInstance.GetTorrentContents(r.PathValue("infoHash"))
+}
+
+func siteStatus(r *http.Request) (any, error) {
+	siteInstance, err := getSite(r)
+	if err != nil {
+		return nil, err
+	}
+	return siteInstance.GetStatus()
+}
+
+func searchSite(r *http.Request) (any, error) {
+	keyword := r.URL.Query().Get("q")
+	if keyword == "" {
+		return nil, badRequest("q (keyword) parameter is required")
+	}
+	siteInstance, err := getSite(r)
+	if err != nil {
+		return nil, err
+	}
+	return siteInstance.SearchTorrents(keyword, "")
+}
+
+func latestSiteTorrents(r *http.Request) (any, error) {
+	siteInstance, err := getSite(r)
+	if err != nil {
+		return nil, err
+	}
+	return siteInstance.GetLatestTorrents(parseBool(r.URL.Query().Get("full")))
+}
@@ -3,11 +3,13 @@ package setcategory
 import (
 	"fmt"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
 	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/journal"
 	"github.com/sagan/ptool/util/helper"
 )
 
@@ -17,8 +19,9 @@ var command = &cobra.Command{
 	Short:       "Set category of torrents in client.",
 	Long: fmt.Sprintf(`Set category of torrents in client.
 %s.
+%s.
 
-To make torrents "uncategoried", set {category} to %q.`, constants.HELP_INFOHASH_ARGS, constants.NONE),
+To make torrents "uncategoried", set {category} to %q.`, constants.HELP_CLIENT_GROUP_ARG, constants.HELP_INFOHASH_ARGS, constants.NONE),
 	Args: cobra.MatchAll(cobra.MinimumNArgs(2), cobra.OnlyValidArgs),
 	RunE: setcategory,
 }
@@ -27,10 +30,12 @@ var (
 	category = ""
 	tag      = ""
 	filter   = ""
+	where    = ""
 )
 
 func init() {
 	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&where, "where", "", "", constants.HELP_ARG_WHERE_TORRENT)
 	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
 	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
 	cmd.RootCmd.AddCommand(command)
@@ -40,32 +45,40 @@ func setcategory(cmd *cobra.Command, args []string) error {
 	clientName := args[0]
 	cat := args[1]
 	infoHashes := args[2:]
-	if category == "" && tag == "" && filter == "" {
+	if category == "" && tag == "" && filter == "" && where == "" {
 		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
 			return err
 		} else {
 			infoHashes = _infoHashes
 		}
 	}
-	clientInstance, err := client.CreateClient(clientName)
-	if err != nil {
-		return fmt.Errorf("failed to create client: %w", err)
-	}
-
-	infoHashes, err = client.SelectTorrents(clientInstance, category, tag, filter, infoHashes...)
+	clientInstances, err := client.CreateClientsFromArg(clientName)
 	if err != nil {
 		return err
 	}
-	if infoHashes == nil {
-		err = clientInstance.SetAllTorrentsCatetory(cat)
+	journalEntry := journal.New(journal.OP_SETCATEGORY)
+	return client.RunOnClients(clientInstances, func(clientInstance client.Client) error {
+		infoHashes, err := client.SelectTorrents(clientInstance, category, tag, filter, where, infoHashes...)
 		if err != nil {
 			return err
 		}
-	} else if len(infoHashes) > 0 {
-		err = clientInstance.SetTorrentsCatetory(infoHashes, cat)
-		if err != nil {
-			return err
+		if err := journalEntry.AddInfoHashes(clientInstance, infoHashes); err != nil {
+			log.Warnf("Failed to record journal, the operation can not be fully undone: %v", err)
 		}
-	}
-	return nil
+		if err := journalEntry.Save(); err != nil {
+			return fmt.Errorf("failed to save journal: %w", err)
+		}
+		if infoHashes == nil {
+			err = clientInstance.SetAllTorrentsCatetory(cat)
+			if err != nil {
+				return err
+			}
+		} else if len(infoHashes) > 0 {
+			err = clientInstance.SetTorrentsCatetory(infoHashes, cat)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
@@ -3,11 +3,13 @@ package setsavepath
 import (
 	"fmt"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
 	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/journal"
 	"github.com/sagan/ptool/util/helper"
 )
 
@@ -25,10 +27,12 @@ var (
 	category = ""
 	tag      = ""
 	filter   = ""
+	where    = ""
 )
 
 func init() {
 	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&where, "where", "", "", constants.HELP_ARG_WHERE_TORRENT)
 	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
 	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
 	cmd.RootCmd.AddCommand(command)
@@ -38,7 +42,7 @@ func setsavepath(cmd *cobra.Command, args []string) error {
 	clientName := args[0]
 	savePath := args[1]
 	infoHashes := args[2:]
-	if category == "" && tag == "" && filter == "" {
+	if category == "" && tag == "" && filter == "" && where == "" {
 		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
 			return err
 		} else {
@@ -50,10 +54,17 @@ func setsavepath(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
 
-	infoHashes, err = client.SelectTorrents(clientInstance, category, tag, filter, infoHashes...)
+	infoHashes, err = client.SelectTorrents(clientInstance, category, tag, filter, where, infoHashes...)
 	if err != nil {
 		return err
 	}
+	journalEntry := journal.New(journal.OP_SETSAVEPATH)
+	if err := journalEntry.AddInfoHashes(clientInstance, infoHashes); err != nil {
+		log.Warnf("Failed to record journal, the operation can not be fully undone: %v", err)
+	}
+	if err := journalEntry.Save(); err != nil {
+		return fmt.Errorf("failed to save journal: %w", err)
+	}
 	if infoHashes == nil {
 		err = clientInstance.SetAllTorrentsSavePath(savePath)
 		if err != nil {
@@ -86,6 +86,20 @@ func ClientArg(prefix string) []prompt.Suggest {
 	return suggestions
 }
 
+// Suggest client or client group names (including the built-in "_all" group).
+func ClientOrGroupArg(prefix string) []prompt.Suggest {
+	suggestions := ClientArg(prefix)
+	for _, group := range config.Get().Groups {
+		if len(group.Clients) > 0 && strings.HasPrefix(group.Name, prefix) {
+			suggestions = append(suggestions, prompt.Suggest{Text: group.Name, Description: "<group>"})
+		}
+	}
+	if strings.HasPrefix("_all", prefix) {
+		suggestions = append(suggestions, prompt.Suggest{Text: "_all", Description: "<group>"})
+	}
+	return suggestions
+}
+
 func SiteArg(prefix string) []prompt.Suggest {
 	suggestions := []prompt.Suggest{}
 	for _, site := range config.Get().SitesEnabled {
@@ -0,0 +1,230 @@
+package show
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+)
+
+// TorrentsQuery is the client torrents filter & sort conditions of "show" command.
+// It's also used by other places that need to select client torrents in the same way (e.g. "serve" api).
+// The zero value of size fields ("") means no limit, same as "-1".
+type TorrentsQuery struct {
+	All             bool // ignore Category, Tag, Filter, Where and InfoHashes, select all torrents
+	InfoHashes      []string
+	Category        string
+	Tag             string
+	Filter          string
+	Where           string // query expression, see client.WhereVars
+	ExcludeTag      string
+	Tracker         string
+	SavePath        string
+	SavePathPrefix  string
+	ContentPath     string
+	MinTorrentSize  string
+	MaxTorrentSize  string
+	MaxTotalSize    string
+	MaxTorrents     int64 // -1 == no limit
+	AddedAfter      string
+	CompletedBefore string
+	ActiveSince     string
+	NotActiveSince  string
+	Partial         bool
+	Excludes        string // comma-separated
+	Sort            string // ClientTorrentSortFlag value
+	Order           string // asc|desc
+
+	prepared           bool
+	minTorrentSize     int64
+	maxTorrentSize     int64
+	maxTotalSize       int64
+	addedAfter         int64
+	completedBefore    int64
+	activeSince        int64
+	notActiveSince     int64
+	excludesList       []string
+	hasFilterCondition bool
+}
+
+// Validate and parse the query conditions. It's called by Query automatically.
+func (q *TorrentsQuery) Prepare() (err error) {
+	if q.prepared {
+		return nil
+	}
+	if util.CountNonZeroVariables(q.SavePath, q.SavePathPrefix, q.ContentPath) > 1 {
+		return fmt.Errorf("save-path, save-path-prefix and content-path conditions are NOT compatible")
+	}
+	if q.SavePath != "" {
+		q.SavePath = path.Clean(q.SavePath)
+	}
+	if q.SavePathPrefix != "" {
+		q.SavePathPrefix = path.Clean(q.SavePathPrefix)
+	}
+	if q.ContentPath != "" {
+		q.ContentPath = path.Clean(q.ContentPath)
+	}
+	q.minTorrentSize, q.maxTorrentSize, q.maxTotalSize = -1, -1, -1
+	if q.MinTorrentSize != "" {
+		q.minTorrentSize, _ = util.RAMInBytes(q.MinTorrentSize)
+	}
+	if q.MaxTorrentSize != "" {
+		q.maxTorrentSize, _ = util.RAMInBytes(q.MaxTorrentSize)
+	}
+	if q.MaxTotalSize != "" {
+		q.maxTotalSize, _ = util.RAMInBytes(q.MaxTotalSize)
+	}
+	now := time.Now()
+	if q.AddedAfter != "" {
+		if q.addedAfter, err = util.ParseTimeWithNow(q.AddedAfter, nil, now); err != nil {
+			return fmt.Errorf("invalid added-after: %w", err)
+		}
+	}
+	if q.CompletedBefore != "" {
+		if q.completedBefore, err = util.ParseTimeWithNow(q.CompletedBefore, nil, now); err != nil {
+			return fmt.Errorf("invalid completed-before: %w", err)
+		}
+	}
+	if q.ActiveSince != "" {
+		if q.activeSince, err = util.ParseTimeWithNow(q.ActiveSince, nil, now); err != nil {
+			return fmt.Errorf("invalid active-since: %w", err)
+		}
+	}
+	if q.NotActiveSince != "" {
+		if q.notActiveSince, err = util.ParseTimeWithNow(q.NotActiveSince, nil, now); err != nil {
+			return fmt.Errorf("invalid not-active-since: %w", err)
+		}
+	}
+	if q.addedAfter > 0 && q.completedBefore > 0 && q.addedAfter > q.completedBefore {
+		return fmt.Errorf("added-after must NOT be after completed-before")
+	}
+	if q.activeSince > 0 && q.notActiveSince > 0 && q.activeSince >= q.notActiveSince {
+		return fmt.Errorf("active-since must be before not-active-since")
+	}
+	if _, err = client.CompileWhere(q.Where); err != nil {
+		return err
+	}
+	q.excludesList = util.SplitCsv(q.Excludes)
+	q.hasFilterCondition = q.SavePath != "" || q.SavePathPrefix != "" || q.ContentPath != "" ||
+		q.Tracker != "" || q.minTorrentSize >= 0 || q.maxTorrentSize >= 0 || q.addedAfter > 0 ||
+		q.completedBefore > 0 || q.activeSince > 0 || q.notActiveSince > 0 || q.Partial ||
+		q.Excludes != "" || q.ExcludeTag != ""
+	q.prepared = true
+	return nil
+}
+
+// Return true if none of the category / tag / filter / other filter conditions is set.
+// Must be called after Prepare.
+func (q *TorrentsQuery) NoConditions() bool {
+	return q.Category == "" && q.Tag == "" && q.Filter == "" && q.Where == "" && !q.hasFilterCondition
+}
+
+// Query client torrents that match the conditions, sorted and truncated.
+// If neither conditions nor InfoHashes are set, it selects current active torrents.
+func (q *TorrentsQuery) Query(clientInstance client.Client) ([]*client.Torrent, error) {
+	torrents, err := q.Select(clientInstance)
+	if err != nil {
+		return nil, err
+	}
+	return q.SortAndLimit(torrents), nil
+}
+
+// Select client torrents that match the conditions, without sorting and truncating.
+// Use it with SortAndLimit to query torrents of multiple clients.
+func (q *TorrentsQuery) Select(clientInstance client.Client) (torrents []*client.Torrent, err error) {
+	if err := q.Prepare(); err != nil {
+		return nil, err
+	}
+	if q.All {
+		torrents, err = client.QueryTorrents(clientInstance, "", "", "", "")
+	} else if q.NoConditions() && len(q.InfoHashes) == 0 {
+		torrents, err = client.QueryTorrents(clientInstance, "", "", "", "", "_active")
+	} else {
+		torrents, err = client.QueryTorrents(clientInstance, q.Category, q.Tag, q.Filter, q.Where, q.InfoHashes...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch client torrents: %w", err)
+	}
+	if q.hasFilterCondition {
+		torrents = util.Filter(torrents, q.match)
+	}
+	return torrents, nil
+}
+
+// Sort torrents and truncate them to max torrents / max total size limits. Must be called after Prepare.
+func (q *TorrentsQuery) SortAndLimit(torrents []*client.Torrent) []*client.Torrent {
+	if q.Sort != "" && q.Sort != constants.NONE {
+		sort.Slice(torrents, func(i, j int) bool {
+			switch q.Sort {
+			case "name":
+				return torrents[i].Name < torrents[j].Name
+			case "size":
+				return torrents[i].Size < torrents[j].Size
+			case "speed":
+				return torrents[i].DownloadSpeed+torrents[i].UploadSpeed <
+					torrents[j].DownloadSpeed+torrents[j].UploadSpeed
+			case "state":
+				if torrents[i].State != torrents[j].State {
+					return torrents[i].State < torrents[j].State
+				}
+				return torrents[i].LowLevelState < torrents[j].LowLevelState
+			case "time":
+				return torrents[i].Atime < torrents[j].Atime
+			case "activity-time":
+				return torrents[i].ActivityTime < torrents[j].ActivityTime
+			case "tracker":
+				if torrents[i].TrackerDomain != torrents[j].TrackerDomain {
+					return torrents[i].TrackerDomain < torrents[j].TrackerDomain
+				}
+				return torrents[i].Atime < torrents[j].Atime
+			}
+			return i < j
+		})
+		if q.Order == "desc" {
+			for i, j := 0, len(torrents)-1; i < j; i, j = i+1, j-1 {
+				torrents[i], torrents[j] = torrents[j], torrents[i]
+			}
+		}
+	}
+	if q.MaxTorrents >= 0 && len(torrents) > int(q.MaxTorrents) {
+		torrents = torrents[:q.MaxTorrents]
+	}
+	if q.maxTotalSize >= 0 {
+		i := 0
+		size := int64(0)
+		for _, torrent := range torrents {
+			if size+torrent.Size > q.maxTotalSize {
+				break
+			}
+			i++
+			size += torrent.Size
+		}
+		torrents = torrents[:i]
+	}
+	return torrents
+}
+
+func (q *TorrentsQuery) match(t *client.Torrent) bool {
+	if q.SavePath != "" && t.SavePath != q.SavePath ||
+		q.SavePathPrefix != "" && t.SavePath != q.SavePathPrefix &&
+			!strings.HasPrefix(t.SavePath, q.SavePathPrefix+`/`) && !strings.HasPrefix(t.SavePath, q.SavePathPrefix+`\`) ||
+		q.ContentPath != "" && t.ContentPath != q.ContentPath ||
+		q.Excludes != "" && t.MatchFiltersOr(q.excludesList) ||
+		q.Tracker != "" && !t.MatchTracker(q.Tracker) ||
+		q.minTorrentSize >= 0 && t.Size < q.minTorrentSize ||
+		q.maxTorrentSize >= 0 && t.Size > q.maxTorrentSize ||
+		q.addedAfter > 0 && t.Atime < q.addedAfter ||
+		q.completedBefore > 0 && (t.Ctime <= 0 || t.Ctime >= q.completedBefore) ||
+		q.activeSince > 0 && t.ActivityTime < q.activeSince ||
+		q.notActiveSince > 0 && t.ActivityTime >= q.notActiveSince ||
+		q.ExcludeTag != "" && t.HasAnyTag(q.ExcludeTag) ||
+		q.Partial && t.Size == t.SizeTotal {
+		return false
+	}
+	return true
+}
@@ -4,11 +4,8 @@ import (
 	"bytes"
 	"fmt"
 	"os"
-	"path"
-	"sort"
 	"strings"
 	"text/template"
-	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -26,9 +23,11 @@ var command = &cobra.Command{
 	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "show"},
 	Short:       "Show torrents of client.",
 	Long: `Show torrents of client.
+` + constants.HELP_CLIENT_GROUP_ARG + `, the torrents of all clients are displayed in one list
+with an additional "Client" field.
 [infoHash]...: Args list, info-hash list of torrents. It's possible to use state filter to select multiple torrents:
   _all, _active, _done, _undone, _downloading, _seeding, _paused, _completed, _error.
-If both filter flags (--category & --tag & --filter) and args are not set, it will display current active torrents.
+If both filter flags (--category & --tag & --filter & --where) and args are not set, it will display current active torrents.
 If at least one filter flag is set but no arg is provided, the args is assumed to be "_all"
 
 By default, it displays found torrents of client in the list, which has several fields like "Name" and "State".
@@ -47,7 +46,7 @@ The "State" field displays some icon texts:
 * ? : Torrent state is unknown.
 * _ : Torrent contents files are partially selected for downloading.
 
-Specially, if all args is an (1) single info-hash, it displays the details of that torrent instead of the list.
+Specially, if {client} is a single client and all args is an (1) single info-hash, it displays the details of that torrent instead of the list.
 
 If "--json" flag is set, it prints torrents info in json (array) format.
 
@@ -82,6 +81,7 @@ type Torrent struct {
 	Seeders            int64 // Cnt of seeders (including self client, if it's seeding), returned by tracker
 	Leechers           int64
 	Meta               map[string]int64
+	Client             string // client name. Only set when torrents of multiple clients are listed together
 }
 
 The template render result will be trim spaced.
@@ -104,6 +104,7 @@ var (
 	activeSinceStr     = ""
 	notActiveSinceStr  = ""
 	filter             = ""
+	where              = ""
 	category           = ""
 	tag                = ""
 	excludeTag         = ""
@@ -153,6 +154,7 @@ func init() {
 	command.Flags().StringVarP(&notActiveSinceStr, "not-active-since", "", "",
 		`Only showing torrent that does NOT has activity since (>=) this time. `+constants.HELP_ARG_TIMES)
 	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&where, "where", "", "", constants.HELP_ARG_WHERE_TORRENT)
 	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
 	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
 	command.Flags().StringVarP(&excludeTag, "exclude-tag", "", "", `Comma-separated tag list. `+
@@ -185,12 +187,9 @@ func show(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf(`--sum, --json, --format, --show-files, --show-trackers flags are NOT compatible ` +
 			`(unless the first two and the last two)`)
 	}
-	if util.CountNonZeroVariables(savePath, savePathPrefix, contentPath) > 1 {
-		return fmt.Errorf("--save-path, --save-path-prefix and --content-path flags are NOT compatible")
-	}
-	clientInstance, err := client.CreateClient(clientName)
+	clientInstances, err := client.CreateClientsFromArg(clientName)
 	if err != nil {
-		return fmt.Errorf("failed to create client: %w", err)
+		return err
 	}
 	if largestFlag && newestFlag {
 		return fmt.Errorf("--largest and --newest flags are NOT compatible")
@@ -202,55 +201,34 @@ func show(cmd *cobra.Command, args []string) error {
 		sortFlag = "time"
 		orderFlag = "desc"
 	}
-	desc := false
-	if orderFlag == "desc" {
-		desc = true
-	}
-	if savePath != "" {
-		savePath = path.Clean(savePath)
-	}
-	if savePathPrefix != "" {
-		savePathPrefix = path.Clean(savePathPrefix)
-	}
-	if contentPath != "" {
-		contentPath = path.Clean(contentPath)
-	}
-	minTorrentSize, _ := util.RAMInBytes(minTorrentSizeStr)
-	maxTorrentSize, _ := util.RAMInBytes(maxTorrentSizeStr)
-	maxTotalSize, _ := util.RAMInBytes(maxTotalSizeStr)
-	var addedAfter, completedBefore, activeSince, notActiveSince int64
-	now := time.Now()
-	if addedAfterStr != "" {
-		addedAfter, err = util.ParseTimeWithNow(addedAfterStr, nil, now)
-		if err != nil {
-			return fmt.Errorf("invalid added-after: %w", err)
-		}
-	}
-	if completedBeforeStr != "" {
-		completedBefore, err = util.ParseTimeWithNow(completedBeforeStr, nil, now)
-		if err != nil {
-			return fmt.Errorf("invalid completed-before: %w", err)
-		}
-	}
-	if activeSinceStr != "" {
-		activeSince, err = util.ParseTimeWithNow(activeSinceStr, nil, now)
-		if err != nil {
-			return fmt.Errorf("invalid active-since: %w", err)
-		}
+	query := &TorrentsQuery{
+		All:             showAll,
+		InfoHashes:      infoHashes,
+		Category:        category,
+		Tag:             tag,
+		Filter:          filter,
+		Where:           where,
+		ExcludeTag:      excludeTag,
+		Tracker:         tracker,
+		SavePath:        savePath,
+		SavePathPrefix:  savePathPrefix,
+		ContentPath:     contentPath,
+		MinTorrentSize:  minTorrentSizeStr,
+		MaxTorrentSize:  maxTorrentSizeStr,
+		MaxTotalSize:    maxTotalSizeStr,
+		MaxTorrents:     maxTorrents,
+		AddedAfter:      addedAfterStr,
+		CompletedBefore: completedBeforeStr,
+		ActiveSince:     activeSinceStr,
+		NotActiveSince:  notActiveSinceStr,
+		Partial:         partial,
+		Excludes:        excludes,
+		Sort:            sortFlag,
+		Order:           orderFlag,
 	}
-	if notActiveSinceStr != "" {
-		notActiveSince, err = util.ParseTimeWithNow(notActiveSinceStr, nil, now)
-		if err != nil {
-			return fmt.Errorf("invalid not-active-since: %w", err)
-		}
-	}
-	if addedAfter > 0 && completedBefore > 0 && addedAfter > completedBefore {
-		return fmt.Errorf("--added-after must NOT be after --completed-before flag")
+	if err = query.Prepare(); err != nil {
+		return err
 	}
-	if activeSince > 0 && notActiveSince > 0 && activeSince >= notActiveSince {
-		return fmt.Errorf("--active-since must be before --not-active-since flag")
-	}
-	excludesList := util.SplitCsv(excludes)
 	var outputTemplate *template.Template
 	if format != "" {
 		if outputTemplate, err = helper.GetTemplate(format); err != nil {
@@ -258,18 +236,10 @@ func show(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	hasFilterCondition := savePath != "" || savePathPrefix != "" || contentPath != "" ||
-		tracker != "" || minTorrentSize >= 0 || maxTorrentSize >= 0 || addedAfter > 0 || completedBefore > 0 ||
-		activeSince > 0 || notActiveSince > 0 || partial || excludes != "" || excludeTag != ""
-	noConditionFlags := category == "" && tag == "" && filter == "" && !hasFilterCondition
-	var torrents []*client.Torrent
-	if showAll {
-		torrents, err = client.QueryTorrents(clientInstance, "", "", "")
-	} else if noConditionFlags && len(infoHashes) == 0 {
-		torrents, err = client.QueryTorrents(clientInstance, "", "", "", "_active")
-	} else if noConditionFlags && len(infoHashes) == 1 && !strings.HasPrefix(infoHashes[0], "_") &&
-		format == "" && !showJson && !showSum {
+	if len(clientInstances) == 1 && !showAll && query.NoConditions() && len(infoHashes) == 1 &&
+		!strings.HasPrefix(infoHashes[0], "_") && format == "" && !showJson && !showSum {
 		// display single torrent details
+		clientInstance := clientInstances[0]
 		if !client.IsValidInfoHash(infoHashes[0]) {
 			return fmt.Errorf("%s is not a valid infoHash", infoHashes[0])
 		}
@@ -300,81 +270,12 @@ func show(cmd *cobra.Command, args []string) error {
 			}
 		}
 		return nil
-	} else {
-		torrents, err = client.QueryTorrents(clientInstance, category, tag, filter, infoHashes...)
 	}
+	torrents, err := client.QueryClientsTorrents(clientInstances, query.Select)
 	if err != nil {
-		return fmt.Errorf("failed to fetch client torrents: %w", err)
-	}
-	if hasFilterCondition {
-		torrents = util.Filter(torrents, func(t *client.Torrent) bool {
-			if savePath != "" && t.SavePath != savePath ||
-				savePathPrefix != "" && t.SavePath != savePathPrefix && !strings.HasPrefix(t.SavePath, savePathPrefix+`/`) &&
-					!strings.HasPrefix(t.SavePath, savePathPrefix+`\`) ||
-				contentPath != "" && t.ContentPath != contentPath ||
-				excludes != "" && t.MatchFiltersOr(excludesList) ||
-				tracker != "" && !t.MatchTracker(tracker) ||
-				minTorrentSize >= 0 && t.Size < minTorrentSize ||
-				maxTorrentSize >= 0 && t.Size > maxTorrentSize ||
-				addedAfter > 0 && t.Atime < addedAfter ||
-				completedBefore > 0 && (t.Ctime <= 0 || t.Ctime >= completedBefore) ||
-				activeSince > 0 && t.ActivityTime < activeSince ||
-				notActiveSince > 0 && t.ActivityTime >= notActiveSince ||
-				excludeTag != "" && t.HasAnyTag(excludeTag) ||
-				partial && t.Size == t.SizeTotal {
-				return false
-			}
-			return true
-		})
-	}
-	if sortFlag != "" && sortFlag != constants.NONE {
-		sort.Slice(torrents, func(i, j int) bool {
-			switch sortFlag {
-			case "name":
-				return torrents[i].Name < torrents[j].Name
-			case "size":
-				return torrents[i].Size < torrents[j].Size
-			case "speed":
-				return torrents[i].DownloadSpeed+torrents[i].UploadSpeed <
-					torrents[j].DownloadSpeed+torrents[j].UploadSpeed
-			case "state":
-				if torrents[i].State != torrents[j].State {
-					return torrents[i].State < torrents[j].State
-				}
-				return torrents[i].LowLevelState < torrents[j].LowLevelState
-			case "time":
-				return torrents[i].Atime < torrents[j].Atime
-			case "activity-time":
-				return torrents[i].ActivityTime < torrents[j].ActivityTime
-			case "tracker":
-				if torrents[i].TrackerDomain != torrents[j].TrackerDomain {
-					return torrents[i].TrackerDomain < torrents[j].TrackerDomain
-				}
-				return torrents[i].Atime < torrents[j].Atime
-			}
-			return i < j
-		})
-		if desc {
-			for i, j := 0, len(torrents)-1; i < j; i, j = i+1, j-1 {
-				torrents[i], torrents[j] = torrents[j], torrents[i]
-			}
-		}
-	}
-	if maxTorrents >= 0 && len(torrents) > int(maxTorrents) {
-		torrents = torrents[:maxTorrents]
-	}
-	if maxTotalSize >= 0 {
-		i := 0
-		size := int64(0)
-		for _, torrent := range torrents {
-			if size+torrent.Size > maxTotalSize {
-				break
-			}
-			i++
-			size += torrent.Size
-		}
-		torrents = torrents[:i]
+		return err
 	}
+	torrents = query.SortAndLimit(torrents)
 
 	if outputTemplate != nil {
 		for _, torrent := range torrents {
@@ -395,22 +296,14 @@ func show(cmd *cobra.Command, args []string) error {
 			sep = " "
 		}
 	} else {
-		clientStatus, err := clientInstance.GetStatus()
-		if err != nil {
-			log.Errorf("Failed to get client status: %v", err)
-			fmt.Printf("Client %s | Showing %d torrents\n\n", clientInstance.GetName(), len(torrents))
+		if len(clientInstances) == 1 {
+			printClientHeader(clientInstances[0], len(torrents))
 		} else {
-			fmt.Printf("Client %s | %s | %s | %s | Showing %d torrents\n\n",
-				clientInstance.GetName(),
-				fmt.Sprintf("↑Spd/Lmt: %s / %s/s", util.BytesSize(float64(clientStatus.UploadSpeed)),
-					util.BytesSize(float64(clientStatus.UploadSpeedLimit))),
-				fmt.Sprintf("↓Spd/Lmt: %s / %s/s", util.BytesSize(float64(clientStatus.DownloadSpeed)),
-					util.BytesSize(float64(clientStatus.DownloadSpeedLimit))),
-				fmt.Sprintf("FreeSpace/UnfinishedDL: %s / %s", util.BytesSize(float64(clientStatus.FreeSpaceOnDisk)),
-					util.BytesSize(float64(clientStatus.UnfinishedDownloadingSize))),
-				len(torrents),
-			)
+			for _, clientInstance := range clientInstances {
+				printClientHeader(clientInstance, len(client.GetClientTorrents(clientInstances, clientInstance, torrents)))
+			}
 		}
+		fmt.Printf("\n")
 		showSummary := int64(1)
 		if showSum {
 			showSummary = 2
@@ -419,3 +312,22 @@ func show(cmd *cobra.Command, args []string) error {
 	}
 	return nil
 }
+
+func printClientHeader(clientInstance client.Client, cnt int) {
+	clientStatus, err := clientInstance.GetStatus()
+	if err != nil {
+		log.Errorf("Failed to get client %s status: %v", clientInstance.GetName(), err)
+		fmt.Printf("Client %s | Showing %d torrents\n", clientInstance.GetName(), cnt)
+		return
+	}
+	fmt.Printf("Client %s | %s | %s | %s | Showing %d torrents\n",
+		clientInstance.GetName(),
+		fmt.Sprintf("↑Spd/Lmt: %s / %s/s", util.BytesSize(float64(clientStatus.UploadSpeed)),
+			util.BytesSize(float64(clientStatus.UploadSpeedLimit))),
+		fmt.Sprintf("↓Spd/Lmt: %s / %s/s", util.BytesSize(float64(clientStatus.DownloadSpeed)),
+			util.BytesSize(float64(clientStatus.DownloadSpeedLimit))),
+		fmt.Sprintf("FreeSpace/UnfinishedDL: %s / %s", util.BytesSize(float64(clientStatus.FreeSpaceOnDisk)),
+			util.BytesSize(float64(clientStatus.UnfinishedDownloadingSize))),
+		cnt,
+	)
+}
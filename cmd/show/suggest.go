@@ -25,7 +25,7 @@ func init() {
 			}
 		}
 		if info.LastArgIndex == 1 {
-			return suggest.ClientArg(info.MatchingPrefix)
+			return suggest.ClientOrGroupArg(info.MatchingPrefix)
 		}
 		return suggest.InfoHashOrFilterArg(info.MatchingPrefix, info.Args[1])
 	})
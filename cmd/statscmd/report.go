@@ -0,0 +1,106 @@
+package statscmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/stats"
+	"github.com/sagan/ptool/util"
+)
+
+func showReport(statDb *stats.StatDb, clientnames []string) (err error) {
+	option := &stats.TrafficReportOption{
+		Period:  period,
+		GroupBy: groupBy,
+		Clients: config.ParseClientGroupAndOtherNames(clientnames...),
+	}
+	if option.Start, err = parseDay(start); err != nil {
+		return fmt.Errorf("invalid start: %w", err)
+	}
+	if option.End, err = parseDay(end); err != nil {
+		return fmt.Errorf("invalid end: %w", err)
+	}
+	rows, err := statDb.GetTrafficReport(option)
+	if err != nil {
+		return err
+	}
+	// columns of report: [period], [group by fields...], downloaded, uploaded
+	columns := []string{}
+	if period != "" {
+		columns = append(columns, "period")
+	}
+	for _, field := range stats.GroupFields {
+		if slices.Contains(groupBy, field) {
+			columns = append(columns, field)
+		}
+	}
+	getColumn := func(row *stats.TrafficReportRow, column string) string {
+		switch column {
+		case "period":
+			return row.Period
+		case "client":
+			return row.Client
+		case "site":
+			return row.Site
+		default:
+			return row.Category
+		}
+	}
+
+	switch format {
+	case "json":
+		return util.PrintJson(os.Stdout, rows)
+	case "csv":
+		writer := csv.NewWriter(os.Stdout)
+		writer.Write(append(slices.Clone(columns), "downloaded", "uploaded"))
+		for _, row := range rows {
+			record := []string{}
+			for _, column := range columns {
+				record = append(record, getColumn(row, column))
+			}
+			record = append(record, fmt.Sprint(row.Downloaded), fmt.Sprint(row.Uploaded))
+			writer.Write(record)
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+
+	for _, column := range columns {
+		fmt.Printf("%-15s  ", column)
+	}
+	fmt.Printf("%12s  %12s\n", "downloaded", "uploaded")
+	allDownloaded := int64(0)
+	allUploaded := int64(0)
+	for _, row := range rows {
+		for _, column := range columns {
+			fmt.Printf("%-15s  ", getColumn(row, column))
+		}
+		fmt.Printf("%12s  %12s\n", util.BytesSize(float64(row.Downloaded)), util.BytesSize(float64(row.Uploaded)))
+		allDownloaded += row.Downloaded
+		allUploaded += row.Uploaded
+	}
+	if len(columns) > 0 {
+		fmt.Printf("%-*s  %12s  %12s\n", len(columns)*17-2, "<total>",
+			util.BytesSize(float64(allDownloaded)), util.BytesSize(float64(allUploaded)))
+	}
+	return nil
+}
+
+// Parse a "2006-01-02" format date or a time duration (treated as time ago from now),
+// return the date in "2006-01-02" format. Empty str returns empty.
+func parseDay(str string) (string, error) {
+	if str == "" {
+		return "", nil
+	}
+	if _, err := util.ParseLocalDateTime(str); err == nil {
+		return str, nil
+	}
+	duration, err := util.ParseTimeDuration(str)
+	if err != nil {
+		return "", fmt.Errorf("%q is neither a date nor a time duration", str)
+	}
+	return util.FormatDate(util.Now() - duration), nil
+}
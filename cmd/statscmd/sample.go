@@ -0,0 +1,73 @@
+package statscmd
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/util"
+)
+
+var sampleCommand = &cobra.Command{
+	Use:   "sample {client | group}...",
+	Short: "Record traffic samples of client torrents to stats db.",
+	Long: `Record traffic samples (uploaded, downloaded, seeders, leechers) of client torrents to stats db.
+By default only brush torrents (of "` + config.BRUSH_CAT + `" category) are sampled.
+Brush command also records samples of client brush torrents each time it runs (if stats is enabled).
+Run this command periodically (e.g. using "daemon" command) to get finer-grained traffic history.
+Use "ptool stats torrent" to view the samples.`,
+	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+	RunE: sample,
+}
+
+var (
+	sampleCategory = ""
+	sampleAll      = false
+)
+
+func init() {
+	sampleCommand.Flags().StringVarP(&sampleCategory, "category", "", config.BRUSH_CAT,
+		"Only sample torrents of this category")
+	sampleCommand.Flags().BoolVarP(&sampleAll, "all", "a", false, "Sample all torrents of client")
+	command.AddCommand(sampleCommand)
+}
+
+func sample(cmd *cobra.Command, args []string) error {
+	clientNames := config.ParseClientGroupAndOtherNames(args...)
+	statDb, err := openStatDb()
+	if err != nil {
+		return err
+	}
+	category := sampleCategory
+	if sampleAll {
+		category = ""
+	}
+	errorCnt := int64(0)
+	for _, clientName := range clientNames {
+		clientInstance, err := client.CreateClient(clientName)
+		if err != nil {
+			log.Errorf("Failed to create client %s: %v", clientName, err)
+			errorCnt++
+			continue
+		}
+		torrents, err := clientInstance.GetTorrents("", category, true)
+		if err != nil {
+			log.Errorf("Failed to get client %s torrents: %v", clientName, err)
+			errorCnt++
+			continue
+		}
+		if err = statDb.AddTorrentSamples(util.Now(), clientName, torrents); err != nil {
+			log.Errorf("Failed to add client %s torrent samples: %v", clientName, err)
+			errorCnt++
+			continue
+		}
+		log.Infof("Sampled %d torrents of client %s", len(torrents), clientName)
+	}
+	if errorCnt > 0 {
+		return fmt.Errorf("%d errors", errorCnt)
+	}
+	return nil
+}
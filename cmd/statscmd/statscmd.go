@@ -3,6 +3,7 @@ package statscmd
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -19,24 +20,52 @@ var command = &cobra.Command{
 Only torrents added by ptool (of this machine) will be counted.
 The traffic info of a torrent will ONLY be recorded when it's been DELETED from the client.
 To use this command, enable the statistics feature by adding the "brushEnableStats = true"
-line to ptool.toml config file.`,
+line to ptool.toml config file.
+Use "stats sample" and "stats torrent" subcommands to record and view per-torrent traffic history.
+
+By default it shows a summary table of recent timespans (all time, last 30d / 7d, yesterday, today).
+If any of --start, --end, --period, --group-by or --format (other than "table") flags is set,
+it shows a report of the traffics in [start, end] date range instead, optionally grouped by
+time period (day|week|month) and / or client, site, category. In this mode the [clients] args
+are used as filter. E.g.:
+  ptool stats --start 2024-01-01 --end 2024-01-31 --period day --group-by client,site --format csv`,
 	RunE: statscmd,
 }
 
 var (
 	statsFilename = ""
+	start         = ""
+	end           = ""
+	period        = ""
+	groupBy       = []string{}
+	format        = ""
 )
 
 func init() {
-	command.Flags().StringVarP(&statsFilename, "stats-file", "", "",
+	command.PersistentFlags().StringVarP(&statsFilename, "stats-file", "", "",
 		"Manually specify stats file ("+config.STATS_FILENAME+") path")
+	command.Flags().StringVarP(&start, "start", "", "",
+		`Report start date (inclusive). "2006-01-02" format, or time duration like "30d" (30 days ago)`)
+	command.Flags().StringVarP(&end, "end", "", "",
+		`Report end date (inclusive). "2006-01-02" format, or time duration like "1d" (yesterday)`)
+	command.Flags().StringVarP(&period, "period", "", "", "Group report by time period: "+
+		strings.Join(stats.Periods, "|")+`. Week is represented by the date of it's Monday`)
+	command.Flags().StringSliceVarP(&groupBy, "group-by", "", nil, "Comma-separated list. Group report by fields: "+
+		strings.Join(stats.GroupFields, ", "))
+	cmd.AddEnumFlagP(command, &format, "format", "", &cmd.EnumFlag{
+		Description: "Output format",
+		Options: [][2]string{
+			{"table", "Human-readable table"},
+			{"json", "JSON array. Traffic values are in bytes"},
+			{"csv", "CSV with header line. Traffic values are in bytes"},
+		},
+	})
 	cmd.RootCmd.AddCommand(command)
 }
 
-func statscmd(cmd *cobra.Command, args []string) error {
-	clientnames := args
+func openStatDb() (*stats.StatDb, error) {
 	if !config.Get().BrushEnableStats {
-		return fmt.Errorf("statistics feature is NOT enabled currently. " +
+		return nil, fmt.Errorf("statistics feature is NOT enabled currently. " +
 			"To enable it, add the \"brushEnableStats = true\" line to the top of ptool.toml config file. " +
 			"It will use the \"ptool_stats.txt\" (in the same dir of ptool.toml file) as the statistics data file")
 	}
@@ -45,7 +74,19 @@ func statscmd(cmd *cobra.Command, args []string) error {
 	}
 	statDb, err := stats.NewDb(statsFilename)
 	if err != nil {
-		return fmt.Errorf("failed to create stats db: %w", err)
+		return nil, fmt.Errorf("failed to create stats db: %w", err)
+	}
+	return statDb, nil
+}
+
+func statscmd(cmd *cobra.Command, args []string) error {
+	clientnames := args
+	statDb, err := openStatDb()
+	if err != nil {
+		return err
+	}
+	if start != "" || end != "" || period != "" || len(groupBy) > 0 || format != "table" {
+		return showReport(statDb, clientnames)
 	}
 	if len(clientnames) == 0 {
 		statDb.ShowTrafficStats("")
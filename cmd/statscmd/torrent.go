@@ -0,0 +1,149 @@
+package statscmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/stats"
+	"github.com/sagan/ptool/util"
+)
+
+var torrentCommand = &cobra.Command{
+	Use:   "torrent [infoHash]...",
+	Short: "Show traffic history of sampled client torrents.",
+	Long: `Show traffic history of sampled client torrents.
+If infoHash args are provided, show all samples of these torrents.
+Otherwise list sampled torrents ordered by uploaded amount (with --by-site, list per-site summaries instead,
+ordered by uploaded amount per GiB of torrents size, which indicates the return of brushing that site).
+Samples are recorded by brush command or "ptool stats sample" command.`,
+	RunE: torrentcmd,
+}
+
+var (
+	torrentClient = ""
+	torrentSite   = ""
+	torrentSince  = ""
+	torrentLimit  = int64(0)
+	torrentBySite = false
+	torrentJson   = false
+)
+
+func init() {
+	torrentCommand.Flags().StringVarP(&torrentClient, "client", "", "", "Only show torrents of this client")
+	torrentCommand.Flags().StringVarP(&torrentSite, "site", "", "", "Only show torrents of this site")
+	torrentCommand.Flags().StringVarP(&torrentSince, "since", "", "",
+		`Only count torrents which have samples in this time. E.g. "7d". With --by-site, `+
+			`count the traffic in this time (calculated from the first and latest samples of it) instead of total`)
+	torrentCommand.Flags().Int64VarP(&torrentLimit, "limit", "", 50, "Max number of torrents to show. -1 == no limit")
+	torrentCommand.Flags().BoolVarP(&torrentBySite, "by-site", "", false, "Show per-site summaries")
+	torrentCommand.Flags().BoolVarP(&torrentJson, "json", "", false, "Show output in json format")
+	command.AddCommand(torrentCommand)
+}
+
+func torrentcmd(cmd *cobra.Command, args []string) error {
+	statDb, err := openStatDb()
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return showTorrentsSamples(statDb, args)
+	}
+	since := int64(0)
+	if torrentSince != "" {
+		duration, err := util.ParseTimeDuration(torrentSince)
+		if err != nil {
+			return fmt.Errorf("invalid since: %w", err)
+		}
+		since = util.Now() - duration
+	}
+	if torrentBySite {
+		summaries, err := statDb.GetSiteSummaries(torrentClient, since)
+		if err != nil {
+			return err
+		}
+		if torrentJson {
+			return util.PrintJson(os.Stdout, summaries)
+		}
+		fmt.Printf("%-15s  %8s  %10s  %10s  %10s  %12s\n", "Site", "Torrents", "Size", "Downloaded", "Uploaded", "Uploaded/GiB")
+		for _, summary := range summaries {
+			fmt.Printf("%-15s  %8d  %10s  %10s  %10s  %12s\n", summary.Site, summary.Torrents,
+				util.BytesSize(float64(summary.Size)), util.BytesSize(float64(summary.Downloaded)),
+				util.BytesSize(float64(summary.Uploaded)), util.BytesSize(summary.UploadedPerGiB()))
+		}
+		return nil
+	}
+	summaries, err := statDb.GetTorrentSummaries(torrentClient, torrentSite, since, int(torrentLimit))
+	if err != nil {
+		return err
+	}
+	if torrentJson {
+		return util.PrintJson(os.Stdout, summaries)
+	}
+	fmt.Printf("%-40s  %-10s  %-10s  %10s  %10s  %10s  %6s  %-19s  %s\n",
+		"InfoHash", "Client", "Site", "Size", "Downloaded", "Uploaded", "Ratio", "LastSample", "Name")
+	for _, summary := range summaries {
+		ratio := float64(0)
+		if summary.Downloaded > 0 {
+			ratio = float64(summary.Uploaded) / float64(summary.Downloaded)
+		}
+		fmt.Printf("%-40s  %-10s  %-10s  %10s  %10s  %10s  %6.2f  %-19s  %s\n",
+			summary.InfoHash, summary.Client, summary.Site, util.BytesSize(float64(summary.Size)),
+			util.BytesSize(float64(summary.Downloaded)), util.BytesSize(float64(summary.Uploaded)), ratio,
+			util.FormatTime(summary.LastTs), summary.Name)
+	}
+	return nil
+}
+
+func showTorrentsSamples(statDb *stats.StatDb, infoHashes []string) error {
+	type torrentSamples struct {
+		*stats.SampledTorrent
+		Samples []*stats.TorrentSample
+	}
+	var results []*torrentSamples
+	for _, infoHash := range infoHashes {
+		torrents, samples, err := statDb.GetTorrentSamples(infoHash)
+		if err != nil {
+			return err
+		}
+		if len(torrents) == 0 {
+			return fmt.Errorf("torrent %s has no samples", infoHash)
+		}
+		for _, torrent := range torrents {
+			if torrentClient != "" && torrent.Client != torrentClient {
+				continue
+			}
+			results = append(results, &torrentSamples{torrent, samples[torrent.Client]})
+		}
+	}
+	if torrentJson {
+		return util.PrintJson(os.Stdout, results)
+	}
+	for i, result := range results {
+		if i > 0 {
+			fmt.Printf("\n")
+		}
+		fmt.Printf("Torrent: %s\nInfoHash: %s\nClient: %s  Site: %s  Category: %s  Size: %s  Added: %s\n",
+			result.Name, result.InfoHash, result.Client, result.Site, result.Category,
+			util.BytesSize(float64(result.Size)), util.FormatTime(result.Atime))
+		fmt.Printf("%-19s  %10s  %10s  %10s  %7s  %8s\n", "Time", "Downloaded", "Uploaded", "↑Speed", "Seeders", "Leechers")
+		var prev *stats.TorrentSample
+		for _, sample := range result.Samples {
+			speed := "-"
+			if prev != nil && sample.Ts > prev.Ts {
+				speed = util.BytesSize(float64(sample.Uploaded-prev.Uploaded)/float64(sample.Ts-prev.Ts)) + "/s"
+			}
+			fmt.Printf("%-19s  %10s  %10s  %10s  %7d  %8d\n", util.FormatTime(sample.Ts),
+				util.BytesSize(float64(sample.Downloaded)), util.BytesSize(float64(sample.Uploaded)), speed,
+				sample.Seeders, sample.Leechers)
+			prev = sample
+		}
+		if len(result.Samples) > 0 && result.Size > 0 {
+			last := result.Samples[len(result.Samples)-1]
+			fmt.Printf("Total uploaded: %s (%.2fx of size) in %s\n", util.BytesSize(float64(last.Uploaded)),
+				float64(last.Uploaded)/float64(result.Size), util.GetDurationString(last.Ts-result.Atime))
+		}
+	}
+	return nil
+}
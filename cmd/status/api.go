@@ -22,7 +22,7 @@ type StatusResponse struct {
 }
 
 func fetchClientStatus(clientInstance client.Client, showTorrents bool, showAllTorrents bool,
-	category string, ch chan *StatusResponse) {
+	category string, where string, ch chan *StatusResponse) {
 	response := &StatusResponse{Name: clientInstance.GetName(), Kind: 1}
 
 	clientStatus, err := clientInstance.GetStatus()
@@ -34,7 +34,13 @@ func fetchClientStatus(clientInstance client.Client, showTorrents bool, showAllT
 	}
 
 	if showTorrents {
-		clientTorrents, err := clientInstance.GetTorrents("", category, showAllTorrents)
+		var clientTorrents []*client.Torrent
+		var err error
+		if showAllTorrents {
+			clientTorrents, err = client.QueryTorrents(clientInstance, category, "", "", where)
+		} else {
+			clientTorrents, err = client.QueryTorrents(clientInstance, category, "", "", where, "_active")
+		}
 		if showAllTorrents {
 			sort.Slice(clientTorrents, func(i, j int) bool {
 				if clientTorrents[i].Name != clientTorrents[j].Name {
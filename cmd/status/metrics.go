@@ -0,0 +1,116 @@
+package status
+
+import (
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util/metrics"
+)
+
+// Fetch status of clients and sites and return them as Prometheus gauges.
+// names: client, site or group names. If empty, use all enabled clients and (non-dead, non-hidden) sites.
+// Errors of a client or site are not returned, "ptool_client_up" / "ptool_site_up" gauge of it is set to 0 instead.
+func CollectMetrics(names []string) []*metrics.Metric {
+	if len(names) == 0 {
+		for _, clientConfig := range config.Get().ClientsEnabled {
+			names = append(names, clientConfig.Name)
+		}
+		for _, siteConfig := range config.Get().SitesEnabled {
+			if !siteConfig.Dead && !siteConfig.Hidden {
+				names = append(names, siteConfig.GetName())
+			}
+		}
+	}
+	names = config.ParseGroupAndOtherNamesWithClients(names...)
+
+	clientUp := metrics.NewGauge("ptool_client_up",
+		"Whether the client status was successfully fetched (1) or not (0).", "client")
+	clientDownloadSpeed := metrics.NewGauge("ptool_client_download_speed_bytes",
+		"Current download speed of client, in bytes/s.", "client")
+	clientUploadSpeed := metrics.NewGauge("ptool_client_upload_speed_bytes",
+		"Current upload speed of client, in bytes/s.", "client")
+	clientDownloadSpeedLimit := metrics.NewGauge("ptool_client_download_speed_limit_bytes",
+		"Download speed limit of client, in bytes/s. <= 0 means no limit.", "client")
+	clientUploadSpeedLimit := metrics.NewGauge("ptool_client_upload_speed_limit_bytes",
+		"Upload speed limit of client, in bytes/s. <= 0 means no limit.", "client")
+	clientFreeSpace := metrics.NewGauge("ptool_client_free_space_bytes",
+		"Free disk space of client default save path. -1 means unknown.", "client")
+	clientUnfinishedSize := metrics.NewGauge("ptool_client_unfinished_size_bytes",
+		"Total un-downloaded size of unfinished torrents of client.", "client")
+	clientUnfinishedDownloadingSize := metrics.NewGauge("ptool_client_unfinished_downloading_size_bytes",
+		"Total un-downloaded size of unfinished torrents of client, excluding paused ones.", "client")
+	clientTorrents := metrics.NewGauge("ptool_client_torrents",
+		"Count of client torrents, by tracker domain.", "client", "tracker")
+	clientTorrentsSize := metrics.NewGauge("ptool_client_torrents_size_bytes",
+		"Total size of client torrents, by tracker domain.", "client", "tracker")
+	siteUp := metrics.NewGauge("ptool_site_up",
+		"Whether the site user status was successfully fetched (1) or not (0).", "site")
+	siteUploaded := metrics.NewGauge("ptool_site_uploaded_bytes", "User uploaded amount of site.", "site")
+	siteDownloaded := metrics.NewGauge("ptool_site_downloaded_bytes", "User downloaded amount of site.", "site")
+	siteSeeding := metrics.NewGauge("ptool_site_seeding_torrents", "Count of user seeding torrents of site.", "site")
+	siteLeeching := metrics.NewGauge("ptool_site_leeching_torrents", "Count of user leeching torrents of site.", "site")
+
+	doneFlag := map[string]bool{}
+	cnt := 0
+	ch := make(chan *StatusResponse, len(names))
+	for _, name := range names {
+		if name == "_" || doneFlag[name] {
+			continue
+		}
+		doneFlag[name] = true
+		if client.ClientExists(name) {
+			clientInstance, err := client.CreateClient(name)
+			if err != nil {
+				clientUp.Set(0, name)
+				continue
+			}
+			go fetchClientStatus(clientInstance, true, true, "", "", ch)
+			cnt++
+		} else if site.GetConfigSiteReginfo(name) != nil {
+			siteInstance, err := site.CreateSite(name)
+			if err != nil {
+				siteUp.Set(0, name)
+				continue
+			}
+			go fetchSiteStatus(siteInstance, false, false, false, ch)
+			cnt++
+		}
+	}
+	for i := 0; i < cnt; i++ {
+		response := <-ch
+		name := response.Name
+		if response.Kind == 1 {
+			if response.ClientStatus == nil {
+				clientUp.Set(0, name)
+				continue
+			}
+			status := response.ClientStatus
+			clientUp.Set(metrics.Bool(response.Error == nil), name)
+			clientDownloadSpeed.Set(float64(status.DownloadSpeed), name)
+			clientUploadSpeed.Set(float64(status.UploadSpeed), name)
+			clientDownloadSpeedLimit.Set(float64(status.DownloadSpeedLimit), name)
+			clientUploadSpeedLimit.Set(float64(status.UploadSpeedLimit), name)
+			clientFreeSpace.Set(float64(status.FreeSpaceOnDisk), name)
+			clientUnfinishedSize.Set(float64(status.UnfinishedSize), name)
+			clientUnfinishedDownloadingSize.Set(float64(status.UnfinishedDownloadingSize), name)
+			for _, torrent := range response.ClientTorrents {
+				clientTorrents.Add(1, name, torrent.TrackerDomain)
+				clientTorrentsSize.Add(float64(torrent.Size), name, torrent.TrackerDomain)
+			}
+		} else {
+			if response.SiteStatus == nil || response.Error != nil {
+				siteUp.Set(0, name)
+				continue
+			}
+			status := response.SiteStatus
+			siteUp.Set(1, name)
+			siteUploaded.Set(float64(status.UserUploaded), name)
+			siteDownloaded.Set(float64(status.UserDownloaded), name)
+			siteSeeding.Set(float64(status.TorrentsSeedingCnt), name)
+			siteLeeching.Set(float64(status.TorrentsLeechingCnt), name)
+		}
+	}
+	return []*metrics.Metric{clientUp, clientDownloadSpeed, clientUploadSpeed, clientDownloadSpeedLimit,
+		clientUploadSpeedLimit, clientFreeSpace, clientUnfinishedSize, clientUnfinishedDownloadingSize,
+		clientTorrents, clientTorrentsSize, siteUp, siteUploaded, siteDownloaded, siteSeeding, siteLeeching}
+}
@@ -30,6 +30,7 @@ var (
 	newestFlag     = false
 	filter         = ""
 	category       = ""
+	where          = ""
 )
 
 var command = &cobra.Command{
@@ -52,6 +53,7 @@ For site, display following status info:
 - ↓: : Current downloading statstics.
 
 If "-t" flag is set, it will also show the active / latest torrents list of client / site.
+The client torrents can be filtered by --category & --where flags.
 For the list format of client torrents, see help of "ptool show" command.
 For the list format of site torrents, see help of "ptool search" command.`,
 	RunE: status,
@@ -72,6 +74,7 @@ func init() {
 	command.Flags().BoolVarP(&newestFlag, "newest", "n", false, `Sort torrents by time in desc order"`)
 	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
 	command.Flags().StringVarP(&category, "category", "", "", "Filter client torrents by category")
+	command.Flags().StringVarP(&where, "where", "", "", constants.HELP_ARG_WHERE_TORRENT)
 	cmd.RootCmd.AddCommand(command)
 }
 
@@ -98,7 +101,10 @@ func status(cmd *cobra.Command, args []string) error {
 			}
 		}
 	}
-	names = config.ParseGroupAndOtherNames(names...)
+	if _, err := client.CompileWhere(where); err != nil {
+		return err
+	}
+	names = config.ParseGroupAndOtherNamesWithClients(names...)
 
 	if len(names) == 0 {
 		return fmt.Errorf("no sites or clients provided")
@@ -120,7 +126,7 @@ func status(cmd *cobra.Command, args []string) error {
 				errorCnt++
 				continue
 			}
-			go fetchClientStatus(clientInstance, showTorrents, showFull, category, ch)
+			go fetchClientStatus(clientInstance, showTorrents, showFull, category, where, ch)
 			cnt++
 		} else if site.GetConfigSiteReginfo(name) != nil {
 			siteInstance, err := site.CreateSite(name)
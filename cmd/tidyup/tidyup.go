@@ -33,6 +33,7 @@ The above cmd changes the category of all torrents in "local" client that has ta
 var (
 	dryRun      = false
 	filter      = ""
+	where       = ""
 	category    = ""
 	tag         = ""
 	maxTorrents = int64(0)
@@ -40,6 +41,7 @@ var (
 
 func init() {
 	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&where, "where", "", "", constants.HELP_ARG_WHERE_TORRENT)
 	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
 	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
 	command.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "Dry run. Do NOT actually modify torrents to client")
@@ -53,7 +55,7 @@ func tidyup(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
-	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter)
+	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, where)
 	if err != nil {
 		return fmt.Errorf("failed to get torrents: %w", err)
 	}
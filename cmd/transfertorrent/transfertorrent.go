@@ -7,9 +7,9 @@ import (
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
-	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/helper"
 )
 
@@ -43,6 +43,7 @@ var (
 	category     string
 	tag          string
 	filter       string
+	where        string
 	dstClient    string
 	mapSavePaths []string
 )
@@ -53,6 +54,7 @@ func init() {
 	command.Flags().Int64VarP(&maxTorrents, "max-torrents", "", -1,
 		"Number limit of transferred torrents. -1 == no limit")
 	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&where, "where", "", "", constants.HELP_ARG_WHERE_TORRENT)
 	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
 	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
 	command.Flags().StringVarP(&dstClient, "dst-client", "", "", `Target client. Transfer torrents to this client`)
@@ -66,16 +68,16 @@ func init() {
 func transfertorrent(cmd *cobra.Command, args []string) (err error) {
 	srcClient := args[0]
 	infoHashes := args[1:]
-	if category == "" && tag == "" && filter == "" {
+	if category == "" && tag == "" && filter == "" && where == "" {
 		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
 			return err
 		} else {
 			infoHashes = _infoHashes
 		}
 	}
-	var savePathMapper *common.PathMapper
+	var savePathMapper *util.PathMapper
 	if len(mapSavePaths) > 0 {
-		savePathMapper, err = common.NewPathMapper(mapSavePaths)
+		savePathMapper, err = util.NewPathMapper(mapSavePaths)
 		if err != nil {
 			return fmt.Errorf("invalid map-save-path(s): %w", err)
 		}
@@ -89,7 +91,7 @@ func transfertorrent(cmd *cobra.Command, args []string) (err error) {
 		return fmt.Errorf("failed to create dst client: %w", err)
 	}
 
-	torrents, err := client.QueryTorrents(srcClientInstance, category, tag, filter, infoHashes...)
+	torrents, err := client.QueryTorrents(srcClientInstance, category, tag, filter, where, infoHashes...)
 	if err != nil {
 		return fmt.Errorf("failed to query client torrents: %w", err)
 	}
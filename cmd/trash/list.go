@@ -0,0 +1,58 @@
+package trash
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+)
+
+var listCommand = &cobra.Command{
+	Use:   "list {client}",
+	Short: "List trashed items of client.",
+	Long: fmt.Sprintf(`List trashed items of client, oldest first.
+%s.`, constants.HELP_CLIENT_GROUP_ARG),
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: list,
+}
+
+var (
+	listJson = false
+)
+
+func init() {
+	listCommand.Flags().BoolVarP(&listJson, "json", "", false, "Show output in json format")
+	command.AddCommand(listCommand)
+}
+
+func list(cmd *cobra.Command, args []string) error {
+	clientInstances, err := client.CreateClientsFromArg(args[0])
+	if err != nil {
+		return err
+	}
+	var items []*client.TrashItem
+	for _, clientInstance := range clientInstances {
+		clientItems, err := client.GetTrashItems(clientInstance)
+		if err != nil {
+			return fmt.Errorf("client %s: %w", clientInstance.GetName(), err)
+		}
+		items = append(items, clientItems...)
+	}
+	if listJson {
+		return util.PrintJson(os.Stdout, items)
+	}
+	fmt.Printf("%-51s  %-15s  %-19s  %-10s  %s\n", "Id", "Client", "Time", "Size", "Name")
+	totalSize := int64(0)
+	for _, item := range items {
+		clientName, _ := util.StringPrefixInWidth(item.Client, 15)
+		fmt.Printf("%-51s  %-15s  %-19s  %-10s  %s\n", item.Id, clientName, util.FormatTime(item.Time),
+			util.BytesSize(float64(item.Size)), item.Name)
+		totalSize += item.Size
+	}
+	fmt.Printf("\n// Total: %d items, %s\n", len(items), util.BytesSize(float64(totalSize)))
+	return nil
+}
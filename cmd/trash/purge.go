@@ -0,0 +1,93 @@
+package trash
+
+import (
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/helper"
+)
+
+var purgeCommand = &cobra.Command{
+	Use:   "purge {client} [--older-than duration]",
+	Short: "Permanently delete trashed items of client.",
+	Long: fmt.Sprintf(`Permanently delete trashed items (including content files) of client.
+%s.
+
+By default it deletes all trashed items. Use --older-than flag to only delete items trashed before some time ago.
+It will ask for confirmation, unless --force flag is set.`, constants.HELP_CLIENT_GROUP_ARG),
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: purge,
+}
+
+var (
+	purgeForce = false
+	olderThan  = ""
+)
+
+func init() {
+	purgeCommand.Flags().BoolVarP(&purgeForce, "force", "", false, "Force action. Do NOT prompt for confirm")
+	purgeCommand.Flags().StringVarP(&olderThan, "older-than", "", "",
+		`Only purge items trashed before this time duration ago. E.g. "7d"`)
+	command.AddCommand(purgeCommand)
+}
+
+func purge(cmd *cobra.Command, args []string) error {
+	maxTime := int64(0)
+	if olderThan != "" {
+		duration, err := util.ParseTimeDuration(olderThan)
+		if err != nil {
+			return fmt.Errorf("invalid --older-than: %w", err)
+		}
+		maxTime = util.Now() - duration
+	}
+	clientInstances, err := client.CreateClientsFromArg(args[0])
+	if err != nil {
+		return err
+	}
+	var items []*client.TrashItem
+	for _, clientInstance := range clientInstances {
+		clientItems, err := client.GetTrashItems(clientInstance)
+		if err != nil {
+			return fmt.Errorf("client %s: %w", clientInstance.GetName(), err)
+		}
+		items = append(items, util.Filter(clientItems, func(item *client.TrashItem) bool {
+			return maxTime == 0 || item.Time < maxTime
+		})...)
+	}
+	if len(items) == 0 {
+		fmt.Printf("No trashed item to purge\n")
+		return nil
+	}
+	totalSize := int64(0)
+	for _, item := range items {
+		totalSize += item.Size
+	}
+	if !purgeForce {
+		for _, item := range items {
+			fmt.Fprintf(os.Stderr, "%-51s  %-19s  %s\n", item.Id, util.FormatTime(item.Time), item.Name)
+		}
+		fmt.Fprintf(os.Stderr, "\n")
+		if !helper.AskYesNoConfirm(fmt.Sprintf("Will permanently delete above %d items (%s) from trash",
+			len(items), util.BytesSize(float64(totalSize)))) {
+			return fmt.Errorf("abort")
+		}
+	}
+	errorCnt := int64(0)
+	for _, item := range items {
+		if err := client.PurgeTrashItem(item); err != nil {
+			log.Errorf("Failed to purge %s: %v", item.Id, err)
+			errorCnt++
+		}
+	}
+	fmt.Printf("Purged %d items (%s)\n", len(items)-int(errorCnt), util.BytesSize(float64(totalSize)))
+	if errorCnt > 0 {
+		return fmt.Errorf("%d errors", errorCnt)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package trash
+
+import (
+	"fmt"
+	"slices"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+)
+
+var restoreCommand = &cobra.Command{
+	Use:   "restore {client} {id}...",
+	Short: "Restore trashed items of client.",
+	Long: `Restore trashed items of client.
+It moves the content files back to the original save path, then re-adds the torrent to client
+with the original save path, category and tags, skipping hash checking.
+If any content file already exists in the original save path, the item will NOT be restored.
+
+{id}: the trashed item id, as displayed in "ptool trash list" output. Use "-" to restore all items.`,
+	Args: cobra.MatchAll(cobra.MinimumNArgs(2), cobra.OnlyValidArgs),
+	RunE: restore,
+}
+
+func init() {
+	command.AddCommand(restoreCommand)
+}
+
+func restore(cmd *cobra.Command, args []string) error {
+	clientInstance, err := client.CreateClient(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	ids := args[1:]
+	items, err := client.GetTrashItems(clientInstance)
+	if err != nil {
+		return err
+	}
+	all := slices.Contains(ids, "-")
+	errorCnt := int64(0)
+	for _, id := range ids {
+		if id != "-" && !slices.ContainsFunc(items, func(item *client.TrashItem) bool { return item.Id == id }) {
+			log.Errorf("Trashed item %s not found", id)
+			errorCnt++
+		}
+	}
+	for _, item := range items {
+		if !all && !slices.Contains(ids, item.Id) {
+			continue
+		}
+		if err := client.RestoreTrashItem(clientInstance, item); err != nil {
+			log.Errorf("Failed to restore %s (%s): %v", item.Id, item.Name, err)
+			errorCnt++
+			continue
+		}
+		fmt.Printf("Restored %s (%s) to %q\n", item.Id, item.Name, item.SavePath)
+	}
+	if errorCnt > 0 {
+		return fmt.Errorf("%d errors", errorCnt)
+	}
+	return nil
+}
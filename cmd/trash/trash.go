@@ -0,0 +1,26 @@
+package trash
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/cmd"
+)
+
+var command = &cobra.Command{
+	Use:   "trash",
+	Short: "Manage the trash of clients.",
+	Long: `Manage the trash of clients.
+If "trashPath" of a client is configured in ptool.toml (trash mode), when deleting torrents of the client with files
+(by "ptool delete" or brush), ptool exports the .torrent files, deletes the torrents from client (preserving files),
+then moves the content files to trash instead of deleting them.
+If the client runs on another machine or in a container, use "trashMapSavePaths" to map client save paths
+to local paths, same format as "--map-save-path" flag.
+
+Each trashed torrent is an item dir in trash: "<trashPath>/<time>.<infoHash>".
+Use "trash list", "trash restore" and "trash purge" subcommands to manage the trashed items.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(0), cobra.OnlyValidArgs),
+}
+
+func init() {
+	cmd.RootCmd.AddCommand(command)
+}
@@ -0,0 +1,92 @@
+package undo
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/journal"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/helper"
+)
+
+var command = &cobra.Command{
+	Use:   "undo [journal-id]",
+	Short: "Undo a destructive operation on client(s) which is recorded in journal.",
+	Long: `Undo a destructive operation on client(s) which is recorded in journal.
+The following commands record the previous state (save path, category, tags, trackers) of affected torrents
+to the journal ("<config_dir>/journal") before changing them:
+  delete, setsavepath, movesavepath, edittracker, removetrackers, setcategory.
+For delete, the .torrent files of deleted torrents are also exported to the journal.
+
+[journal-id]: the id of journal entry to undo. If not provided, undo the latest not-undone operation.
+Use "--list" flag to list all journal entries.
+
+The previous state of torrents is restored through client:
+- delete : Add back deleted torrents, with previous save path, category and tags.
+  If content files were also deleted, client will re-download them.
+- setsavepath / movesavepath : Set the save path of torrents back. Client will move the content files back.
+- edittracker / removetrackers : Restore the trackers list of torrents.
+- setcategory : Set the category of torrents back.
+
+It will ask for confirmation, unless --force flag is set.`,
+	Args: cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+	RunE: undo,
+}
+
+var (
+	listFlag = false
+	force    = false
+)
+
+func init() {
+	command.Flags().BoolVarP(&listFlag, "list", "l", false, "List journal entries (newest first)")
+	command.Flags().BoolVarP(&force, "force", "", false, "Force action. Do NOT prompt for confirm")
+	cmd.RootCmd.AddCommand(command)
+}
+
+func undo(cmd *cobra.Command, args []string) error {
+	if listFlag {
+		if len(args) > 0 {
+			return fmt.Errorf("--list flag can not be used with journal-id arg")
+		}
+		entries, err := journal.List()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%-36s  %-19s  %-8s  %-20s  %-6s  %s\n", "Id", "Time", "Torrents", "Clients", "Undone", "Command")
+		for _, entry := range entries {
+			clients, _ := util.StringPrefixInWidth(strings.Join(entry.Clients(), ","), 20)
+			fmt.Printf("%-36s  %-19s  %-8d  %-20s  %-6t  %s\n", entry.Id, util.FormatTime(entry.Time),
+				len(entry.Torrents), clients, entry.UndoneTime > 0, entry.Command)
+		}
+		return nil
+	}
+	id := ""
+	if len(args) > 0 {
+		id = args[0]
+	}
+	entry, err := journal.Get(id)
+	if err != nil {
+		return err
+	}
+	if entry.UndoneTime > 0 {
+		return fmt.Errorf("journal %s was already undone at %s", entry.Id, util.FormatTime(entry.UndoneTime))
+	}
+	if !force {
+		fmt.Fprintf(os.Stderr, "%-20s  %-40s  %s\n", "Client", "InfoHash", "Name")
+		for _, record := range entry.Torrents {
+			fmt.Fprintf(os.Stderr, "%-20s  %-40s  %s\n", record.Client, record.InfoHash, record.Name)
+		}
+		fmt.Fprintf(os.Stderr, "\nJournal: %s\nTime: %s\nCommand: %s\n\n",
+			entry.Id, util.FormatTime(entry.Time), entry.Command)
+		if !helper.AskYesNoConfirm(fmt.Sprintf("Will undo the %s operation of above %d torrents",
+			entry.Operation, len(entry.Torrents))) {
+			return fmt.Errorf("abort")
+		}
+	}
+	return entry.Undo()
+}
@@ -17,12 +17,13 @@ import (
 	"github.com/sagan/ptool/rclone"
 	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/helper"
+	"github.com/sagan/ptool/util/torrentutil"
 )
 
 var command = &cobra.Command{
 	Use: "verifytorrent {torrentFilename | torrentId | torrentUrl}... " +
 		"{--save-path dir | --content-path path | --use-comment-meta | --rclone-lsjson-file file | " +
-		"--rclone-save-path path} [--check | --check-quick]",
+		"--rclone-save-path path} [--check | --check-quick] [--workers n] [--json]",
 	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "verifytorrent"},
 	Aliases:     []string{"verify", "verifytorrents"},
 	Short:       "Verify .torrent (metainfo) files are consistent with local disk contents.",
@@ -49,7 +50,18 @@ Exact one (not less or more) of the following flags must be set.
   and use it's output as index contents. E.g. "remote:Downloads".
 
 By default it will only examine file meta infos (file path & size).
-If --check flag is set, it will also do the hash checking.`, constants.HELP_TORRENT_ARGS),
+If --check flag is set, it will also do the hash checking.
+For BitTorrent v2 or hybrid torrent, the hash checking uses the v2 (SHA-256 Merkle tree) hashes:
+each file is checked against it's "pieces root" and "piece layers" independently.
+
+Pieces are hash checked in parallel by "--workers" (default: number of CPUs) workers,
+each of which uses a buffer of one piece length. For HDD, use a small value (e.g. 1 or 2) to avoid random reads.
+A progress line is displayed in stderr if it's a terminal.
+
+For torrent whose contents do NOT match, it displays the status of each damaged file:
+missing, wrong size, or the bad pieces and the byte ranges of the file they cover.
+Note pieces that overlap with missing or wrong size files are not checked.
+Use "--json" flag to output a detailed report of all torrents in json format instead.`, constants.HELP_TORRENT_ARGS),
 	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
 	RunE: verifytorrent,
 }
@@ -63,6 +75,8 @@ var (
 	checkQuick           = false
 	forceLocal           = false
 	showAll              = false
+	showJson             = false
+	workers              = 0
 	contentPath          = ""
 	defaultSite          = ""
 	savePath             = ""
@@ -90,6 +104,9 @@ func init() {
 			"only the first and last piece of each file will do hash computing")
 	command.Flags().BoolVarP(&forceLocal, "force-local", "", false, "Force treat all arg as local torrent filename")
 	command.Flags().BoolVarP(&showAll, "all", "a", false, "Show all info")
+	command.Flags().BoolVarP(&showJson, "json", "", false, "Output detailed verification report in json format")
+	command.Flags().IntVarP(&workers, "workers", "", 0,
+		"Number of concurrent hash checking workers. 0 means the number of CPUs")
 	command.Flags().StringVarP(&contentPath, "content-path", "", "",
 		"The path of torrent content. Can only be used with single torrent arg")
 	command.Flags().StringVarP(&defaultSite, "site", "", "", "Set default site of torrent url")
@@ -120,13 +137,16 @@ func verifytorrent(cmd *cobra.Command, args []string) error {
 	if !useCommentMeta && len(mapSavePaths) > 0 {
 		return fmt.Errorf("--map-save-path must be used with --use-comment-meta flag")
 	}
-	if showSum && showAll {
-		return fmt.Errorf("--sum and --all flags are NOT compatible")
+	if util.CountNonZeroVariables(showSum, showAll, showJson) > 1 {
+		return fmt.Errorf("--sum, --all and --json flags are NOT compatible")
 	}
 	if rcloneSavePath != "" || rcloneLsjsonFilename != "" {
 		if checkHash || checkQuick {
 			return fmt.Errorf("--rclone-* can NOT be used with --check or --check-quick flags")
 		}
+		if showJson {
+			return fmt.Errorf("--rclone-* can NOT be used with --json flag")
+		}
 	} else {
 		if checkHash && checkQuick {
 			return fmt.Errorf("--check and --check-quick flags are NOT compatible")
@@ -184,25 +204,29 @@ func verifytorrent(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("failed to parse rclone lsjson file: %w", err)
 		}
 	}
-	var savePathMapper *common.PathMapper
+	var savePathMapper *util.PathMapper
 	if len(mapSavePaths) > 0 {
-		savePathMapper, err = common.NewPathMapper(mapSavePaths)
+		savePathMapper, err = util.NewPathMapper(mapSavePaths)
 		if err != nil {
 			return fmt.Errorf("invalid map-save-path (s): %w", err)
 		}
 	}
 
+	// quiet: do not output the result of each torrent
+	quiet := showSum || showJson
 	statistics := common.NewTorrentsStatistics()
+	var reports []*TorrentVerifyReport
 	for i, torrent := range torrents {
-		if !showSum {
+		if !quiet {
 			fmt.Printf("(%d/%d) ", i+1, len(torrents))
 		}
 		_, tinfo, _, _, _, _, isLocal, err :=
 			helper.GetTorrentContent(torrent, defaultSite, forceLocal, false, stdinTorrentContents, false, nil)
 		if err != nil {
-			if !showSum {
+			if !quiet {
 				fmt.Printf("X torrent %s: failed to get: %v\n", torrent, err)
 			}
+			reports = append(reports, &TorrentVerifyReport{Torrent: torrent, Error: err.Error()})
 			statistics.UpdateTinfo(common.TORRENT_INVALID, nil)
 			errorCnt++
 			continue
@@ -227,25 +251,47 @@ func verifytorrent(cmd *cobra.Command, args []string) error {
 				}
 			}
 			if err != nil {
-				if !showSum {
+				if !quiet {
 					fmt.Printf("✕ %s : %v\n", torrent, err)
 				}
+				reports = append(reports, &TorrentVerifyReport{Torrent: torrent, Error: err.Error()})
 				statistics.UpdateTinfo(common.TORRENT_FAILURE, tinfo)
 				errorCnt++
 				continue
 			}
 		}
+		var report *torrentutil.VerifyReport
 		if rcloneSavePathFs != nil {
 			log.Infof("Verifying %s against rclone lsjson output", torrent)
 			err = tinfo.VerifyAgaintSavePathFs(rcloneSavePathFs)
 		} else {
 			log.Infof("Verifying %s (savepath=%s, contentpath=%s, checkhash=%t)", torrent, savePath, contentPath, checkHash)
-			_, err = tinfo.Verify(savePath, contentPath, checkMode, checkMinLength)
+			progress := helper.NewProgress(fmt.Sprintf("(%d/%d) %s", i+1, len(torrents), tinfo.ContentPath))
+			report, err = tinfo.VerifyDetailed(&torrentutil.VerifyOptions{
+				SavePath:       savePath,
+				ContentPath:    contentPath,
+				CheckHash:      checkMode,
+				CheckMinLength: checkMinLength,
+				Workers:        workers,
+				Progress:       progress.Update,
+			})
+			progress.Done()
+			if err == nil {
+				err = report.Err()
+			}
+			torrentReport := &TorrentVerifyReport{Torrent: torrent, Report: report}
+			if report == nil {
+				torrentReport.Error = err.Error()
+			}
+			reports = append(reports, torrentReport)
 		}
 		if err != nil {
-			if !showSum {
+			if !quiet {
 				fmt.Printf("X torrent %s: contents do NOT match with disk content(s) (hash check = %s): %v\n",
 					torrent, checkModeStr, err)
+				if report != nil {
+					report.FprintFiles(os.Stdout, showAll)
+				}
 			}
 			statistics.UpdateTinfo(common.TORRENT_FAILURE, tinfo)
 			errorCnt++
@@ -263,7 +309,7 @@ func verifytorrent(cmd *cobra.Command, args []string) error {
 					log.Debugf("Failed to rename %s to *%s: %v", torrent, constants.FILENAME_SUFFIX_OK, err)
 				}
 			}
-			if !showSum {
+			if !quiet {
 				fmt.Printf("✓ torrent %s: contents match with disk content(s) (hash check = %s)\n", torrent, checkModeStr)
 			}
 		}
@@ -271,10 +317,23 @@ func verifytorrent(cmd *cobra.Command, args []string) error {
 			fmt.Printf("\n")
 		}
 	}
-	fmt.Printf("\n")
-	statistics.Print(os.Stdout)
+	if showJson {
+		if err := util.PrintJson(os.Stdout, reports); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("\n")
+		statistics.Print(os.Stdout)
+	}
 	if errorCnt > 0 {
 		return fmt.Errorf("%d errors", errorCnt)
 	}
 	return nil
 }
+
+// Verification result of a torrent in "--json" output.
+type TorrentVerifyReport struct {
+	Torrent string
+	Error   string // error of getting or parsing torrent
+	Report  *torrentutil.VerifyReport
+}
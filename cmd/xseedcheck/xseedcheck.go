@@ -20,6 +20,8 @@ var command = &cobra.Command{
 site torrent id (e.g. "mteam.488424") or url (e.g. "https://kp.m-team.cc/details.php?id=488424").
 Torrent url that does NOT belong to any site (e.g. a public site url) is also supported.
 Use a single "-" to read .torrent file contents from stdin.
+{infoHash}: info-hash of client torrent. For BitTorrent v2 or hybrid torrent, the v2 info-hash
+(either full 64 chars or truncated 40 chars form) is also accepted.
 
 Only filename and size will be compared. Not the disk file contents themselves.`,
 	Args: cobra.MatchAll(cobra.ExactArgs(3), cobra.OnlyValidArgs),
@@ -51,7 +53,7 @@ func xseedcheck(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to get %s: %w", torrent, err)
 	}
-	if tinfo.InfoHash == infoHash {
+	if tinfo.MatchInfoHash(infoHash) {
 		fmt.Printf("Result: identical. Torrent %s has the same infoHash with client %s torrent.\n", torrent, clientName)
 		return nil
 	}
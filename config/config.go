@@ -21,6 +21,7 @@ import (
 
 	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/expr"
 )
 
 const (
@@ -40,12 +41,16 @@ const (
 	PRIVATE_TAG                = "_private"
 	PUBLIC_TAG                 = "_public"
 	STATS_FILENAME             = "ptool_stats.txt"
+	BRUSH_RECORDS_DIR          = "brush_records" // "brush --record" snapshots dir, relative to config dir
+	JOURNAL_DIR                = "journal"       // journal of destructive client operations, relative to config dir
 	HISTORY_FILENAME           = "ptool_history"
 	SITE_TORRENTS_WIDTH        = 120 // min width for printing site torrents
 	CLIENT_TORRENTS_WIDTH      = 120 // min width for printing client torrents
 	GLOBAL_INTERNAL_LOCK_FILE  = "ptool.lock"
 	GLOBAL_LOCK_FILE           = "ptool-global.lock"
 	CLIENT_LOCK_FILE           = "client-%s.lock"
+	TRANSMISSION_META_FILE     = "transmission-%s.json"
+	DELUGE_META_FILE           = "deluge-%s.json"
 	EXAMPLE_CONFIG_FILE        = "ptool.example" // .toml , .yaml
 
 	DEFAULT_EXPORT_TORRENT_RENAME = "{{.name128}}.{{.infohash16}}.torrent"
@@ -70,6 +75,11 @@ const (
 	DEFAULT_SITE_FLOW_CONTROL_INTERVAL              = int64(3)
 	DEFAULT_SITE_MAX_REDIRECTS                      = int64(3)
 	DEFAULT_COOKIECLOUD_TIMEOUT                     = DEFAULT_TIMEOUT
+	// brush default strategy parameters
+	DEFAULT_CLIENT_BRUSH_BANDWIDTH_FULL_PERCENT          = float64(0.8)
+	DEFAULT_CLIENT_BRUSH_NEW_TORRENTS_TIMESPAN           = int64(15 * 60)
+	DEFAULT_CLIENT_BRUSH_SLOW_TORRENTS_CHECK_TIMESPAN    = int64(15 * 60)
+	DEFAULT_CLIENT_BRUSH_STALL_TORRENT_DELETION_TIMESPAN = int64(30 * 60)
 )
 
 type CookiecloudConfigStruct struct {
@@ -87,6 +97,7 @@ type CookiecloudConfigStruct struct {
 type GroupConfigStruct struct {
 	Name    string   `yaml:"name"`
 	Sites   []string `yaml:"sites"`
+	Clients []string `yaml:"clients"`
 	Comment string   `yaml:"comment"`
 }
 
@@ -99,6 +110,75 @@ type AliasConfigStruct struct {
 	Internal    bool
 }
 
+// A job that "ptool daemon" runs periodically.
+type ScheduleConfigStruct struct {
+	Name     string `yaml:"name"`
+	Cmd      string `yaml:"cmd"`      // ptool cmdline (without "ptool"), e.g. "brush local mteam"
+	Interval string `yaml:"interval"` // e.g. "10m", "1h". Default is 10m
+	Disabled bool   `yaml:"disabled"`
+	Comment  string `yaml:"comment"`
+}
+
+// A time slot of client bandwidth schedule, applied by "ptool bandwidth" cmd.
+// Slots are matched against current (local) time in order, the first matched one is applied.
+type BandwidthScheduleConfigStruct struct {
+	Name    string `yaml:"name"`
+	Comment string `yaml:"comment"`
+	// 适用的星期，例如 ["mon", "tue"]。也可以使用 "weekdays" (周一至周五) 或 "weekends" (周六、周日)。为空则适用于每天
+	Days []string `yaml:"days"`
+	// 适用的时间段，格式 "HH:MM-HH:MM"，例如 "09:00-18:00"。可以跨越午夜，例如 "23:00-07:00"
+	// (午夜后的部分属于前一天)。为空则适用于全天
+	Time               string `yaml:"time"`
+	DownloadSpeedLimit string `yaml:"downloadSpeedLimit"` // 全局下载速度限制(/s)，例如 "10MiB"。"-1": 无限制。为空则不修改
+	UploadSpeedLimit   string `yaml:"uploadSpeedLimit"`   // 全局上传速度限制(/s)。"-1": 无限制。为空则不修改
+}
+
+// A torrent making profile of site, applied by "ptool maketorrent --profile site[/name]" cmd.
+type MakeTorrentProfileConfigStruct struct {
+	Name     string   `yaml:"name"`
+	Comment  string   `yaml:"comment"`  // 配置的备注说明。不会写入生成的种子，种子的 "comment" 字段使用 TorrentComment
+	Trackers []string `yaml:"trackers"` // 生成种子的 tracker 地址列表(Announce & AnnounceList 字段)
+	Private  bool     `yaml:"private"`  // 将生成种子标记为 private
+	Source   string   `yaml:"source"`   // 生成种子的 "info.source" 字段。部分站点要求该字段为站点名称
+	// 生成种子的 "comment" 字段
+	TorrentComment string `yaml:"torrentComment"`
+	// 不添加到种子的文件 (gitignore 格式)。与默认的排除规则及命令行 --exclude 参数的规则合并使用
+	Excludes []string `yaml:"excludes"`
+	// 文件名长度限制 (字节，UTF-8)。0: 使用默认值；-1: 不限制
+	FilenameLengthLimit int64 `yaml:"filenameLengthLimit"`
+}
+
+// A rule of "ptool autorule" cmd. Rules are matched against client torrents in order.
+// A rule matches a torrent if all of it's (set) conditions are met; the actions of all matched rules are applied,
+// later rules' actions override earlier ones (tags are accumulated).
+type AutoruleConfigStruct struct {
+	Name     string   `yaml:"name"`
+	Comment  string   `yaml:"comment"`
+	Disabled bool     `yaml:"disabled"`
+	Clients  []string `yaml:"clients"` // 仅适用于这些客户端(或客户端分组)。为空则适用于所有客户端
+	Final    bool     `yaml:"final"`   // 匹配本规则的种子不再继续匹配后面的规则
+
+	// 匹配条件。所有设置了的条件都满足时规则匹配种子
+	Sites      []string `yaml:"sites"`      // 种子所属站点(根据 site:xxx 标签或 tracker 域名判断)
+	Trackers   []string `yaml:"trackers"`   // tracker 域名(或其父域名)，例如 "m-team.cc"
+	NameRegex  string   `yaml:"nameRegex"`  // 种子名称正则
+	MinSize    string   `yaml:"minSize"`    // 种子内容最小大小，例如 "1GiB"
+	MaxSize    string   `yaml:"maxSize"`    // 种子内容最大大小
+	Extensions []string `yaml:"extensions"` // 种子包含这些扩展名的文件(任意一个)，例如 ["mkv", "mp4"]
+	SavePaths  []string `yaml:"savePaths"`  // 种子保存路径为这些路径(或其子路径)
+	Where      string   `yaml:"where"`      // "--where" 查询表达式
+
+	// 动作
+	SetCategory        string   `yaml:"setCategory"`
+	AddTags            []string `yaml:"addTags"`
+	RemoveTags         []string `yaml:"removeTags"`
+	SetSavePath        string   `yaml:"setSavePath"`
+	RatioLimit         float64  `yaml:"ratioLimit"`         // 分享率限制。-1: 无限制
+	SeedingTimeLimit   string   `yaml:"seedingTimeLimit"`   // 做种时间限制，例如 "7d"。"-1": 无限制
+	UploadSpeedLimit   string   `yaml:"uploadSpeedLimit"`   // 上传速度限制(/s)，例如 "10MiB"。"-1": 无限制
+	DownloadSpeedLimit string   `yaml:"downloadSpeedLimit"` // 下载速度限制(/s)。"-1": 无限制
+}
+
 type ClientConfigStruct struct {
 	Type     string `yaml:"type"`
 	Name     string `yaml:"name"`
@@ -110,7 +190,8 @@ type ClientConfigStruct struct {
 	// 适用于本机上的 BT 客户端。当前客户端存放种子 (<info-hash>.torrent)的路径。各个客户端默认值：
 	// Transmission on Windows: %SystemRoot%\ServiceProfiles\LocalService\AppData\Local\transmission-daemon\Torrents .
 	// qBittorrent on Windows: %USERPROFILE%\AppData\Local\qBittorrent\BT_backup .
-	// 对于 TR 需要配置此选项才能使用部分命令（例如“导出种子”）。
+	// 对于 TR 推荐配置此选项（如果 TR 位于 Docker 容器等环境里，设为映射到本机的 TR torrents 文件夹路径）；
+	// 未配置时，TR 会尝试读取 RPC 返回的种子文件路径或从站点重新下载种子来“导出种子”。
 	// 对于 QB 此配置是可选的(因为 QB web API 提供导出种子接口)，但配置后会提高相关命令的性能。
 	LocalTorrentsPath                 string  `yaml:"localTorrentsPath"`
 	BrushMinDiskSpace                 string  `yaml:"brushMinDiskSpace"`
@@ -124,6 +205,32 @@ type ClientConfigStruct struct {
 	BrushDefaultUploadSpeedLimitValue int64 ``
 	QbittorrentNoLogin                bool  `yaml:"qbittorrentNoLogin"`  // if set, will NOT send login request
 	QbittorrentNoLogout               bool  `yaml:"qbittorrentNoLogout"` // if set, will NOT send logout request
+	// 刷流策略。默认 "default"
+	BrushStrategy string `yaml:"brushStrategy"`
+	// 上传速度达到上传限速的此比例时视为带宽已满，不再获取站点新种子。默认 0.8
+	BrushBandwidthFullPercent float64 `yaml:"brushBandwidthFullPercent"`
+	// 新添加的刷流种子在此时间内不会被检查(删除)。默认 15m
+	BrushNewTorrentsTimespan string `yaml:"brushNewTorrentsTimespan"`
+	// 慢速种子检查时间窗口：种子被标记为慢速后，经过此时间后平均上传速度仍然慢速的将成为删除候选。默认 15m
+	BrushSlowTorrentsCheckTimespan string `yaml:"brushSlowTorrentsCheckTimespan"`
+	// 被停止下载(stall)的未完成种子在此时间后将被删除。默认 30m
+	BrushStallTorrentDeletionTimespan      string `yaml:"brushStallTorrentDeletionTimespan"`
+	BrushNewTorrentsTimespanValue          int64  // seconds
+	BrushSlowTorrentsCheckTimespanValue    int64  // seconds
+	BrushStallTorrentDeletionTimespanValue int64  // seconds
+
+	// 回收站目录（本机路径）。设置后启用回收站模式：删除种子并同时删除文件时（delete 命令、刷流等自动删除），
+	// 不会删除硬盘上的内容文件，而是导出种子的 .torrent 文件、从客户端删除种子（保留文件），
+	// 然后将内容文件移动到此目录里。回收站目录应与客户端下载目录位于同一磁盘分区。
+	// 使用 "ptool trash list|restore|purge" 命令管理回收站。
+	TrashPath string `yaml:"trashPath"`
+	// 回收站模式下，本机路径与 BT 客户端保存路径的映射规则列表，格式 "本机路径|客户端路径"。
+	// 适用于 ptool 与 BT 客户端的文件系统路径不同的情况（例如客户端运行在 Docker 容器里）
+	TrashMapSavePaths []string `yaml:"trashMapSavePaths"`
+
+	// 客户端带宽(全局速度限制)时间表。"ptool bandwidth" 命令按当前时间应用第一个匹配的时间段的速度限制；
+	// 刷流时也会以当前时间段的上传速度限制作为客户端上传速度限制
+	BandwidthSchedule []*BandwidthScheduleConfigStruct `yaml:"bandwidthSchedule"`
 }
 
 type SiteConfigStruct struct {
@@ -240,6 +347,15 @@ type SiteConfigStruct struct {
 	DynamicSeedingTorrentMaxSizeValue int64
 	AutoComment                       string // 自动更新 ptool.toml 时系统生成的 comment。会被写入 Comment 字段
 	BrushAllowAddTorrentsPercent      int    `yaml:"brushAllowAddTorrentsPercent"` // Site种子数量占比(0~100]: ConfigStruct.BrushMaxTorrents; 0 = no limit
+	// 刷流种子评分表达式。可以使用种子属性、BT客户端状态和默认算法评分(score)等变量，结果 <= 0 的种子不会被添加。
+	// 例如 "age < 1h && seeders <= 3 ? max(score, 100) * 2 : score"。语法和可用变量参考 README
+	BrushScore        string `yaml:"brushScore"`
+	BrushScoreProgram *expr.Program
+	// 站点 API 访问密钥。UNIT3D: 个人设置里的 API Token；Gazelle: API Key (作为 Authorization header 发送，
+	// 例如 RED 直接使用 key，OPS 需要设置为 "token <key>" 格式)
+	ApiKey string `yaml:"apiKey"`
+	// 制作种子配置。"ptool maketorrent --profile site" 使用第一个配置，"--profile site/name" 使用指定名称的配置
+	MakeTorrentProfiles []*MakeTorrentProfileConfigStruct `yaml:"makeTorrentProfiles"`
 }
 
 type ConfigStruct struct {
@@ -259,11 +375,14 @@ type ConfigStruct struct {
 	SiteInsecure        bool                       `yaml:"siteInsecure"` // 强制禁用所有站点 TLS 证书校验。
 	SiteH2Fingerprint   string                     `yaml:"siteH2Fingerprint"`
 	BrushEnableStats    bool                       `yaml:"brushEnableStats"`
+	ServeToken          string                     `yaml:"serveToken"` // "serve" 命令 API 访问 token
 	Clients             []*ClientConfigStruct      `yaml:"clients"`
 	Sites               []*SiteConfigStruct        `yaml:"sites"`
 	Groups              []*GroupConfigStruct       `yaml:"groups"`
 	Aliases             []*AliasConfigStruct       `yaml:"aliases"`
 	Cookieclouds        []*CookiecloudConfigStruct `yaml:"cookieclouds"`
+	Schedules           []*ScheduleConfigStruct    `yaml:"schedules"`
+	Autorules           []*AutoruleConfigStruct    `yaml:"autorules"`
 	Comment             string                     `yaml:"comment"`
 	// 公网 BT 种子的分享率(Up/Dl)限制(到达后停止做种)。"add" 等命令添加公网种子到BT客户端时会自动应用此限制。
 	// 0 : unlimited。仅 qBittorrent 支持此选项。
@@ -444,6 +563,34 @@ func Get() *ConfigStruct {
 			}
 			client.BrushDefaultUploadSpeedLimitValue = v
 
+			for _, timespan := range []struct {
+				name  string
+				value string
+				def   int64
+				ptr   *int64
+			}{
+				{"brushNewTorrentsTimespan", client.BrushNewTorrentsTimespan,
+					DEFAULT_CLIENT_BRUSH_NEW_TORRENTS_TIMESPAN, &client.BrushNewTorrentsTimespanValue},
+				{"brushSlowTorrentsCheckTimespan", client.BrushSlowTorrentsCheckTimespan,
+					DEFAULT_CLIENT_BRUSH_SLOW_TORRENTS_CHECK_TIMESPAN, &client.BrushSlowTorrentsCheckTimespanValue},
+				{"brushStallTorrentDeletionTimespan", client.BrushStallTorrentDeletionTimespan,
+					DEFAULT_CLIENT_BRUSH_STALL_TORRENT_DELETION_TIMESPAN, &client.BrushStallTorrentDeletionTimespanValue},
+			} {
+				*timespan.ptr = timespan.def
+				if timespan.value != "" {
+					if *timespan.ptr, err = util.ParseTimeDuration(timespan.value); err != nil || *timespan.ptr <= 0 {
+						log.Fatalf("Invalid %s value %q in client %s config", timespan.name, timespan.value, client.Name)
+					}
+				}
+			}
+
+			if client.BrushBandwidthFullPercent == 0 {
+				client.BrushBandwidthFullPercent = DEFAULT_CLIENT_BRUSH_BANDWIDTH_FULL_PERCENT
+			} else if client.BrushBandwidthFullPercent < 0 || client.BrushBandwidthFullPercent > 1 {
+				log.Fatalf("Invalid brushBandwidthFullPercent value %v in client %s config, should between (0, 1]",
+					client.BrushBandwidthFullPercent, client.Name)
+			}
+
 			if client.Url != "" {
 				urlObj, err := url.Parse(client.Url)
 				if err != nil {
@@ -573,6 +720,19 @@ func GetGroupSites(name string) []string {
 	return nil
 }
 
+// if name is a client group, return it's clients, otherwise return nil.
+// The special "_all" group contains all enabled clients.
+func GetGroupClients(name string) []string {
+	if name == "_all" {
+		return util.Map(Get().ClientsEnabled, func(c *ClientConfigStruct) string { return c.Name })
+	}
+	group := GetGroupConfig(name)
+	if group != nil && len(group.Clients) > 0 {
+		return group.Clients
+	}
+	return nil
+}
+
 func ParseGroupAndOtherNamesWithoutDeduplicate(names ...string) []string {
 	names2 := []string{}
 	for _, name := range names {
@@ -592,6 +752,37 @@ func ParseGroupAndOtherNames(names ...string) []string {
 	return util.UniqueSlice(names)
 }
 
+// Parse an slice of clientGroupOrOther names, expand client group name to client names,
+// return the final slice of names
+func ParseClientGroupAndOtherNames(names ...string) []string {
+	names2 := []string{}
+	for _, name := range names {
+		if groupClients := GetGroupClients(name); groupClients != nil {
+			names2 = append(names2, groupClients...)
+		} else {
+			names2 = append(names2, name)
+		}
+	}
+	return util.UniqueSlice(names2)
+}
+
+// Similar to ParseGroupAndOtherNames, but group name is expanded to both it's site and client names.
+// The special "_all" group is still expanded to all sites only.
+func ParseGroupAndOtherNamesWithClients(names ...string) []string {
+	names2 := []string{}
+	for _, name := range names {
+		if name != "_all" {
+			if groupClients := GetGroupClients(name); groupClients != nil {
+				names2 = append(names2, groupClients...)
+				names2 = append(names2, GetGroupConfig(name).Sites...)
+				continue
+			}
+		}
+		names2 = append(names2, name)
+	}
+	return ParseGroupAndOtherNames(names2...)
+}
+
 func (cookieCloudConfig *CookiecloudConfigStruct) MatchFilter(filter string) bool {
 	return util.ContainsI(cookieCloudConfig.Name, filter) || util.ContainsI(cookieCloudConfig.Uuid, filter) ||
 		slices.ContainsFunc(cookieCloudConfig.Sites, func(s string) bool {
@@ -607,6 +798,9 @@ func (groupConfig *GroupConfigStruct) MatchFilter(filter string) bool {
 	return util.ContainsI(groupConfig.Name, filter) ||
 		slices.ContainsFunc(groupConfig.Sites, func(s string) bool {
 			return strings.EqualFold(s, filter)
+		}) ||
+		slices.ContainsFunc(groupConfig.Clients, func(s string) bool {
+			return strings.EqualFold(s, filter)
 		})
 }
 
@@ -665,6 +859,13 @@ func (siteConfig *SiteConfigStruct) Register() {
 		siteConfig.DynamicSeedingTorrentMinSizeValue = v
 	}
 
+	if siteConfig.BrushScore != "" {
+		if siteConfig.BrushScoreProgram, err = expr.Compile(siteConfig.BrushScore); err != nil {
+			log.Fatalf("Invalid brushScore expression %q in site %s config: %v",
+				siteConfig.BrushScore, siteConfig.GetName(), err)
+		}
+	}
+
 	if siteConfig.BrushAllowAddTorrentsPercent < 0 || siteConfig.BrushAllowAddTorrentsPercent > 100 {
 		log.Fatalf("Invalid allowAddTorrentsPercent value %v in site config, should between [0, 100]", siteConfig.BrushAllowAddTorrentsPercent)
 	}
@@ -688,6 +889,16 @@ func (siteConfig *SiteConfigStruct) GetTimezone() string {
 	return tz
 }
 
+// Return the make torrent profile of the name. If name is empty, return the first profile.
+func (siteConfig *SiteConfigStruct) GetMakeTorrentProfile(name string) *MakeTorrentProfileConfigStruct {
+	for _, profile := range siteConfig.MakeTorrentProfiles {
+		if name == "" || profile.Name == name {
+			return profile
+		}
+	}
+	return nil
+}
+
 func (siteConfig *SiteConfigStruct) MatchFilter(filter string) bool {
 	return util.ContainsI(siteConfig.GetName(), filter) || util.ContainsI(siteConfig.Type, filter) ||
 		util.ContainsI(siteConfig.Url, filter) || util.ContainsI(siteConfig.Comment, filter)
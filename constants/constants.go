@@ -45,6 +45,7 @@ const CLIENT_DEFAULT_DOWNLOADING_SPEED_LIMIT = 300 * 1024 * 1024 / 8 // BT客户
 // See: https://github.com/qbittorrent/qBittorrent/issues/7038 .
 const TORRENT_CONTENT_FILENAME_LENGTH_LIMIT = 240
 const TORRENT_DEFAULT_PIECE_LENGTH = "16MiB"
+const TORRENT_PIECE_LENGTH_AUTO = "auto" // choose piece length automatically based on contents size
 const META_TORRENT_FILE = ".torrent"
 const METADATA_FILE = "metadata.nfo"
 const METADATA_KEY_ARRAY_KEYS = "_array_keys"
@@ -21,6 +21,10 @@ This also applies when the args is "-", in which case the info-hash list read fr
 If any filter flag is set, the args list can be empty. If the args list is not empty,
 only torrents that match both the filter flags AND the args list will be selected.`
 
+const HELP_CLIENT_GROUP_ARG = `{client}: name of a client, or a client group (a group that has "clients" defined
+in config file, or the built-in "_all" group of all enabled clients). In the later case,
+the command runs on all clients of the group in parallel`
+
 const HELP_TIP_TTY_BINARY_OUTPUT = "binary .torrent file will mess up the terminal. Use pipe to redirect stdout"
 
 const HELP_ARG_TRACKER = `Filter torrents by tracker url or domain. Use "` +
@@ -56,3 +60,10 @@ const HELP_ARG_USE_REF_LINK = `Create reflinks instead of hardlinks. ` +
 
 const HELP_ARG_MIN_TORRENT_SIZE = `Skip torrent with size smaller than (<) this value. E.g. "1GiB". -1 == no limit`
 const HELP_ARG_MAX_TORRENT_SIZE = `Skip torrent with size larger than (>) this value. E.g. "1GiB". -1 == no limit`
+
+const HELP_ARG_WHERE_TORRENT = `Filter torrents by query expression, e.g. ` +
+	`'size > 10GiB && seeders < 3 && tracker =~ "m-team" && ratio < 1 && !("nodel" in tags)'. ` +
+	`Available variables: name, infohash, category, tags, site, tracker, trackerDomain, state, savePath, ` +
+	`contentPath, size, sizeTotal, sizeCompleted, progress, complete, downloaded, uploaded, ratio, ` +
+	`downloadSpeed, uploadSpeed, seeders, leechers, atime, ctime, activityTime, age, seedingTime, inactive. ` +
+	`See README for details`
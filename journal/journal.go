@@ -0,0 +1,244 @@
+// Package journal records destructive operations on BitTorrent clients (delete torrents, change save path,
+// category or trackers), so that they can be undone later by "ptool undo".
+//
+// Each operation is an entry stored in "<config_dir>/journal/<id>.json".
+// Exported .torrent files of deleted torrents are stored in "<config_dir>/journal/<id>/<client>.<infoHash>.torrent".
+package journal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/natefinch/atomic"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+)
+
+// Journaled operations.
+const (
+	OP_DELETE         = "delete"
+	OP_SETSAVEPATH    = "setsavepath"
+	OP_MOVESAVEPATH   = "movesavepath"
+	OP_EDITTRACKER    = "edittracker"
+	OP_REMOVETRACKERS = "removetrackers"
+	OP_SETCATEGORY    = "setcategory"
+)
+
+// The previous state of a client torrent before the operation.
+type TorrentRecord struct {
+	Client      string           `json:"client"`
+	InfoHash    string           `json:"infoHash"`
+	Name        string           `json:"name"`
+	SavePath    string           `json:"savePath"`
+	Category    string           `json:"category"`
+	Tags        []string         `json:"tags"`
+	Meta        map[string]int64 `json:"meta,omitempty"`
+	Trackers    []string         `json:"trackers,omitempty"`    // only recorded by tracker operations
+	TorrentFile string           `json:"torrentFile,omitempty"` // exported .torrent filename. Only for delete
+}
+
+type Entry struct {
+	Id          string           `json:"id"`
+	Time        int64            `json:"time"`
+	Operation   string           `json:"operation"`
+	Command     string           `json:"command"` // the ptool command line args
+	DeleteFiles bool             `json:"deleteFiles,omitempty"`
+	Torrents    []*TorrentRecord `json:"torrents"`
+	UndoneTime  int64            `json:"undoneTime,omitempty"`
+	mu          sync.Mutex
+}
+
+func Dir() string {
+	return filepath.Join(config.ConfigDir, config.JOURNAL_DIR)
+}
+
+// Create a new journal entry of operation. It's not saved until Save is called.
+func New(operation string) *Entry {
+	now := time.Now()
+	id := now.Format("20060102-150405") + "-" + operation
+	for i := 2; util.FileExists(filepath.Join(Dir(), id+".json")); i++ {
+		id = fmt.Sprintf("%s-%s-%d", now.Format("20060102-150405"), operation, i)
+	}
+	command := ""
+	if len(os.Args) > 1 {
+		command = strings.Join(os.Args[1:], " ")
+	}
+	return &Entry{
+		Id:        id,
+		Time:      now.Unix(),
+		Operation: operation,
+		Command:   command,
+	}
+}
+
+// Record the current state of torrents of clientInstance.
+// If the operation is delete, the .torrent files of torrents are also exported to journal dir;
+// If it's a tracker operation, the trackers of torrents are also recorded.
+// It's safe to be called concurrently. Torrents that fail to be recorded are skipped and an error is returned.
+func (entry *Entry) AddTorrents(clientInstance client.Client, torrents []*client.Torrent) error {
+	errorCnt := int64(0)
+	var lastErr error
+	for _, torrent := range torrents {
+		record := &TorrentRecord{
+			Client:   clientInstance.GetName(),
+			InfoHash: torrent.InfoHash,
+			Name:     torrent.Name,
+			SavePath: torrent.SavePath,
+			Category: torrent.Category,
+			Tags:     torrent.Tags,
+			Meta:     torrent.Meta,
+		}
+		switch entry.Operation {
+		case OP_DELETE:
+			if err := entry.exportTorrent(clientInstance, record); err != nil {
+				errorCnt++
+				lastErr = fmt.Errorf("failed to export torrent %s: %w", torrent.InfoHash, err)
+				continue
+			}
+		case OP_EDITTRACKER, OP_REMOVETRACKERS:
+			trackers, err := clientInstance.GetTorrentTrackers(torrent.InfoHash)
+			if err != nil {
+				errorCnt++
+				lastErr = fmt.Errorf("failed to get torrent %s trackers: %w", torrent.InfoHash, err)
+				continue
+			}
+			record.Trackers = util.Map(trackers, func(t client.TorrentTracker) string { return t.Url })
+		}
+		entry.mu.Lock()
+		entry.Torrents = append(entry.Torrents, record)
+		entry.mu.Unlock()
+	}
+	if errorCnt > 0 {
+		return fmt.Errorf("%d torrents not recorded, last error: %w", errorCnt, lastErr)
+	}
+	return nil
+}
+
+// Similar to AddTorrents, but use info-hashes of torrents. Info-hashes that do not exist in client are ignored.
+// If infoHashes is nil, all torrents of client are recorded.
+func (entry *Entry) AddInfoHashes(clientInstance client.Client, infoHashes []string) error {
+	if infoHashes == nil {
+		torrents, err := clientInstance.GetTorrents("", "", true)
+		if err != nil {
+			return fmt.Errorf("failed to get client torrents: %w", err)
+		}
+		return entry.AddTorrents(clientInstance, torrents)
+	}
+	var torrents []*client.Torrent
+	for _, infoHash := range infoHashes {
+		torrent, err := clientInstance.GetTorrent(infoHash)
+		if err != nil {
+			return fmt.Errorf("failed to get torrent %s: %w", infoHash, err)
+		}
+		if torrent != nil {
+			torrents = append(torrents, torrent)
+		}
+	}
+	return entry.AddTorrents(clientInstance, torrents)
+}
+
+func (entry *Entry) exportTorrent(clientInstance client.Client, record *TorrentRecord) error {
+	contents, err := clientInstance.ExportTorrentFile(record.InfoHash)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(Dir(), entry.Id)
+	if err = os.MkdirAll(dir, constants.PERM_DIR); err != nil {
+		return err
+	}
+	record.TorrentFile = record.Client + "." + record.InfoHash + ".torrent"
+	return atomic.WriteFile(filepath.Join(dir, record.TorrentFile), bytes.NewReader(contents))
+}
+
+// Save entry to journal dir. Do nothing if no torrent is recorded.
+func (entry *Entry) Save() error {
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if len(entry.Torrents) == 0 {
+		return nil
+	}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(Dir(), constants.PERM_DIR); err != nil {
+		return err
+	}
+	return atomic.WriteFile(filepath.Join(Dir(), entry.Id+".json"), bytes.NewReader(data))
+}
+
+// Return names of clients that have torrents recorded in entry.
+func (entry *Entry) Clients() []string {
+	return util.UniqueSlice(util.Map(entry.Torrents, func(r *TorrentRecord) string { return r.Client }))
+}
+
+// Get journal entry by id. If id is empty, return the latest entry that has not been undone.
+func Get(id string) (*Entry, error) {
+	if id == "" {
+		entries, err := List()
+		if err != nil {
+			return nil, err
+		}
+		index := slices.IndexFunc(entries, func(e *Entry) bool { return e.UndoneTime == 0 })
+		if index == -1 {
+			return nil, fmt.Errorf("no journal entry to undo")
+		}
+		return entries[index], nil
+	}
+	if strings.ContainsAny(id, `/\`) {
+		return nil, fmt.Errorf("invalid journal id %q", id)
+	}
+	return load(filepath.Join(Dir(), id+".json"))
+}
+
+// List all journal entries, newest first.
+func List() ([]*Entry, error) {
+	dirEntries, err := os.ReadDir(Dir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var entries []*Entry
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), ".json") {
+			continue
+		}
+		entry, err := load(filepath.Join(Dir(), dirEntry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Time != entries[j].Time {
+			return entries[i].Time > entries[j].Time
+		}
+		return entries[i].Id > entries[j].Id
+	})
+	return entries, nil
+}
+
+func load(filename string) (*Entry, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	entry := &Entry{}
+	if err = json.Unmarshal(data, entry); err != nil {
+		return nil, fmt.Errorf("invalid journal file %s: %w", filename, err)
+	}
+	return entry, nil
+}
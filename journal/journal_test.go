@@ -0,0 +1,126 @@
+package journal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/client/clienttest"
+	"github.com/sagan/ptool/config"
+)
+
+func TestJournal(t *testing.T) {
+	config.ConfigDir = t.TempDir()
+	torrents := []*client.Torrent{
+		{InfoHash: "a", Name: "A", Category: "movies"},
+		{InfoHash: "b", Name: "B"},
+	}
+	clientInstance := clienttest.New(torrents...)
+	clientInstance.Trackers["a"] = []string{"https://old.example.com/announce"}
+
+	entry := New(OP_SETCATEGORY)
+	if err := entry.AddTorrents(clientInstance, torrents); err != nil {
+		t.Fatalf("AddTorrents error: %v", err)
+	}
+	if err := entry.Save(); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+	trackerEntry := New(OP_EDITTRACKER)
+	if trackerEntry.Id == entry.Id {
+		t.Errorf("journal id %s is not unique", entry.Id)
+	}
+	if err := trackerEntry.AddTorrents(clientInstance, torrents[:1]); err != nil {
+		t.Fatalf("AddTorrents error: %v", err)
+	}
+	if err := trackerEntry.Save(); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+
+	entries, err := List()
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("List() = %d entries, %v; want 2 entries", len(entries), err)
+	}
+	loaded, err := Get(entry.Id)
+	if err != nil {
+		t.Fatalf("Get(%s) error: %v", entry.Id, err)
+	}
+	if loaded.Operation != OP_SETCATEGORY || len(loaded.Torrents) != 2 ||
+		!slices.Equal(loaded.Clients(), []string{"local"}) {
+		t.Errorf("Get(%s) = %+v, want the saved entry", entry.Id, loaded)
+	}
+
+	clientInstance.SetTorrentsCatetory([]string{"a", "b"}, "tv")
+	if err := undoSetCategory(clientInstance, loaded.Torrents); err != nil {
+		t.Fatalf("undoSetCategory error: %v", err)
+	}
+	if torrents[0].Category != "movies" || torrents[1].Category != "" {
+		t.Errorf("categories after undo = %q, %q; want movies, none", torrents[0].Category, torrents[1].Category)
+	}
+
+	clientInstance.Trackers["a"] = []string{"https://new.example.com/announce"}
+	loaded, err = Get(trackerEntry.Id)
+	if err != nil {
+		t.Fatalf("Get(%s) error: %v", trackerEntry.Id, err)
+	}
+	if err := undoTrackers(clientInstance, loaded.Torrents); err != nil {
+		t.Fatalf("undoTrackers error: %v", err)
+	}
+	if want := []string{"https://old.example.com/announce"}; !slices.Equal(clientInstance.Trackers["a"], want) {
+		t.Errorf("trackers after undo = %v, want %v", clientInstance.Trackers["a"], want)
+	}
+}
+
+func TestUndoDelete(t *testing.T) {
+	config.ConfigDir = t.TempDir()
+	clientInstance := clienttest.New(
+		&client.Torrent{InfoHash: "a", Name: "A", SavePath: "/downloads/movies", Category: "movies",
+			Tags: []string{"foo"}, Meta: map[string]int64{"id": 1}},
+		&client.Torrent{InfoHash: "b", Name: "B", SavePath: "/downloads"},
+	)
+	createClient = func(name string) (client.Client, error) { return clientInstance, nil }
+	t.Cleanup(func() { createClient = client.CreateClient })
+
+	// same as what delete cmd does
+	entry := New(OP_DELETE)
+	if err := entry.AddInfoHashes(clientInstance, []string{"a", "c"}); err != nil {
+		t.Fatalf("AddInfoHashes error: %v", err)
+	}
+	if err := entry.Save(); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+	if err := clientInstance.DeleteTorrents([]string{"a"}, true); err != nil {
+		t.Fatal(err)
+	}
+	if len(entry.Torrents) != 1 {
+		t.Fatalf("journal has %d torrents, want 1", len(entry.Torrents))
+	}
+	contents, err := os.ReadFile(filepath.Join(Dir(), entry.Id, entry.Torrents[0].TorrentFile))
+	if err != nil || !bytes.Equal(contents, clienttest.TorrentFile("a")) {
+		t.Errorf("exported torrent = %q, %v", contents, err)
+	}
+
+	loaded, err := Get(entry.Id)
+	if err != nil {
+		t.Fatalf("Get(%s) error: %v", entry.Id, err)
+	}
+	if err := loaded.Undo(); err != nil {
+		t.Fatalf("Undo error: %v", err)
+	}
+	torrent, _ := clientInstance.GetTorrent("a")
+	if torrent == nil || torrent.SavePath != "/downloads/movies" || torrent.Category != "movies" ||
+		!slices.Equal(torrent.Tags, []string{"foo"}) || torrent.Meta["id"] != 1 {
+		t.Errorf("torrent after undo = %+v, want the deleted torrent added back", torrent)
+	}
+	if len(clientInstance.Torrents) != 2 {
+		t.Errorf("client has %d torrents after undo, want 2", len(clientInstance.Torrents))
+	}
+	if loaded, err = Get(entry.Id); err != nil || loaded.UndoneTime == 0 {
+		t.Errorf("entry is not marked as undone: %v", err)
+	}
+	if err := loaded.Undo(); err == nil {
+		t.Errorf("Undo of an undone entry should fail")
+	}
+}
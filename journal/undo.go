@@ -0,0 +1,144 @@
+package journal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+)
+
+// Create the client of recorded torrents. Replaced in tests.
+var createClient = client.CreateClient
+
+// Restore the previous state of recorded torrents through client, then mark the entry as undone.
+//   - delete: add back deleted torrents using exported .torrent files, with previous save path, category & tags.
+//     If the content files were also deleted, the client will re-download them.
+//   - setsavepath / movesavepath: set the save path of torrents back. The client moves the content files.
+//   - edittracker / removetrackers: restore the trackers list of torrents.
+//   - setcategory: set the category of torrents back.
+func (entry *Entry) Undo() error {
+	if entry.UndoneTime > 0 {
+		return fmt.Errorf("journal %s was already undone at %s", entry.Id, util.FormatTime(entry.UndoneTime))
+	}
+	errorCnt := int64(0)
+	for _, clientName := range entry.Clients() {
+		records := util.Filter(entry.Torrents, func(r *TorrentRecord) bool { return r.Client == clientName })
+		clientInstance, err := createClient(clientName)
+		if err != nil {
+			log.Errorf("Failed to create client %s: %v", clientName, err)
+			errorCnt++
+			continue
+		}
+		var undoErr error
+		switch entry.Operation {
+		case OP_DELETE:
+			undoErr = entry.undoDelete(clientInstance, records)
+		case OP_SETSAVEPATH, OP_MOVESAVEPATH:
+			undoErr = undoSetSavePath(clientInstance, records)
+		case OP_EDITTRACKER, OP_REMOVETRACKERS:
+			undoErr = undoTrackers(clientInstance, records)
+		case OP_SETCATEGORY:
+			undoErr = undoSetCategory(clientInstance, records)
+		default:
+			return fmt.Errorf("unsupported journal operation %q", entry.Operation)
+		}
+		if undoErr != nil {
+			log.Errorf("Failed to undo %s of client %s: %v", entry.Operation, clientName, undoErr)
+			errorCnt++
+		}
+	}
+	if errorCnt > 0 {
+		return fmt.Errorf("%d errors", errorCnt)
+	}
+	entry.UndoneTime = time.Now().Unix()
+	return entry.Save()
+}
+
+func (entry *Entry) undoDelete(clientInstance client.Client, records []*TorrentRecord) error {
+	errorCnt := int64(0)
+	for _, record := range records {
+		if torrent, err := clientInstance.GetTorrent(record.InfoHash); err == nil && torrent != nil {
+			log.Infof("Torrent %s (%s) already exists in client %s", record.InfoHash, record.Name,
+				clientInstance.GetName())
+			continue
+		}
+		contents, err := os.ReadFile(filepath.Join(Dir(), entry.Id, record.TorrentFile))
+		if err != nil {
+			log.Errorf("Failed to read exported torrent %s (%s): %v", record.InfoHash, record.Name, err)
+			errorCnt++
+			continue
+		}
+		err = clientInstance.AddTorrent(contents, &client.TorrentOption{
+			SavePath: record.SavePath,
+			Category: record.Category,
+			Tags:     record.Tags,
+		}, record.Meta)
+		if err != nil {
+			log.Errorf("Failed to add back torrent %s (%s): %v", record.InfoHash, record.Name, err)
+			errorCnt++
+			continue
+		}
+		fmt.Printf("Added back torrent %s (%s) to client %s\n", record.InfoHash, record.Name, clientInstance.GetName())
+	}
+	if errorCnt > 0 {
+		return fmt.Errorf("%d errors", errorCnt)
+	}
+	return nil
+}
+
+func undoSetSavePath(clientInstance client.Client, records []*TorrentRecord) error {
+	savePaths := util.UniqueSlice(util.Map(records, func(r *TorrentRecord) string { return r.SavePath }))
+	for _, savePath := range savePaths {
+		infoHashes := util.Map(util.Filter(records, func(r *TorrentRecord) bool {
+			return r.SavePath == savePath
+		}), func(r *TorrentRecord) string { return r.InfoHash })
+		if err := clientInstance.SetTorrentsSavePath(infoHashes, savePath); err != nil {
+			return fmt.Errorf("failed to set save path to %q: %w", savePath, err)
+		}
+		fmt.Printf("Set save path of %d torrents of client %s back to %q\n",
+			len(infoHashes), clientInstance.GetName(), savePath)
+	}
+	return nil
+}
+
+func undoSetCategory(clientInstance client.Client, records []*TorrentRecord) error {
+	categories := util.UniqueSlice(util.Map(records, func(r *TorrentRecord) string { return r.Category }))
+	for _, category := range categories {
+		infoHashes := util.Map(util.Filter(records, func(r *TorrentRecord) bool {
+			return r.Category == category
+		}), func(r *TorrentRecord) string { return r.InfoHash })
+		cat := category
+		if cat == "" {
+			cat = constants.NONE
+		}
+		if err := clientInstance.SetTorrentsCatetory(infoHashes, cat); err != nil {
+			return fmt.Errorf("failed to set category to %q: %w", category, err)
+		}
+		fmt.Printf("Set category of %d torrents of client %s back to %q\n",
+			len(infoHashes), clientInstance.GetName(), category)
+	}
+	return nil
+}
+
+func undoTrackers(clientInstance client.Client, records []*TorrentRecord) error {
+	errorCnt := int64(0)
+	for _, record := range records {
+		added, removed, err := client.SetTorrentTrackers(clientInstance, record.InfoHash, record.Trackers)
+		if err != nil {
+			log.Errorf("Failed to restore trackers of torrent %s: %v", record.InfoHash, err)
+			errorCnt++
+			continue
+		}
+		fmt.Printf("Restored trackers of torrent %s (%s): +%d / -%d\n", record.InfoHash, record.Name, added, removed)
+	}
+	if errorCnt > 0 {
+		return fmt.Errorf("%d errors", errorCnt)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package gazelle
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// Number which may be encoded as json number or string.
+type apiNumber int64
+
+func (n *apiNumber) UnmarshalJSON(data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case float64:
+		*n = apiNumber(v)
+	case string:
+		i, _ := strconv.ParseInt(v, 10, 64)
+		*n = apiNumber(i)
+	default:
+		*n = 0
+	}
+	return nil
+}
+
+// https://github.com/WhatCD/Gazelle/wiki/JSON-API-Documentation#torrent-search
+// GET ajax.php?action=browse&searchstr=&page=&order_by=&order_way=
+type apiBrowseResponse struct {
+	Status   string `json:"status"` // "success" | "failure"
+	Error    string `json:"error"`
+	Response struct {
+		CurrentPage apiNumber         `json:"currentPage"`
+		Pages       apiNumber         `json:"pages"`
+		Results     []*apiBrowseGroup `json:"results"`
+	} `json:"response"`
+}
+
+// A torrent group (e.g. music album). Non-music torrents have no "torrents" list,
+// instead the group itself has the torrent fields.
+type apiBrowseGroup struct {
+	apiBrowseTorrent
+	GroupId     apiNumber           `json:"groupId"`
+	GroupName   string              `json:"groupName"`
+	Artist      string              `json:"artist"`
+	Tags        []string            `json:"tags"`
+	GroupYear   apiNumber           `json:"groupYear"`
+	GroupTime   apiNumber           `json:"groupTime"`
+	Torrents    []*apiBrowseTorrent `json:"torrents"`
+	ReleaseType string              `json:"releaseType"`
+}
+
+type apiBrowseTorrent struct {
+	TorrentId           apiNumber `json:"torrentId"`
+	Media               string    `json:"media"`
+	Encoding            string    `json:"encoding"`
+	Format              string    `json:"format"`
+	RemasterTitle       string    `json:"remasterTitle"`
+	Scene               bool      `json:"scene"`
+	Time                string    `json:"time"` // "2009-06-06 19:04:22"
+	Size                apiNumber `json:"size"`
+	Snatches            apiNumber `json:"snatches"`
+	Seeders             apiNumber `json:"seeders"`
+	Leechers            apiNumber `json:"leechers"`
+	IsFreeleech         bool      `json:"isFreeleech"`
+	IsNeutralLeech      bool      `json:"isNeutralLeech"`
+	IsPersonalFreeleech bool      `json:"isPersonalFreeleech"`
+}
@@ -5,6 +5,9 @@ package gazelle
 // 种子下载链接：https://dicmusic.club/torrents.php?action=download&id=&authkey=&torrent_pass=
 // (如果cookie有效，authkey 和 torrent_pass 可省略)
 // 注意下载时的 id 与 torrent.php 页面url里的 id 不同，后者是当前音乐专辑的 id
+// 种子列表和搜索使用 JSON API ( ajax.php?action=browse )，使用 cookie 认证；
+// 如果站点配置了 apiKey，会额外设置 "Authorization" header (OPS 等站需要设置为 "token <key>" 格式)
+// 刷流时 (full) 会额外获取最新种子列表的第 2 页和站点配置的 torrentsExtraUrls (browse api url) 里的种子
 
 import (
 	"fmt"
@@ -16,6 +19,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/site"
 	"github.com/sagan/ptool/util"
 )
@@ -35,6 +39,7 @@ func (gzsite *Site) PublishTorrent(contents []byte, metadata url.Values) (id str
 }
 
 const (
+	API_BROWSE_PATH          = "ajax.php?action=browse"
 	SELECTOR_USERNAME        = "#nav_userinfo"
 	SELECTOR_USER_UPLOADED   = "#stats_seeding"
 	SELECTOR_USER_DOWNLOADED = "#stats_leeching"
@@ -85,17 +90,193 @@ func (gzsite *Site) GetStatus() (*site.Status, error) {
 	}, nil
 }
 
+// Gazelle browse api only supports sorting by time / size / snatched / seeders / leechers / random.
+var sortFields = map[string]string{
+	"":             "time",
+	constants.NONE: "time",
+	"time":         "time",
+	"size":         "size",
+	"snatched":     "snatched",
+	"seeders":      "seeders",
+	"leechers":     "leechers",
+}
+
 func (gzsite *Site) GetAllTorrents(sort string, desc bool, pageMarker string, baseUrl string) (
 	torrents []*site.Torrent, nextPageMarker string, err error) {
-	return nil, "", site.ErrUnimplemented
+	sortField := sortFields[sort]
+	if sortField == "" {
+		return nil, "", fmt.Errorf("unsupported sort field: %s", sort)
+	}
+	page := int64(1)
+	if pageMarker != "" {
+		if page = util.ParseInt(pageMarker); page < 1 {
+			return nil, "", fmt.Errorf("invalid page marker: %s", pageMarker)
+		}
+	}
+	orderWay := "asc"
+	if desc {
+		orderWay = "desc"
+	}
+	params := url.Values{}
+	params.Set("order_by", sortField)
+	params.Set("order_way", orderWay)
+	params.Set("page", fmt.Sprint(page))
+	res, err := gzsite.browse(baseUrl, params)
+	if err != nil {
+		return nil, "", err
+	}
+	torrents = gzsite.convertTorrents(res.Response.Results)
+	if int64(res.Response.Pages) > page {
+		nextPageMarker = fmt.Sprint(page + 1)
+	}
+	return
 }
 
 func (gzsite *Site) GetLatestTorrents(full bool) ([]*site.Torrent, error) {
-	return nil, site.ErrUnimplemented
+	params := url.Values{}
+	params.Set("order_by", "time")
+	params.Set("order_way", "desc")
+	res, err := gzsite.browse(gzsite.SiteConfig.TorrentsUrl, params)
+	if err != nil {
+		return nil, err
+	}
+	torrents := gzsite.convertTorrents(res.Response.Results)
+	if !full {
+		return torrents, nil
+	}
+	// also fetch the next page and the extra api urls (e.g. other categories) of site
+	extraUrls := append([]string{gzsite.SiteConfig.TorrentsUrl}, gzsite.SiteConfig.TorrentsExtraUrls...)
+	for i, extraUrl := range extraUrls {
+		extraParams := url.Values{}
+		extraParams.Set("order_by", "time")
+		extraParams.Set("order_way", "desc")
+		if i == 0 {
+			if res.Response.Pages <= 1 {
+				continue
+			}
+			extraParams.Set("page", "2")
+		}
+		res, err := gzsite.browse(extraUrl, extraParams)
+		if err != nil {
+			log.Errorf("failed to fetch site extra torrents of %s: %v", extraUrl, err)
+			continue
+		}
+		torrents = append(torrents, gzsite.convertTorrents(res.Response.Results)...)
+	}
+	return util.UniqueSliceFn(torrents, func(t *site.Torrent) string { return t.Id }), nil
 }
 
+// baseUrl: optional api url, can use "%s" as keyword placeholder.
 func (gzsite *Site) SearchTorrents(keyword string, baseUrl string) ([]*site.Torrent, error) {
-	return nil, site.ErrUnimplemented
+	params := url.Values{}
+	if baseUrl != "" && strings.Contains(baseUrl, "%s") {
+		baseUrl = strings.ReplaceAll(baseUrl, "%s", url.QueryEscape(keyword))
+	} else {
+		params.Set("searchstr", keyword)
+	}
+	params.Set("order_by", "time")
+	params.Set("order_way", "desc")
+	res, err := gzsite.browse(baseUrl, params)
+	if err != nil {
+		return nil, err
+	}
+	return gzsite.convertTorrents(res.Response.Results), nil
+}
+
+// Call browse api. apiUrl: default to "ajax.php?action=browse".
+// params are added to apiUrl query string, overriding existing ones.
+func (gzsite *Site) browse(apiUrl string, params url.Values) (*apiBrowseResponse, error) {
+	if apiUrl == "" {
+		apiUrl = API_BROWSE_PATH
+	}
+	urlObj, err := url.Parse(gzsite.SiteConfig.ParseSiteUrl(apiUrl, false))
+	if err != nil {
+		return nil, fmt.Errorf("invalid api url %s: %w", apiUrl, err)
+	}
+	query := urlObj.Query()
+	if query.Get("action") == "" {
+		query.Set("action", "browse")
+	}
+	for key := range params {
+		query.Set(key, params.Get(key))
+	}
+	urlObj.RawQuery = query.Encode()
+	headers := gzsite.GetDefaultHttpHeaders()
+	if gzsite.SiteConfig.ApiKey != "" {
+		headers = append([][]string{{"Authorization", gzsite.SiteConfig.ApiKey}}, headers...)
+	}
+	res := &apiBrowseResponse{}
+	err = util.FetchJsonWithAzuretls(urlObj.String(), res, gzsite.HttpClient,
+		gzsite.SiteConfig.Cookie, site.GetUa(gzsite), headers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch browse api: %w", err)
+	}
+	if res.Status != "success" {
+		return nil, fmt.Errorf("browse api error: status=%s, error=%s", res.Status, res.Error)
+	}
+	return res, nil
+}
+
+// Flatten torrent groups to torrents. Each torrent of a group becomes a site torrent.
+func (gzsite *Site) convertTorrents(groups []*apiBrowseGroup) []*site.Torrent {
+	torrents := []*site.Torrent{}
+	for _, group := range groups {
+		name := group.GroupName
+		if group.Artist != "" {
+			name = group.Artist + " - " + name
+		}
+		if group.GroupYear > 0 {
+			name += fmt.Sprintf(" [%d]", group.GroupYear)
+		}
+		groupTorrents := group.Torrents
+		if len(groupTorrents) == 0 && group.TorrentId > 0 {
+			groupTorrents = []*apiBrowseTorrent{&group.apiBrowseTorrent}
+		}
+		for _, apiTorrent := range groupTorrents {
+			id := fmt.Sprint(apiTorrent.TorrentId)
+			downloadMultiplier := 1.0
+			uploadMultiplier := 1.0
+			if apiTorrent.IsFreeleech || apiTorrent.IsPersonalFreeleech {
+				downloadMultiplier = 0
+			}
+			if apiTorrent.IsNeutralLeech {
+				downloadMultiplier = 0
+				uploadMultiplier = 0
+			}
+			description := []string{}
+			for _, field := range []string{apiTorrent.Format, apiTorrent.Encoding, apiTorrent.Media,
+				apiTorrent.RemasterTitle} {
+				if field != "" {
+					description = append(description, field)
+				}
+			}
+			torrentTime := int64(0)
+			if apiTorrent.Time != "" {
+				torrentTime, _ = util.ParseTime(apiTorrent.Time, gzsite.Location)
+			} else if group.GroupTime > 0 {
+				torrentTime = int64(group.GroupTime)
+			}
+			torrents = append(torrents, &site.Torrent{
+				Name:               name,
+				Description:        strings.Join(description, " / "),
+				Id:                 gzsite.Name + "." + id,
+				DownloadUrl:        gzsite.SiteConfig.Url + "torrents.php?action=download&id=" + id,
+				DownloadMultiplier: downloadMultiplier,
+				UploadMultiplier:   uploadMultiplier,
+				DiscountEndTime:    -1,
+				Time:               torrentTime,
+				Size:               int64(apiTorrent.Size),
+				IsSizeAccurate:     true,
+				Seeders:            int64(apiTorrent.Seeders),
+				Leechers:           int64(apiTorrent.Leechers),
+				Snatched:           int64(apiTorrent.Snatches),
+				HasHnR:             gzsite.SiteConfig.GlobalHnR,
+				Neutral:            apiTorrent.IsNeutralLeech,
+				Tags:               group.Tags,
+			})
+		}
+	}
+	return torrents
 }
 
 func (gzsite *Site) DownloadTorrent(torrentUrl string) (content []byte, filename string, id string, err error) {
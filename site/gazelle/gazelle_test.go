@@ -0,0 +1,119 @@
+package gazelle
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"sync"
+	"time"
+
+	"github.com/Noooste/azuretls-client"
+
+	"github.com/sagan/ptool/config"
+)
+
+func loadFixture(t *testing.T, name string) *apiBrowseResponse {
+	contents, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := &apiBrowseResponse{}
+	if err := json.Unmarshal(contents, res); err != nil {
+		t.Fatalf("failed to parse %s: %v", name, err)
+	}
+	return res
+}
+
+func TestConvertTorrents(t *testing.T) {
+	location, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gzsite := &Site{
+		Name:       "gz",
+		Location:   location,
+		SiteConfig: &config.SiteConfigStruct{Url: "https://gazelle.example.com/"},
+	}
+	res := loadFixture(t, "browse.json")
+	if res.Status != "success" || res.Response.CurrentPage != 1 || res.Response.Pages != 3 {
+		t.Errorf("unexpected response: status=%s, currentPage=%d, pages=%d", res.Status,
+			res.Response.CurrentPage, res.Response.Pages)
+	}
+	torrents := gzsite.convertTorrents(res.Response.Results)
+	if len(torrents) != 3 {
+		t.Fatalf("convertTorrents returned %d torrents, want 3", len(torrents))
+	}
+	torrent := torrents[0]
+	if torrent.Id != "gz.959473" || torrent.Name != "Some Artist - Some Album [2009]" ||
+		torrent.Description != "FLAC / Lossless / CD" ||
+		torrent.DownloadUrl != "https://gazelle.example.com/torrents.php?action=download&id=959473" ||
+		torrent.Size != 311154320 || torrent.Seeders != 10 || torrent.Leechers != 0 || torrent.Snatched != 120 ||
+		torrent.Time != time.Date(2009, 6, 6, 19, 4, 22, 0, location).Unix() ||
+		torrent.DownloadMultiplier != 1 || torrent.UploadMultiplier != 1 ||
+		!slices.Equal(torrent.Tags, []string{"rock", "alternative.rock"}) {
+		t.Errorf("unexpected torrent: %+v", torrent)
+	}
+	torrent = torrents[1]
+	if torrent.Id != "gz.959474" || torrent.Description != "MP3 / 320 / WEB / Deluxe Edition" ||
+		torrent.Size != 120586240 || torrent.Seeders != 4 || torrent.Leechers != 1 || torrent.Snatched != 30 ||
+		torrent.DownloadMultiplier != 0 || torrent.UploadMultiplier != 1 {
+		t.Errorf("unexpected torrent: %+v", torrent)
+	}
+	// non-music group without "torrents" list
+	torrent = torrents[2]
+	if torrent.Id != "gz.7001" || torrent.Name != "Some Audiobook" || torrent.Description != "" ||
+		torrent.Size != 52428800 || torrent.Time != 1446451200 || !torrent.Neutral ||
+		torrent.DownloadMultiplier != 0 || torrent.UploadMultiplier != 0 ||
+		!slices.Equal(torrent.Tags, []string{"audiobook"}) {
+		t.Errorf("unexpected torrent: %+v", torrent)
+	}
+}
+
+func TestFailureResponse(t *testing.T) {
+	res := loadFixture(t, "failure.json")
+	if res.Status != "failure" || res.Error != "bad parameters" || len(res.Response.Results) != 0 {
+		t.Errorf("unexpected failure response: %+v", res)
+	}
+}
+
+func TestGetLatestTorrents(t *testing.T) {
+	contents, err := os.ReadFile(filepath.Join("testdata", "browse.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.URL.Query().Get("page")+"|"+r.URL.Query().Get("filter_cat[2]"))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(contents)
+	}))
+	defer server.Close()
+	gzsite := &Site{
+		Name:     "gz",
+		Location: time.UTC,
+		SiteConfig: &config.SiteConfigStruct{
+			Url:               server.URL + "/",
+			TorrentsExtraUrls: []string{"ajax.php?action=browse&filter_cat[2]=1"},
+		},
+		HttpClient: azuretls.NewSession(),
+	}
+	defer gzsite.HttpClient.Close()
+
+	torrents, err := gzsite.GetLatestTorrents(false)
+	if err != nil || len(torrents) != 3 || !slices.Equal(requests, []string{"|"}) {
+		t.Errorf("GetLatestTorrents(false) = %d torrents, %v; requests %v", len(torrents), err, requests)
+	}
+	requests = nil
+	// the next page and extra url are also fetched, same torrents are merged
+	torrents, err = gzsite.GetLatestTorrents(true)
+	if err != nil || len(torrents) != 3 || !slices.Equal(requests, []string{"|", "2|", "|1"}) {
+		t.Errorf("GetLatestTorrents(true) = %d torrents, %v; requests %v", len(torrents), err, requests)
+	}
+}
@@ -0,0 +1,78 @@
+package unit3d
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Number which may be encoded as json number or string (e.g. "100%").
+type apiNumber float64
+
+func (n *apiNumber) UnmarshalJSON(data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case float64:
+		*n = apiNumber(v)
+	case bool:
+		if v {
+			*n = 1
+		} else {
+			*n = 0
+		}
+	case string:
+		f, _ := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(v), "%"), 64)
+		*n = apiNumber(f)
+	default:
+		*n = 0
+	}
+	return nil
+}
+
+// https://hdinnovations.github.io/UNIT3D/torrent_api.html
+// GET /api/torrents/filter?api_token=&name=&perPage=&page=&sortField=&sortDirection=
+type apiTorrentsResponse struct {
+	Data []*apiTorrent `json:"data"`
+	Meta struct {
+		CurrentPage int64 `json:"current_page"`
+		LastPage    int64 `json:"last_page"`
+	} `json:"meta"`
+	Links struct {
+		Next string `json:"next"`
+	} `json:"links"`
+	Message string `json:"message"` // error message, e.g. "Unauthenticated."
+}
+
+type apiTorrent struct {
+	Id         json.Number `json:"id"`
+	Attributes struct {
+		Name           string    `json:"name"`
+		Category       string    `json:"category"`
+		Type           string    `json:"type"`
+		Resolution     string    `json:"resolution"`
+		InfoHash       string    `json:"info_hash"`
+		Size           apiNumber `json:"size"`
+		Freeleech      apiNumber `json:"freeleech"` // "100%", "50%", "0%". Old versions use 0 / 1
+		DoubleUpload   apiNumber `json:"double_upload"`
+		Internal       apiNumber `json:"internal"`
+		Seeders        apiNumber `json:"seeders"`
+		Leechers       apiNumber `json:"leechers"`
+		TimesCompleted apiNumber `json:"times_completed"`
+		CreatedAt      string    `json:"created_at"`
+		DownloadLink   string    `json:"download_link"`
+		DetailsLink    string    `json:"details_link"`
+	} `json:"attributes"`
+}
+
+// Return freeleech percent [0, 100].
+func (t *apiTorrent) freeleechPercent() float64 {
+	percent := float64(t.Attributes.Freeleech)
+	if percent == 1 {
+		// old versions: freeleech is a 0 / 1 flag. "1%" freeleech does not exist in practice
+		percent = 100
+	}
+	return min(max(percent, 0), 100)
+}
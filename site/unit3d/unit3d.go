@@ -3,6 +3,7 @@ package unit3d
 // UNIT3D ( https://github.com/HDInnovations/UNIT3D-Community-Edition )
 // JptvClub、莫妮卡、普斯特等站使用架构
 // 种子下载链接格式：https://jptv.club/torrents/download/39683
+// 种子列表和搜索使用官方 API ( /api/torrents/filter )，需要在站点配置里设置 apiKey (个人设置里的 API Token)
 
 import (
 	"fmt"
@@ -15,6 +16,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/site"
 	"github.com/sagan/ptool/util"
 )
@@ -34,6 +36,8 @@ func (usite *Site) PublishTorrent(contents []byte, metadata url.Values) (id stri
 }
 
 const (
+	API_TORRENTS_PATH        = "api/torrents/filter"
+	MAX_PER_PAGE             = 100
 	SELECTOR_USERNAME        = ".top-nav__username"
 	SELECTOR_USER_UPLOADED   = ".ratio-bar__uploaded"
 	SELECTOR_USER_DOWNLOADED = ".ratio-bar__downloaded"
@@ -87,17 +91,164 @@ func (usite *Site) GetStatus() (*site.Status, error) {
 	}, nil
 }
 
+var sortFields = map[string]string{
+	"":             "created_at",
+	constants.NONE: "created_at",
+	"time":         "created_at",
+	"size":         "size",
+	"name":         "name",
+}
+
 func (usite *Site) GetAllTorrents(sort string, desc bool, pageMarker string, baseUrl string) (
 	torrents []*site.Torrent, nextPageMarker string, err error) {
-	return nil, "", site.ErrUnimplemented
+	sortField := sortFields[sort]
+	if sortField == "" {
+		return nil, "", fmt.Errorf("unsupported sort field: %s", sort)
+	}
+	page := int64(1)
+	if pageMarker != "" {
+		if page = util.ParseInt(pageMarker); page < 1 {
+			return nil, "", fmt.Errorf("invalid page marker: %s", pageMarker)
+		}
+	}
+	sortDirection := "asc"
+	if desc {
+		sortDirection = "desc"
+	}
+	params := url.Values{}
+	params.Set("sortField", sortField)
+	params.Set("sortDirection", sortDirection)
+	params.Set("perPage", fmt.Sprint(MAX_PER_PAGE))
+	params.Set("page", fmt.Sprint(page))
+	res, err := usite.fetchTorrents(baseUrl, params)
+	if err != nil {
+		return nil, "", err
+	}
+	torrents = usite.convertTorrents(res.Data)
+	if res.Meta.LastPage > page || res.Meta.LastPage == 0 && res.Links.Next != "" {
+		nextPageMarker = fmt.Sprint(page + 1)
+	}
+	return
 }
 
 func (usite *Site) GetLatestTorrents(full bool) ([]*site.Torrent, error) {
-	return nil, site.ErrUnimplemented
+	params := url.Values{}
+	params.Set("sortField", "created_at")
+	params.Set("sortDirection", "desc")
+	if full {
+		params.Set("perPage", fmt.Sprint(MAX_PER_PAGE))
+	} else {
+		params.Set("perPage", "50")
+	}
+	res, err := usite.fetchTorrents(usite.SiteConfig.TorrentsUrl, params)
+	if err != nil {
+		return nil, err
+	}
+	return usite.convertTorrents(res.Data), nil
 }
 
+// baseUrl: optional api url, can use "%s" as keyword placeholder.
 func (usite *Site) SearchTorrents(keyword string, baseUrl string) ([]*site.Torrent, error) {
-	return nil, site.ErrUnimplemented
+	params := url.Values{}
+	if baseUrl != "" && strings.Contains(baseUrl, "%s") {
+		baseUrl = strings.ReplaceAll(baseUrl, "%s", url.QueryEscape(keyword))
+	} else {
+		params.Set("name", keyword)
+	}
+	params.Set("sortField", "created_at")
+	params.Set("sortDirection", "desc")
+	params.Set("perPage", fmt.Sprint(MAX_PER_PAGE))
+	res, err := usite.fetchTorrents(baseUrl, params)
+	if err != nil {
+		return nil, err
+	}
+	return usite.convertTorrents(res.Data), nil
+}
+
+// Fetch torrents from api. apiUrl: default to "api/torrents/filter".
+// params are added to apiUrl query string, overriding existing ones.
+func (usite *Site) fetchTorrents(apiUrl string, params url.Values) (*apiTorrentsResponse, error) {
+	if usite.SiteConfig.ApiKey == "" {
+		return nil, fmt.Errorf("site %s has no apiKey (UNIT3D API token) configured", usite.Name)
+	}
+	if apiUrl == "" {
+		apiUrl = API_TORRENTS_PATH
+	}
+	urlObj, err := url.Parse(usite.SiteConfig.ParseSiteUrl(apiUrl, false))
+	if err != nil {
+		return nil, fmt.Errorf("invalid api url %s: %w", apiUrl, err)
+	}
+	query := urlObj.Query()
+	for key := range params {
+		query.Set(key, params.Get(key))
+	}
+	query.Set("api_token", usite.SiteConfig.ApiKey)
+	urlObj.RawQuery = query.Encode()
+	headers := append([][]string{{"Accept", "application/json"}}, usite.GetDefaultHttpHeaders()...)
+	res := &apiTorrentsResponse{}
+	err = util.FetchJsonWithAzuretls(urlObj.String(), res, usite.HttpClient, "", site.GetUa(usite), headers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch torrents api: %w", err)
+	}
+	if res.Data == nil && res.Message != "" {
+		return nil, fmt.Errorf("torrents api error: %s", res.Message)
+	}
+	return res, nil
+}
+
+func (usite *Site) convertTorrents(apiTorrents []*apiTorrent) []*site.Torrent {
+	torrents := []*site.Torrent{}
+	for _, apiTorrent := range apiTorrents {
+		attrs := &apiTorrent.Attributes
+		id := apiTorrent.Id.String()
+		downloadUrl := attrs.DownloadLink
+		if downloadUrl == "" {
+			downloadUrl = usite.SiteConfig.Url + "torrents/download/" + id
+		}
+		downloadMultiplier := 1 - apiTorrent.freeleechPercent()/100
+		uploadMultiplier := 1.0
+		if attrs.DoubleUpload != 0 {
+			uploadMultiplier = 2
+		}
+		tags := []string{}
+		for _, tag := range []string{attrs.Category, attrs.Type, attrs.Resolution} {
+			if tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+		if attrs.Internal != 0 {
+			tags = append(tags, "internal")
+		}
+		torrents = append(torrents, &site.Torrent{
+			Name:               attrs.Name,
+			Id:                 usite.Name + "." + id,
+			InfoHash:           strings.ToLower(attrs.InfoHash),
+			DownloadUrl:        downloadUrl,
+			DownloadMultiplier: downloadMultiplier,
+			UploadMultiplier:   uploadMultiplier,
+			DiscountEndTime:    -1,
+			Time:               usite.parseTime(attrs.CreatedAt),
+			Size:               int64(attrs.Size),
+			IsSizeAccurate:     true,
+			Seeders:            int64(attrs.Seeders),
+			Leechers:           int64(attrs.Leechers),
+			Snatched:           int64(attrs.TimesCompleted),
+			// UNIT3D HnR rules apply to all (actually) downloaded torrents, so only full freeleech ones are exempted.
+			HasHnR: usite.SiteConfig.GlobalHnR && downloadMultiplier > 0,
+			Tags:   tags,
+		})
+	}
+	return torrents
+}
+
+// Parse api time, which is ISO 8601 format (e.g. "2023-10-14T12:00:00.000000Z")
+// or "2006-01-02 15:04:05" in site timezone.
+func (usite *Site) parseTime(str string) int64 {
+	if t, err := time.Parse(time.RFC3339Nano, str); err == nil {
+		return t.Unix()
+	}
+	ts, _ := util.ParseTime(str, usite.Location)
+	return ts
 }
 
 func (usite *Site) DownloadTorrent(torrentUrl string) (content []byte, filename string, id string, err error) {
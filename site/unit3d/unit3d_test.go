@@ -0,0 +1,92 @@
+package unit3d
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sagan/ptool/config"
+)
+
+func loadFixture(t *testing.T, name string) *apiTorrentsResponse {
+	contents, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := &apiTorrentsResponse{}
+	if err := json.Unmarshal(contents, res); err != nil {
+		t.Fatalf("failed to parse %s: %v", name, err)
+	}
+	return res
+}
+
+func newTestSite(t *testing.T) *Site {
+	location, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Site{
+		Name:       "u3d",
+		Location:   location,
+		SiteConfig: &config.SiteConfigStruct{Url: "https://unit3d.example.com/", GlobalHnR: true},
+	}
+}
+
+func TestConvertTorrents(t *testing.T) {
+	usite := newTestSite(t)
+	res := loadFixture(t, "torrents.json")
+	if res.Meta.LastPage != 0 || res.Links.Next == "" {
+		t.Errorf("unexpected pagination: meta=%+v, links=%+v", res.Meta, res.Links)
+	}
+	torrents := usite.convertTorrents(res.Data)
+	if len(torrents) != 2 {
+		t.Fatalf("convertTorrents returned %d torrents, want 2", len(torrents))
+	}
+	torrent := torrents[0]
+	if torrent.Id != "u3d.39683" || torrent.Name != "Some Movie 2023 1080p WEB-DL H.264 AAC" ||
+		torrent.InfoHash != "0123456789abcdef0123456789abcdef01234567" ||
+		torrent.DownloadUrl != "https://unit3d.example.com/torrents/download/39683.passkey" ||
+		torrent.Size != 4831838208 || torrent.Seeders != 12 || torrent.Leechers != 3 || torrent.Snatched != 45 ||
+		torrent.Time != time.Date(2023, 10, 14, 12, 0, 0, 0, time.UTC).Unix() {
+		t.Errorf("unexpected torrent: %+v", torrent)
+	}
+	if torrent.DownloadMultiplier != 0 || torrent.UploadMultiplier != 1 || torrent.HasHnR ||
+		!slices.Equal(torrent.Tags, []string{"Movie", "WEB-DL", "1080p"}) {
+		t.Errorf("unexpected torrent discount / tags: %+v", torrent)
+	}
+	torrent = torrents[1]
+	if torrent.Id != "u3d.39682" || torrent.DownloadUrl != "https://unit3d.example.com/torrents/download/39682" ||
+		torrent.Size != 107374182400 || torrent.DownloadMultiplier != 0.75 || torrent.UploadMultiplier != 2 ||
+		!torrent.HasHnR || !slices.Equal(torrent.Tags, []string{"TV", "Remux", "2160p", "internal"}) {
+		t.Errorf("unexpected torrent: %+v", torrent)
+	}
+}
+
+// Old UNIT3D versions encode freeleech as 0 / 1 flag, some numbers as strings and time in site timezone.
+func TestConvertTorrentsOld(t *testing.T) {
+	usite := newTestSite(t)
+	res := loadFixture(t, "torrents_old.json")
+	if res.Meta.CurrentPage != 1 || res.Meta.LastPage != 3 {
+		t.Errorf("unexpected pagination: meta=%+v", res.Meta)
+	}
+	torrents := usite.convertTorrents(res.Data)
+	if len(torrents) != 1 {
+		t.Fatalf("convertTorrents returned %d torrents, want 1", len(torrents))
+	}
+	torrent := torrents[0]
+	if torrent.Id != "u3d.1024" || torrent.Size != 2147483648 || torrent.Seeders != 3 || torrent.Leechers != 1 ||
+		torrent.DownloadMultiplier != 0 || torrent.UploadMultiplier != 1 ||
+		torrent.Time != time.Date(2019, 5, 1, 10, 20, 30, 0, usite.Location).Unix() {
+		t.Errorf("unexpected torrent: %+v", torrent)
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	res := loadFixture(t, "unauthenticated.json")
+	if res.Data != nil || res.Message != "Unauthenticated." {
+		t.Errorf("unexpected error response: %+v", res)
+	}
+}
@@ -0,0 +1,83 @@
+package stats
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	PERIOD_DAY   = "day"
+	PERIOD_WEEK  = "week"
+	PERIOD_MONTH = "month"
+)
+
+var (
+	Periods = []string{PERIOD_DAY, PERIOD_WEEK, PERIOD_MONTH}
+	// Dimensions that traffic report can be grouped by
+	GroupFields = []string{"client", "site", "category"}
+)
+
+// sql expression of each period key. week is represented by the date of Monday of that week.
+var periodExprs = map[string]string{
+	PERIOD_DAY:   "day",
+	PERIOD_WEEK:  "date(day, 'weekday 0', '-6 days')",
+	PERIOD_MONTH: "substr(day, 1, 7)",
+}
+
+type TrafficReportOption struct {
+	Start   string   // start day (inclusive), "2006-01-02" format. Empty == no limit
+	End     string   // end day (inclusive), "2006-01-02" format. Empty == no limit
+	Period  string   // day|week|month. Empty == do not group by time
+	GroupBy []string // any of GroupFields
+	Clients []string // only count these clients. Empty == all clients
+}
+
+type TrafficReportRow struct {
+	Period     string `json:"period,omitempty"`
+	Client     string `json:"client,omitempty"`
+	Site       string `json:"site,omitempty"`
+	Category   string `json:"category,omitempty"`
+	Downloaded int64  `json:"downloaded"`
+	Uploaded   int64  `json:"uploaded"`
+}
+
+// Return traffic report rows, ordered by period, then uploaded desc.
+func (db *StatDb) GetTrafficReport(option *TrafficReportOption) (rows []*TrafficReportRow, err error) {
+	fields := []string{}
+	groups := []string{}
+	if option.Period != "" {
+		periodExpr, ok := periodExprs[option.Period]
+		if !ok {
+			return nil, fmt.Errorf("invalid period %q, valid values: %v", option.Period, Periods)
+		}
+		fields = append(fields, periodExpr+" AS period")
+		groups = append(groups, "period")
+	}
+	for _, field := range option.GroupBy {
+		if !slices.Contains(GroupFields, field) {
+			return nil, fmt.Errorf("invalid group by field %q, valid values: %v", field, GroupFields)
+		}
+		fields = append(fields, field)
+		groups = append(groups, field)
+	}
+	fields = append(fields, "ifnull(sum(downloaded),0) AS downloaded", "ifnull(sum(uploaded),0) AS uploaded")
+	tx := db.sqldb.Table("torrent_traffics").Select(strings.Join(fields, ", "))
+	if option.Start != "" {
+		tx = tx.Where("day >= ?", option.Start)
+	}
+	if option.End != "" {
+		tx = tx.Where("day <= ?", option.End)
+	}
+	if len(option.Clients) > 0 {
+		tx = tx.Where("client IN ?", option.Clients)
+	}
+	if len(groups) > 0 {
+		tx = tx.Group(strings.Join(groups, ", "))
+	}
+	if option.Period != "" {
+		tx = tx.Order("period")
+	}
+	err = tx.Order("uploaded desc").Find(&rows).Error
+	return rows, err
+}
@@ -0,0 +1,240 @@
+package stats
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/sagan/ptool/client"
+)
+
+// Basic info of a sampled client torrent.
+type SampledTorrent struct {
+	Client   string `gorm:"primaryKey"`
+	InfoHash string `gorm:"primaryKey"`
+	Site     string `gorm:"index"`
+	Category string
+	Name     string
+	Size     int64
+	Atime    int64
+}
+
+// A periodic traffic sample of client torrent. Uploaded / Downloaded are the accumulated values.
+type TorrentSample struct {
+	Client     string `gorm:"primaryKey"`
+	InfoHash   string `gorm:"primaryKey"`
+	Ts         int64  `gorm:"primaryKey"`
+	Uploaded   int64
+	Downloaded int64
+	Seeders    int64
+	Leechers   int64
+}
+
+// Summary of sampled torrent, with it's latest sample.
+type TorrentSummary struct {
+	SampledTorrent
+	Uploaded   int64
+	Downloaded int64
+	Seeders    int64
+	Leechers   int64
+	FirstTs    int64 // first sample time
+	LastTs     int64 // latest sample time
+}
+
+// Summary of sampled torrents of a site.
+type SiteSummary struct {
+	Site       string
+	Torrents   int64
+	Size       int64
+	Uploaded   int64
+	Downloaded int64
+}
+
+// Return uploaded amount per GiB of torrent size.
+func (s *SiteSummary) UploadedPerGiB() float64 {
+	if s.Size == 0 {
+		return 0
+	}
+	return float64(s.Uploaded) / (float64(s.Size) / (1 << 30))
+}
+
+// Filename of the persistent SQLite database of torrent samples: the stats file with ".db" ext,
+// e.g. "ptool_stats.db" for "ptool_stats.txt".
+func (db *StatDb) SampleDbFilename() string {
+	return strings.TrimSuffix(db.statFilename, filepath.Ext(db.statFilename)) + ".db"
+}
+
+func (db *StatDb) getSampleDb() (*gorm.DB, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.sampledb != nil {
+		return db.sampledb, nil
+	}
+	sampledb, err := gorm.Open(sqlite.Open(db.SampleDbFilename()+"?_pragma=busy_timeout(10000)"), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stats samples db: %w", err)
+	}
+	if err = sampledb.AutoMigrate(&SampledTorrent{}, &TorrentSample{}); err != nil {
+		return nil, fmt.Errorf("samples db schema init error: %w", err)
+	}
+	db.sampledb = sampledb
+	return sampledb, nil
+}
+
+// Add traffic samples of client torrents at ts time.
+func (db *StatDb) AddTorrentSamples(ts int64, clientName string, torrents []*client.Torrent) error {
+	if len(torrents) == 0 {
+		return nil
+	}
+	sampledb, err := db.getSampleDb()
+	if err != nil {
+		return err
+	}
+	var sampledTorrents []*SampledTorrent
+	var samples []*TorrentSample
+	for _, torrent := range torrents {
+		sampledTorrents = append(sampledTorrents, &SampledTorrent{
+			Client:   clientName,
+			InfoHash: torrent.InfoHash,
+			Site:     torrent.GetSiteFromTag(),
+			Category: torrent.Category,
+			Name:     torrent.Name,
+			Size:     torrent.Size,
+			Atime:    torrent.Atime,
+		})
+		samples = append(samples, &TorrentSample{
+			Client:     clientName,
+			InfoHash:   torrent.InfoHash,
+			Ts:         ts,
+			Uploaded:   torrent.Uploaded,
+			Downloaded: torrent.Downloaded,
+			Seeders:    torrent.Seeders,
+			Leechers:   torrent.Leechers,
+		})
+	}
+	return sampledb.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(sampledTorrents, 100).Error; err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(samples, 100).Error
+	})
+}
+
+// Return sampled torrents of infoHash (in any client) and all their samples (ordered by time).
+func (db *StatDb) GetTorrentSamples(infoHash string) (torrents []*SampledTorrent,
+	samples map[string][]*TorrentSample, err error) {
+	sampledb, err := db.getSampleDb()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = sampledb.Where("info_hash = ?", infoHash).Order("client").Find(&torrents).Error; err != nil {
+		return nil, nil, err
+	}
+	samples = map[string][]*TorrentSample{}
+	for _, torrent := range torrents {
+		var torrentSamples []*TorrentSample
+		err = sampledb.Where("client = ? AND info_hash = ?", torrent.Client, infoHash).
+			Order("ts").Find(&torrentSamples).Error
+		if err != nil {
+			return nil, nil, err
+		}
+		samples[torrent.Client] = torrentSamples
+	}
+	return torrents, samples, nil
+}
+
+const summarySql = `SELECT t.*, s.uploaded, s.downloaded, s.seeders, s.leechers, r.first_ts, r.last_ts
+FROM sampled_torrents t
+JOIN (SELECT client, info_hash, MIN(ts) first_ts, MAX(ts) last_ts FROM torrent_samples
+  WHERE ts >= ? GROUP BY client, info_hash) r
+  ON r.client = t.client AND r.info_hash = t.info_hash
+JOIN torrent_samples s ON s.client = t.client AND s.info_hash = t.info_hash AND s.ts = r.last_ts`
+
+// Return summaries of sampled torrents which have samples since "since" timestamp (0 == all),
+// ordered by uploaded desc. clientName & site are optional filters. limit <= 0 means no limit.
+func (db *StatDb) GetTorrentSummaries(clientName string, site string, since int64,
+	limit int) (summaries []*TorrentSummary, err error) {
+	sampledb, err := db.getSampleDb()
+	if err != nil {
+		return nil, err
+	}
+	query := "SELECT * FROM (" + summarySql + ") WHERE 1 = 1"
+	args := []any{since}
+	if clientName != "" {
+		query += " AND client = ?"
+		args = append(args, clientName)
+	}
+	if site != "" {
+		query += " AND site = ?"
+		args = append(args, site)
+	}
+	query += " ORDER BY uploaded DESC"
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	err = sampledb.Raw(query, args...).Scan(&summaries).Error
+	return summaries, err
+}
+
+// Return per-site summaries of sampled torrents which have samples since "since" timestamp (0 == all),
+// ordered by uploaded per GiB desc. clientName is optional filter.
+// If since > 0, the uploaded / downloaded amounts are the traffic during the period, calculated from
+// the first and latest samples of each torrent in the period, the same as GetTorrentsUploadedSince.
+// Otherwise they are the accumulated values of latest samples.
+func (db *StatDb) GetSiteSummaries(clientName string, since int64) (summaries []*SiteSummary, err error) {
+	sampledb, err := db.getSampleDb()
+	if err != nil {
+		return nil, err
+	}
+	query := `SELECT t.site, COUNT(*) torrents, SUM(t.size) size,
+  SUM(MAX(l.uploaded - f.uploaded, 0)) uploaded, SUM(MAX(l.downloaded - f.downloaded, 0)) downloaded
+FROM sampled_torrents t
+JOIN (SELECT client, info_hash, MIN(ts) first_ts, MAX(ts) last_ts FROM torrent_samples
+  WHERE ts >= ? GROUP BY client, info_hash) r
+  ON r.client = t.client AND r.info_hash = t.info_hash
+JOIN torrent_samples f ON f.client = r.client AND f.info_hash = r.info_hash AND f.ts = r.first_ts
+JOIN torrent_samples l ON l.client = r.client AND l.info_hash = r.info_hash AND l.ts = r.last_ts`
+	if since <= 0 {
+		query = `SELECT site, COUNT(*) torrents, SUM(size) size, SUM(uploaded) uploaded, SUM(downloaded) downloaded
+FROM (` + summarySql + `) t`
+	}
+	args := []any{since}
+	if clientName != "" {
+		query += " WHERE t.client = ?"
+		args = append(args, clientName)
+	}
+	query = "SELECT * FROM (" + query + " GROUP BY t.site) ORDER BY CAST(uploaded AS REAL) / MAX(size, 1) DESC"
+	err = sampledb.Raw(query, args...).Scan(&summaries).Error
+	return summaries, err
+}
+
+// Return the uploaded amount of each sampled torrent of client during the time since "since" timestamp,
+// calculated from the first and latest samples in the period. Key is infoHash.
+func (db *StatDb) GetTorrentsUploadedSince(clientName string, since int64) (uploaded map[string]int64, err error) {
+	sampledb, err := db.getSampleDb()
+	if err != nil {
+		return nil, err
+	}
+	var rows []*struct {
+		InfoHash string
+		Uploaded int64
+	}
+	err = sampledb.Raw(`SELECT r.info_hash, l.uploaded - f.uploaded uploaded
+FROM (SELECT client, info_hash, MIN(ts) first_ts, MAX(ts) last_ts FROM torrent_samples
+  WHERE client = ? AND ts >= ? GROUP BY client, info_hash) r
+JOIN torrent_samples f ON f.client = r.client AND f.info_hash = r.info_hash AND f.ts = r.first_ts
+JOIN torrent_samples l ON l.client = r.client AND l.info_hash = r.info_hash AND l.ts = r.last_ts`,
+		clientName, since).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	uploaded = map[string]int64{}
+	for _, row := range rows {
+		uploaded[row.InfoHash] = max(row.Uploaded, 0)
+	}
+	return uploaded, nil
+}
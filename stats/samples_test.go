@@ -0,0 +1,74 @@
+package stats_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/stats"
+)
+
+func TestTorrentSamples(t *testing.T) {
+	statDb, err := stats.NewDb(filepath.Join(t.TempDir(), "ptool_stats.txt"))
+	if err != nil {
+		t.Fatalf("NewDb error: %v", err)
+	}
+	gib := int64(1 << 30)
+	torrents := []*client.Torrent{
+		{InfoHash: "aaa", Name: "A", Size: 2 * gib, Tags: []string{"site:mt"}, Uploaded: gib},
+		{InfoHash: "bbb", Name: "B", Size: 10 * gib, Tags: []string{"site:hdsky"}, Uploaded: gib},
+	}
+	if err = statDb.AddTorrentSamples(1000, "local", torrents); err != nil {
+		t.Fatalf("AddTorrentSamples error: %v", err)
+	}
+	torrents[0].Uploaded = 5 * gib
+	torrents[0].Seeders = 3
+	if err = statDb.AddTorrentSamples(2000, "local", torrents); err != nil {
+		t.Fatalf("AddTorrentSamples error: %v", err)
+	}
+
+	sampledTorrents, samples, err := statDb.GetTorrentSamples("aaa")
+	if err != nil || len(sampledTorrents) != 1 || sampledTorrents[0].Site != "mt" {
+		t.Fatalf("GetTorrentSamples() = %v, %v", sampledTorrents, err)
+	}
+	if len(samples["local"]) != 2 || samples["local"][1].Uploaded != 5*gib || samples["local"][1].Seeders != 3 {
+		t.Errorf("unexpected samples: %v", samples["local"])
+	}
+
+	summaries, err := statDb.GetTorrentSummaries("", "", 0, 0)
+	if err != nil || len(summaries) != 2 {
+		t.Fatalf("GetTorrentSummaries() = %v, %v", summaries, err)
+	}
+	if summaries[0].InfoHash != "aaa" || summaries[0].Uploaded != 5*gib || summaries[0].Name != "A" ||
+		summaries[0].FirstTs != 1000 || summaries[0].LastTs != 2000 {
+		t.Errorf("unexpected first summary: %+v", summaries[0])
+	}
+	if summaries, _ = statDb.GetTorrentSummaries("local", "hdsky", 1500, 0); len(summaries) != 1 {
+		t.Errorf("GetTorrentSummaries(site=hdsky) returned %d summaries, want 1", len(summaries))
+	}
+
+	uploaded, err := statDb.GetTorrentsUploadedSince("local", 0)
+	if err != nil || len(uploaded) != 2 || uploaded["aaa"] != 4*gib || uploaded["bbb"] != 0 {
+		t.Errorf("GetTorrentsUploadedSince() = %v, %v", uploaded, err)
+	}
+	if uploaded, _ = statDb.GetTorrentsUploadedSince("other", 0); len(uploaded) != 0 {
+		t.Errorf("GetTorrentsUploadedSince(other) = %v, want empty", uploaded)
+	}
+
+	siteSummaries, err := statDb.GetSiteSummaries("", 0)
+	if err != nil || len(siteSummaries) != 2 {
+		t.Fatalf("GetSiteSummaries() = %v, %v", siteSummaries, err)
+	}
+	if siteSummaries[0].Site != "mt" || siteSummaries[0].UploadedPerGiB() != float64(5*gib)/2 {
+		t.Errorf("unexpected first site summary: %+v", siteSummaries[0])
+	}
+	// traffic since 1500 is 0 as both sites have only one sample in the period
+	if siteSummaries, err = statDb.GetSiteSummaries("local", 1500); err != nil || len(siteSummaries) != 2 ||
+		siteSummaries[0].Uploaded != 0 || siteSummaries[1].Uploaded != 0 {
+		t.Errorf("GetSiteSummaries(since=1500) = %v, %v, want zero uploaded", siteSummaries, err)
+	}
+	if siteSummaries, _ = statDb.GetSiteSummaries("local", 1000); len(siteSummaries) != 2 ||
+		siteSummaries[0].Site != "mt" || siteSummaries[0].Uploaded != 4*gib || siteSummaries[1].Uploaded != 0 {
+		t.Errorf("GetSiteSummaries(since=1000) = %v, want mt uploaded 4GiB", siteSummaries)
+	}
+}
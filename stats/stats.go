@@ -20,6 +20,7 @@ type TorrentTraffic struct {
 	Client     string `gorm:"primaryKey"`
 	Day        string `gorm:"primaryKey"`
 	Site       string `gorm:"primaryKey"`
+	Category   string `gorm:"primaryKey"`
 	Downloaded int64
 	Uploaded   int64
 }
@@ -50,6 +51,7 @@ type StatDb struct {
 	file         *os.File
 	mu           sync.Mutex
 	sqldb        *gorm.DB
+	sampledb     *gorm.DB // persistent db of torrent samples. lazy opened
 }
 
 func (db *StatDb) AddTorrentStats(ts int64, event int64, torrentStats []*TorrentStat) {
@@ -255,10 +257,10 @@ func NewDb(statFilename string) (*StatDb, error) {
 				downloaded = (time2 - time) * aDownloadSpeed
 				uploaded = (time2 - time) * aUploadSpeed
 			}
-			// INSERT INTO torrent_traffics (client, day, site, downloaded, uploaded) VALUES (?,?,?,?,?)
-			//	ON CONFLICT(client, day, site) DO UPDATE SET downloaded = downloaded + ?, uploaded = uploaded + ?;
+			// INSERT INTO torrent_traffics (client, day, site, category, downloaded, uploaded) VALUES (?,?,?,?,?,?)
+			//	ON CONFLICT(client, day, site, category) DO UPDATE SET downloaded = downloaded + ?, uploaded = uploaded + ?;
 			db.sqldb.Clauses(clause.OnConflict{
-				Columns: []clause.Column{{Name: "client"}, {Name: "day"}, {Name: "site"}},
+				Columns: []clause.Column{{Name: "client"}, {Name: "day"}, {Name: "site"}, {Name: "category"}},
 				DoUpdates: clause.Assignments(map[string]interface{}{
 					"downloaded": gorm.Expr("downloaded + ?", downloaded),
 					"uploaded":   gorm.Expr("uploaded + ?", uploaded),
@@ -267,6 +269,7 @@ func NewDb(statFilename string) (*StatDb, error) {
 				Client:     statRecord.Data.Client,
 				Day:        day,
 				Site:       statRecord.Data.Site,
+				Category:   statRecord.Data.Category,
 				Downloaded: downloaded,
 				Uploaded:   uploaded,
 			})
@@ -0,0 +1,55 @@
+package stats_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/sagan/ptool/stats"
+	"github.com/sagan/ptool/util"
+)
+
+func TestTrafficReport(t *testing.T) {
+	statFilename := filepath.Join(t.TempDir(), "ptool_stats.txt")
+	statDb, err := stats.NewDb(statFilename)
+	if err != nil {
+		t.Fatalf("NewDb error: %v", err)
+	}
+	day1, _ := util.ParseLocalDateTime("2024-01-01") // Monday
+	statDb.AddTorrentStats(day1+86400*2, 1, []*stats.TorrentStat{
+		{Client: "local", Site: "mt", Category: "_brush", InfoHash: "a", Atime: day1,
+			Uploaded: 2 * 86400 * 100, Downloaded: 2 * 86400 * 10},
+	})
+	statDb.AddTorrentStats(day1+86400*10, 1, []*stats.TorrentStat{
+		{Client: "remote", Site: "hdsky", Category: "_brush", InfoHash: "b", Atime: day1 + 86400*9,
+			Uploaded: 86400 * 50, Downloaded: 86400 * 5},
+	})
+	// reload db from stats file
+	if statDb, err = stats.NewDb(statFilename); err != nil {
+		t.Fatalf("NewDb error: %v", err)
+	}
+	rows, err := statDb.GetTrafficReport(&stats.TrafficReportOption{Period: stats.PERIOD_WEEK, GroupBy: []string{"client"}})
+	if err != nil {
+		t.Fatalf("GetTrafficReport error: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Period != "2024-01-01" || rows[0].Client != "local" || rows[0].Uploaded != 2*86400*100 ||
+		rows[1].Period != "2024-01-08" || rows[1].Client != "remote" || rows[1].Uploaded != 86400*50 {
+		t.Errorf("unexpected week report rows: %+v %+v", rows[0], rows[len(rows)-1])
+	}
+	rows, err = statDb.GetTrafficReport(&stats.TrafficReportOption{Start: "2024-01-02", End: "2024-01-02"})
+	if err != nil || len(rows) != 1 || rows[0].Uploaded != 86400*100 || rows[0].Downloaded != 86400*10 {
+		t.Errorf("unexpected day report rows: %v, %v", rows, err)
+	}
+	rows, _ = statDb.GetTrafficReport(&stats.TrafficReportOption{Period: stats.PERIOD_MONTH,
+		GroupBy: []string{"category"}, Clients: []string{"remote"}})
+	if len(rows) != 1 || rows[0].Period != "2024-01" || rows[0].Category != "_brush" ||
+		rows[0].Uploaded != 86400*50 {
+		t.Errorf("unexpected month report rows: %v", rows)
+	}
+	rows, err = statDb.GetTrafficReport(&stats.TrafficReportOption{Period: stats.PERIOD_DAY})
+	if err != nil || len(rows) != 3 || rows[0].Period != "2024-01-01" {
+		t.Errorf("unexpected day period report rows: %v, %v", rows, err)
+	}
+	if _, err = statDb.GetTrafficReport(&stats.TrafficReportOption{GroupBy: []string{"name"}}); err == nil {
+		t.Errorf("GetTrafficReport with invalid group by field expect error")
+	}
+}
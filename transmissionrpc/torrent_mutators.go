@@ -89,6 +89,10 @@ func (tsp TorrentSetPayload) MarshalJSON() (data []byte, err error) {
 				for j = 0; j < nestedStruct.NumField(); j++ {
 					currentNestedValue = nestedStruct.Field(j)
 					currentNestedStructField = nestedStruct.Type().Field(j)
+					// already handled by overloaded field
+					if currentNestedStructField.Name == "SeedIdleLimit" {
+						continue
+					}
 					if !currentNestedValue.IsNil() {
 						cleanPayload[currentNestedStructField.Tag.Get("json")] = currentNestedValue.Interface()
 					}
@@ -0,0 +1,477 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/sagan/ptool/util"
+)
+
+type node interface {
+	eval(env map[string]any) (any, error)
+}
+
+type literalNode struct {
+	value any
+}
+
+type varNode struct {
+	name string
+}
+
+type listNode struct {
+	items []node
+}
+
+type unaryNode struct {
+	op      string
+	operand node
+}
+
+type binaryNode struct {
+	op    string
+	left  node
+	right node
+	re    *regexp.Regexp // pre-compiled regexp of "=~" / "!~" with literal pattern
+}
+
+type condNode struct {
+	cond      node
+	then      node
+	otherwise node
+}
+
+type callNode struct {
+	name string
+	fn   func(args []any) (any, error)
+	args []node
+}
+
+func walk(n node, fn func(node)) {
+	fn(n)
+	switch n := n.(type) {
+	case *listNode:
+		for _, item := range n.items {
+			walk(item, fn)
+		}
+	case *unaryNode:
+		walk(n.operand, fn)
+	case *binaryNode:
+		walk(n.left, fn)
+		walk(n.right, fn)
+	case *condNode:
+		walk(n.cond, fn)
+		walk(n.then, fn)
+		walk(n.otherwise, fn)
+	case *callNode:
+		for _, arg := range n.args {
+			walk(arg, fn)
+		}
+	}
+}
+
+func (n *literalNode) eval(env map[string]any) (any, error) {
+	return n.value, nil
+}
+
+func (n *varNode) eval(env map[string]any) (any, error) {
+	value, ok := env[n.name]
+	if !ok {
+		return nil, fmt.Errorf("undefined variable %q", n.name)
+	}
+	return normalize(value)
+}
+
+func (n *listNode) eval(env map[string]any) (any, error) {
+	list := []string{}
+	for _, item := range n.items {
+		value, err := item.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("list item must be string, got %s", typeName(value))
+		}
+		list = append(list, str)
+	}
+	return list, nil
+}
+
+func (n *unaryNode) eval(env map[string]any) (any, error) {
+	value, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "!" {
+		return !truthy(value), nil
+	}
+	num, ok := value.(float64)
+	if !ok {
+		return nil, fmt.Errorf("operand of unary - must be number, got %s", typeName(value))
+	}
+	return -num, nil
+}
+
+func (n *condNode) eval(env map[string]any) (any, error) {
+	cond, err := n.cond.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if truthy(cond) {
+		return n.then.eval(env)
+	}
+	return n.otherwise.eval(env)
+}
+
+func (n *callNode) eval(env map[string]any) (any, error) {
+	args := []any{}
+	for _, arg := range n.args {
+		value, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+	}
+	value, err := n.fn(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %w", n.name, err)
+	}
+	return value, nil
+}
+
+func (n *binaryNode) eval(env map[string]any) (any, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	// short-circuit
+	switch n.op {
+	case "&&":
+		if !truthy(left) {
+			return false, nil
+		}
+		right, err := n.right.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		return truthy(right), nil
+	case "||":
+		if truthy(left) {
+			return true, nil
+		}
+		right, err := n.right.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		return truthy(right), nil
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "=~", "!~":
+		str, ok := left.(string)
+		if !ok {
+			return nil, fmt.Errorf("left operand of %s must be string, got %s", n.op, typeName(left))
+		}
+		re := n.re
+		if re == nil {
+			pattern, ok := right.(string)
+			if !ok {
+				return nil, fmt.Errorf("right operand of %s must be string, got %s", n.op, typeName(right))
+			}
+			if re, err = regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("invalid regexp %q: %w", pattern, err)
+			}
+		}
+		return re.MatchString(str) == (n.op == "=~"), nil
+	case "in":
+		return contains(right, left)
+	case "+":
+		if l, ok := left.(string); ok {
+			if r, ok := right.(string); ok {
+				return l + r, nil
+			}
+		}
+	case "<", "<=", ">", ">=":
+		if l, ok := left.(string); ok {
+			if r, ok := right.(string); ok {
+				cmp := strings.Compare(l, r)
+				return compare(n.op, float64(cmp), 0), nil
+			}
+		}
+	}
+	l, ok1 := left.(float64)
+	r, ok2 := right.(float64)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("invalid operands of %s: %s and %s", n.op, typeName(left), typeName(right))
+	}
+	switch n.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		return l / r, nil
+	case "%":
+		return math.Mod(l, r), nil
+	default:
+		return compare(n.op, l, r), nil
+	}
+}
+
+func compare(op string, l, r float64) bool {
+	switch op {
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	default:
+		return l >= r
+	}
+}
+
+// Values of different types are never equal.
+func equal(left, right any) bool {
+	switch l := left.(type) {
+	case []string:
+		r, ok := right.([]string)
+		return ok && slices.Equal(l, r)
+	default:
+		return left == right
+	}
+}
+
+// Return whether container (list or string) contains item.
+func contains(container any, item any) (bool, error) {
+	switch c := container.(type) {
+	case []string:
+		str, ok := item.(string)
+		return ok && slices.Contains(c, str), nil
+	case string:
+		str, ok := item.(string)
+		if !ok {
+			return false, fmt.Errorf("can not test whether a string contains %s", typeName(item))
+		}
+		return strings.Contains(c, str), nil
+	case nil:
+		return false, nil
+	}
+	return false, fmt.Errorf("can not test whether %s contains something", typeName(container))
+}
+
+func truthy(value any) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0 && !math.IsNaN(v)
+	case string:
+		return v != ""
+	case []string:
+		return len(v) > 0
+	}
+	return false
+}
+
+// Convert variable value to one of the value types.
+func normalize(value any) (any, error) {
+	switch v := value.(type) {
+	case nil, float64, string, bool, []string:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case uint:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case uint32:
+		return float64(v), nil
+	case float32:
+		return float64(v), nil
+	}
+	return nil, fmt.Errorf("unsupported variable type %T", value)
+}
+
+func typeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "nil"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case []string:
+		return "list"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func numberArgs(args []any, min int, max int) ([]float64, error) {
+	if len(args) < min || max >= 0 && len(args) > max {
+		return nil, fmt.Errorf("invalid number of arguments: %d", len(args))
+	}
+	nums := []float64{}
+	for _, arg := range args {
+		num, ok := arg.(float64)
+		if !ok {
+			return nil, fmt.Errorf("argument must be number, got %s", typeName(arg))
+		}
+		nums = append(nums, num)
+	}
+	return nums, nil
+}
+
+func stringArgs(args []any, n int) ([]string, error) {
+	if len(args) != n {
+		return nil, fmt.Errorf("invalid number of arguments: %d", len(args))
+	}
+	strs := []string{}
+	for _, arg := range args {
+		str, ok := arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("argument must be string, got %s", typeName(arg))
+		}
+		strs = append(strs, str)
+	}
+	return strs, nil
+}
+
+func mathFunc(fn func(float64) float64) func(args []any) (any, error) {
+	return func(args []any) (any, error) {
+		nums, err := numberArgs(args, 1, 1)
+		if err != nil {
+			return nil, err
+		}
+		return fn(nums[0]), nil
+	}
+}
+
+func stringFunc(fn func(string) any) func(args []any) (any, error) {
+	return func(args []any) (any, error) {
+		strs, err := stringArgs(args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return fn(strs[0]), nil
+	}
+}
+
+var functions map[string]func(args []any) (any, error)
+
+func init() {
+	functions = map[string]func(args []any) (any, error){
+		"min": func(args []any) (any, error) {
+			nums, err := numberArgs(args, 1, -1)
+			if err != nil {
+				return nil, err
+			}
+			return slices.Min(nums), nil
+		},
+		"max": func(args []any) (any, error) {
+			nums, err := numberArgs(args, 1, -1)
+			if err != nil {
+				return nil, err
+			}
+			return slices.Max(nums), nil
+		},
+		"pow": func(args []any) (any, error) {
+			nums, err := numberArgs(args, 2, 2)
+			if err != nil {
+				return nil, err
+			}
+			return math.Pow(nums[0], nums[1]), nil
+		},
+		"abs":   mathFunc(math.Abs),
+		"floor": mathFunc(math.Floor),
+		"ceil":  mathFunc(math.Ceil),
+		"round": mathFunc(math.Round),
+		"sqrt":  mathFunc(math.Sqrt),
+		"log":   mathFunc(math.Log),
+		"log10": mathFunc(math.Log10),
+		"len": func(args []any) (any, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("invalid number of arguments: %d", len(args))
+			}
+			switch v := args[0].(type) {
+			case string:
+				return float64(len([]rune(v))), nil
+			case []string:
+				return float64(len(v)), nil
+			case nil:
+				return float64(0), nil
+			}
+			return nil, fmt.Errorf("argument must be string or list, got %s", typeName(args[0]))
+		},
+		"lower": stringFunc(func(s string) any { return strings.ToLower(s) }),
+		"upper": stringFunc(func(s string) any { return strings.ToUpper(s) }),
+		"contains": func(args []any) (any, error) {
+			if len(args) != 2 {
+				return nil, fmt.Errorf("invalid number of arguments: %d", len(args))
+			}
+			return contains(args[0], args[1])
+		},
+		"hasPrefix": func(args []any) (any, error) {
+			strs, err := stringArgs(args, 2)
+			if err != nil {
+				return nil, err
+			}
+			return strings.HasPrefix(strs[0], strs[1]), nil
+		},
+		"hasSuffix": func(args []any) (any, error) {
+			strs, err := stringArgs(args, 2)
+			if err != nil {
+				return nil, err
+			}
+			return strings.HasSuffix(strs[0], strs[1]), nil
+		},
+		"matches": func(args []any) (any, error) {
+			strs, err := stringArgs(args, 2)
+			if err != nil {
+				return nil, err
+			}
+			return regexp.MatchString(strs[1], strs[0])
+		},
+		"size": func(args []any) (any, error) {
+			strs, err := stringArgs(args, 1)
+			if err != nil {
+				return nil, err
+			}
+			size, err := util.RAMInBytes(strs[0])
+			if err != nil {
+				return nil, err
+			}
+			return float64(size), nil
+		},
+		"duration": func(args []any) (any, error) {
+			strs, err := stringArgs(args, 1)
+			if err != nil {
+				return nil, err
+			}
+			d, err := util.ParseDuration(strs[0])
+			if err != nil {
+				return nil, err
+			}
+			return d.Seconds(), nil
+		},
+	}
+}
@@ -0,0 +1,128 @@
+// Package expr implements a small expression language, which is used to write user-defined
+// conditions and formulas in config file or cmdline flags (e.g. brush scores).
+//
+// Syntax is C-like:
+//
+//	score * 2 + (seeders <= 1 ? 100 : 0)
+//	size >= 10GiB && age < 1h && !hr
+//	name =~ "(?i)\bS01E\d+" || "_hr" in tags
+//
+// Value types: number (float64), string, bool, string list and nil.
+// Literals: 123, 1.5, "str" or 'str', true, false, nil, ["a", "b"].
+// Number literals may have a size unit suffix (KiB, MiB, GiB, TiB, PiB, or KB, MB, GB... which are
+// also 1024-based) which evaluates to bytes, or a time unit suffix (s, m, h, d, w, M, y)
+// which evaluates to seconds. E.g. 1GiB == 1073741824, 1h == 3600.
+// Operators, by increasing precedence:
+//
+//	? :  (ternary)
+//	||
+//	&&
+//	==  !=  <  <=  >  >=  =~ (regexp match)  !~ (regexp not match)  in (list contains / substring)
+//	+  -  (numbers add / strings concat)
+//	*  /  %
+//	!  -  (unary)
+//
+// Operands of "!", "&&", "||" and condition of "? :" are tested for truthiness:
+// false, 0, "", nil and empty list are false.
+// Builtin functions: min, max, abs, floor, ceil, round, sqrt, pow, log (natural), log10, len,
+// lower, upper, contains, hasPrefix, hasSuffix, matches, size ("10GiB" => bytes), duration ("1h" => seconds).
+package expr
+
+import (
+	"fmt"
+	"slices"
+)
+
+// A compiled expression. It's safe for concurrent use.
+type Program struct {
+	source string
+	root   node
+}
+
+// Compile expression source.
+func Compile(source string) (*Program, error) {
+	p := &parser{lexer: &lexer{src: source}}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+	return &Program{source: source, root: root}, nil
+}
+
+// Compile expression source, panic if it's invalid.
+func MustCompile(source string) *Program {
+	program, err := Compile(source)
+	if err != nil {
+		panic(err)
+	}
+	return program
+}
+
+func (p *Program) String() string {
+	return p.source
+}
+
+// Marshal program as it's source, so it can be used in json / yaml serialized structs.
+func (p *Program) MarshalText() ([]byte, error) {
+	return []byte(p.source), nil
+}
+
+func (p *Program) UnmarshalText(text []byte) error {
+	program, err := Compile(string(text))
+	if err != nil {
+		return err
+	}
+	*p = *program
+	return nil
+}
+
+// Evaluate the expression with variables in env. Accepted variable value types:
+// all int & float types (converted to float64), string, bool, []string and nil.
+// Accessing an undefined variable is an error.
+func (p *Program) Eval(env map[string]any) (any, error) {
+	return p.root.eval(env)
+}
+
+// Evaluate the expression and test the result for truthiness.
+func (p *Program) EvalBool(env map[string]any) (bool, error) {
+	value, err := p.Eval(env)
+	if err != nil {
+		return false, err
+	}
+	return truthy(value), nil
+}
+
+// Evaluate the expression which must produce a number (or bool, which is converted to 1 or 0).
+func (p *Program) EvalNumber(env map[string]any) (float64, error) {
+	value, err := p.Eval(env)
+	if err != nil {
+		return 0, err
+	}
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, fmt.Errorf("expression result is %s, not a number", typeName(value))
+}
+
+// Return names of all variables referenced in the expression.
+func (p *Program) Vars() []string {
+	vars := []string{}
+	walk(p.root, func(n node) {
+		if v, ok := n.(*varNode); ok && !slices.Contains(vars, v.name) {
+			vars = append(vars, v.name)
+		}
+	})
+	return vars
+}
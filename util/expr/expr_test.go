@@ -0,0 +1,79 @@
+package expr_test
+
+import (
+	"testing"
+
+	"github.com/sagan/ptool/util/expr"
+)
+
+func TestEval(t *testing.T) {
+	env := map[string]any{
+		"score":   100.0,
+		"seeders": int64(1),
+		"size":    int64(2 * 1024 * 1024 * 1024),
+		"age":     int64(1800),
+		"name":    "Foo.S01E02.1080p",
+		"tags":    []string{"_hr", "movie"},
+		"free":    true,
+		"a":       false,
+		"b":       true,
+	}
+	cases := []struct {
+		source string
+		want   any
+	}{
+		{"1 + 2 * 3", 7.0},
+		{"(1 + 2) * 3", 9.0},
+		{"-score + 10 % 4", -98.0},
+		{"1GiB", 1073741824.0},
+		{"1.5h + 30m", 7200.0},
+		{"seeders <= 1 ? score * 2 : score", 200.0},
+		{"free && size >= 1GiB && age < 1h", true},
+		{"!free || 1 / 0 > 0", true},
+		{`name =~ "(?i)s\d+e\d+"`, true},
+		{`name !~ "720p"`, true},
+		{`"_hr" in tags`, true},
+		{`"1080p" in name && !("foo" in name)`, true},
+		{`name == "Foo.S01E02.1080p" && name != 'bar'`, true},
+		{"seeders == 1 && seeders == '1'", false},
+		{"tags == ['_hr', 'movie']", true},
+		{"max(1, score, 3) + min(2, 5) + abs(-1)", 103.0},
+		{`len(tags) + len("ab") + size("1KiB") + duration("1m")`, 1088.0},
+		{`hasPrefix(lower(name), "foo") ? "yes" : "no"`, "yes"},
+		{"a ? 1 : b ? 2 : 3", 2.0},
+	}
+	for _, c := range cases {
+		program, err := expr.Compile(c.source)
+		if err != nil {
+			t.Errorf("Compile(%q) error: %v", c.source, err)
+			continue
+		}
+		got, err := program.Eval(env)
+		if err != nil {
+			t.Errorf("Eval(%q) error: %v", c.source, err)
+		} else if got != c.want {
+			t.Errorf("Eval(%q) = %v, want %v", c.source, got, c.want)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, source := range []string{"1 +", "(1", "foo(1)", "1 < 2 < 3", `"abc`, "1xb", "a =~ '('", "1 @ 2"} {
+		if _, err := expr.Compile(source); err == nil {
+			t.Errorf("Compile(%q) expect error", source)
+		}
+	}
+	env := map[string]any{"name": "foo"}
+	for _, source := range []string{"undefined > 1", "name * 2", "-name", "max(name)"} {
+		if _, err := expr.MustCompile(source).Eval(env); err == nil {
+			t.Errorf("Eval(%q) expect error", source)
+		}
+	}
+	program := &expr.Program{}
+	if err := program.UnmarshalText([]byte("max(")); err == nil {
+		t.Errorf("UnmarshalText expect error")
+	}
+	if vars := expr.MustCompile("name == 'a' || max(size, name) > size").Vars(); len(vars) != 2 {
+		t.Errorf("Vars() = %v, want [name size]", vars)
+	}
+}
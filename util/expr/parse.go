@@ -0,0 +1,354 @@
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sagan/ptool/util"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind  tokenKind
+	text  string // op or ident text
+	num   float64
+	str   string
+	start int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokNumber:
+		return fmt.Sprintf("number %v", t.num)
+	case tokString:
+		return fmt.Sprintf("string %q", t.str)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// Operators, longest first.
+var operators = []string{"==", "!=", "<=", ">=", "=~", "!~", "&&", "||",
+	"<", ">", "+", "-", "*", "/", "%", "!", "?", ":", "(", ")", "[", "]", ","}
+
+var timeUnits = map[string]bool{"s": true, "m": true, "h": true, "d": true, "w": true, "M": true, "y": true}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) && strings.IndexByte(" \t\r\n", l.src[l.pos]) >= 0 {
+		l.pos++
+	}
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, start: start}, nil
+	}
+	c := l.src[l.pos]
+	switch {
+	case c >= '0' && c <= '9' || c == '.' && l.pos+1 < len(l.src) && l.src[l.pos+1] >= '0' && l.src[l.pos+1] <= '9':
+		for l.pos < len(l.src) && (l.src[l.pos] >= '0' && l.src[l.pos] <= '9' || l.src[l.pos] == '.') {
+			l.pos++
+		}
+		numStr := l.src[start:l.pos]
+		for l.pos < len(l.src) && isLetter(l.src[l.pos]) {
+			l.pos++
+		}
+		suffix := l.src[start+len(numStr) : l.pos]
+		num, err := parseNumber(numStr, suffix)
+		if err != nil {
+			return token{}, fmt.Errorf("invalid number %q at position %d: %w", l.src[start:l.pos], start, err)
+		}
+		return token{kind: tokNumber, num: num, start: start}, nil
+	case c == '"' || c == '\'':
+		quote := c
+		sb := &strings.Builder{}
+		l.pos++
+		for {
+			if l.pos >= len(l.src) {
+				return token{}, fmt.Errorf("unterminated string at position %d", start)
+			}
+			ch := l.src[l.pos]
+			if ch == quote {
+				l.pos++
+				break
+			}
+			if ch == '\\' && l.pos+1 < len(l.src) {
+				l.pos++
+				switch esc := l.src[l.pos]; esc {
+				case 'n':
+					sb.WriteByte('\n')
+				case 't':
+					sb.WriteByte('\t')
+				case '\\', '"', '\'':
+					sb.WriteByte(esc)
+				default:
+					// keep unknown escapes as is, so regexps like "\d" can be written without double escaping
+					sb.WriteByte('\\')
+					sb.WriteByte(esc)
+				}
+				l.pos++
+				continue
+			}
+			sb.WriteByte(ch)
+			l.pos++
+		}
+		return token{kind: tokString, str: sb.String(), start: start}, nil
+	case isLetter(c) || c == '_' || c >= utf8.RuneSelf:
+		for l.pos < len(l.src) {
+			r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+			if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				break
+			}
+			l.pos += size
+		}
+		if l.pos == start {
+			return token{}, fmt.Errorf("invalid character at position %d", start)
+		}
+		return token{kind: tokIdent, text: l.src[start:l.pos], start: start}, nil
+	}
+	for _, op := range operators {
+		if strings.HasPrefix(l.src[l.pos:], op) {
+			l.pos += len(op)
+			return token{kind: tokOp, text: op, start: start}, nil
+		}
+	}
+	return token{}, fmt.Errorf("invalid character %q at position %d", c, start)
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func parseNumber(numStr string, suffix string) (float64, error) {
+	if suffix == "" {
+		return strconv.ParseFloat(numStr, 64)
+	}
+	if timeUnits[suffix] {
+		d, err := util.ParseDuration(numStr + suffix)
+		if err != nil {
+			return 0, err
+		}
+		return d.Seconds(), nil
+	}
+	if strings.HasSuffix(strings.ToLower(suffix), "b") {
+		size, err := util.RAMInBytes(numStr + suffix)
+		if err != nil {
+			return 0, err
+		}
+		return float64(size), nil
+	}
+	return 0, fmt.Errorf("unknown unit %q", suffix)
+}
+
+type parser struct {
+	lexer *lexer
+	tok   token
+}
+
+func (p *parser) next() (err error) {
+	p.tok, err = p.lexer.next()
+	return err
+}
+
+func (p *parser) errorf(format string, a ...any) error {
+	return fmt.Errorf("%s at position %d", fmt.Sprintf(format, a...), p.tok.start)
+}
+
+func (p *parser) isOp(ops ...string) bool {
+	if p.tok.kind != tokOp && !(p.tok.kind == tokIdent && p.tok.text == "in") {
+		return false
+	}
+	for _, op := range ops {
+		if p.tok.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.isOp(op) {
+		return p.errorf("expect %q, got %s", op, p.tok)
+	}
+	return p.next()
+}
+
+func (p *parser) parseExpr() (node, error) {
+	cond, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOp("?") {
+		return cond, nil
+	}
+	if err = p.next(); err != nil {
+		return nil, err
+	}
+	then, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return &condNode{cond: cond, then: then, otherwise: otherwise}, nil
+}
+
+// Binary operators of each precedence level, from lowest to highest.
+var binaryLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">=", "=~", "!~", "in"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *parser) parseBinary(level int) (node, error) {
+	if level >= len(binaryLevels) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp(binaryLevels[level]...) {
+		op := p.tok.text
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		n := &binaryNode{op: op, left: left, right: right}
+		if op == "=~" || op == "!~" {
+			if str, ok := right.(*literalNode); ok {
+				s, ok := str.value.(string)
+				if !ok {
+					return nil, fmt.Errorf("right operand of %s must be a string", op)
+				}
+				if n.re, err = regexp.Compile(s); err != nil {
+					return nil, fmt.Errorf("invalid regexp %q: %w", s, err)
+				}
+			}
+		}
+		left = n
+		if level == 2 && p.isOp(binaryLevels[level]...) {
+			return nil, p.errorf("comparison operators can not be chained")
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOp("!", "-") {
+		op := p.tok.text
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: op, operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokNumber:
+		return &literalNode{value: tok.num}, p.next()
+	case tokString:
+		return &literalNode{value: tok.str}, p.next()
+	case tokIdent:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		switch tok.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "nil":
+			return &literalNode{value: nil}, nil
+		case "in":
+			return nil, fmt.Errorf("unexpected \"in\" at position %d", tok.start)
+		}
+		if !p.isOp("(") {
+			return &varNode{name: tok.text}, nil
+		}
+		fn := functions[tok.text]
+		if fn == nil {
+			return nil, fmt.Errorf("unknown function %q at position %d", tok.text, tok.start)
+		}
+		args, err := p.parseList(")")
+		if err != nil {
+			return nil, err
+		}
+		return &callNode{name: tok.text, fn: fn, args: args}, nil
+	case tokOp:
+		switch tok.text {
+		case "(":
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			n, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			return n, p.expect(")")
+		case "[":
+			items, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+			return &listNode{items: items}, nil
+		}
+	}
+	return nil, p.errorf("unexpected %s", tok)
+}
+
+// Parse comma-separated expressions list until the end op. Current token is the start op.
+func (p *parser) parseList(end string) ([]node, error) {
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	items := []node{}
+	for !p.isOp(end) {
+		if len(items) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		item, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, p.next()
+}
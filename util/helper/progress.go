@@ -0,0 +1,73 @@
+package helper
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/sagan/ptool/util"
+)
+
+const PROGRESS_INTERVAL = 200 * time.Millisecond
+
+// A single line progress indicator of a bytes processing task, rendered to stderr.
+// It does nothing if stderr is not a terminal.
+type Progress struct {
+	Title   string
+	enabled bool
+	start   time.Time
+	last    time.Time
+	width   int // length of last rendered line
+}
+
+func NewProgress(title string) *Progress {
+	return &Progress{
+		Title:   title,
+		enabled: term.IsTerminal(int(os.Stderr.Fd())),
+		start:   time.Now(),
+	}
+}
+
+// Update progress. It's throttled and could be called frequently.
+func (p *Progress) Update(done, total int64) {
+	if !p.enabled {
+		return
+	}
+	now := time.Now()
+	if now.Sub(p.last) < PROGRESS_INTERVAL && done < total {
+		return
+	}
+	p.last = now
+	percent := float64(100)
+	if total > 0 {
+		percent = float64(done) * 100 / float64(total)
+	}
+	speed := float64(0)
+	if elapsed := now.Sub(p.start).Seconds(); elapsed > 0 {
+		speed = float64(done) / elapsed
+	}
+	line := fmt.Sprintf("%s: %.1f%% (%s / %s) %s/s", p.Title, percent,
+		util.BytesSize(float64(done)), util.BytesSize(float64(total)), util.BytesSize(speed))
+	p.render(line)
+}
+
+// Clear the progress line.
+func (p *Progress) Done() {
+	if !p.enabled || p.width == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\r%s\r", strings.Repeat(" ", p.width))
+	p.width = 0
+}
+
+func (p *Progress) render(line string) {
+	padding := ""
+	if len(line) < p.width {
+		padding = strings.Repeat(" ", p.width-len(line))
+	}
+	fmt.Fprintf(os.Stderr, "\r%s%s", line, padding)
+	p.width = len(line)
+}
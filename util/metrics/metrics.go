@@ -0,0 +1,167 @@
+// Package metrics is a minimal Prometheus metrics implementation.
+// It supports gauges and counters with labels, and writes them in the Prometheus text exposition format:
+// https://prometheus.io/docs/instrumenting/exposition_formats/#text-based-format .
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	GAUGE   = "gauge"
+	COUNTER = "counter"
+)
+
+// The content type of text exposition format.
+const CONTENT_TYPE = "text/plain; version=0.0.4; charset=utf-8"
+
+type sample struct {
+	labelValues []string
+	value       float64
+}
+
+// A metric family. It's safe for concurrent use.
+type Metric struct {
+	Name       string
+	Help       string
+	Type       string
+	LabelNames []string
+	mu         sync.Mutex
+	samples    map[string]*sample
+	keys       []string // keep samples in insertion order
+}
+
+var (
+	registered   []*Metric
+	registeredMu sync.Mutex
+)
+
+func NewGauge(name string, help string, labelNames ...string) *Metric {
+	return &Metric{Name: name, Help: help, Type: GAUGE, LabelNames: labelNames, samples: map[string]*sample{}}
+}
+
+func NewCounter(name string, help string, labelNames ...string) *Metric {
+	return &Metric{Name: name, Help: help, Type: COUNTER, LabelNames: labelNames, samples: map[string]*sample{}}
+}
+
+// Register metrics globally, they will be included in the output of WriteRegistered.
+// It's used for process-lifetime metrics like counters.
+func Register(metrics ...*Metric) {
+	registeredMu.Lock()
+	defer registeredMu.Unlock()
+	registered = append(registered, metrics...)
+}
+
+// Set sample value of the label values. Label values must be in the same order of LabelNames.
+func (m *Metric) Set(value float64, labelValues ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.get(labelValues).value = value
+}
+
+// Add delta to sample value of the label values.
+func (m *Metric) Add(delta float64, labelValues ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.get(labelValues).value += delta
+}
+
+func (m *Metric) get(labelValues []string) *sample {
+	if len(labelValues) != len(m.LabelNames) {
+		panic(fmt.Sprintf("metric %s: expect %d label values, got %d", m.Name, len(m.LabelNames), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s := m.samples[key]
+	if s == nil {
+		s = &sample{labelValues: append([]string{}, labelValues...)}
+		m.samples[key] = s
+		m.keys = append(m.keys, key)
+	}
+	return s
+}
+
+// Write metric in text exposition format. Metric without any sample is skipped.
+func (m *Metric) Write(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.keys) == 0 {
+		return nil
+	}
+	bw := bufio.NewWriter(w)
+	if m.Help != "" {
+		fmt.Fprintf(bw, "# HELP %s %s\n", m.Name, escape(m.Help, false))
+	}
+	fmt.Fprintf(bw, "# TYPE %s %s\n", m.Name, m.Type)
+	for _, key := range m.keys {
+		s := m.samples[key]
+		bw.WriteString(m.Name)
+		if len(m.LabelNames) > 0 {
+			bw.WriteString("{")
+			for i, name := range m.LabelNames {
+				if i > 0 {
+					bw.WriteString(",")
+				}
+				fmt.Fprintf(bw, `%s="%s"`, name, escape(s.labelValues[i], true))
+			}
+			bw.WriteString("}")
+		}
+		bw.WriteString(" ")
+		bw.WriteString(formatValue(s.value))
+		bw.WriteString("\n")
+	}
+	return bw.Flush()
+}
+
+// Write metrics in text exposition format.
+func Write(w io.Writer, metrics ...*Metric) error {
+	for _, metric := range metrics {
+		if err := metric.Write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write all registered metrics.
+func WriteRegistered(w io.Writer) error {
+	registeredMu.Lock()
+	metrics := append([]*Metric{}, registered...)
+	registeredMu.Unlock()
+	return Write(w, metrics...)
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// Escape help text or label value. In help text, only "\" and line feed are escaped.
+func escape(str string, isLabelValue bool) string {
+	str = strings.ReplaceAll(str, `\`, `\\`)
+	str = strings.ReplaceAll(str, "\n", `\n`)
+	if isLabelValue {
+		str = strings.ReplaceAll(str, `"`, `\"`)
+	}
+	return str
+}
+
+// Convert a bool to gauge value (1 or 0).
+func Bool(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
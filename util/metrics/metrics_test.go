@@ -0,0 +1,33 @@
+package metrics_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sagan/ptool/util/metrics"
+)
+
+func TestWrite(t *testing.T) {
+	gauge := metrics.NewGauge("ptool_test_bytes", "Test gauge.\nSecond line", "client", "tracker")
+	gauge.Set(1024, "local", "tracker.example.com")
+	gauge.Set(0.5, "local", `a"b\c`)
+	gauge.Set(2048, "local", "tracker.example.com")
+	counter := metrics.NewCounter("ptool_test_total", "")
+	counter.Add(1)
+	counter.Add(2)
+	empty := metrics.NewGauge("ptool_test_empty", "Empty gauge", "client")
+	buf := &bytes.Buffer{}
+	if err := metrics.Write(buf, gauge, counter, empty); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	want := `# HELP ptool_test_bytes Test gauge.\nSecond line
+# TYPE ptool_test_bytes gauge
+ptool_test_bytes{client="local",tracker="tracker.example.com"} 2048
+ptool_test_bytes{client="local",tracker="a\"b\\c"} 0.5
+# TYPE ptool_test_total counter
+ptool_test_total 3
+`
+	if got := buf.String(); got != want {
+		t.Errorf("Write output:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -56,3 +56,17 @@ func Fork(removeArg string) {
 		file.Close()
 	}
 }
+
+// Return true if the files (or dirs) of path1 and path2 are on the same device (filesystem),
+// so they can be moved between each other by renaming.
+func SameDevice(path1 string, path2 string) (bool, error) {
+	stat1, err := os.Stat(path1)
+	if err != nil {
+		return false, err
+	}
+	stat2, err := os.Stat(path2)
+	if err != nil {
+		return false, err
+	}
+	return stat1.Sys().(*syscall.Stat_t).Dev == stat2.Sys().(*syscall.Stat_t).Dev, nil
+}
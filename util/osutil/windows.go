@@ -5,7 +5,9 @@ package osutil
 
 import (
 	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/sys/windows"
@@ -42,3 +44,16 @@ func SetupConsole() {
 func Fork(removeArg string) {
 	log.Fatalf("Fork mode is NOT supported on current platform %s", runtime.GOOS)
 }
+
+// Return true if path1 and path2 are on the same volume, so they can be moved between each other by renaming.
+func SameDevice(path1 string, path2 string) (bool, error) {
+	absPath1, err := filepath.Abs(path1)
+	if err != nil {
+		return false, err
+	}
+	absPath2, err := filepath.Abs(path2)
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(filepath.VolumeName(absPath1), filepath.VolumeName(absPath2)), nil
+}
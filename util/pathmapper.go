@@ -0,0 +1,67 @@
+package util
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Map paths between two file systems (e.g. local file system and BitTorrent client's),
+// using rules of "before_path|after_path" format. See constants.HELP_ARG_PATH_MAPPERS.
+type PathMapper struct {
+	mapper  map[string]string
+	befores []string
+}
+
+func (spm *PathMapper) Before2After(beforePath string) (afterPath string, match bool) {
+	beforePath = path.Clean(ToSlash(beforePath))
+	for _, before := range spm.befores {
+		if before == "/" {
+			if strings.HasPrefix(beforePath, before) {
+				return spm.mapper[before] + strings.TrimPrefix(beforePath, before), true
+			}
+		} else if beforePath == before || strings.HasPrefix(beforePath, before+"/") {
+			return spm.mapper[before] + strings.TrimPrefix(beforePath, before), true
+		}
+	}
+	return beforePath, false
+}
+
+func (spm *PathMapper) After2Before(afterPath string) (beforePath string, match bool) {
+	afterPath = path.Clean(ToSlash(afterPath))
+	for _, before := range spm.befores {
+		after := spm.mapper[before]
+		if after == "/" {
+			if strings.HasPrefix(afterPath, after) {
+				return before + strings.TrimPrefix(afterPath, after), true
+			}
+		} else if afterPath == after || strings.HasPrefix(afterPath, after+"/") {
+			return before + strings.TrimPrefix(afterPath, after), true
+		}
+	}
+	return afterPath, false
+}
+
+func NewPathMapper(rules []string) (*PathMapper, error) {
+	pm := &PathMapper{
+		mapper: map[string]string{},
+	}
+	for _, rule := range rules {
+		sep := "|"
+		// use ":" as sep only when "|" not exists and no Windows abs path (e.g. "E:\Downloads") exists
+		if !strings.Contains(rule, "|") && !strings.Contains(rule, `:\`) {
+			sep = ":"
+		}
+		before, after, found := strings.Cut(rule, sep)
+		if !found || before == "" || after == "" {
+			return nil, fmt.Errorf("invalid path mapper rule %q", rule)
+		}
+		before = path.Clean(ToSlash(before))
+		after = path.Clean(ToSlash(after))
+		pm.mapper[before] = after
+		pm.befores = append(pm.befores, before)
+	}
+	slices.SortFunc(pm.befores, func(a, b string) int { return len(b) - len(a) }) // longest first
+	return pm, nil
+}
@@ -0,0 +1,38 @@
+package util
+
+import "testing"
+
+func TestPathMapper(t *testing.T) {
+	mapper, err := NewPathMapper([]string{"/root/Downloads|/downloads", "/root/Downloads/tv|/tv", `E:\Data|/data`})
+	if err != nil {
+		t.Fatalf("NewPathMapper error: %v", err)
+	}
+	for _, c := range []struct {
+		before string
+		after  string
+		match  bool
+	}{
+		{"/root/Downloads", "/downloads", true}, // the rule root path itself
+		{"/root/Downloads/", "/downloads", true},
+		{"/root/Downloads/movies", "/downloads/movies", true},
+		{"/root/Downloads/tv/Show", "/tv/Show", true}, // longest rule first
+		{"/root/Downloads2", "/root/Downloads2", false},
+		{`E:\Data\a`, "/data/a", true},
+		{"/root", "/root", false},
+	} {
+		if after, match := mapper.Before2After(c.before); after != c.after || match != c.match {
+			t.Errorf("Before2After(%q) = %q, %t; want %q, %t", c.before, after, match, c.after, c.match)
+		}
+		if c.match && c.before != "/root/Downloads/" {
+			if before, match := mapper.After2Before(c.after); before != ToSlash(c.before) || !match {
+				t.Errorf("After2Before(%q) = %q, %t; want %q, true", c.after, before, match, ToSlash(c.before))
+			}
+		}
+	}
+	if _, match := mapper.After2Before("/downloads2"); match {
+		t.Errorf("After2Before(%q) should not match", "/downloads2")
+	}
+	if _, err := NewPathMapper([]string{"/root/Downloads"}); err == nil {
+		t.Errorf("NewPathMapper with invalid rule should fail")
+	}
+}
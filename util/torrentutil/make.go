@@ -0,0 +1,313 @@
+package torrentutil
+
+// Parallel and resumable pieces hashing of torrent making.
+// Content files are read sequentially (in torrent order) by a single reader, and the pieces are hashed
+// by a pool of workers. The number of piece buffers is bounded, so the memory used is at most
+// 2 * workers * piece length.
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/torrent/merkle"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/natefinch/atomic"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	AUTO_PIECE_LENGTH_PIECES = 2000     // target max number of pieces of automatically chosen piece length
+	AUTO_PIECE_LENGTH_MIN    = 16 << 10 // 16KiB
+	AUTO_PIECE_LENGTH_MAX    = 16 << 20 // 16MiB
+	CHECKPOINT_INTERVAL      = 10 * time.Second
+)
+
+// Return the automatically chosen piece length for torrent of totalSize contents: the smallest power of 2 that
+// makes the number of pieces <= AUTO_PIECE_LENGTH_PIECES, in range [AUTO_PIECE_LENGTH_MIN, AUTO_PIECE_LENGTH_MAX].
+func AutoPieceLength(totalSize int64) int64 {
+	pieceLength := int64(AUTO_PIECE_LENGTH_MIN)
+	for pieceLength < AUTO_PIECE_LENGTH_MAX && (totalSize+pieceLength-1)/pieceLength > AUTO_PIECE_LENGTH_PIECES {
+		pieceLength *= 2
+	}
+	return pieceLength
+}
+
+type hashOptions struct {
+	v1       bool // generate v1 (SHA-1) pieces hashes
+	v2       bool // generate v2 (SHA-256 Merkle) hashes. Each file starts at a piece boundary
+	hybrid   bool // pad v1 piece hash of each file (except the last one) to piece length
+	workers  int
+	progress func(done, total int64)
+	// If not empty, save hashing state to this file periodically, and resume from it if it exists.
+	checkpoint string
+}
+
+// A piece to be hashed.
+type makePiece struct {
+	segments []pieceSegment
+	length   int64
+	pad      bool // hybrid: pad v1 hash with zeros to piece length
+	small    bool // v2: the only piece of a file not larger than piece length. It's v2 hash is file's "pieces root"
+}
+
+type makeTask struct {
+	index int
+	buf   []byte
+}
+
+type makeResult struct {
+	index  int
+	v1Hash [sha1.Size]byte
+	v2Hash [32]byte
+}
+
+type checkpointFile struct {
+	Path  string
+	Size  int64
+	Mtime int64
+}
+
+// Saved state of an interrupted hashing.
+type makeCheckpoint struct {
+	PieceLength int64
+	V1          bool
+	V2          bool
+	Hybrid      bool
+	Files       []checkpointFile
+	Done        int    // number of hashed pieces, which are the first ones
+	V1Hashes    []byte // v1 hashes of done pieces
+	V2Hashes    []byte // v2 hashes of done pieces
+}
+
+func (c *makeCheckpoint) match(other *makeCheckpoint) bool {
+	return c.PieceLength == other.PieceLength && c.V1 == other.V1 && c.V2 == other.V2 && c.Hybrid == other.Hybrid &&
+		slices.Equal(c.Files, other.Files)
+}
+
+// Layout content files to pieces. For v2, each file starts at a new piece.
+func makePiecesLayout(files []metainfo.FileInfo, pieceLength int64, options *hashOptions) (pieces []*makePiece) {
+	if !options.v2 {
+		var piece *makePiece
+		for i, file := range files {
+			for offset := int64(0); offset < file.Length; {
+				if piece == nil || piece.length == pieceLength {
+					piece = &makePiece{}
+					pieces = append(pieces, piece)
+				}
+				length := min(file.Length-offset, pieceLength-piece.length)
+				piece.segments = append(piece.segments, pieceSegment{file: i, offset: offset, length: length})
+				piece.length += length
+				offset += length
+			}
+		}
+		return pieces
+	}
+	for i, file := range files {
+		for offset := int64(0); offset < file.Length; offset += pieceLength {
+			length := min(file.Length-offset, pieceLength)
+			pieces = append(pieces, &makePiece{
+				segments: []pieceSegment{{file: i, offset: offset, length: length}},
+				length:   length,
+				pad:      options.hybrid && i < len(files)-1 && length < pieceLength,
+				small:    file.Length <= pieceLength,
+			})
+		}
+	}
+	return pieces
+}
+
+// Hash content files of info in root. Return v1 (20 bytes each) and / or v2 (32 bytes each) hashes of all pieces.
+// For v2 piece which is the only piece of a small file, the v2 hash is the "pieces root" of the file.
+func hashPieces(info *metainfo.Info, root string, options *hashOptions) (v1Hashes []byte, v2Hashes []byte, err error) {
+	files := info.UpvertedV1Files()
+	pieceLength := info.PieceLength
+	filenames := []string{}
+	state := &makeCheckpoint{PieceLength: pieceLength, V1: options.v1, V2: options.v2, Hybrid: options.hybrid}
+	total := int64(0)
+	for _, file := range files {
+		filename := root
+		if len(info.Files) > 0 {
+			filename = filepath.Join(root, filepath.Join(file.Path...))
+		}
+		stat, err := os.Stat(filename)
+		if err != nil {
+			return nil, nil, err
+		}
+		filenames = append(filenames, filename)
+		state.Files = append(state.Files, checkpointFile{
+			Path:  filepath.ToSlash(filename),
+			Size:  file.Length,
+			Mtime: stat.ModTime().Unix(),
+		})
+		total += file.Length
+	}
+	pieces := makePiecesLayout(files, pieceLength, options)
+	v1Hashes = make([]byte, len(pieces)*sha1.Size)
+	v2Hashes = make([]byte, len(pieces)*32)
+	done := int64(0)
+	if options.checkpoint != "" {
+		if checkpoint := loadCheckpoint(options.checkpoint); checkpoint != nil && checkpoint.match(state) &&
+			checkpoint.Done <= len(pieces) &&
+			(!options.v1 || len(checkpoint.V1Hashes) == checkpoint.Done*sha1.Size) &&
+			(!options.v2 || len(checkpoint.V2Hashes) == checkpoint.Done*32) {
+			log.Warnf("Resume from checkpoint %q: %d/%d pieces are already hashed", options.checkpoint,
+				checkpoint.Done, len(pieces))
+			state.Done = checkpoint.Done
+			copy(v1Hashes, checkpoint.V1Hashes)
+			copy(v2Hashes, checkpoint.V2Hashes)
+			for _, piece := range pieces[:state.Done] {
+				done += piece.length
+			}
+		}
+	}
+	saveState := func() {
+		if options.checkpoint == "" {
+			return
+		}
+		if options.v1 {
+			state.V1Hashes = v1Hashes[:state.Done*sha1.Size]
+		}
+		if options.v2 {
+			state.V2Hashes = v2Hashes[:state.Done*32]
+		}
+		if err := saveCheckpoint(options.checkpoint, state); err != nil {
+			log.Warnf("Failed to save checkpoint: %v", err)
+		}
+	}
+
+	workers := options.workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	pool := make(chan []byte, workers*2)
+	for range cap(pool) {
+		pool <- make([]byte, pieceLength)
+	}
+	tasks := make(chan *makeTask, workers)
+	results := make(chan *makeResult, workers)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(tasks)
+		reader := &verifyWorker{filenames: filenames}
+		defer reader.close()
+		for i := state.Done; i < len(pieces); i++ {
+			buf := (<-pool)[:0]
+			for _, segment := range pieces[i].segments {
+				n := int64(len(buf))
+				buf = buf[:n+segment.length]
+				file, err := reader.open(segment.file)
+				if err == nil {
+					_, err = file.ReadAt(buf[n:], segment.offset)
+				}
+				if err != nil {
+					readErr <- fmt.Errorf("failed to read %s: %w", filenames[segment.file], err)
+					return
+				}
+			}
+			tasks <- &makeTask{index: i, buf: buf}
+		}
+	}()
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			zeros := []byte{}
+			for task := range tasks {
+				piece := pieces[task.index]
+				result := &makeResult{index: task.index}
+				if options.v1 {
+					hash := sha1.New()
+					hash.Write(task.buf)
+					if piece.pad {
+						if len(zeros) == 0 {
+							zeros = make([]byte, pieceLength)
+						}
+						hash.Write(zeros[:pieceLength-piece.length])
+					}
+					hash.Sum(result.v1Hash[:0])
+				}
+				if options.v2 {
+					hash := merkle.NewHash()
+					hash.Write(task.buf)
+					if piece.small {
+						hash.Sum(result.v2Hash[:0])
+					} else {
+						hash.SumMinLength(result.v2Hash[:0], int(pieceLength))
+					}
+				}
+				pool <- task.buf[:cap(task.buf)]
+				results <- result
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	finished := make([]bool, len(pieces))
+	lastSave := time.Now()
+	for result := range results {
+		copy(v1Hashes[result.index*sha1.Size:], result.v1Hash[:])
+		copy(v2Hashes[result.index*32:], result.v2Hash[:])
+		finished[result.index] = true
+		done += pieces[result.index].length
+		for state.Done < len(pieces) && finished[state.Done] {
+			state.Done++
+		}
+		if options.progress != nil {
+			options.progress(done, total)
+		}
+		if options.checkpoint != "" && time.Since(lastSave) >= CHECKPOINT_INTERVAL {
+			saveState()
+			lastSave = time.Now()
+		}
+	}
+	select {
+	case err = <-readErr:
+		saveState()
+		return nil, nil, err
+	default:
+	}
+	if options.checkpoint != "" {
+		if err := os.Remove(options.checkpoint); err != nil && !os.IsNotExist(err) {
+			log.Warnf("Failed to remove checkpoint file: %v", err)
+		}
+	}
+	if !options.v1 {
+		v1Hashes = nil
+	}
+	if !options.v2 {
+		v2Hashes = nil
+	}
+	return v1Hashes, v2Hashes, nil
+}
+
+func loadCheckpoint(filename string) *makeCheckpoint {
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		return nil
+	}
+	var checkpoint *makeCheckpoint
+	if err := json.Unmarshal(contents, &checkpoint); err != nil {
+		log.Warnf("Ignore invalid checkpoint file %q: %v", filename, err)
+		return nil
+	}
+	return checkpoint
+}
+
+func saveCheckpoint(filename string, checkpoint *makeCheckpoint) error {
+	contents, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	return atomic.WriteFile(filename, bytes.NewReader(contents))
+}
@@ -0,0 +1,116 @@
+package torrentutil
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+func TestAutoPieceLength(t *testing.T) {
+	for _, c := range []struct {
+		size int64
+		want int64
+	}{
+		{0, 16 << 10},
+		{1, 16 << 10},
+		{2000 * 16 << 10, 16 << 10},
+		{2000*16<<10 + 1, 32 << 10},
+		{4 << 30, 4 << 20},
+		{1 << 50, 16 << 20},
+	} {
+		if got := AutoPieceLength(c.size); got != c.want {
+			t.Errorf("AutoPieceLength(%d) = %d, want %d", c.size, got, c.want)
+		}
+	}
+}
+
+func testBuildInfo(t *testing.T, contentPath string, pieceLength int64) *metainfo.Info {
+	info := &metainfo.Info{PieceLength: pieceLength}
+	if err := infoBuildFromFilePath(info, contentPath, nil, false, 0); err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func TestHashPieces(t *testing.T) {
+	contentPath := makeTestContents(t)
+	info := testBuildInfo(t, contentPath, 16<<10)
+	expected := &metainfo.Info{PieceLength: info.PieceLength, Files: info.Files}
+	if err := expected.GeneratePieces(func(fi metainfo.FileInfo) (io.ReadCloser, error) {
+		return os.Open(filepath.Join(contentPath, strings.Join(fi.Path, string(filepath.Separator))))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, workers := range []int{1, 3, 8} {
+		progress := int64(0)
+		pieces, _, err := hashPieces(info, contentPath, &hashOptions{v1: true, workers: workers,
+			progress: func(done, total int64) { progress = done }})
+		if err != nil {
+			t.Fatalf("hashPieces(workers=%d) error: %v", workers, err)
+		}
+		if !bytes.Equal(pieces, expected.Pieces) {
+			t.Errorf("hashPieces(workers=%d) pieces mismatch", workers)
+		}
+		if progress != info.TotalLength() {
+			t.Errorf("hashPieces(workers=%d) progress = %d, want %d", workers, progress, info.TotalLength())
+		}
+	}
+
+	// Resume from checkpoint. The hashes of done pieces are taken from checkpoint without re-hashing.
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint.json")
+	options := &hashOptions{v1: true, workers: 2, checkpoint: checkpoint}
+	state := &makeCheckpoint{PieceLength: info.PieceLength, V1: true, Done: 2}
+	for _, file := range info.UpvertedV1Files() {
+		filename := filepath.Join(contentPath, filepath.Join(file.Path...))
+		stat, err := os.Stat(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		state.Files = append(state.Files, checkpointFile{Path: filepath.ToSlash(filename), Size: file.Length,
+			Mtime: stat.ModTime().Unix()})
+	}
+	fakePieces := bytes.Repeat([]byte{1}, 2*sha1.Size)
+	state.V1Hashes = fakePieces
+	if err := saveCheckpoint(checkpoint, state); err != nil {
+		t.Fatal(err)
+	}
+	pieces, _, err := hashPieces(info, contentPath, options)
+	if err != nil {
+		t.Fatalf("hashPieces error: %v", err)
+	}
+	if !bytes.Equal(pieces[:2*sha1.Size], fakePieces) || !bytes.Equal(pieces[2*sha1.Size:], expected.Pieces[2*sha1.Size:]) {
+		t.Errorf("hashPieces did not resume from checkpoint")
+	}
+	if _, err := os.Stat(checkpoint); !os.IsNotExist(err) {
+		t.Errorf("checkpoint file is not removed after hashing completes")
+	}
+
+	// Checkpoint of different contents is ignored.
+	state.PieceLength *= 2
+	if err := saveCheckpoint(checkpoint, state); err != nil {
+		t.Fatal(err)
+	}
+	if pieces, _, err = hashPieces(info, contentPath, options); err != nil || !bytes.Equal(pieces, expected.Pieces) {
+		t.Errorf("hashPieces with mismatched checkpoint = %v, pieces match = %t", err, bytes.Equal(pieces, expected.Pieces))
+	}
+
+	// A failed hashing saves checkpoint.
+	if err := os.Remove(filepath.Join(contentPath, "sub", "c d.bin")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(contentPath, "sub", "c d.bin"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = hashPieces(info, contentPath, options); err == nil {
+		t.Errorf("hashPieces with truncated file should fail")
+	}
+	if loadCheckpoint(checkpoint) == nil {
+		t.Errorf("checkpoint is not saved after hashing fails")
+	}
+}
@@ -2,7 +2,6 @@ package torrentutil
 
 import (
 	"bytes"
-	"crypto/sha1"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -44,8 +43,9 @@ type TorrentMetaFile struct {
 }
 
 type TorrentMeta struct {
-	InfoHash          string
-	PiecesHash        string // sha1(torrent.info.pieces)
+	InfoHash          string // v1 info-hash. For v2-only torrent, it's the truncated (first 20 bytes) v2 info-hash
+	InfoHashV2        string // v2 (SHA-256) info-hash. Empty for v1-only torrent
+	PiecesHash        string // sha1(torrent.info.pieces). For v2-only torrent, sha1 of all files' "pieces root"
 	Trackers          []string
 	Size              int64
 	SingleFileTorrent bool
@@ -66,6 +66,7 @@ type TorrentMakeOptions struct {
 	Force                         bool
 	Comment                       string
 	InfoName                      string
+	Source                        string // "info.source" field
 	UrlList                       metainfo.UrlList
 	Trackers                      []string
 	CreatedBy                     string
@@ -76,6 +77,12 @@ type TorrentMakeOptions struct {
 	AllowRestrictedCharInFilename bool
 	// If > 0, limit filename (not full path) length to at most these bytes (UTF-8 string).
 	FilenameLengthLimit int64
+	V2                  bool // create BitTorrent v2 only torrent
+	Hybrid              bool // create hybrid (v1 + v2) torrent
+	Workers             int  // number of concurrent pieces hashing workers. 0 means the number of CPUs
+	// If not empty, save pieces hashing state to this file periodically, so an interrupted making can resume.
+	Checkpoint string
+	Progress   func(done, total int64) // if not nil, called with pieces hashing progress (bytes)
 }
 
 var (
@@ -92,6 +99,8 @@ var (
 func (tm TorrentMeta) MarshalJSON() ([]byte, error) {
 	data := map[string]any{
 		"InfoHash":           tm.InfoHash,
+		"InfoHashV2":         tm.InfoHashV2,
+		"Version":            tm.Version(),
 		"PiecesHash":         tm.PiecesHash,
 		"Trackers":           tm.Trackers,
 		"Size":               tm.Size,
@@ -139,8 +148,17 @@ func FromMetaInfo(metaInfo *metainfo.MetaInfo, info *metainfo.Info) (*TorrentMet
 		}
 		torrentMeta.Info = &_info
 	}
-	torrentMeta.PiecesHash = util.Sha1(torrentMeta.Info.Pieces)
 	info = torrentMeta.Info
+	if info.HasV2() {
+		torrentMeta.InfoHashV2 = infoHashV2(metaInfo.InfoBytes)
+		if !info.HasV1() {
+			torrentMeta.InfoHash = torrentMeta.InfoHashV2[:40]
+			torrentMeta.PiecesHash = piecesRootsHash(info)
+			torrentMeta.parseV2Files()
+			return torrentMeta, nil
+		}
+	}
+	torrentMeta.PiecesHash = util.Sha1(info.Pieces)
 	piecesCnt := int64(info.NumPieces())
 	// single file torrent
 	if len(info.Files) == 0 {
@@ -180,21 +198,24 @@ func FromMetaInfo(metaInfo *metainfo.MetaInfo, info *metainfo.Info) (*TorrentMet
 			} else if currentPieceIndex == endPieceIndex {
 				lastPieceBytes = firstPieceLeft
 			}
-			torrentMeta.Files = append(torrentMeta.Files, &TorrentMetaFile{
-				Path:             util.Clean(strings.Join(metafile.Path, "/")),
-				Size:             metafile.Length,
-				StartPieceIndex:  currentPieceIndex,
-				StartPieceOffset: currentPieceOffset,
-				EndPieceIndex:    endPieceIndex,
-				LastPieceBytes:   lastPieceBytes,
-			})
+			// BEP 47 padding files (e.g. in hybrid torrents) are not real contents
+			if !isPadFile(&metafile) {
+				torrentMeta.Files = append(torrentMeta.Files, &TorrentMetaFile{
+					Path:             util.Clean(strings.Join(metafile.Path, "/")),
+					Size:             metafile.Length,
+					StartPieceIndex:  currentPieceIndex,
+					StartPieceOffset: currentPieceOffset,
+					EndPieceIndex:    endPieceIndex,
+					LastPieceBytes:   lastPieceBytes,
+				})
+				torrentMeta.Size += metafile.Length
+			}
 			if currentPieceIndex == endPieceIndex {
 				currentPieceOffset += firstPieceLeft
 			} else {
 				currentPieceOffset = lastIncompletePieceBytes
 			}
 			currentPieceIndex = endPieceIndex
-			torrentMeta.Size += metafile.Length
 		}
 	}
 	return torrentMeta, nil
@@ -488,6 +509,9 @@ func (meta *TorrentMeta) Fprint(f io.Writer, name string, showAll bool) {
 		} else {
 			fmt.Fprintf(f, "! RootDir = %q ; ", meta.RootDir)
 		}
+		if meta.InfoHashV2 != "" {
+			fmt.Fprintf(f, "! Version = %s ; InfoHashV2 = %s\n", meta.Version(), meta.InfoHashV2)
+		}
 		fmt.Fprintf(f, "RawSize = %d ; PieceLength = %s ; PiecesHash = %s ; CreationDate = %s ; AllTrackers (%d): %s ;%s\n",
 			meta.Size, util.BytesSizeAround(float64(meta.Info.PieceLength)), meta.PiecesHash,
 			creationDate, len(meta.Trackers), strings.Join(meta.Trackers, " | "), comment)
@@ -612,113 +636,20 @@ func (meta *TorrentMeta) VerifyAgaintSavePathFs(savePathFs fs.FS) error {
 // checkHash: 0 - none; 1 - quick; 2+ - full.
 // checkMinLength : Used with quick hash mode; if > 0, at least this size of file head & tail must be checked.
 // ts: timestamp of newest file in torrent contents.
+// It stops at the first error. Use VerifyDetailed to get a full report.
 func (meta *TorrentMeta) Verify(savePath, contentPath string, checkHash, checkMinLength int64) (ts int64, err error) {
-	var filenames []string
-	prefixPath := ""
-	if contentPath != "" {
-		contentPath, err = filepath.Abs(contentPath)
-		if err != nil {
-			return 0, fmt.Errorf("invalid content-path: %w", err)
-		}
-		prefixPath = contentPath + "/"
-	} else {
-		prefixPath = savePath + "/"
-		if meta.RootDir != "" {
-			prefixPath += meta.RootDir + "/"
-		}
-	}
-	for _, file := range meta.Files {
-		filename := ""
-		if contentPath != "" && meta.SingleFileTorrent {
-			filename = contentPath
-		} else {
-			filename = prefixPath + file.Path
-		}
-		stat, err := os.Stat(filename)
-		if err != nil {
-			return ts, fmt.Errorf("failed to get file %q stat: %w", file.Path, err)
-		}
-		ts = max(stat.ModTime().Unix(), ts)
-		if stat.Size() != file.Size {
-			return ts, fmt.Errorf("file %q has wrong length: expect=%d, actual=%d", file.Path, file.Size, stat.Size())
-		}
-		if checkHash > 0 {
-			filenames = append(filenames, filename)
-		}
-	}
-	if checkHash > 0 && len(meta.Files) > 0 {
-		piecesCnt := meta.Info.NumPieces()
-		var currentFileIndex = int64(0)
-		var currentFileOffset = int64(0)
-		var currentFileRemain = int64(0)
-		var currentFile *os.File
-		var err error
-		i := 0
-		// At least 1 piece length of each file's head & tail must be checked
-		checkMinLength = max(meta.Info.PieceLength, checkMinLength)
-		for {
-			if i >= piecesCnt {
-				break
-			}
-			if checkHash == 1 && currentFile != nil &&
-				currentFileOffset >= checkMinLength && currentFileRemain > checkMinLength {
-				skipPieces := (currentFileRemain - checkMinLength) / meta.Info.PieceLength
-				skipLength := skipPieces * meta.Info.PieceLength
-				currentFileOffset += skipLength
-				currentFileRemain -= skipLength
-				i += int(skipPieces)
-			}
-			p := meta.Info.Piece(i)
-			hash := sha1.New()
-			len := p.Length()
-			for len > 0 {
-				if currentFile == nil {
-					if currentFile, err = os.Open(filenames[currentFileIndex]); err != nil {
-						return ts, fmt.Errorf("piece %d/%d: failed to open file %s: %w",
-							i, piecesCnt-1, filenames[currentFileIndex], err)
-					}
-					log.Tracef("piece %d/%d: open file %s", i, piecesCnt-1, filenames[currentFileIndex])
-					currentFileOffset = 0
-					currentFileRemain = meta.Files[currentFileIndex].Size
-				}
-				readlen := min(currentFileRemain, len)
-				_, err := io.Copy(hash, io.NewSectionReader(currentFile, currentFileOffset, readlen))
-				if err != nil {
-					currentFile.Close()
-					return ts, err
-				}
-				currentFileOffset += readlen
-				currentFileRemain -= readlen
-				len -= readlen
-				if currentFileRemain == 0 {
-					currentFile.Close()
-					currentFile = nil
-					currentFileIndex++
-				}
-			}
-			good := bytes.Equal(hash.Sum(nil), p.V1Hash().Value.Bytes())
-			if !good {
-				return ts, fmt.Errorf("piece %d/%d: hash mismatch", i, piecesCnt-1)
-			}
-			log.Tracef("piece %d/%d verify-hash %x: %v", i, piecesCnt-1, p.V1Hash().Value, good)
-			i++
-		}
-	}
-	if contentPath != "" {
-		fileStats, err := os.Stat(contentPath)
-		if err == nil {
-			if meta.SingleFileTorrent {
-				if fileStats.Name() != meta.Files[0].Path {
-					return ts, ErrDifferentName
-				}
-			} else {
-				if fileStats.Name() != meta.RootDir {
-					return ts, ErrDifferentRootName
-				}
-			}
-		}
+	report, err := meta.VerifyDetailed(&VerifyOptions{
+		SavePath:       savePath,
+		ContentPath:    contentPath,
+		CheckHash:      checkHash,
+		CheckMinLength: checkMinLength,
+		Workers:        1,
+		FailFast:       true,
+	})
+	if err != nil {
+		return 0, err
 	}
-	return ts, nil
+	return report.Ts, report.Err()
 }
 
 // Rename torrent (downloaded filename or name of torrent added to client) according to renameTemplate,
@@ -825,19 +756,26 @@ func MakeTorrent(options *TorrentMakeOptions) (tinfo *TorrentMeta, err error) {
 		}
 	}
 	info := &metainfo.Info{}
-	if pieceLength, err := util.RAMInBytes(options.PieceLengthStr); err != nil {
-		return nil, fmt.Errorf("invalid piece-length: %w", err)
-	} else {
-		info.PieceLength = pieceLength
+	if options.PieceLengthStr != constants.TORRENT_PIECE_LENGTH_AUTO {
+		if pieceLength, err := util.RAMInBytes(options.PieceLengthStr); err != nil {
+			return nil, fmt.Errorf("invalid piece-length: %w", err)
+		} else {
+			info.PieceLength = pieceLength
+		}
 	}
 	if options.Private {
 		private := true
 		info.Private = &private
 	}
+	info.Source = options.Source
 	if !options.All {
 		options.Excludes = append(options.Excludes, constants.DefaultIgnorePatterns...)
 	}
 	log.Infof("Creating torrent for %q", options.ContentPath)
+	if options.V2 && options.Hybrid {
+		return nil, fmt.Errorf("v2 and hybrid options are mutually exclusive")
+	}
+	v2 := options.V2 || options.Hybrid
 	if err := infoBuildFromFilePath(info, options.ContentPath, options.Excludes,
 		options.AllowRestrictedCharInFilename, options.FilenameLengthLimit); err != nil {
 		return nil, fmt.Errorf("failed to build info from content-path: %w", err)
@@ -857,6 +795,23 @@ func MakeTorrent(options *TorrentMakeOptions) (tinfo *TorrentMeta, err error) {
 	if options.InfoName != "" {
 		info.Name = options.InfoName
 	}
+	log.Infof("Hashing contents (piece length %s)", util.BytesSize(float64(info.PieceLength)))
+	hashOptions := &hashOptions{
+		hybrid:     options.Hybrid,
+		workers:    options.Workers,
+		progress:   options.Progress,
+		checkpoint: options.Checkpoint,
+	}
+	if v2 {
+		if mi.PieceLayers, err = infoGenerateV2(info, options.ContentPath, hashOptions); err != nil {
+			return nil, fmt.Errorf("error generating v2 hashes: %w", err)
+		}
+	} else {
+		hashOptions.v1 = true
+		if info.Pieces, _, err = hashPieces(info, options.ContentPath, hashOptions); err != nil {
+			return nil, fmt.Errorf("error generating pieces: %w", err)
+		}
+	}
 	if mi.InfoBytes, err = bencode.Marshal(info); err != nil {
 		return nil, fmt.Errorf("failed to marshal info: %w", err)
 	}
@@ -898,6 +853,7 @@ func MakeTorrent(options *TorrentMakeOptions) (tinfo *TorrentMeta, err error) {
 
 // Adapted from metainfo.BuildFromFilePath.
 // excludes: gitignore style exclude-file-patterns.
+// If info.PieceLength is 0, it is chosen automatically by AutoPieceLength. Pieces hashes are not generated.
 func infoBuildFromFilePath(info *metainfo.Info, root string, excludes []string,
 	allowAnyCharInName bool, filenameLengthLimit int64) (err error) {
 	info.Name = func() string {
@@ -966,13 +922,7 @@ func infoBuildFromFilePath(info *metainfo.Info, root string, excludes []string,
 		return 0
 	})
 	if info.PieceLength == 0 {
-		info.PieceLength = metainfo.ChoosePieceLength(info.TotalLength())
-	}
-	err = info.GeneratePieces(func(fi metainfo.FileInfo) (io.ReadCloser, error) {
-		return os.Open(filepath.Join(root, strings.Join(fi.Path, string(filepath.Separator))))
-	})
-	if err != nil {
-		err = fmt.Errorf("error generating pieces: %s", err)
+		info.PieceLength = AutoPieceLength(info.TotalLength())
 	}
 	return
 }
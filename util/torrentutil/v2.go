@@ -0,0 +1,190 @@
+package torrentutil
+
+// BitTorrent v2 (BEP 52) and hybrid torrents support.
+// A v2 torrent has "meta version = 2" and "file tree" in info, and "piece layers" in metainfo.
+// Each file is hashed independently by a SHA-256 Merkle tree of 16KiB blocks, and starts at a piece boundary.
+// A hybrid torrent has both v1 ("pieces" / "files" / "length") and v2 fields in info, the v1 files are
+// padded with BEP 47 padding files so that both versions have the same piece layout.
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/anacrolix/torrent/merkle"
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+const (
+	TORRENT_VERSION_V1     = "v1"
+	TORRENT_VERSION_V2     = "v2"
+	TORRENT_VERSION_HYBRID = "hybrid"
+)
+
+// Return the BitTorrent protocol version of torrent: "v1", "v2" or "hybrid".
+func (meta *TorrentMeta) Version() string {
+	if !meta.Info.HasV2() {
+		return TORRENT_VERSION_V1
+	}
+	if meta.Info.HasV1() {
+		return TORRENT_VERSION_HYBRID
+	}
+	return TORRENT_VERSION_V2
+}
+
+// Return true if infoHash is the v1 info-hash, the v2 info-hash, or the truncated (first 20 bytes)
+// v2 info-hash of torrent. The truncated form is used by clients to identify v2-only torrents.
+func (meta *TorrentMeta) MatchInfoHash(infoHash string) bool {
+	infoHash = strings.ToLower(infoHash)
+	if infoHash == meta.InfoHash {
+		return true
+	}
+	if meta.InfoHashV2 == "" {
+		return false
+	}
+	return infoHash == meta.InfoHashV2 || infoHash == meta.InfoHashV2[:40]
+}
+
+// Return true if file is a BEP 47 padding file.
+func isPadFile(file *metainfo.FileInfo) bool {
+	return strings.Contains(file.Attr, "p")
+}
+
+func infoHashV2(infoBytes []byte) string {
+	sum := sha256.Sum256(infoBytes)
+	return hex.EncodeToString(sum[:])
+}
+
+// sha1 of the concatenated "pieces root"s of all files, as the v2 equivalent of v1 PiecesHash.
+func piecesRootsHash(info *metainfo.Info) string {
+	var roots []byte
+	for _, file := range info.UpvertedFiles() {
+		if file.PiecesRoot.Ok {
+			roots = append(roots, file.PiecesRoot.Value[:]...)
+		}
+	}
+	sum := sha1.Sum(roots)
+	return hex.EncodeToString(sum[:])
+}
+
+// Parse files of v2-only torrent from the "file tree".
+func (meta *TorrentMeta) parseV2Files() {
+	info := meta.Info
+	files := info.UpvertedFiles()
+	if len(files) == 1 && len(files[0].Path) == 1 && files[0].Path[0] == info.Name {
+		meta.SingleFileTorrent = true
+		meta.ContentPath = info.Name
+	} else if info.Name != "" && info.Name != metainfo.NoName {
+		meta.RootDir = info.Name
+		meta.ContentPath = info.Name
+	}
+	for _, file := range files {
+		startPieceIndex := file.TorrentOffset / info.PieceLength
+		endPieceIndex := startPieceIndex
+		if file.Length > 0 {
+			endPieceIndex = (file.TorrentOffset + file.Length - 1) / info.PieceLength
+		}
+		path := strings.Join(file.Path, "/")
+		if meta.SingleFileTorrent {
+			path = info.Name
+		}
+		meta.Files = append(meta.Files, &TorrentMetaFile{
+			Path:             path,
+			Size:             file.Length,
+			StartPieceIndex:  startPieceIndex,
+			EndPieceIndex:    endPieceIndex,
+			StartPieceOffset: 0,
+			LastPieceBytes:   file.Length - (endPieceIndex-startPieceIndex)*info.PieceLength,
+		})
+		meta.Size += file.Length
+	}
+}
+
+// Generate v2 fields ("file tree", "piece layers") of info from files in root.
+// If options.hybrid is true, also generate v1 "pieces" and insert padding files, making a hybrid torrent.
+// info.Files (or info.Length for single file torrent) and info.PieceLength must already be set.
+func infoGenerateV2(info *metainfo.Info, root string, options *hashOptions) (pieceLayers map[string]string, err error) {
+	if info.PieceLength < merkle.BlockSize || info.PieceLength&(info.PieceLength-1) != 0 {
+		return nil, fmt.Errorf("piece length of v2 torrent must be a power of 2 and at least 16KiB")
+	}
+	singleFile := len(info.Files) == 0
+	// "file tree" is ordered by path components, and files of hybrid torrent must be in the same order
+	slices.SortStableFunc(info.Files, func(l, r metainfo.FileInfo) int {
+		return slices.Compare(l.Path, r.Path)
+	})
+	hybrid := options.hybrid
+	options.v1 = hybrid
+	options.v2 = true
+	v1Hashes, v2Hashes, err := hashPieces(info, root, options)
+	if err != nil {
+		return nil, err
+	}
+	files := info.UpvertedV1Files()
+	fileTree := metainfo.FileTree{Dir: map[string]metainfo.FileTree{}}
+	pieceLayers = map[string]string{}
+	var v1Files []metainfo.FileInfo
+	pieceIndex := int64(0)
+	for i, file := range files {
+		path := []string{info.Name}
+		if !singleFile {
+			path = file.Path
+		}
+		last := i == len(files)-1
+		numPieces := (file.Length + info.PieceLength - 1) / info.PieceLength
+		layer := v2Hashes[pieceIndex*sha256.Size : (pieceIndex+numPieces)*sha256.Size]
+		pieceIndex += numPieces
+		var piecesRoot []byte
+		if file.Length > info.PieceLength {
+			layerHashes := make([][32]byte, numPieces)
+			for j := range layerHashes {
+				copy(layerHashes[j][:], layer[j*sha256.Size:])
+			}
+			root := merkle.RootWithPadHash(layerHashes, metainfo.HashForPiecePad(info.PieceLength))
+			piecesRoot = root[:]
+			pieceLayers[string(piecesRoot)] = string(layer)
+		} else if file.Length > 0 {
+			// For file not larger than piece length, the root is the Merkle root of it's blocks.
+			piecesRoot = layer
+		}
+		insertFileTree(&fileTree, path, metainfo.FileTreeFile{Length: file.Length, PiecesRoot: string(piecesRoot)})
+		if hybrid {
+			v1Files = append(v1Files, metainfo.FileInfo{Path: file.Path, Length: file.Length})
+			if padding := (info.PieceLength - file.Length%info.PieceLength) % info.PieceLength; !last && padding > 0 {
+				v1Files = append(v1Files, metainfo.FileInfo{
+					Path:              []string{".pad", fmt.Sprint(padding)},
+					Length:            padding,
+					ExtendedFileAttrs: metainfo.ExtendedFileAttrs{Attr: "p"},
+				})
+			}
+		}
+	}
+	info.MetaVersion = 2
+	info.FileTree = fileTree
+	if hybrid {
+		info.Pieces = v1Hashes
+		if !singleFile {
+			info.Files = v1Files
+		}
+	} else {
+		info.Pieces = nil
+		info.Files = nil
+		info.Length = 0
+	}
+	return pieceLayers, nil
+}
+
+func insertFileTree(fileTree *metainfo.FileTree, path []string, file metainfo.FileTreeFile) {
+	if len(path) == 0 {
+		fileTree.File = file
+		return
+	}
+	if fileTree.Dir == nil {
+		fileTree.Dir = map[string]metainfo.FileTree{}
+	}
+	sub := fileTree.Dir[path[0]]
+	insertFileTree(&sub, path[1:], file)
+	fileTree.Dir[path[0]] = sub
+}
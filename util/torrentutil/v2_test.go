@@ -0,0 +1,152 @@
+package torrentutil
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+func makeTestContents(t *testing.T) string {
+	dir := filepath.Join(t.TempDir(), "contents")
+	r := rand.New(rand.NewSource(1))
+	for name, size := range map[string]int{
+		"a.bin":        40000,  // spans multiple pieces
+		"sub/b.bin":    10000,  // smaller than a piece
+		"sub/c d.bin":  131072, // exact multiple of piece length
+		"sub 2/e.bin":  1,
+		"sub/empty.md": 0,
+	} {
+		data := make([]byte, size)
+		r.Read(data)
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func makeTestTorrent(t *testing.T, contentPath string, v2 bool, hybrid bool) *TorrentMeta {
+	output := filepath.Join(t.TempDir(), "test.torrent")
+	_, err := MakeTorrent(&TorrentMakeOptions{
+		ContentPath:    contentPath,
+		Output:         output,
+		All:            true,
+		PieceLengthStr: "16KiB",
+		V2:             v2,
+		Hybrid:         hybrid,
+	})
+	if err != nil {
+		t.Fatalf("MakeTorrent(v2=%t, hybrid=%t) error: %v", v2, hybrid, err)
+	}
+	contents, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tinfo, err := ParseTorrent(contents)
+	if err != nil {
+		t.Fatalf("ParseTorrent error: %v", err)
+	}
+	return tinfo
+}
+
+func TestV2Torrent(t *testing.T) {
+	contentPath := makeTestContents(t)
+	v1 := makeTestTorrent(t, contentPath, false, false)
+	var tinfos []*TorrentMeta
+	for _, c := range []struct {
+		v2      bool
+		hybrid  bool
+		version string
+	}{
+		{false, false, TORRENT_VERSION_V1},
+		{true, false, TORRENT_VERSION_V2},
+		{false, true, TORRENT_VERSION_HYBRID},
+	} {
+		tinfo := makeTestTorrent(t, contentPath, c.v2, c.hybrid)
+		tinfos = append(tinfos, tinfo)
+		if tinfo.Version() != c.version {
+			t.Errorf("Version() = %s, want %s", tinfo.Version(), c.version)
+		}
+		if len(tinfo.Files) != 5 || tinfo.Size != v1.Size || tinfo.RootDir != "contents" {
+			t.Errorf("%s: unexpected files: %d files, size %d, root %q", c.version, len(tinfo.Files), tinfo.Size,
+				tinfo.RootDir)
+		}
+		// v2 "file tree" is ordered by path components, which may differ from v1 order
+		for _, file := range tinfo.Files {
+			if !slices.ContainsFunc(v1.Files, func(f *TorrentMetaFile) bool {
+				return f.Path == file.Path && f.Size == file.Size
+			}) {
+				t.Errorf("%s: unexpected file %q (%d)", c.version, file.Path, file.Size)
+			}
+		}
+		if c.version == TORRENT_VERSION_V1 {
+			if tinfo.InfoHashV2 != "" || tinfo.InfoHash != v1.InfoHash {
+				t.Errorf("v1: unexpected info-hash: %s, %s", tinfo.InfoHash, tinfo.InfoHashV2)
+			}
+		} else {
+			if len(tinfo.InfoHashV2) != 64 || !tinfo.MatchInfoHash(tinfo.InfoHashV2) ||
+				!tinfo.MatchInfoHash(tinfo.InfoHashV2[:40]) {
+				t.Errorf("%s: unexpected v2 info-hash: %s", c.version, tinfo.InfoHashV2)
+			}
+			if c.version == TORRENT_VERSION_V2 && tinfo.InfoHash != tinfo.InfoHashV2[:40] {
+				t.Errorf("v2: InfoHash = %s, want truncated v2 info-hash", tinfo.InfoHash)
+			}
+			if err := metainfo.ValidatePieceLayers(tinfo.MetaInfo.PieceLayers, &tinfo.Info.FileTree,
+				tinfo.Info.PieceLength); err != nil {
+				t.Errorf("%s: invalid piece layers: %v", c.version, err)
+			}
+		}
+		if _, err := tinfo.Verify("", contentPath, 99, 0); err != nil {
+			t.Errorf("%s: Verify() error: %v", c.version, err)
+		}
+		if c.version == TORRENT_VERSION_HYBRID {
+			// check v1 part of hybrid torrent, which contains padding files
+			info := *tinfo.Info
+			info.MetaVersion = 0
+			info.FileTree = metainfo.FileTree{}
+			v1Part := *tinfo
+			v1Part.Info = &info
+			if _, err := v1Part.Verify("", contentPath, 99, 0); err != nil {
+				t.Errorf("hybrid: v1 Verify() error: %v", err)
+			}
+		}
+	}
+
+	// single file torrents
+	for _, hybrid := range []bool{false, true} {
+		filename := filepath.Join(contentPath, "a.bin")
+		tinfo := makeTestTorrent(t, filename, !hybrid, hybrid)
+		tinfos = append(tinfos, tinfo)
+		if !tinfo.SingleFileTorrent || len(tinfo.Files) != 1 || tinfo.Files[0].Path != "a.bin" || tinfo.Size != 40000 {
+			t.Errorf("%s: unexpected single file torrent: %v", tinfo.Version(), tinfo.Files)
+		}
+		if _, err := tinfo.Verify("", filename, 99, 0); err != nil {
+			t.Errorf("%s: single file Verify() error: %v", tinfo.Version(), err)
+		}
+	}
+
+	// corrupt a byte in the middle of a multi-piece file
+	filename := filepath.Join(contentPath, "a.bin")
+	data, _ := os.ReadFile(filename)
+	data[20000]++
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tinfo := range tinfos {
+		path := contentPath
+		if tinfo.SingleFileTorrent {
+			path = filename
+		}
+		if _, err := tinfo.Verify("", path, 99, 0); err == nil {
+			t.Errorf("%s: Verify() of corrupted contents should fail", tinfo.Version())
+		}
+	}
+}
@@ -0,0 +1,574 @@
+package torrentutil
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/anacrolix/torrent/merkle"
+	"github.com/anacrolix/torrent/metainfo"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/util"
+)
+
+// Status of a content file in verification report.
+const (
+	VERIFY_FILE_OK         = "ok"
+	VERIFY_FILE_MISSING    = "missing"
+	VERIFY_FILE_WRONG_SIZE = "wrong_size"
+	VERIFY_FILE_BAD        = "bad"     // has bad pieces
+	VERIFY_FILE_PARTIAL    = "partial" // no bad pieces, but pieces overlapping with other damaged files are not checked
+)
+
+type VerifyOptions struct {
+	SavePath    string
+	ContentPath string
+	// 0 - none; 1 - quick; 2+ - full.
+	CheckHash int64
+	// Used with quick hash mode; if > 0, at least this size of file head & tail must be checked.
+	CheckMinLength int64
+	// Number of concurrent hashing workers. <= 0: number of CPUs.
+	// Each worker holds a buffer of one piece length, so the memory used is bounded by workers * piece length.
+	Workers int
+	// Stop at the first failure.
+	FailFast bool
+	// If not nil, it's called after each piece is checked, with bytes checked and total bytes to check.
+	// It's always called from the goroutine which calls VerifyDetailed.
+	Progress func(checked, total int64)
+}
+
+// A byte range [Start, End) of file.
+type ByteRange struct {
+	Start int64
+	End   int64
+}
+
+type VerifyFileResult struct {
+	Path       string // path in torrent
+	Filename   string // local file path
+	Size       int64  // size in torrent
+	ActualSize int64  // size of local file. -1 if it does not exist
+	Status     string // VERIFY_FILE_*
+	Error      string
+	BadPieces  []int64      // indexes of bad pieces that overlap with this file
+	BadRanges  []*ByteRange // byte ranges of this file that belong to bad pieces, sorted and merged
+	// indexes of pieces that overlap with this file but are not checked, as they overlap with missing or wrong size files
+	UncheckedPieces []int64
+}
+
+type VerifyReport struct {
+	InfoHash      string
+	ContentPath   string
+	Version       string
+	CheckHash     int64
+	Ok            bool
+	Error         string // the first error
+	Ts            int64  // timestamp of newest file in torrent contents
+	Files         []*VerifyFileResult
+	PiecesTotal   int64 // number of pieces to check
+	PiecesChecked int64
+	PiecesBad     int64
+	BytesChecked  int64
+	err           error
+}
+
+func (r *VerifyReport) fail(err error) {
+	if r.err == nil {
+		r.err = err
+		r.Error = err.Error()
+	}
+}
+
+// Return the first error found during verification. nil if torrent contents are good.
+func (r *VerifyReport) Err() error {
+	return r.err
+}
+
+// Return the results of files which are damaged. Partially checked files are not included.
+func (r *VerifyReport) BadFiles() []*VerifyFileResult {
+	var files []*VerifyFileResult
+	for _, file := range r.Files {
+		if file.Status != VERIFY_FILE_OK && file.Status != VERIFY_FILE_PARTIAL {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// A piece (or part of a piece) to be hash checked.
+type verifyPiece struct {
+	index    int64 // index of piece in torrent
+	segments []pieceSegment
+	length   int64
+	hash     []byte // expected hash
+	v2       bool   // hash is SHA-256 Merkle root of piece blocks, padded to piece length
+	v2Root   bool   // hash is "pieces root" of a file not larger than piece length
+}
+
+// A segment of file in a piece.
+type pieceSegment struct {
+	file   int // index of file. -1: padding file (zeros)
+	offset int64
+	length int64
+}
+
+type verifyResult struct {
+	index int // index of verifyPiece
+	good  bool
+	err   error
+}
+
+// Return the local paths of meta.Files, and the absolute contentPath (if provided).
+func (meta *TorrentMeta) contentFilenames(savePath, contentPath string) (filenames []string, absContentPath string,
+	err error) {
+	prefixPath := ""
+	if contentPath != "" {
+		if contentPath, err = filepath.Abs(contentPath); err != nil {
+			return nil, "", fmt.Errorf("invalid content-path: %w", err)
+		}
+		prefixPath = contentPath + "/"
+	} else {
+		prefixPath = savePath + "/"
+		if meta.RootDir != "" {
+			prefixPath += meta.RootDir + "/"
+		}
+	}
+	for _, file := range meta.Files {
+		if contentPath != "" && meta.SingleFileTorrent {
+			filenames = append(filenames, contentPath)
+		} else {
+			filenames = append(filenames, prefixPath+file.Path)
+		}
+	}
+	return filenames, contentPath, nil
+}
+
+// Check the name of contentPath on disk matches with torrent root folder or single file name.
+func (meta *TorrentMeta) checkContentPathName(contentPath string) error {
+	fileStats, err := os.Stat(contentPath)
+	if err != nil {
+		return nil
+	}
+	if meta.SingleFileTorrent {
+		if fileStats.Name() != meta.Files[0].Path {
+			return ErrDifferentName
+		}
+	} else if fileStats.Name() != meta.RootDir {
+		return ErrDifferentRootName
+	}
+	return nil
+}
+
+// Verify torrent against local disk contents and return a detailed report of each file.
+// Pieces are hash checked in parallel by a pool of workers.
+// The returned error is only for failures that make verification impossible (e.g. invalid torrent);
+// contents problems are reported in report, use report.Err() to get the first one.
+func (meta *TorrentMeta) VerifyDetailed(options *VerifyOptions) (report *VerifyReport, err error) {
+	filenames, contentPath, err := meta.contentFilenames(options.SavePath, options.ContentPath)
+	if err != nil {
+		return nil, err
+	}
+	report = &VerifyReport{
+		InfoHash:    meta.InfoHash,
+		ContentPath: meta.ContentPath,
+		Version:     meta.Version(),
+		CheckHash:   options.CheckHash,
+	}
+	for i, file := range meta.Files {
+		result := &VerifyFileResult{
+			Path:       file.Path,
+			Filename:   filenames[i],
+			Size:       file.Size,
+			ActualSize: -1,
+			Status:     VERIFY_FILE_OK,
+		}
+		if stat, err := os.Stat(filenames[i]); err != nil {
+			result.Status = VERIFY_FILE_MISSING
+			result.Error = err.Error()
+			report.fail(fmt.Errorf("failed to get file %q stat: %w", file.Path, err))
+		} else {
+			report.Ts = max(stat.ModTime().Unix(), report.Ts)
+			result.ActualSize = stat.Size()
+			if stat.Size() != file.Size {
+				result.Status = VERIFY_FILE_WRONG_SIZE
+				result.Error = fmt.Sprintf("wrong length: expect=%d, actual=%d", file.Size, stat.Size())
+				report.fail(fmt.Errorf("file %q has wrong length: expect=%d, actual=%d", file.Path, file.Size, stat.Size()))
+			}
+		}
+		report.Files = append(report.Files, result)
+	}
+	if options.CheckHash > 0 && len(meta.Files) > 0 && (report.err == nil || !options.FailFast) {
+		quick := options.CheckHash == 1
+		// At least 1 piece length of each file's head & tail must be checked
+		checkMinLength := max(meta.Info.PieceLength, options.CheckMinLength)
+		var pieces []*verifyPiece
+		if meta.Info.HasV2() {
+			if pieces, err = meta.v2VerifyPieces(quick, checkMinLength); err != nil {
+				return nil, err
+			}
+		} else {
+			pieces = meta.v1VerifyPieces(quick, checkMinLength)
+		}
+		// pieces overlapping with missing or wrong size files can not be checked,
+		// other files in these pieces are only partially checked
+		pieces = slices.DeleteFunc(pieces, func(piece *verifyPiece) bool {
+			if !slices.ContainsFunc(piece.segments, func(segment pieceSegment) bool {
+				return segment.file >= 0 && (report.Files[segment.file].Status == VERIFY_FILE_MISSING ||
+					report.Files[segment.file].Status == VERIFY_FILE_WRONG_SIZE)
+			}) {
+				return false
+			}
+			for _, segment := range piece.segments {
+				if segment.file < 0 {
+					continue
+				}
+				file := report.Files[segment.file]
+				if file.Status == VERIFY_FILE_OK || file.Status == VERIFY_FILE_PARTIAL {
+					file.Status = VERIFY_FILE_PARTIAL
+					file.UncheckedPieces = append(file.UncheckedPieces, piece.index)
+				}
+			}
+			return true
+		})
+		meta.checkPieces(report, pieces, filenames, options)
+	}
+	if contentPath != "" {
+		if err := meta.checkContentPathName(contentPath); err != nil {
+			report.fail(err)
+		}
+	}
+	report.Ok = report.err == nil
+	return report, nil
+}
+
+func (meta *TorrentMeta) checkPieces(report *VerifyReport, pieces []*verifyPiece, filenames []string,
+	options *VerifyOptions) {
+	workers := options.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	workers = max(min(workers, len(pieces)), 1)
+	total := int64(0)
+	for _, piece := range pieces {
+		total += piece.length
+	}
+	report.PiecesTotal = int64(len(pieces))
+	piecesCnt := meta.Info.NumPieces()
+	tasks := make(chan int)
+	results := make(chan verifyResult)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker := &verifyWorker{filenames: filenames, buf: make([]byte, meta.Info.PieceLength),
+				pieceLength: meta.Info.PieceLength}
+			defer worker.close()
+			for i := range tasks {
+				good, err := worker.check(pieces[i])
+				results <- verifyResult{index: i, good: good, err: err}
+			}
+		}()
+	}
+	go func() {
+		defer close(tasks)
+		for i := range pieces {
+			select {
+			case tasks <- i:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	stopped := false
+	for result := range results {
+		piece := pieces[result.index]
+		report.PiecesChecked++
+		report.BytesChecked += piece.length
+		log.Tracef("piece %d/%d verify-hash: %t (err=%v)", piece.index, piecesCnt-1, result.good, result.err)
+		if !result.good {
+			report.PiecesBad++
+			if result.err != nil {
+				report.fail(fmt.Errorf("piece %d/%d: %w", piece.index, piecesCnt-1, result.err))
+			} else {
+				report.fail(fmt.Errorf("piece %d/%d: hash mismatch", piece.index, piecesCnt-1))
+			}
+			for _, segment := range piece.segments {
+				if segment.file < 0 {
+					continue
+				}
+				file := report.Files[segment.file]
+				file.Status = VERIFY_FILE_BAD
+				if result.err != nil && file.Error == "" {
+					file.Error = result.err.Error()
+				}
+				file.BadPieces = append(file.BadPieces, piece.index)
+				file.BadRanges = append(file.BadRanges,
+					&ByteRange{Start: segment.offset, End: segment.offset + segment.length})
+			}
+			if options.FailFast && !stopped {
+				stopped = true
+				close(stop)
+			}
+		}
+		if options.Progress != nil {
+			options.Progress(report.BytesChecked, total)
+		}
+	}
+	for _, file := range report.Files {
+		if file.Status != VERIFY_FILE_BAD {
+			continue
+		}
+		slices.Sort(file.BadPieces)
+		file.BadRanges = mergeByteRanges(file.BadRanges)
+	}
+}
+
+func mergeByteRanges(ranges []*ByteRange) (merged []*ByteRange) {
+	slices.SortFunc(ranges, func(a, b *ByteRange) int {
+		return cmp.Compare(a.Start, b.Start)
+	})
+	for _, r := range ranges {
+		if len(merged) > 0 && merged[len(merged)-1].End >= r.Start {
+			merged[len(merged)-1].End = max(merged[len(merged)-1].End, r.End)
+		} else {
+			merged = append(merged, r)
+		}
+	}
+	return merged
+}
+
+// Pieces of v1 torrent to check. Padding files are treated as zeros.
+func (meta *TorrentMeta) v1VerifyPieces(quick bool, checkMinLength int64) (pieces []*verifyPiece) {
+	info := meta.Info
+	pieceLength := info.PieceLength
+	// all files of v1 info, including padding files
+	type v1File struct {
+		file int // index of meta.Files; -1: padding file
+		size int64
+	}
+	var files []v1File
+	if len(info.Files) == 0 {
+		files = append(files, v1File{0, info.Length})
+	} else {
+		j := 0
+		for _, file := range info.Files {
+			if isPadFile(&file) {
+				files = append(files, v1File{-1, file.Length})
+			} else {
+				files = append(files, v1File{j, file.Length})
+				j++
+			}
+		}
+	}
+	piecesCnt := int64(info.NumPieces())
+	var selected []bool
+	if quick {
+		// only check the head & tail pieces of each file
+		selected = make([]bool, piecesCnt)
+		offset := int64(0)
+		for _, file := range files {
+			start, end := offset, offset+file.size
+			offset = end
+			if file.file < 0 || file.size == 0 {
+				continue
+			}
+			for i := start / pieceLength; i <= (min(start+checkMinLength, end)-1)/pieceLength; i++ {
+				selected[i] = true
+			}
+			for i := max(end-checkMinLength, start) / pieceLength; i <= (end-1)/pieceLength; i++ {
+				selected[i] = true
+			}
+		}
+	}
+	fileIndex, fileOffset := 0, int64(0)
+	for i := int64(0); i < piecesCnt; i++ {
+		piece := &verifyPiece{index: i, hash: info.Pieces[i*sha1.Size : (i+1)*sha1.Size]}
+		for piece.length < pieceLength && fileIndex < len(files) {
+			n := min(files[fileIndex].size-fileOffset, pieceLength-piece.length)
+			if n > 0 {
+				piece.segments = append(piece.segments, pieceSegment{
+					file:   files[fileIndex].file,
+					offset: fileOffset,
+					length: n,
+				})
+			}
+			piece.length += n
+			if fileOffset += n; fileOffset == files[fileIndex].size {
+				fileIndex++
+				fileOffset = 0
+			}
+		}
+		if !quick || selected[i] {
+			pieces = append(pieces, piece)
+		}
+	}
+	return pieces
+}
+
+// Pieces of v2 (or hybrid) torrent to check. Each file is checked against v2 hashes independently:
+// the "piece layers" for file larger than piece length, otherwise the "pieces root" of file.
+func (meta *TorrentMeta) v2VerifyPieces(quick bool, checkMinLength int64) (pieces []*verifyPiece, err error) {
+	info := meta.Info
+	pieceLength := info.PieceLength
+	if err := metainfo.ValidatePieceLayers(meta.MetaInfo.PieceLayers, &info.FileTree, pieceLength); err != nil {
+		return nil, fmt.Errorf("invalid piece layers: %w", err)
+	}
+	piecesRoots := map[string][]byte{}
+	for _, file := range info.UpvertedFiles() {
+		if file.PiecesRoot.Ok {
+			piecesRoots[strings.Join(file.Path, "/")] = file.PiecesRoot.Value[:]
+		}
+	}
+	for i, file := range meta.Files {
+		if file.Size == 0 {
+			continue
+		}
+		path := file.Path
+		if meta.SingleFileTorrent {
+			path = info.Name
+		}
+		piecesRoot := piecesRoots[path]
+		if piecesRoot == nil {
+			return nil, fmt.Errorf("file %q: no pieces root", file.Path)
+		}
+		if file.Size <= pieceLength {
+			pieces = append(pieces, &verifyPiece{
+				index:    file.StartPieceIndex,
+				segments: []pieceSegment{{file: i, offset: 0, length: file.Size}},
+				length:   file.Size,
+				hash:     piecesRoot,
+				v2Root:   true,
+			})
+			continue
+		}
+		layer := meta.MetaInfo.PieceLayers[string(piecesRoot)]
+		for k := int64(0); k*pieceLength < file.Size; k++ {
+			offset := k * pieceLength
+			if quick && offset >= checkMinLength && file.Size-offset > checkMinLength {
+				continue
+			}
+			length := min(pieceLength, file.Size-offset)
+			pieces = append(pieces, &verifyPiece{
+				index:    file.StartPieceIndex + k,
+				segments: []pieceSegment{{file: i, offset: offset, length: length}},
+				length:   length,
+				hash:     []byte(layer[k*sha256.Size : (k+1)*sha256.Size]),
+				v2:       true,
+			})
+		}
+	}
+	return pieces, nil
+}
+
+type verifyWorker struct {
+	filenames   []string
+	buf         []byte
+	pieceLength int64
+	file        *os.File // current opened file
+	fileIndex   int
+}
+
+func (w *verifyWorker) open(index int) (*os.File, error) {
+	if w.file != nil && w.fileIndex == index {
+		return w.file, nil
+	}
+	w.close()
+	file, err := os.Open(w.filenames[index])
+	if err != nil {
+		return nil, err
+	}
+	w.file = file
+	w.fileIndex = index
+	return file, nil
+}
+
+func (w *verifyWorker) close() {
+	if w.file != nil {
+		w.file.Close()
+		w.file = nil
+	}
+}
+
+func (w *verifyWorker) check(piece *verifyPiece) (good bool, err error) {
+	buf := w.buf[:0]
+	for _, segment := range piece.segments {
+		n := int64(len(buf))
+		buf = buf[:n+segment.length]
+		if segment.file < 0 {
+			clear(buf[n:])
+			continue
+		}
+		file, err := w.open(segment.file)
+		if err != nil {
+			return false, err
+		}
+		if _, err := file.ReadAt(buf[n:], segment.offset); err != nil {
+			return false, fmt.Errorf("failed to read %s: %w", w.filenames[segment.file], err)
+		}
+	}
+	var sum []byte
+	switch {
+	case piece.v2Root:
+		hash := merkle.NewHash()
+		hash.Write(buf)
+		sum = hash.Sum(nil)
+	case piece.v2:
+		hash := merkle.NewHash()
+		hash.Write(buf)
+		sum = hash.SumMinLength(nil, int(w.pieceLength))
+	default:
+		hash := sha1.Sum(buf)
+		sum = hash[:]
+	}
+	return bytes.Equal(sum, piece.hash), nil
+}
+
+// Display the status of content files. If all is false, only display files which are not ok.
+func (r *VerifyReport) FprintFiles(f io.Writer, all bool) {
+	for _, file := range r.Files {
+		switch file.Status {
+		case VERIFY_FILE_OK:
+			if all {
+				fmt.Fprintf(f, "  ✓ %s\n", file.Path)
+			}
+		case VERIFY_FILE_PARTIAL:
+			fmt.Fprintf(f, "  ? %s: partial, %d pieces overlapping with damaged files are not checked\n", file.Path,
+				len(file.UncheckedPieces))
+		case VERIFY_FILE_BAD:
+			var ranges []string
+			badSize := int64(0)
+			for _, r := range file.BadRanges {
+				ranges = append(ranges, fmt.Sprintf("%d-%d", r.Start, r.End-1))
+				badSize += r.End - r.Start
+			}
+			errorStr := ""
+			if file.Error != "" {
+				errorStr = fmt.Sprintf(" (%s)", file.Error)
+			}
+			fmt.Fprintf(f, "  X %s: %d bad pieces (%s), bad bytes: %s%s\n", file.Path, len(file.BadPieces),
+				util.BytesSize(float64(badSize)), strings.Join(ranges, ", "), errorStr)
+		default:
+			fmt.Fprintf(f, "  X %s: %s (%s)\n", file.Path, file.Status, file.Error)
+		}
+	}
+	if r.PiecesTotal > 0 {
+		fmt.Fprintf(f, "  Checked %d/%d pieces (%s), %d bad\n", r.PiecesChecked, r.PiecesTotal,
+			util.BytesSize(float64(r.BytesChecked)), r.PiecesBad)
+	}
+}
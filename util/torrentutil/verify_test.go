@@ -0,0 +1,73 @@
+package torrentutil
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestVerifyDetailed(t *testing.T) {
+	contentPath := makeTestContents(t)
+	v1 := makeTestTorrent(t, contentPath, false, false)
+	v2 := makeTestTorrent(t, contentPath, true, false)
+
+	for _, tinfo := range []*TorrentMeta{v1, v2} {
+		report, err := tinfo.VerifyDetailed(&VerifyOptions{ContentPath: contentPath, CheckHash: 99, Workers: 4})
+		if err != nil || !report.Ok || len(report.BadFiles()) != 0 || report.PiecesChecked != report.PiecesTotal {
+			t.Fatalf("%s: VerifyDetailed() of good contents = %+v, %v", tinfo.Version(), report, err)
+		}
+	}
+
+	// corrupt the second piece of a.bin, and delete sub/b.bin
+	filename := filepath.Join(contentPath, "a.bin")
+	data, _ := os.ReadFile(filename)
+	data[20000]++
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(contentPath, "sub", "b.bin")); err != nil {
+		t.Fatal(err)
+	}
+	for _, tinfo := range []*TorrentMeta{v1, v2} {
+		report, err := tinfo.VerifyDetailed(&VerifyOptions{ContentPath: contentPath, CheckHash: 99, Workers: 4})
+		if err != nil {
+			t.Fatalf("%s: VerifyDetailed() error: %v", tinfo.Version(), err)
+		}
+		if report.Ok || report.Err() == nil || report.PiecesBad != 1 {
+			t.Errorf("%s: unexpected report: ok=%t, err=%v, bad pieces=%d", tinfo.Version(), report.Ok, report.Err(),
+				report.PiecesBad)
+		}
+		files := map[string]*VerifyFileResult{}
+		for _, file := range report.Files {
+			files[file.Path] = file
+		}
+		if file := files["a.bin"]; file.Status != VERIFY_FILE_BAD || !slices.Equal(file.BadPieces, []int64{1}) ||
+			len(file.BadRanges) != 1 || *file.BadRanges[0] != (ByteRange{16384, 32768}) {
+			t.Errorf("%s: unexpected a.bin result: %+v", tinfo.Version(), file)
+		}
+		if file := files["sub/b.bin"]; file.Status != VERIFY_FILE_MISSING || file.ActualSize != -1 {
+			t.Errorf("%s: unexpected sub/b.bin result: %+v", tinfo.Version(), file)
+		}
+		// v1: piece 3 is shared with the missing sub/b.bin, so it's not checked. v2: files are checked independently
+		if file := files["sub/c d.bin"]; tinfo == v1 && (file.Status != VERIFY_FILE_PARTIAL ||
+			!slices.Equal(file.UncheckedPieces, []int64{3})) || tinfo == v2 && file.Status != VERIFY_FILE_OK {
+			t.Errorf("%s: unexpected sub/c d.bin result: %+v", tinfo.Version(), file)
+		}
+		if len(report.BadFiles()) != 2 {
+			t.Errorf("%s: BadFiles() = %d files, want 2", tinfo.Version(), len(report.BadFiles()))
+		}
+		// fail fast: stops after stat
+		if report, _ = tinfo.VerifyDetailed(&VerifyOptions{ContentPath: contentPath, CheckHash: 99,
+			FailFast: true}); report.PiecesChecked != 0 {
+			t.Errorf("%s: fail fast checked %d pieces", tinfo.Version(), report.PiecesChecked)
+		}
+	}
+}
+
+func TestMergeByteRanges(t *testing.T) {
+	ranges := mergeByteRanges([]*ByteRange{{32, 48}, {0, 16}, {16, 32}, {64, 80}})
+	if len(ranges) != 2 || *ranges[0] != (ByteRange{0, 48}) || *ranges[1] != (ByteRange{64, 80}) {
+		t.Errorf("mergeByteRanges() = %v", ranges)
+	}
+}